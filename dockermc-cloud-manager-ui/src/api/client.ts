@@ -3,12 +3,14 @@ import type {
   MinecraftServer,
   CreateServerRequest,
 } from '../schemas/minecraft-server';
+import type { Operation } from '../schemas/operation';
 import type {
   ProxyServer,
   UpdateProxyRequest,
 } from '../schemas/proxy';
 import {
   minecraftServerSchema,
+  operationSchema,
   proxyServerSchema,
 } from '../schemas';
 
@@ -75,10 +77,10 @@ class ApiClient {
     );
   }
 
-  async createServer(request: CreateServerRequest): Promise<MinecraftServer> {
-    return this.fetchJson<MinecraftServer>(
+  async createServer(request: CreateServerRequest): Promise<Operation> {
+    return this.fetchJson<Operation>(
       '/api/v1/servers',
-      minecraftServerSchema,
+      operationSchema,
       {
         method: 'POST',
         body: JSON.stringify(request),
@@ -86,32 +88,40 @@ class ApiClient {
     );
   }
 
-  async deleteServer(id: string): Promise<void> {
-    return this.fetchJson<void>(`/api/v1/servers/${id}`, undefined, {
+  async deleteServer(id: string): Promise<Operation> {
+    return this.fetchJson<Operation>(`/api/v1/servers/${id}`, operationSchema, {
       method: 'DELETE',
     });
   }
 
-  async startServer(id: string): Promise<{ status: string }> {
-    return this.fetchJson<{ status: string }>(
+  async startServer(id: string): Promise<Operation> {
+    return this.fetchJson<Operation>(
       `/api/v1/servers/${id}/start`,
-      z.object({ status: z.string() }),
+      operationSchema,
       {
         method: 'POST',
       }
     );
   }
 
-  async stopServer(id: string): Promise<{ status: string }> {
-    return this.fetchJson<{ status: string }>(
+  async stopServer(id: string): Promise<Operation> {
+    return this.fetchJson<Operation>(
       `/api/v1/servers/${id}/stop`,
-      z.object({ status: z.string() }),
+      operationSchema,
       {
         method: 'POST',
       }
     );
   }
 
+  // Operations
+  async getOperation(id: string): Promise<Operation> {
+    return this.fetchJson<Operation>(
+      `/api/v1/operations/${id}`,
+      operationSchema
+    );
+  }
+
   // Proxy
   async getProxy(): Promise<ProxyServer> {
     return this.fetchJson<ProxyServer>(
@@ -3,14 +3,20 @@ import { Badge } from "./ui/badge";
 
 function getStatusColor(status: ContainerStatus) {
   switch (status) {
+    case 'starting':
+      return 'bg-cyan-500';
     case 'running':
       return 'bg-green-500';
+    case 'stopping':
+      return 'bg-yellow-500';
     case 'stopped':
       return 'bg-gray-500';
     case 'creating':
       return 'bg-blue-500';
     case 'error':
       return 'bg-red-500';
+    case 'crash_loop':
+      return 'bg-orange-500';
     default:
       return 'bg-gray-500';
   }
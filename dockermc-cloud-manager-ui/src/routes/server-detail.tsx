@@ -39,7 +39,7 @@ export function ServerDetailPage() {
   }
 
   const handleStartStop = () => {
-    if (server.status === 'running') {
+    if (server.status === 'running' || server.status === 'starting') {
       stopServer.mutate(server.id);
     } else if (server.status === 'stopped') {
       startServer.mutate(server.id);
@@ -101,10 +101,10 @@ export function ServerDetailPage() {
 
             <Button
               onClick={handleStartStop}
-              disabled={server.status === 'creating' || server.status === 'error' || startServer.isPending || stopServer.isPending}
+              disabled={server.status === 'creating' || server.status === 'stopping' || server.status === 'error' || startServer.isPending || stopServer.isPending}
               className="w-full"
             >
-              {server.status === 'running' ? (
+              {server.status === 'running' || server.status === 'starting' ? (
                 <>
                   <Square className="mr-2 h-4 w-4" />
                   Stop Server
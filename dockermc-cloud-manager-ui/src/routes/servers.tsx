@@ -13,14 +13,20 @@ import type { MinecraftServer } from '@/schemas';
 
 function getStatusColor(status: MinecraftServer['status']) {
   switch (status) {
+    case 'starting':
+      return 'bg-cyan-500';
     case 'running':
       return 'bg-green-500';
+    case 'stopping':
+      return 'bg-yellow-500';
     case 'stopped':
       return 'bg-gray-500';
     case 'creating':
       return 'bg-blue-500';
     case 'error':
       return 'bg-red-500';
+    case 'crash_loop':
+      return 'bg-orange-500';
     default:
       return 'bg-gray-500';
   }
@@ -34,7 +40,7 @@ export function ServersPage() {
   const deleteServer = useDeleteServer();
 
   const handleStartStop = (server: MinecraftServer) => {
-    if (server.status === 'running') {
+    if (server.status === 'running' || server.status === 'starting') {
       stopServer.mutate(server.id);
     } else if (server.status === 'stopped') {
       startServer.mutate(server.id);
@@ -118,10 +124,10 @@ export function ServersPage() {
                 variant="outline"
                 size="sm"
                 onClick={() => handleStartStop(server)}
-                disabled={server.status === 'creating' || server.status === 'error' || startServer.isPending || stopServer.isPending}
+                disabled={server.status === 'creating' || server.status === 'stopping' || server.status === 'error' || startServer.isPending || stopServer.isPending}
                 className="flex-1"
               >
-                {server.status === 'running' ? (
+                {server.status === 'running' || server.status === 'starting' ? (
                   <>
                     <Square className="mr-2 h-4 w-4" />
                     Stop
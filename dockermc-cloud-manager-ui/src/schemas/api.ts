@@ -3,7 +3,7 @@ import { z } from 'zod';
 /**
  * Container status enum - represents the current state of a Docker container
  */
-export const containerStatusSchema = z.enum(['creating', 'running', 'stopped', 'error']);
+export const containerStatusSchema = z.enum(['creating', 'starting', 'running', 'stopping', 'stopped', 'error', 'crash_loop']);
 export type ContainerStatus = z.infer<typeof containerStatusSchema>;
 
 /**
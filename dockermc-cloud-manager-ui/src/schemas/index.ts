@@ -6,4 +6,5 @@
 // Re-export all schemas and types
 export * from './api';
 export * from './minecraft-server';
+export * from './operation';
 export * from './proxy';
@@ -1,6 +1,22 @@
 import { z } from 'zod';
 import { containerStatusSchema } from './api';
 
+/**
+ * Server List Ping schema - the last ping of a starting or running server
+ */
+export const serverPingSchema = z.object({
+  ready: z.boolean(),
+  online_players: z.number().int(),
+  max_players: z.number().int(),
+  version: z.string().optional(),
+  protocol: z.number().int().optional(),
+  motd: z.string().optional(),
+  latency_ms: z.number().int(),
+  checked_at: z.string(),
+  error: z.string().optional(),
+});
+export type ServerPing = z.infer<typeof serverPingSchema>;
+
 /**
  * Minecraft Server entity schema - represents a running Minecraft server instance
  */
@@ -13,6 +29,7 @@ export const minecraftServerSchema = z.object({
   port: z.number(),
   max_players: z.number().int(),
   motd: z.string(),
+  ping: serverPingSchema.optional(),
   created_at: z.string(),
   updated_at: z.string(),
 });
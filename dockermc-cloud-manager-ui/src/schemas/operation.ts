@@ -0,0 +1,36 @@
+import { z } from 'zod';
+
+/**
+ * Operation status enum - state of a background operation or one of its steps
+ */
+export const operationStatusSchema = z.enum(['pending', 'running', 'succeeded', 'failed']);
+export type OperationStatus = z.infer<typeof operationStatusSchema>;
+
+/**
+ * Operation step schema - a single step of a background operation
+ */
+export const operationStepSchema = z.object({
+  name: z.string(),
+  status: operationStatusSchema,
+  started_at: z.string(),
+  finished_at: z.string().optional(),
+});
+export type OperationStep = z.infer<typeof operationStepSchema>;
+
+/**
+ * Operation schema - tracks a long running server operation (create, start, stop, delete)
+ */
+export const operationSchema = z.object({
+  id: z.uuid(),
+  type: z.string(),
+  server_id: z.string().optional(),
+  status: operationStatusSchema,
+  progress: z.number().int(),
+  steps: z.array(operationStepSchema),
+  result: z.unknown().optional(),
+  error: z.string().optional(),
+  created_at: z.string(),
+  updated_at: z.string(),
+  finished_at: z.string().optional(),
+});
+export type Operation = z.infer<typeof operationSchema>;
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/service"
+)
+
+// BackupHandler handles HTTP requests for server backups
+type BackupHandler struct {
+	backupService *service.BackupService
+	operations    *service.OperationService
+	logger        *slog.Logger
+}
+
+// NewBackupHandler creates a new BackupHandler
+func NewBackupHandler(backupService *service.BackupService, operations *service.OperationService, logger *slog.Logger) *BackupHandler {
+	return &BackupHandler{
+		backupService: backupService,
+		operations:    operations,
+		logger:        logger,
+	}
+}
+
+// CreateBackup handles POST /api/v1/servers/{id}/backups
+func (h *BackupHandler) CreateBackup(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	// The body is optional, an empty body creates a backup without saving the world first
+	var req models.CreateBackupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		h.logger.WarnContext(r.Context(), "Invalid request body for backup creation", "id", id, "error", err)
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Reject unknown servers and destinations right away instead of failing the operation later
+	if err := h.backupService.ValidateCreateBackupRequest(r.Context(), id, &req); err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "Creating backup", "id", id, "save_world", req.SaveWorld, "destination", req.Destination)
+
+	operation, err := h.operations.Run(r.Context(), models.OperationCreateBackup, id, func(ctx context.Context) (any, error) {
+		return h.backupService.CreateBackup(ctx, id, &req)
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondOperation(w, operation)
+}
+
+// ListDestinations handles GET /api/v1/backup-destinations
+func (h *BackupHandler) ListDestinations(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, h.backupService.ListDestinations())
+}
+
+// ListBackups handles GET /api/v1/servers/{id}/backups
+func (h *BackupHandler) ListBackups(w http.ResponseWriter, r *http.Request) {
+	backups, err := h.backupService.ListBackups(r.Context(), r.PathValue("id"))
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, backups)
+}
+
+// GetBackup handles GET /api/v1/servers/{id}/backups/{backupId}
+func (h *BackupHandler) GetBackup(w http.ResponseWriter, r *http.Request) {
+	backup, err := h.backupService.GetBackup(r.Context(), r.PathValue("id"), r.PathValue("backupId"))
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, backup)
+}
+
+// DownloadBackup handles GET /api/v1/servers/{id}/backups/{backupId}/download
+func (h *BackupHandler) DownloadBackup(w http.ResponseWriter, r *http.Request) {
+	backup, archive, err := h.backupService.OpenBackup(r.Context(), r.PathValue("id"), r.PathValue("backupId"))
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+	defer archive.Close()
+
+	// Large archives take longer than the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to clear write deadline for backup download", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(backup.FileName)))
+	w.Header().Set("X-Checksum-Sha256", backup.Checksum)
+	http.ServeContent(w, r, filepath.Base(backup.FileName), backup.CreatedAt, archive)
+}
+
+// RestoreBackup handles POST /api/v1/servers/{id}/backups/{backupId}/restore
+func (h *BackupHandler) RestoreBackup(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	backupID := r.PathValue("backupId")
+
+	if _, err := h.backupService.GetBackup(r.Context(), id, backupID); err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "Restoring backup", "id", id, "backup_id", backupID)
+
+	operation, err := h.operations.Run(r.Context(), models.OperationRestoreBackup, id, func(ctx context.Context) (any, error) {
+		return nil, h.backupService.RestoreBackup(ctx, id, backupID)
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondOperation(w, operation)
+}
+
+// DeleteBackup handles DELETE /api/v1/servers/{id}/backups/{backupId}
+func (h *BackupHandler) DeleteBackup(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	backupID := r.PathValue("backupId")
+
+	if err := h.backupService.DeleteBackup(r.Context(), id, backupID); err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to delete backup", "id", id, "backup_id", backupID, "error", err)
+		respondServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
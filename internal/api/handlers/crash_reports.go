@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/service"
+)
+
+// CrashReportHandler handles HTTP requests for the crash reports of servers
+type CrashReportHandler struct {
+	mcService *service.MinecraftServerService
+	logger    *slog.Logger
+}
+
+// NewCrashReportHandler creates a new CrashReportHandler
+func NewCrashReportHandler(mcService *service.MinecraftServerService, logger *slog.Logger) *CrashReportHandler {
+	return &CrashReportHandler{
+		mcService: mcService,
+		logger:    logger,
+	}
+}
+
+// ListCrashReports handles GET /api/v1/servers/{id}/crash-reports
+func (h *CrashReportHandler) ListCrashReports(w http.ResponseWriter, r *http.Request) {
+	reports, err := h.mcService.CrashReports(r.Context(), r.PathValue("id"))
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, reports)
+}
+
+// GetCrashReport handles GET /api/v1/servers/{id}/crash-reports/{reportId}
+func (h *CrashReportHandler) GetCrashReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.mcService.GetCrashReport(r.Context(), r.PathValue("id"), r.PathValue("reportId"))
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/service"
+)
+
+// eventsKeepAliveInterval is how often a comment is sent on idle streams so proxies don't close them
+const eventsKeepAliveInterval = 15 * time.Second
+
+// EventsHandler streams server and proxy events as Server-Sent Events
+type EventsHandler struct {
+	eventBus *service.EventBus
+	logger   *slog.Logger
+}
+
+// NewEventsHandler creates a new EventsHandler
+func NewEventsHandler(eventBus *service.EventBus, logger *slog.Logger) *EventsHandler {
+	return &EventsHandler{
+		eventBus: eventBus,
+		logger:   logger,
+	}
+}
+
+// StreamEvents streams events until the client disconnects.
+// Clients resume with the Last-Event-ID header (sent by EventSource automatically) or the cursor
+// query parameter. If the events after the cursor are no longer known, a resync event is sent first.
+// The optional server_id query parameter limits the stream to the events of one server.
+func (h *EventsHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	cursor := r.Header.Get("Last-Event-ID")
+	if cursor == "" {
+		cursor = r.URL.Query().Get("cursor")
+	}
+	serverID := r.URL.Query().Get("server_id")
+
+	// The stream outlives the server's write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.WarnContext(ctx, "Failed to clear write deadline for event stream", "error", err)
+	}
+
+	backlog, events, resumed, cancel := h.eventBus.Subscribe(cursor)
+	defer cancel()
+
+	h.logger.InfoContext(ctx, "Event stream opened", "cursor", cursor, "resumed", resumed, "server_id", serverID)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if !resumed {
+		resync := models.Event{
+			ID:        h.eventBus.Cursor(),
+			Type:      models.EventResync,
+			Timestamp: time.Now().UTC(),
+		}
+		if err := h.writeEvent(w, rc, resync); err != nil {
+			return
+		}
+	}
+
+	for _, event := range backlog {
+		if serverID != "" && event.ServerID != serverID {
+			continue
+		}
+		if err := h.writeEvent(w, rc, event); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		h.logger.ErrorContext(ctx, "Event stream does not support flushing", "error", err)
+		return
+	}
+
+	keepAlive := time.NewTicker(eventsKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			h.logger.InfoContext(ctx, "Event stream closed by client")
+			return
+		case event, ok := <-events:
+			if !ok {
+				// Dropped for lagging behind or shutting down, the client reconnects with its cursor
+				h.logger.InfoContext(ctx, "Event subscription ended")
+				return
+			}
+			if serverID != "" && event.ServerID != serverID {
+				continue
+			}
+			if err := h.writeEvent(w, rc, event); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeEvent writes an event in the Server-Sent Events format and flushes it to the client
+func (h *EventsHandler) writeEvent(w http.ResponseWriter, rc *http.ResponseController, event models.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		h.logger.Error("Failed to marshal event", "event_id", event.ID, "error", err)
+		return nil // Skip the event instead of ending the stream
+	}
+
+	if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/service"
+)
+
+// FileHandler handles HTTP requests for the files in server volumes
+type FileHandler struct {
+	fileService *service.FileService
+	logger      *slog.Logger
+}
+
+// NewFileHandler creates a new FileHandler
+func NewFileHandler(fileService *service.FileService, logger *slog.Logger) *FileHandler {
+	return &FileHandler{
+		fileService: fileService,
+		logger:      logger,
+	}
+}
+
+// filePath returns the volume path of a files request
+func filePath(r *http.Request) string {
+	return service.CleanVolumePath(r.PathValue("path"))
+}
+
+// clearDeadlines lifts the server's timeouts for transfers of large files
+func (h *FileHandler) clearDeadlines(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to clear read deadline for file transfer", "error", err)
+	}
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to clear write deadline for file transfer", "error", err)
+	}
+}
+
+// GetFile handles GET /api/v1/servers/{id}/files/{path...}.
+// Directories are listed as JSON, files are sent as they are.
+func (h *FileHandler) GetFile(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	p := filePath(r)
+
+	info, err := h.fileService.Stat(r.Context(), id, p)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	if info.IsDir {
+		listing, err := h.fileService.ListDirectory(r.Context(), id, p)
+		if err != nil {
+			respondServiceError(w, err)
+			return
+		}
+		respondJSON(w, http.StatusOK, listing)
+		return
+	}
+
+	content, info, err := h.fileService.OpenFile(r.Context(), id, p)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+	defer content.Close()
+
+	h.clearDeadlines(w, r)
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", info.Name))
+	w.Header().Set("Last-Modified", info.ModTime.Format(http.TimeFormat))
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, content); err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to send file", "id", id, "path", p, "error", err)
+	}
+}
+
+// PutFile handles PUT /api/v1/servers/{id}/files/{path...}.
+// The body is written to the file as it is, with ?type=directory a directory is created instead.
+func (h *FileHandler) PutFile(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	p := filePath(r)
+
+	if r.URL.Query().Get("type") == "directory" {
+		info, err := h.fileService.CreateDirectory(r.Context(), id, p)
+		if err != nil {
+			respondServiceError(w, err)
+			return
+		}
+		respondJSON(w, http.StatusCreated, info)
+		return
+	}
+
+	h.clearDeadlines(w, r)
+
+	info, err := h.fileService.WriteFile(r.Context(), id, p, r.Body, r.ContentLength)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, info)
+}
+
+// UploadFiles handles POST /api/v1/servers/{id}/files/{path...}.
+// Every file of the multipart form is written into the directory, named after its file name.
+func (h *FileHandler) UploadFiles(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	dir := filePath(r)
+
+	h.clearDeadlines(w, r)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Expected a multipart/form-data body")
+		return
+	}
+
+	uploaded := []*models.FileInfo{}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid multipart body")
+			return
+		}
+
+		// Only the base name counts, so a crafted file name can't leave the directory
+		fileName := path.Base(service.CleanVolumePath(part.FileName()))
+		if part.FileName() == "" || fileName == "/" {
+			continue
+		}
+
+		info, err := h.fileService.WriteFile(r.Context(), id, path.Join(dir, fileName), part, -1)
+		if err != nil {
+			respondServiceError(w, err)
+			return
+		}
+		uploaded = append(uploaded, info)
+	}
+
+	if len(uploaded) == 0 {
+		respondError(w, http.StatusBadRequest, "No files in multipart body")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, uploaded)
+}
+
+// MoveFile handles PATCH /api/v1/servers/{id}/files/{path...}
+func (h *FileHandler) MoveFile(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var req models.MoveFileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Path == "" {
+		respondError(w, http.StatusBadRequest, "Invalid request body, expected the new path")
+		return
+	}
+
+	info, err := h.fileService.MoveFile(r.Context(), id, filePath(r), req.Path)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, info)
+}
+
+// DeleteFile handles DELETE /api/v1/servers/{id}/files/{path...}
+func (h *FileHandler) DeleteFile(w http.ResponseWriter, r *http.Request) {
+	recursive, _ := strconv.ParseBool(r.URL.Query().Get("recursive"))
+
+	if err := h.fileService.DeleteFile(r.Context(), r.PathValue("id"), filePath(r), recursive); err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
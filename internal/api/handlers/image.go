@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/service"
+)
+
+// ImageHandler handles HTTP requests for Docker images
+type ImageHandler struct {
+	dockerService *service.DockerService
+	logger        *slog.Logger
+}
+
+// NewImageHandler creates a new ImageHandler
+func NewImageHandler(dockerService *service.DockerService, logger *slog.Logger) *ImageHandler {
+	return &ImageHandler{
+		dockerService: dockerService,
+		logger:        logger,
+	}
+}
+
+// ListImagePulls handles GET /api/v1/images/pulls
+func (h *ImageHandler) ListImagePulls(w http.ResponseWriter, r *http.Request) {
+	pulls := h.dockerService.ListImagePulls()
+
+	// Filter by image if requested, image names contain slashes so they can't be a path segment
+	if imageName := r.URL.Query().Get("image"); imageName != "" {
+		for _, pull := range pulls {
+			if pull.Image == imageName {
+				respondJSON(w, http.StatusOK, pull)
+				return
+			}
+		}
+		respondError(w, http.StatusNotFound, "No pull of this image since startup")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, pulls)
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/coder/websocket"
 	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
 	"github.com/mlhmz/dockermc-cloud-manager/internal/service"
 )
 
@@ -94,7 +95,7 @@ func (h *LogsHandler) StreamLogs(w http.ResponseWriter, r *http.Request) {
 	logsDone := make(chan struct{})
 
 	// Start goroutine to read commands from client
-	go h.handleClientMessages(ctx, conn, server.ContainerID, serverID, cancel)
+	go h.handleClientMessages(ctx, conn, server, cancel)
 
 	// Stream logs in a goroutine
 	go func() {
@@ -109,8 +110,9 @@ func (h *LogsHandler) StreamLogs(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleClientMessages reads incoming WebSocket messages and handles commands
-func (h *LogsHandler) handleClientMessages(ctx context.Context, conn *websocket.Conn, containerID, serverID string, cancel context.CancelFunc) {
+func (h *LogsHandler) handleClientMessages(ctx context.Context, conn *websocket.Conn, server *models.MinecraftServer, cancel context.CancelFunc) {
 	defer cancel() // Cancel context when client disconnects
+	serverID := server.ID
 
 	for {
 		// Read message from client
@@ -142,7 +144,7 @@ func (h *LogsHandler) handleClientMessages(ctx context.Context, conn *websocket.
 		if cmdMsg.Type == "command" {
 			h.logger.InfoContext(ctx, "Executing command", "server_id", serverID, "command", cmdMsg.Command)
 
-			output, err := h.mcService.ExecuteCommand(ctx, containerID, cmdMsg.Command)
+			output, err := h.mcService.ExecuteCommand(ctx, server, cmdMsg.Command)
 			if err != nil {
 				h.logger.ErrorContext(ctx, "Failed to execute command", "server_id", serverID, "command", cmdMsg.Command, "error", err)
 				h.sendError(ctx, conn, "Failed to execute command: "+err.Error())
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/database"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/service"
+)
+
+// defaultOperationsLimit is the number of operations listed if no limit is given
+const defaultOperationsLimit = 50
+
+// OperationHandler handles HTTP requests for background operations
+type OperationHandler struct {
+	operations *service.OperationService
+	logger     *slog.Logger
+}
+
+// NewOperationHandler creates a new OperationHandler
+func NewOperationHandler(operations *service.OperationService, logger *slog.Logger) *OperationHandler {
+	return &OperationHandler{
+		operations: operations,
+		logger:     logger,
+	}
+}
+
+// GetOperation handles GET /api/v1/operations/{id}
+func (h *OperationHandler) GetOperation(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		respondError(w, http.StatusBadRequest, "Operation ID is required")
+		return
+	}
+
+	operation, err := h.operations.GetOperation(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, database.ErrOperationNotFound) {
+			respondError(w, http.StatusNotFound, "Operation not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, operation)
+}
+
+// ListOperations handles GET /api/v1/operations
+func (h *OperationHandler) ListOperations(w http.ResponseWriter, r *http.Request) {
+	limit := defaultOperationsLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l < 1 || l > 500 {
+			respondError(w, http.StatusBadRequest, "limit must be between 1 and 500")
+			return
+		}
+		limit = l
+	}
+
+	operations, err := h.operations.ListOperations(r.Context(), r.URL.Query().Get("server_id"), limit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, operations)
+}
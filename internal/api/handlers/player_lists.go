@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/service"
+)
+
+// PlayerListHandler handles HTTP requests for the whitelist, operators and bans of servers
+type PlayerListHandler struct {
+	playerListService *service.PlayerListService
+	logger            *slog.Logger
+}
+
+// NewPlayerListHandler creates a new PlayerListHandler
+func NewPlayerListHandler(playerListService *service.PlayerListService, logger *slog.Logger) *PlayerListHandler {
+	return &PlayerListHandler{
+		playerListService: playerListService,
+		logger:            logger,
+	}
+}
+
+// decodeBody decodes a JSON request body and responds with 400 if it is invalid
+func (h *PlayerListHandler) decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		h.logger.WarnContext(r.Context(), "Invalid request body for player list", "id", r.PathValue("id"), "error", err)
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return false
+	}
+	return true
+}
+
+// respondList responds with a player list or the error of the service
+func respondList[T any](w http.ResponseWriter, list []T, err error) {
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, list)
+}
+
+// GetWhitelist handles GET /api/v1/servers/{id}/whitelist
+func (h *PlayerListHandler) GetWhitelist(w http.ResponseWriter, r *http.Request) {
+	list, err := h.playerListService.Whitelist(r.Context(), r.PathValue("id"))
+	respondList(w, list, err)
+}
+
+// AddToWhitelist handles POST /api/v1/servers/{id}/whitelist
+func (h *PlayerListHandler) AddToWhitelist(w http.ResponseWriter, r *http.Request) {
+	var req models.PlayerRequest
+	if !h.decodeBody(w, r, &req) {
+		return
+	}
+	list, err := h.playerListService.AddToWhitelist(r.Context(), r.PathValue("id"), &req)
+	respondList(w, list, err)
+}
+
+// RemoveFromWhitelist handles DELETE /api/v1/servers/{id}/whitelist/{name}
+func (h *PlayerListHandler) RemoveFromWhitelist(w http.ResponseWriter, r *http.Request) {
+	list, err := h.playerListService.RemoveFromWhitelist(r.Context(), r.PathValue("id"), r.PathValue("name"))
+	respondList(w, list, err)
+}
+
+// GetOperators handles GET /api/v1/servers/{id}/ops
+func (h *PlayerListHandler) GetOperators(w http.ResponseWriter, r *http.Request) {
+	list, err := h.playerListService.Operators(r.Context(), r.PathValue("id"))
+	respondList(w, list, err)
+}
+
+// AddOperator handles POST /api/v1/servers/{id}/ops
+func (h *PlayerListHandler) AddOperator(w http.ResponseWriter, r *http.Request) {
+	var req models.PlayerRequest
+	if !h.decodeBody(w, r, &req) {
+		return
+	}
+	list, err := h.playerListService.AddOperator(r.Context(), r.PathValue("id"), &req)
+	respondList(w, list, err)
+}
+
+// RemoveOperator handles DELETE /api/v1/servers/{id}/ops/{name}
+func (h *PlayerListHandler) RemoveOperator(w http.ResponseWriter, r *http.Request) {
+	list, err := h.playerListService.RemoveOperator(r.Context(), r.PathValue("id"), r.PathValue("name"))
+	respondList(w, list, err)
+}
+
+// GetPlayerBans handles GET /api/v1/servers/{id}/bans
+func (h *PlayerListHandler) GetPlayerBans(w http.ResponseWriter, r *http.Request) {
+	list, err := h.playerListService.PlayerBans(r.Context(), r.PathValue("id"))
+	respondList(w, list, err)
+}
+
+// BanPlayer handles POST /api/v1/servers/{id}/bans
+func (h *PlayerListHandler) BanPlayer(w http.ResponseWriter, r *http.Request) {
+	var req models.BanPlayerRequest
+	if !h.decodeBody(w, r, &req) {
+		return
+	}
+	list, err := h.playerListService.BanPlayer(r.Context(), r.PathValue("id"), &req)
+	respondList(w, list, err)
+}
+
+// PardonPlayer handles DELETE /api/v1/servers/{id}/bans/{name}
+func (h *PlayerListHandler) PardonPlayer(w http.ResponseWriter, r *http.Request) {
+	list, err := h.playerListService.PardonPlayer(r.Context(), r.PathValue("id"), r.PathValue("name"))
+	respondList(w, list, err)
+}
+
+// GetIPBans handles GET /api/v1/servers/{id}/ip-bans
+func (h *PlayerListHandler) GetIPBans(w http.ResponseWriter, r *http.Request) {
+	list, err := h.playerListService.IPBans(r.Context(), r.PathValue("id"))
+	respondList(w, list, err)
+}
+
+// BanIP handles POST /api/v1/servers/{id}/ip-bans
+func (h *PlayerListHandler) BanIP(w http.ResponseWriter, r *http.Request) {
+	var req models.BanIPRequest
+	if !h.decodeBody(w, r, &req) {
+		return
+	}
+	list, err := h.playerListService.BanIP(r.Context(), r.PathValue("id"), &req)
+	respondList(w, list, err)
+}
+
+// PardonIP handles DELETE /api/v1/servers/{id}/ip-bans/{ip}
+func (h *PlayerListHandler) PardonIP(w http.ResponseWriter, r *http.Request) {
+	list, err := h.playerListService.PardonIP(r.Context(), r.PathValue("id"), r.PathValue("ip"))
+	respondList(w, list, err)
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/service"
+)
+
+// PlayerHandler handles HTTP requests for the players seen on the servers
+type PlayerHandler struct {
+	players *service.PlayerService
+	logger  *slog.Logger
+}
+
+// NewPlayerHandler creates a new PlayerHandler
+func NewPlayerHandler(players *service.PlayerService, logger *slog.Logger) *PlayerHandler {
+	return &PlayerHandler{
+		players: players,
+		logger:  logger,
+	}
+}
+
+// ListPlayers handles GET /api/v1/players
+func (h *PlayerHandler) ListPlayers(w http.ResponseWriter, r *http.Request) {
+	filter, err := parsePlayerFilter(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	players, err := h.players.ListPlayers(r.Context(), filter)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, players)
+}
+
+// GetPlayer handles GET /api/v1/players/{uuid}
+func (h *PlayerHandler) GetPlayer(w http.ResponseWriter, r *http.Request) {
+	filter, err := parsePlayerFilter(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	player, err := h.players.GetPlayer(r.Context(), r.PathValue("uuid"), filter)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, player)
+}
+
+// ListOnlinePlayers handles GET /api/v1/servers/{id}/players
+func (h *PlayerHandler) ListOnlinePlayers(w http.ResponseWriter, r *http.Request) {
+	sessions, err := h.players.OnlinePlayers(r.Context(), r.PathValue("id"))
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, sessions)
+}
+
+// parsePlayerFilter reads the server_id, since and until query parameters
+func parsePlayerFilter(r *http.Request) (models.PlayerSessionFilter, error) {
+	query := r.URL.Query()
+	filter := models.PlayerSessionFilter{ServerID: query.Get("server_id")}
+
+	for name, target := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+		}
+		*target = t
+	}
+	return filter, nil
+}
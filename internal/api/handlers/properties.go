@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/service"
+)
+
+// PropertiesHandler handles HTTP requests for the server.properties of servers
+type PropertiesHandler struct {
+	propertiesService *service.PropertiesService
+	logger            *slog.Logger
+}
+
+// NewPropertiesHandler creates a new PropertiesHandler
+func NewPropertiesHandler(propertiesService *service.PropertiesService, logger *slog.Logger) *PropertiesHandler {
+	return &PropertiesHandler{
+		propertiesService: propertiesService,
+		logger:            logger,
+	}
+}
+
+// GetProperties handles GET /api/v1/servers/{id}/properties
+func (h *PropertiesHandler) GetProperties(w http.ResponseWriter, r *http.Request) {
+	properties, err := h.propertiesService.GetProperties(r.Context(), r.PathValue("id"))
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, properties)
+}
+
+// UpdateProperties handles PUT /api/v1/servers/{id}/properties
+func (h *PropertiesHandler) UpdateProperties(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	// Unknown fields are rejected, so settings the manager controls like motd aren't silently ignored
+	var req models.ServerProperties
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		h.logger.WarnContext(r.Context(), "Invalid request body for server properties", "id", id, "error", err)
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	properties, err := h.propertiesService.UpdateProperties(r.Context(), id, &req)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, properties)
+}
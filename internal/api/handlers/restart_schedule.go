@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/service"
+)
+
+// RestartScheduleHandler handles HTTP requests for restart schedules
+type RestartScheduleHandler struct {
+	scheduler *service.RestartScheduler
+	logger    *slog.Logger
+}
+
+// NewRestartScheduleHandler creates a new RestartScheduleHandler
+func NewRestartScheduleHandler(scheduler *service.RestartScheduler, logger *slog.Logger) *RestartScheduleHandler {
+	return &RestartScheduleHandler{
+		scheduler: scheduler,
+		logger:    logger,
+	}
+}
+
+// GetSchedule handles GET /api/v1/servers/{id}/restart-schedule
+func (h *RestartScheduleHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	schedule, err := h.scheduler.GetSchedule(r.Context(), r.PathValue("id"))
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, schedule)
+}
+
+// SetSchedule handles PUT /api/v1/servers/{id}/restart-schedule
+func (h *RestartScheduleHandler) SetSchedule(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var req models.RestartScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WarnContext(r.Context(), "Invalid request body for restart schedule", "id", id, "error", err)
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	schedule, err := h.scheduler.SetSchedule(r.Context(), id, &req)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, schedule)
+}
+
+// DeleteSchedule handles DELETE /api/v1/servers/{id}/restart-schedule
+func (h *RestartScheduleHandler) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	if err := h.scheduler.DeleteSchedule(r.Context(), r.PathValue("id")); err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,25 +1,32 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/mlhmz/dockermc-cloud-manager/internal/database"
 	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
 	"github.com/mlhmz/dockermc-cloud-manager/internal/service"
 )
 
 // ServerHandler handles HTTP requests for server management
 type ServerHandler struct {
-	mcService *service.MinecraftServerService
-	logger    *slog.Logger
+	mcService  *service.MinecraftServerService
+	operations *service.OperationService
+	logger     *slog.Logger
 }
 
 // NewServerHandler creates a new ServerHandler
-func NewServerHandler(mcService *service.MinecraftServerService, logger *slog.Logger) *ServerHandler {
+func NewServerHandler(mcService *service.MinecraftServerService, operations *service.OperationService, logger *slog.Logger) *ServerHandler {
 	return &ServerHandler{
-		mcService: mcService,
-		logger:    logger,
+		mcService:  mcService,
+		operations: operations,
+		logger:     logger,
 	}
 }
 
@@ -32,17 +39,25 @@ func (h *ServerHandler) CreateServer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Reject invalid requests right away instead of failing the operation later
+	if err := h.mcService.ValidateCreateServerRequest(&req); err != nil {
+		h.logger.WarnContext(r.Context(), "Invalid server creation request", "name", req.Name, "error", err)
+		respondServiceError(w, err)
+		return
+	}
+
 	h.logger.InfoContext(r.Context(), "Creating new server", "name", req.Name)
 
-	server, err := h.mcService.CreateServer(r.Context(), &req)
+	operation, err := h.operations.Run(r.Context(), models.OperationCreateServer, "", func(ctx context.Context) (any, error) {
+		return h.mcService.CreateServer(ctx, &req)
+	})
 	if err != nil {
-		h.logger.ErrorContext(r.Context(), "Failed to create server", "name", req.Name, "error", err)
+		h.logger.ErrorContext(r.Context(), "Failed to start server creation", "name", req.Name, "error", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	h.logger.InfoContext(r.Context(), "Server created successfully", "id", server.ID, "name", server.Name)
-	respondJSON(w, http.StatusCreated, server)
+	respondOperation(w, operation)
 }
 
 // ListServers handles GET /api/v1/servers
@@ -73,24 +88,49 @@ func (h *ServerHandler) GetServer(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, server)
 }
 
-// DeleteServer handles DELETE /api/v1/servers/{id}
-func (h *ServerHandler) DeleteServer(w http.ResponseWriter, r *http.Request) {
+// UpdateServer handles PATCH /api/v1/servers/{id}
+func (h *ServerHandler) UpdateServer(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	if id == "" {
 		respondError(w, http.StatusBadRequest, "Server ID is required")
 		return
 	}
 
-	h.logger.InfoContext(r.Context(), "Deleting server", "id", id)
+	var req models.UpdateServerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WarnContext(r.Context(), "Invalid request body for server update", "id", id, "error", err)
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
 
-	if err := h.mcService.DeleteServer(r.Context(), id); err != nil {
-		h.logger.ErrorContext(r.Context(), "Failed to delete server", "id", id, "error", err)
-		respondError(w, http.StatusInternalServerError, err.Error())
+	// Reject invalid requests right away instead of failing the operation later
+	if err := h.mcService.ValidateUpdateServerRequest(&req); err != nil {
+		h.logger.WarnContext(r.Context(), "Invalid server update request", "id", id, "error", err)
+		respondServiceError(w, err)
 		return
 	}
 
-	h.logger.InfoContext(r.Context(), "Server deleted successfully", "id", id)
-	w.WriteHeader(http.StatusNoContent)
+	h.logger.InfoContext(r.Context(), "Updating server", "id", id)
+
+	// Recreating the container outlives the request timeout, so it runs as an operation
+	h.runServerOperation(w, r, models.OperationUpdateServer, id, func(ctx context.Context) (any, error) {
+		return h.mcService.UpdateServer(ctx, id, &req)
+	})
+}
+
+// DeleteServer handles DELETE /api/v1/servers/{id}
+func (h *ServerHandler) DeleteServer(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		respondError(w, http.StatusBadRequest, "Server ID is required")
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "Deleting server", "id", id)
+
+	h.runServerOperation(w, r, models.OperationDeleteServer, id, func(ctx context.Context) (any, error) {
+		return nil, h.mcService.DeleteServer(ctx, id)
+	})
 }
 
 // StartServer handles POST /api/v1/servers/{id}/start
@@ -103,34 +143,101 @@ func (h *ServerHandler) StartServer(w http.ResponseWriter, r *http.Request) {
 
 	h.logger.InfoContext(r.Context(), "Starting server", "id", id)
 
-	if err := h.mcService.StartServer(r.Context(), id); err != nil {
-		h.logger.ErrorContext(r.Context(), "Failed to start server", "id", id, "error", err)
-		respondError(w, http.StatusInternalServerError, err.Error())
+	h.runServerOperation(w, r, models.OperationStartServer, id, func(ctx context.Context) (any, error) {
+		return nil, h.mcService.StartServer(ctx, id)
+	})
+}
+
+// StopServer handles POST /api/v1/servers/{id}/stop
+func (h *ServerHandler) StopServer(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		respondError(w, http.StatusBadRequest, "Server ID is required")
+		return
+	}
+
+	grace, ok := parseGrace(w, r)
+	if !ok {
 		return
 	}
 
-	h.logger.InfoContext(r.Context(), "Server started successfully", "id", id)
-	respondJSON(w, http.StatusOK, map[string]string{"status": "started"})
+	h.logger.InfoContext(r.Context(), "Stopping server", "id", id, "grace", grace)
+
+	h.runServerOperation(w, r, models.OperationStopServer, id, func(ctx context.Context) (any, error) {
+		return nil, h.mcService.StopServerGracefully(ctx, id, grace)
+	})
 }
 
-// StopServer handles POST /api/v1/servers/{id}/stop
-func (h *ServerHandler) StopServer(w http.ResponseWriter, r *http.Request) {
+// RestartServer handles POST /api/v1/servers/{id}/restart
+func (h *ServerHandler) RestartServer(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	if id == "" {
 		respondError(w, http.StatusBadRequest, "Server ID is required")
 		return
 	}
 
-	h.logger.InfoContext(r.Context(), "Stopping server", "id", id)
+	grace, ok := parseGrace(w, r)
+	if !ok {
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "Restarting server", "id", id, "grace", grace)
+
+	h.runServerOperation(w, r, models.OperationRestartServer, id, func(ctx context.Context) (any, error) {
+		return nil, h.mcService.RestartServer(ctx, id, grace)
+	})
+}
+
+// RotateRCONPassword handles POST /api/v1/servers/{id}/rcon-password/rotate
+func (h *ServerHandler) RotateRCONPassword(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		respondError(w, http.StatusBadRequest, "Server ID is required")
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "Rotating RCON password", "id", id)
+
+	h.runServerOperation(w, r, models.OperationRotateRCONPassword, id, func(ctx context.Context) (any, error) {
+		return nil, h.mcService.RotateRCONPassword(ctx, id)
+	})
+}
+
+// parseGrace reads the optional grace period in seconds, which stops the server gracefully with a countdown
+// for the players. It responds with 400 if the value is invalid.
+func parseGrace(w http.ResponseWriter, r *http.Request) (time.Duration, bool) {
+	value := r.URL.Query().Get("grace")
+	if value == "" {
+		return 0, true
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "grace must be a number of seconds")
+		return 0, false
+	}
+	grace := time.Duration(seconds) * time.Second
+	if err := service.ValidateStopGrace(grace); err != nil {
+		respondServiceError(w, err)
+		return 0, false
+	}
+	return grace, true
+}
+
+// runServerOperation checks that the server exists and runs fn as a background operation
+func (h *ServerHandler) runServerOperation(w http.ResponseWriter, r *http.Request, opType models.OperationType, id string, fn service.OperationFunc) {
+	if _, err := h.mcService.GetServer(r.Context(), id); err != nil {
+		respondServiceError(w, err)
+		return
+	}
 
-	if err := h.mcService.StopServer(r.Context(), id); err != nil {
-		h.logger.ErrorContext(r.Context(), "Failed to stop server", "id", id, "error", err)
+	operation, err := h.operations.Run(r.Context(), opType, id, fn)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to start operation", "type", opType, "id", id, "error", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	h.logger.InfoContext(r.Context(), "Server stopped successfully", "id", id)
-	respondJSON(w, http.StatusOK, map[string]string{"status": "stopped"})
+	respondOperation(w, operation)
 }
 
 // Helper functions
@@ -144,3 +251,37 @@ func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 func respondError(w http.ResponseWriter, status int, message string) {
 	respondJSON(w, status, map[string]string{"error": message})
 }
+
+// respondOperation responds with 202 Accepted and the operation that tracks the accepted work
+func respondOperation(w http.ResponseWriter, operation *models.Operation) {
+	w.Header().Set("Location", "/api/v1/operations/"+operation.ID)
+	respondJSON(w, http.StatusAccepted, operation)
+}
+
+// respondServiceError maps errors returned by the service layer to HTTP status codes
+func respondServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, database.ErrServerNotFound):
+		respondError(w, http.StatusNotFound, "Server not found")
+	case errors.Is(err, database.ErrBackupNotFound):
+		respondError(w, http.StatusNotFound, "Backup not found")
+	case errors.Is(err, database.ErrBackupScheduleNotFound):
+		respondError(w, http.StatusNotFound, "Backup schedule not found")
+	case errors.Is(err, database.ErrRestartScheduleNotFound):
+		respondError(w, http.StatusNotFound, "Restart schedule not found")
+	case errors.Is(err, database.ErrCrashReportNotFound):
+		respondError(w, http.StatusNotFound, "Crash report not found")
+	case errors.Is(err, database.ErrPlayerNotFound):
+		respondError(w, http.StatusNotFound, "Player not found")
+	case errors.Is(err, service.ErrFileNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrTooLarge):
+		respondError(w, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, service.ErrValidation):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrConflict):
+		respondError(w, http.StatusConflict, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
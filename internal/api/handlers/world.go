@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/service"
+)
+
+// WorldHandler handles HTTP requests for importing and exporting server worlds
+type WorldHandler struct {
+	worldService *service.WorldService
+	logger       *slog.Logger
+}
+
+// NewWorldHandler creates a new WorldHandler
+func NewWorldHandler(worldService *service.WorldService, logger *slog.Logger) *WorldHandler {
+	return &WorldHandler{
+		worldService: worldService,
+		logger:       logger,
+	}
+}
+
+// ImportWorld handles POST /api/v1/servers/{id}/world
+func (h *WorldHandler) ImportWorld(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	// Large worlds take longer to upload and extract than the server's timeouts
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to clear read deadline for world import", "error", err)
+	}
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to clear write deadline for world import", "error", err)
+	}
+
+	// The archive is either the raw body or the "file" field of a multipart form
+	var upload io.Reader = r.Body
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		part, err := multipartFile(r, "file")
+		if err != nil {
+			h.logger.WarnContext(r.Context(), "Invalid multipart body for world import", "id", id, "error", err)
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		upload = part
+	}
+
+	h.logger.InfoContext(r.Context(), "Importing world", "id", id)
+
+	result, err := h.worldService.ImportWorld(r.Context(), id, upload)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
+
+// multipartFile returns the content of a file field of a multipart request
+func multipartFile(r *http.Request, field string) (io.Reader, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("missing %q field", field)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == field {
+			return part, nil
+		}
+	}
+}
+
+// ExportWorld handles GET /api/v1/servers/{id}/world
+func (h *WorldHandler) ExportWorld(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	saveWorld := false
+	if value := r.URL.Query().Get("save_world"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "save_world must be a boolean")
+			return
+		}
+		saveWorld = parsed
+	}
+
+	export, err := h.worldService.OpenWorldExport(r.Context(), id, saveWorld)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+	defer export.Close()
+
+	// Large worlds take longer than the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to clear write deadline for world export", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.FileName()))
+	w.WriteHeader(http.StatusOK)
+
+	// The status is sent already, a failure can only be logged and shows up as a truncated archive
+	if err := export.WriteZip(w); err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to export world", "id", id, "error", err)
+	}
+}
@@ -14,24 +14,107 @@ import (
 )
 
 // NewRouter creates and configures the HTTP router
-func NewRouter(mcService *service.MinecraftServerService, proxyService *service.ProxyService, logger *slog.Logger) http.Handler {
+func NewRouter(
+	dockerService *service.DockerService,
+	mcService *service.MinecraftServerService,
+	proxyService *service.ProxyService,
+	operationService *service.OperationService,
+	backupService *service.BackupService,
+	backupScheduler *service.BackupScheduler,
+	restartScheduler *service.RestartScheduler,
+	worldService *service.WorldService,
+	fileService *service.FileService,
+	propertiesService *service.PropertiesService,
+	playerListService *service.PlayerListService,
+	playerService *service.PlayerService,
+	eventBus *service.EventBus,
+	logger *slog.Logger,
+) http.Handler {
 	mux := http.NewServeMux()
 
 	// Health check endpoint
 	mux.HandleFunc("/health", healthCheckHandler)
 
 	// Initialize handlers
-	serverHandler := handlers.NewServerHandler(mcService, logger)
+	serverHandler := handlers.NewServerHandler(mcService, operationService, logger)
 	logsHandler := handlers.NewLogsHandler(mcService, logger)
 	proxyHandler := handlers.NewProxyHandler(proxyService, logger)
+	eventsHandler := handlers.NewEventsHandler(eventBus, logger)
+	operationHandler := handlers.NewOperationHandler(operationService, logger)
+	imageHandler := handlers.NewImageHandler(dockerService, logger)
+	backupHandler := handlers.NewBackupHandler(backupService, operationService, logger)
+	backupScheduleHandler := handlers.NewBackupScheduleHandler(backupScheduler, logger)
+	restartScheduleHandler := handlers.NewRestartScheduleHandler(restartScheduler, logger)
+	worldHandler := handlers.NewWorldHandler(worldService, logger)
+	fileHandler := handlers.NewFileHandler(fileService, logger)
+	propertiesHandler := handlers.NewPropertiesHandler(propertiesService, logger)
+	playerListHandler := handlers.NewPlayerListHandler(playerListService, logger)
+	crashReportHandler := handlers.NewCrashReportHandler(mcService, logger)
+	playerHandler := handlers.NewPlayerHandler(playerService, logger)
 
 	// Server management endpoints
 	mux.HandleFunc("POST /api/v1/servers", serverHandler.CreateServer)
 	mux.HandleFunc("GET /api/v1/servers", serverHandler.ListServers)
 	mux.HandleFunc("GET /api/v1/servers/{id}", serverHandler.GetServer)
+	mux.HandleFunc("PATCH /api/v1/servers/{id}", serverHandler.UpdateServer)
 	mux.HandleFunc("DELETE /api/v1/servers/{id}", serverHandler.DeleteServer)
 	mux.HandleFunc("POST /api/v1/servers/{id}/start", serverHandler.StartServer)
 	mux.HandleFunc("POST /api/v1/servers/{id}/stop", serverHandler.StopServer)
+	mux.HandleFunc("POST /api/v1/servers/{id}/restart", serverHandler.RestartServer)
+	mux.HandleFunc("POST /api/v1/servers/{id}/rcon-password/rotate", serverHandler.RotateRCONPassword)
+	mux.HandleFunc("GET /api/v1/servers/{id}/restart-schedule", restartScheduleHandler.GetSchedule)
+	mux.HandleFunc("PUT /api/v1/servers/{id}/restart-schedule", restartScheduleHandler.SetSchedule)
+	mux.HandleFunc("DELETE /api/v1/servers/{id}/restart-schedule", restartScheduleHandler.DeleteSchedule)
+	mux.HandleFunc("GET /api/v1/servers/{id}/crash-reports", crashReportHandler.ListCrashReports)
+	mux.HandleFunc("GET /api/v1/servers/{id}/crash-reports/{reportId}", crashReportHandler.GetCrashReport)
+
+	// Backup endpoints
+	mux.HandleFunc("POST /api/v1/servers/{id}/backups", backupHandler.CreateBackup)
+	mux.HandleFunc("GET /api/v1/servers/{id}/backups", backupHandler.ListBackups)
+	mux.HandleFunc("GET /api/v1/servers/{id}/backups/{backupId}", backupHandler.GetBackup)
+	mux.HandleFunc("DELETE /api/v1/servers/{id}/backups/{backupId}", backupHandler.DeleteBackup)
+	mux.HandleFunc("GET /api/v1/servers/{id}/backups/{backupId}/download", backupHandler.DownloadBackup)
+	mux.HandleFunc("POST /api/v1/servers/{id}/backups/{backupId}/restore", backupHandler.RestoreBackup)
+	mux.HandleFunc("GET /api/v1/backup-destinations", backupHandler.ListDestinations)
+	mux.HandleFunc("GET /api/v1/servers/{id}/backup-schedule", backupScheduleHandler.GetSchedule)
+	mux.HandleFunc("PUT /api/v1/servers/{id}/backup-schedule", backupScheduleHandler.SetSchedule)
+	mux.HandleFunc("DELETE /api/v1/servers/{id}/backup-schedule", backupScheduleHandler.DeleteSchedule)
+
+	// World endpoints
+	mux.HandleFunc("POST /api/v1/servers/{id}/world", worldHandler.ImportWorld)
+	mux.HandleFunc("GET /api/v1/servers/{id}/world", worldHandler.ExportWorld)
+
+	// File manager endpoints, the bare /files path is the volume root
+	mux.HandleFunc("GET /api/v1/servers/{id}/files", fileHandler.GetFile)
+	mux.HandleFunc("GET /api/v1/servers/{id}/files/{path...}", fileHandler.GetFile)
+	mux.HandleFunc("PUT /api/v1/servers/{id}/files/{path...}", fileHandler.PutFile)
+	mux.HandleFunc("POST /api/v1/servers/{id}/files", fileHandler.UploadFiles)
+	mux.HandleFunc("POST /api/v1/servers/{id}/files/{path...}", fileHandler.UploadFiles)
+	mux.HandleFunc("PATCH /api/v1/servers/{id}/files/{path...}", fileHandler.MoveFile)
+	mux.HandleFunc("DELETE /api/v1/servers/{id}/files/{path...}", fileHandler.DeleteFile)
+
+	// server.properties endpoints
+	mux.HandleFunc("GET /api/v1/servers/{id}/properties", propertiesHandler.GetProperties)
+	mux.HandleFunc("PUT /api/v1/servers/{id}/properties", propertiesHandler.UpdateProperties)
+
+	// Whitelist, operator and ban endpoints
+	mux.HandleFunc("GET /api/v1/servers/{id}/whitelist", playerListHandler.GetWhitelist)
+	mux.HandleFunc("POST /api/v1/servers/{id}/whitelist", playerListHandler.AddToWhitelist)
+	mux.HandleFunc("DELETE /api/v1/servers/{id}/whitelist/{name}", playerListHandler.RemoveFromWhitelist)
+	mux.HandleFunc("GET /api/v1/servers/{id}/ops", playerListHandler.GetOperators)
+	mux.HandleFunc("POST /api/v1/servers/{id}/ops", playerListHandler.AddOperator)
+	mux.HandleFunc("DELETE /api/v1/servers/{id}/ops/{name}", playerListHandler.RemoveOperator)
+	mux.HandleFunc("GET /api/v1/servers/{id}/bans", playerListHandler.GetPlayerBans)
+	mux.HandleFunc("POST /api/v1/servers/{id}/bans", playerListHandler.BanPlayer)
+	mux.HandleFunc("DELETE /api/v1/servers/{id}/bans/{name}", playerListHandler.PardonPlayer)
+	mux.HandleFunc("GET /api/v1/servers/{id}/ip-bans", playerListHandler.GetIPBans)
+	mux.HandleFunc("POST /api/v1/servers/{id}/ip-bans", playerListHandler.BanIP)
+	mux.HandleFunc("DELETE /api/v1/servers/{id}/ip-bans/{ip}", playerListHandler.PardonIP)
+
+	// Player session endpoints
+	mux.HandleFunc("GET /api/v1/players", playerHandler.ListPlayers)
+	mux.HandleFunc("GET /api/v1/players/{uuid}", playerHandler.GetPlayer)
+	mux.HandleFunc("GET /api/v1/servers/{id}/players", playerHandler.ListOnlinePlayers)
 
 	// WebSocket endpoints
 	mux.HandleFunc("GET /api/v1/servers/{id}/logs", logsHandler.StreamLogs)
@@ -43,6 +126,16 @@ func NewRouter(mcService *service.MinecraftServerService, proxyService *service.
 	mux.HandleFunc("POST /api/v1/proxy/stop", proxyHandler.StopProxy)
 	mux.HandleFunc("POST /api/v1/proxy/regenerate-config", proxyHandler.RegenerateConfig)
 
+	// Operation endpoints
+	mux.HandleFunc("GET /api/v1/operations", operationHandler.ListOperations)
+	mux.HandleFunc("GET /api/v1/operations/{id}", operationHandler.GetOperation)
+
+	// Image endpoints
+	mux.HandleFunc("GET /api/v1/images/pulls", imageHandler.ListImagePulls)
+
+	// Event stream endpoint (Server-Sent Events)
+	mux.HandleFunc("GET /api/v1/events", eventsHandler.StreamEvents)
+
 	// API Documentation endpoints
 	mux.HandleFunc("GET /api/openapi.yaml", handlers.ServeOpenAPISpec)
 	mux.Handle("/swagger/", httpSwagger.Handler(
@@ -95,6 +188,18 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Flush implements http.Flusher interface for Server-Sent Events support
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the wrapped writer so http.ResponseController can reach it
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // Hijack implements http.Hijacker interface for WebSocket support
 func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	h, ok := rw.ResponseWriter.(http.Hijacker)
@@ -108,8 +213,8 @@ func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Upgrade, Connection, Sec-WebSocket-Key, Sec-WebSocket-Version, Sec-WebSocket-Extensions, Sec-WebSocket-Protocol")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Last-Event-ID, Upgrade, Connection, Sec-WebSocket-Key, Sec-WebSocket-Version, Sec-WebSocket-Extensions, Sec-WebSocket-Protocol")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
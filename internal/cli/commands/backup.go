@@ -0,0 +1,407 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/database"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/service"
+	"github.com/spf13/cobra"
+)
+
+// Helper function to initialize the backup service on top of the server services
+func initializeBackupService(db *database.DB, dockerService *service.DockerService, mcService *service.MinecraftServerService) *service.BackupService {
+	backupRepo := database.NewBackupRepository(db)
+
+	localStorage := service.NewLocalBackupStorage(cfg.BackupDir)
+	if cfg.BackupS3Bucket == "" {
+		return service.NewBackupService(dockerService, mcService, backupRepo, localStorage, logger)
+	}
+
+	s3Storage, err := service.NewS3BackupStorage(service.S3Config{
+		Endpoint:        cfg.BackupS3Endpoint,
+		Bucket:          cfg.BackupS3Bucket,
+		Region:          cfg.BackupS3Region,
+		AccessKeyID:     cfg.BackupS3AccessKeyID,
+		SecretAccessKey: cfg.BackupS3SecretAccessKey,
+		Prefix:          cfg.BackupS3Prefix,
+		UseSSL:          cfg.BackupS3UseSSL,
+		PartSizeMB:      cfg.BackupS3PartSizeMB,
+	})
+	if err != nil {
+		logger.Error("Failed to initialize S3 backup destination", "error", err)
+		os.Exit(1)
+	}
+
+	// Both destinations stay available, so backups written before switching the default can still be restored
+	if cfg.BackupDestination == service.S3Destination {
+		backupService := service.NewBackupService(dockerService, mcService, backupRepo, s3Storage, logger)
+		backupService.AddStorage(localStorage)
+		return backupService
+	}
+	backupService := service.NewBackupService(dockerService, mcService, backupRepo, localStorage, logger)
+	backupService.AddStorage(s3Storage)
+	return backupService
+}
+
+// backupDestinationNames returns the backup destinations initializeBackupService configures
+func backupDestinationNames() []string {
+	if cfg.BackupS3Bucket == "" {
+		return []string{service.LocalDestination}
+	}
+	return []string{service.LocalDestination, service.S3Destination}
+}
+
+var serverBackupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Manage server backups",
+	Long:  `Create, list, restore, and delete backups of a server's data volume.`,
+}
+
+var serverBackupCreateCmd = &cobra.Command{
+	Use:   "create <server-id>",
+	Short: "Back up a server",
+	Long: `Archive the data volume of a server to a tar.gz file in the backup directory.
+
+With --save-world a running server stops saving and flushes the world to disk first,
+so the archive is consistent. Saving is turned back on afterwards.`,
+	Example: `  dockermc-cloud-manager server backup create abc123...
+  dockermc-cloud-manager server backup create abc123... --save-world
+  dockermc-cloud-manager server backup create abc123... --destination s3`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID := args[0]
+		saveWorld, _ := cmd.Flags().GetBool("save-world")
+		destination, _ := cmd.Flags().GetString("destination")
+		ctx := context.Background()
+
+		// Initialize services
+		db, dockerService, mcService, cleanup := initializeServices()
+		defer cleanup()
+		backupService := initializeBackupService(db, dockerService, mcService)
+
+		logger.Info("Creating backup", "id", serverID)
+		backup, err := backupService.CreateBackup(ctx, serverID, &models.CreateBackupRequest{
+			SaveWorld:   saveWorld,
+			Destination: destination,
+		})
+		if err != nil {
+			logger.Error("Failed to create backup", "error", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ Backup %s created successfully!\n\n", backup.ID)
+		fmt.Printf("Stored In: %s\n", backup.Destination)
+		fmt.Printf("File:      %s\n", backup.FileName)
+		fmt.Printf("Size:      %s\n", formatBytes(backup.SizeBytes))
+		fmt.Printf("SHA-256:   %s\n", backup.Checksum)
+	},
+}
+
+var serverBackupListCmd = &cobra.Command{
+	Use:   "list <server-id>",
+	Short: "List the backups of a server",
+	Long:  `List the backups of a server, newest first.`,
+	Example: `  dockermc-cloud-manager server backup list abc123...
+  dockermc-cloud-manager server backup list abc123... --output json`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID := args[0]
+		outputFormat, _ := cmd.Flags().GetString("output")
+		ctx := context.Background()
+
+		// Initialize services
+		db, dockerService, mcService, cleanup := initializeServices()
+		defer cleanup()
+		backupService := initializeBackupService(db, dockerService, mcService)
+
+		backups, err := backupService.ListBackups(ctx, serverID)
+		if err != nil {
+			logger.Error("Failed to list backups", "error", err)
+			os.Exit(1)
+		}
+
+		if outputFormat == "json" {
+			data, _ := json.MarshalIndent(backups, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+
+		if len(backups) == 0 {
+			fmt.Println("No backups found.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "ID\tCREATED\tDESTINATION\tSIZE\tVERSION\tWORLD SAVED\tSCHEDULED")
+		for _, backup := range backups {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\t%t\n",
+				backup.ID,
+				backup.CreatedAt.Format("2006-01-02 15:04"),
+				backup.Destination,
+				formatBytes(backup.SizeBytes),
+				backup.MinecraftVersion,
+				backup.WorldSaved,
+				backup.Scheduled,
+			)
+		}
+		w.Flush()
+	},
+}
+
+var serverBackupRestoreCmd = &cobra.Command{
+	Use:   "restore <server-id> <backup-id>",
+	Short: "Restore a backup into a server",
+	Long: `Replace the data volume of a stopped server with the content of a backup.
+
+The archive's checksum is verified before anything is changed.`,
+	Example: `  dockermc-cloud-manager server backup restore abc123... def456...
+  dockermc-cloud-manager server backup restore abc123... def456... --force`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID := args[0]
+		backupID := args[1]
+		force, _ := cmd.Flags().GetBool("force")
+		ctx := context.Background()
+
+		// Confirmation prompt
+		if !force {
+			fmt.Printf("⚠ Are you sure you want to replace the data of server %s with backup %s? [y/N]: ", serverID, backupID)
+			var response string
+			fmt.Scanln(&response)
+			if response != "y" && response != "Y" {
+				fmt.Println("Cancelled.")
+				return
+			}
+		}
+
+		// Initialize services
+		db, dockerService, mcService, cleanup := initializeServices()
+		defer cleanup()
+		backupService := initializeBackupService(db, dockerService, mcService)
+
+		logger.Info("Restoring backup", "id", serverID, "backup_id", backupID)
+		if err := backupService.RestoreBackup(ctx, serverID, backupID); err != nil {
+			logger.Error("Failed to restore backup", "error", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ Backup %s restored into server %s!\n", backupID, serverID)
+	},
+}
+
+var serverBackupDeleteCmd = &cobra.Command{
+	Use:     "delete <server-id> <backup-id>",
+	Short:   "Delete a backup",
+	Long:    `Delete a backup and its archive.`,
+	Example: `  dockermc-cloud-manager server backup delete abc123... def456...`,
+	Args:    cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID := args[0]
+		backupID := args[1]
+		ctx := context.Background()
+
+		// Initialize services
+		db, dockerService, mcService, cleanup := initializeServices()
+		defer cleanup()
+		backupService := initializeBackupService(db, dockerService, mcService)
+
+		if err := backupService.DeleteBackup(ctx, serverID, backupID); err != nil {
+			logger.Error("Failed to delete backup", "error", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ Backup %s deleted successfully!\n", backupID)
+	},
+}
+
+// Helper function to initialize the backup scheduler, the schedules are run by the serve command
+func initializeBackupScheduler(db *database.DB, backupService *service.BackupService) *service.BackupScheduler {
+	backupScheduleRepo := database.NewBackupScheduleRepository(db)
+	return service.NewBackupScheduler(backupService, backupScheduleRepo, logger)
+}
+
+var serverBackupScheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Manage the backup schedule of a server",
+	Long: `Set, show, and remove the schedule a server is backed up on automatically.
+
+Scheduled backups are run by the serve command. Runs missed while it wasn't running
+are made up once on the next start.`,
+}
+
+var serverBackupScheduleSetCmd = &cobra.Command{
+	Use:   "set <server-id>",
+	Short: "Set the backup schedule of a server",
+	Long: `Create or replace the backup schedule of a server.
+
+The schedule is a standard 5 field cron expression (minute hour day-of-month month day-of-week)
+or a descriptor like @daily. Prefix it with CRON_TZ=<zone> to use a time zone other than the local one.
+
+The retention flags decide which scheduled backups are kept after each run. A backup is kept
+if any of them keeps it. Manual backups are never pruned. Without retention flags all backups are kept.`,
+	Example: `  # Back up every night at 4:00, keep a week of dailies and a month of weeklies
+  dockermc-cloud-manager server backup schedule set abc123... --cron "0 4 * * *" --keep-daily 7 --keep-weekly 4
+
+  # Back up every 6 hours and keep the last 10
+  dockermc-cloud-manager server backup schedule set abc123... --cron "0 */6 * * *" --keep-last 10 --save-world`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID := args[0]
+		cronExpr, _ := cmd.Flags().GetString("cron")
+		keepLast, _ := cmd.Flags().GetInt("keep-last")
+		keepDaily, _ := cmd.Flags().GetInt("keep-daily")
+		keepWeekly, _ := cmd.Flags().GetInt("keep-weekly")
+		saveWorld, _ := cmd.Flags().GetBool("save-world")
+		destination, _ := cmd.Flags().GetString("destination")
+		disabled, _ := cmd.Flags().GetBool("disabled")
+		ctx := context.Background()
+
+		// Initialize services
+		db, dockerService, mcService, cleanup := initializeServices()
+		defer cleanup()
+		scheduler := initializeBackupScheduler(db, initializeBackupService(db, dockerService, mcService))
+
+		enabled := !disabled
+		schedule, err := scheduler.SetSchedule(ctx, serverID, &models.BackupScheduleRequest{
+			Cron:        cronExpr,
+			Enabled:     &enabled,
+			SaveWorld:   saveWorld,
+			Destination: destination,
+			Retention: models.RetentionPolicy{
+				Last:   keepLast,
+				Daily:  keepDaily,
+				Weekly: keepWeekly,
+			},
+		})
+		if err != nil {
+			logger.Error("Failed to set backup schedule", "error", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ Backup schedule of server %s set!\n\n", serverID)
+		printBackupSchedule(schedule)
+	},
+}
+
+var serverBackupScheduleShowCmd = &cobra.Command{
+	Use:     "show <server-id>",
+	Short:   "Show the backup schedule of a server",
+	Long:    `Show the backup schedule of a server and the outcome of its last run.`,
+	Example: `  dockermc-cloud-manager server backup schedule show abc123...`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID := args[0]
+		outputFormat, _ := cmd.Flags().GetString("output")
+		ctx := context.Background()
+
+		// Initialize services
+		db, dockerService, mcService, cleanup := initializeServices()
+		defer cleanup()
+		scheduler := initializeBackupScheduler(db, initializeBackupService(db, dockerService, mcService))
+
+		schedule, err := scheduler.GetSchedule(ctx, serverID)
+		if err != nil {
+			logger.Error("Failed to get backup schedule", "error", err)
+			os.Exit(1)
+		}
+
+		if outputFormat == "json" {
+			data, _ := json.MarshalIndent(schedule, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+
+		printBackupSchedule(schedule)
+	},
+}
+
+var serverBackupScheduleRemoveCmd = &cobra.Command{
+	Use:     "remove <server-id>",
+	Short:   "Remove the backup schedule of a server",
+	Long:    `Remove the backup schedule of a server. Existing backups are kept.`,
+	Example: `  dockermc-cloud-manager server backup schedule remove abc123...`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID := args[0]
+		ctx := context.Background()
+
+		// Initialize services
+		db, dockerService, mcService, cleanup := initializeServices()
+		defer cleanup()
+		scheduler := initializeBackupScheduler(db, initializeBackupService(db, dockerService, mcService))
+
+		if err := scheduler.DeleteSchedule(ctx, serverID); err != nil {
+			logger.Error("Failed to remove backup schedule", "error", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ Backup schedule of server %s removed!\n", serverID)
+	},
+}
+
+// printBackupSchedule prints a backup schedule in a human readable form
+func printBackupSchedule(schedule *models.BackupSchedule) {
+	fmt.Printf("Cron:        %s\n", schedule.Cron)
+	fmt.Printf("Enabled:     %t\n", schedule.Enabled)
+	fmt.Printf("Save World:  %t\n", schedule.SaveWorld)
+	if schedule.Destination != "" {
+		fmt.Printf("Destination: %s\n", schedule.Destination)
+	} else {
+		fmt.Printf("Destination: default\n")
+	}
+	if schedule.Retention.IsZero() {
+		fmt.Printf("Retention:   keep all\n")
+	} else {
+		fmt.Printf("Retention:   last %d, daily %d, weekly %d\n",
+			schedule.Retention.Last, schedule.Retention.Daily, schedule.Retention.Weekly)
+	}
+	if schedule.NextRunAt != nil && schedule.Enabled {
+		fmt.Printf("Next Run:    %s\n", schedule.NextRunAt.Local().Format("2006-01-02 15:04"))
+	}
+	if schedule.LastRunAt != nil {
+		fmt.Printf("Last Run:    %s (%s)\n", schedule.LastRunAt.Local().Format("2006-01-02 15:04"), schedule.LastStatus)
+	}
+	if schedule.LastError != "" {
+		fmt.Printf("Last Error:  %s\n", schedule.LastError)
+	}
+	if schedule.ConsecutiveFailures > 0 {
+		fmt.Printf("Failures:    %d in a row\n", schedule.ConsecutiveFailures)
+	}
+}
+
+func init() {
+	serverCmd.AddCommand(serverBackupCmd)
+
+	serverBackupCmd.AddCommand(serverBackupCreateCmd)
+	serverBackupCreateCmd.Flags().Bool("save-world", false, "Flush the world with save-off/save-all before archiving a running server")
+	serverBackupCreateCmd.Flags().String("destination", "", "Backup destination to write to (local, s3), the configured default if empty")
+
+	serverBackupCmd.AddCommand(serverBackupListCmd)
+	serverBackupListCmd.Flags().StringP("output", "o", "table", "Output format (table, json)")
+
+	serverBackupCmd.AddCommand(serverBackupRestoreCmd)
+	serverBackupRestoreCmd.Flags().BoolP("force", "f", false, "Skip confirmation prompt")
+
+	serverBackupCmd.AddCommand(serverBackupDeleteCmd)
+
+	serverBackupCmd.AddCommand(serverBackupScheduleCmd)
+	serverBackupScheduleCmd.AddCommand(serverBackupScheduleSetCmd)
+	serverBackupScheduleSetCmd.Flags().String("cron", "", "Cron expression or descriptor like @daily (required)")
+	serverBackupScheduleSetCmd.Flags().Int("keep-last", 0, "Keep the N most recent scheduled backups")
+	serverBackupScheduleSetCmd.Flags().Int("keep-daily", 0, "Keep the newest scheduled backup of each of the last N days")
+	serverBackupScheduleSetCmd.Flags().Int("keep-weekly", 0, "Keep the newest scheduled backup of each of the last N weeks")
+	serverBackupScheduleSetCmd.Flags().Bool("save-world", false, "Flush the world with save-off/save-all before each backup")
+	serverBackupScheduleSetCmd.Flags().String("destination", "", "Backup destination to write to (local, s3), the configured default if empty")
+	serverBackupScheduleSetCmd.Flags().Bool("disabled", false, "Store the schedule without running it")
+	serverBackupScheduleSetCmd.MarkFlagRequired("cron")
+
+	serverBackupScheduleCmd.AddCommand(serverBackupScheduleShowCmd)
+	serverBackupScheduleShowCmd.Flags().StringP("output", "o", "table", "Output format (table, json)")
+
+	serverBackupScheduleCmd.AddCommand(serverBackupScheduleRemoveCmd)
+}
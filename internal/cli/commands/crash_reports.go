@@ -0,0 +1,159 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"github.com/spf13/cobra"
+)
+
+// addCrashPolicyFlags adds the flags of the crash policy to a command
+func addCrashPolicyFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("max-crashes", 0, "Crashes within the crash window before the server is stopped (0 for the default of 5)")
+	cmd.Flags().Duration("crash-window", 0, "Window crashes are counted in (0 for the default of 10m)")
+	cmd.Flags().Duration("crash-backoff", 0, "Delay before a crashed server is started again, doubled per crash (0 for the default of 10s)")
+	cmd.Flags().Duration("max-crash-backoff", 0, "Longest delay before a crashed server is started again (0 for the default of 5m)")
+}
+
+// crashPolicyFlagsChanged reports whether any crash policy flag was set
+func crashPolicyFlagsChanged(cmd *cobra.Command) bool {
+	for _, name := range []string{"max-crashes", "crash-window", "crash-backoff", "max-crash-backoff"} {
+		if cmd.Flags().Changed(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyCrashPolicyFlags sets the fields of a crash policy whose flags were set
+func applyCrashPolicyFlags(cmd *cobra.Command, policy *models.CrashPolicy) {
+	if cmd.Flags().Changed("max-crashes") {
+		policy.MaxCrashes, _ = cmd.Flags().GetInt("max-crashes")
+	}
+	if cmd.Flags().Changed("crash-window") {
+		window, _ := cmd.Flags().GetDuration("crash-window")
+		policy.WindowSeconds = int(window.Seconds())
+	}
+	if cmd.Flags().Changed("crash-backoff") {
+		backoff, _ := cmd.Flags().GetDuration("crash-backoff")
+		policy.BackoffSeconds = int(backoff.Seconds())
+	}
+	if cmd.Flags().Changed("max-crash-backoff") {
+		maxBackoff, _ := cmd.Flags().GetDuration("max-crash-backoff")
+		policy.MaxBackoffSeconds = int(maxBackoff.Seconds())
+	}
+}
+
+var serverCrashReportsCmd = &cobra.Command{
+	Use:   "crash-reports",
+	Short: "Show the crash reports of a server",
+	Long: `List and show the crash reports of a server.
+
+A crash report is captured when a server crashed too often within its crash window and was
+stopped with the status crash_loop. It holds the last lines of the server log and the newest
+file Minecraft wrote to crash-reports/. Start the server again once the cause is fixed.`,
+}
+
+var serverCrashReportsListCmd = &cobra.Command{
+	Use:   "list <server-id>",
+	Short: "List the crash reports of a server",
+	Long:  `List the crash reports of a server, newest first.`,
+	Example: `  dockermc-cloud-manager server crash-reports list abc123...
+  dockermc-cloud-manager server crash-reports list abc123... --output json`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID := args[0]
+		outputFormat, _ := cmd.Flags().GetString("output")
+		ctx := context.Background()
+
+		// Initialize services
+		_, _, mcService, cleanup := initializeServices()
+		defer cleanup()
+
+		reports, err := mcService.CrashReports(ctx, serverID)
+		if err != nil {
+			logger.Error("Failed to list crash reports", "error", err)
+			os.Exit(1)
+		}
+
+		if outputFormat == "json" {
+			data, _ := json.MarshalIndent(reports, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+
+		if len(reports) == 0 {
+			fmt.Println("No crash reports found.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "ID\tCREATED\tCRASHES\tEXIT CODE\tOOM KILLED\tCRASH REPORT")
+		for _, report := range reports {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%t\t%s\n",
+				report.ID,
+				report.CreatedAt.Format("2006-01-02 15:04"),
+				report.Crashes,
+				report.ExitCode,
+				report.OOMKilled,
+				report.CrashReportFile,
+			)
+		}
+		w.Flush()
+	},
+}
+
+var serverCrashReportsShowCmd = &cobra.Command{
+	Use:     "show <server-id> <report-id>",
+	Short:   "Show a crash report of a server",
+	Long:    `Show a crash report with the captured log lines and Minecraft crash report.`,
+	Example: `  dockermc-cloud-manager server crash-reports show abc123... def456...`,
+	Args:    cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID := args[0]
+		reportID := args[1]
+		outputFormat, _ := cmd.Flags().GetString("output")
+		ctx := context.Background()
+
+		// Initialize services
+		_, _, mcService, cleanup := initializeServices()
+		defer cleanup()
+
+		report, err := mcService.GetCrashReport(ctx, serverID, reportID)
+		if err != nil {
+			logger.Error("Failed to get crash report", "error", err)
+			os.Exit(1)
+		}
+
+		if outputFormat == "json" {
+			data, _ := json.MarshalIndent(report, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+
+		fmt.Printf("ID:         %s\n", report.ID)
+		fmt.Printf("Created:    %s\n", report.CreatedAt.Format(time.RFC1123))
+		fmt.Printf("Crashes:    %d\n", report.Crashes)
+		fmt.Printf("Exit Code:  %d\n", report.ExitCode)
+		fmt.Printf("OOM Killed: %t\n", report.OOMKilled)
+		fmt.Printf("\nLast log lines:\n%s\n", report.Logs)
+		if report.CrashReportFile != "" {
+			fmt.Printf("\ncrash-reports/%s:\n%s\n", report.CrashReportFile, report.CrashReport)
+		}
+	},
+}
+
+func init() {
+	serverCmd.AddCommand(serverCrashReportsCmd)
+
+	serverCrashReportsCmd.AddCommand(serverCrashReportsListCmd)
+	serverCrashReportsListCmd.Flags().StringP("output", "o", "table", "Output format (table, json)")
+
+	serverCrashReportsCmd.AddCommand(serverCrashReportsShowCmd)
+	serverCrashReportsShowCmd.Flags().StringP("output", "o", "table", "Output format (table, json)")
+}
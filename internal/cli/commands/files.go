@@ -0,0 +1,234 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/service"
+	"github.com/spf13/cobra"
+)
+
+// Helper function to initialize the file service on top of the server services
+func initializeFileService(dockerService *service.DockerService, mcService *service.MinecraftServerService) *service.FileService {
+	return service.NewFileService(dockerService, mcService, int64(cfg.FileMaxSizeMB)*1024*1024, logger)
+}
+
+var serverFilesCmd = &cobra.Command{
+	Use:   "files",
+	Short: "Manage the files of a server",
+	Long: `List, read, write, move and delete files in a server's volume.
+
+Paths are relative to the server's /data directory and work whether the server is running or stopped.`,
+}
+
+var serverFilesListCmd = &cobra.Command{
+	Use:   "ls <server-id> [path]",
+	Short: "List a directory of a server",
+	Example: `  dockermc-cloud-manager server files ls abc123...
+  dockermc-cloud-manager server files ls abc123... plugins`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID := args[0]
+		dir := "/"
+		if len(args) > 1 {
+			dir = args[1]
+		}
+		ctx := context.Background()
+
+		// Initialize services
+		_, dockerService, mcService, cleanup := initializeServices()
+		defer cleanup()
+		fileService := initializeFileService(dockerService, mcService)
+
+		listing, err := fileService.ListDirectory(ctx, serverID, dir)
+		if err != nil {
+			logger.Error("Failed to list directory", "error", err)
+			os.Exit(1)
+		}
+
+		if len(listing.Entries) == 0 {
+			fmt.Printf("%s is empty.\n", listing.Path)
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "MODE\tSIZE\tMODIFIED\tNAME")
+		for _, entry := range listing.Entries {
+			name := entry.Name
+			size := formatBytes(entry.Size)
+			if entry.IsDir {
+				name += "/"
+				size = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.Mode, size, entry.ModTime.Format("2006-01-02 15:04"), name)
+		}
+		w.Flush()
+	},
+}
+
+var serverFilesGetCmd = &cobra.Command{
+	Use:   "get <server-id> <path> [file]",
+	Short: "Download a file from a server",
+	Long:  `Download a file from a server's volume. Without a target file it is written to stdout.`,
+	Example: `  dockermc-cloud-manager server files get abc123... server.properties
+  dockermc-cloud-manager server files get abc123... logs/latest.log ./latest.log`,
+	Args: cobra.RangeArgs(2, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID := args[0]
+		ctx := context.Background()
+
+		// Initialize services
+		_, dockerService, mcService, cleanup := initializeServices()
+		defer cleanup()
+		fileService := initializeFileService(dockerService, mcService)
+
+		content, _, err := fileService.OpenFile(ctx, serverID, args[1])
+		if err != nil {
+			logger.Error("Failed to open file", "error", err)
+			os.Exit(1)
+		}
+		defer content.Close()
+
+		var out io.Writer = os.Stdout
+		if len(args) > 2 {
+			file, err := os.Create(args[2])
+			if err != nil {
+				logger.Error("Failed to create file", "error", err)
+				os.Exit(1)
+			}
+			defer file.Close()
+			out = file
+		}
+
+		if _, err := io.Copy(out, content); err != nil {
+			logger.Error("Failed to download file", "error", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var serverFilesPutCmd = &cobra.Command{
+	Use:     "put <server-id> <file> <path>",
+	Short:   "Upload a file to a server",
+	Long:    `Upload a local file into a server's volume. Missing directories are created, an existing file is replaced.`,
+	Example: `  dockermc-cloud-manager server files put abc123... ./EssentialsX.jar plugins/EssentialsX.jar`,
+	Args:    cobra.ExactArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID := args[0]
+		ctx := context.Background()
+
+		file, err := os.Open(args[1])
+		if err != nil {
+			logger.Error("Failed to open file", "error", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+
+		stat, err := file.Stat()
+		if err != nil {
+			logger.Error("Failed to read file", "error", err)
+			os.Exit(1)
+		}
+
+		// Initialize services
+		_, dockerService, mcService, cleanup := initializeServices()
+		defer cleanup()
+		fileService := initializeFileService(dockerService, mcService)
+
+		info, err := fileService.WriteFile(ctx, serverID, args[2], file, stat.Size())
+		if err != nil {
+			logger.Error("Failed to upload file", "error", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ Uploaded %s to %s (%s)\n", args[1], info.Path, formatBytes(info.Size))
+	},
+}
+
+var serverFilesMkdirCmd = &cobra.Command{
+	Use:   "mkdir <server-id> <path>",
+	Short: "Create a directory on a server",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID := args[0]
+		ctx := context.Background()
+
+		// Initialize services
+		_, dockerService, mcService, cleanup := initializeServices()
+		defer cleanup()
+		fileService := initializeFileService(dockerService, mcService)
+
+		info, err := fileService.CreateDirectory(ctx, serverID, args[1])
+		if err != nil {
+			logger.Error("Failed to create directory", "error", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ Created directory %s\n", info.Path)
+	},
+}
+
+var serverFilesMoveCmd = &cobra.Command{
+	Use:     "mv <server-id> <path> <new-path>",
+	Short:   "Rename or move a file or directory on a server",
+	Example: `  dockermc-cloud-manager server files mv abc123... plugins/Broken.jar plugins/disabled/Broken.jar`,
+	Args:    cobra.ExactArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID := args[0]
+		ctx := context.Background()
+
+		// Initialize services
+		_, dockerService, mcService, cleanup := initializeServices()
+		defer cleanup()
+		fileService := initializeFileService(dockerService, mcService)
+
+		info, err := fileService.MoveFile(ctx, serverID, args[1], args[2])
+		if err != nil {
+			logger.Error("Failed to move file", "error", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ Moved %s to %s\n", service.CleanVolumePath(args[1]), info.Path)
+	},
+}
+
+var serverFilesRemoveCmd = &cobra.Command{
+	Use:   "rm <server-id> <path>",
+	Short: "Delete a file or directory on a server",
+	Example: `  dockermc-cloud-manager server files rm abc123... plugins/Broken.jar
+  dockermc-cloud-manager server files rm abc123... logs --recursive`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID := args[0]
+		recursive, _ := cmd.Flags().GetBool("recursive")
+		ctx := context.Background()
+
+		// Initialize services
+		_, dockerService, mcService, cleanup := initializeServices()
+		defer cleanup()
+		fileService := initializeFileService(dockerService, mcService)
+
+		if err := fileService.DeleteFile(ctx, serverID, args[1], recursive); err != nil {
+			logger.Error("Failed to delete file", "error", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ Deleted %s\n", service.CleanVolumePath(args[1]))
+	},
+}
+
+func init() {
+	serverCmd.AddCommand(serverFilesCmd)
+
+	serverFilesCmd.AddCommand(serverFilesListCmd)
+	serverFilesCmd.AddCommand(serverFilesGetCmd)
+	serverFilesCmd.AddCommand(serverFilesPutCmd)
+	serverFilesCmd.AddCommand(serverFilesMkdirCmd)
+	serverFilesCmd.AddCommand(serverFilesMoveCmd)
+
+	serverFilesCmd.AddCommand(serverFilesRemoveCmd)
+	serverFilesRemoveCmd.Flags().BoolP("recursive", "r", false, "Delete directories together with their content")
+}
@@ -0,0 +1,285 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/service"
+	"github.com/spf13/cobra"
+)
+
+// Helper function to initialize the player list service for whitelist, op and ban commands
+func initializePlayerListService(dockerService *service.DockerService, mcService *service.MinecraftServerService) *service.PlayerListService {
+	return service.NewPlayerListService(initializeFileService(dockerService, mcService), mcService, service.NewMojangClient(), logger)
+}
+
+// runPlayerListCommand initializes the services, runs a player list action and prints its result
+func runPlayerListCommand[T any](cmd *cobra.Command, action func(ctx context.Context, s *service.PlayerListService) ([]T, error), print func([]T)) {
+	outputFormat, _ := cmd.Flags().GetString("output")
+
+	// Initialize services
+	_, dockerService, mcService, cleanup := initializeServices()
+	defer cleanup()
+	playerListService := initializePlayerListService(dockerService, mcService)
+
+	list, err := action(context.Background(), playerListService)
+	if err != nil {
+		logger.Error("Failed to change player list", "error", err)
+		os.Exit(1)
+	}
+
+	if outputFormat == "json" {
+		data, _ := json.MarshalIndent(list, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	if len(list) == 0 {
+		fmt.Println("The list is empty.")
+		return
+	}
+	print(list)
+}
+
+func printWhitelist(entries []models.WhitelistEntry) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tUUID")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\n", entry.Name, entry.UUID)
+	}
+	w.Flush()
+}
+
+func printOperators(entries []models.OperatorEntry) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tUUID\tLEVEL\tBYPASSES LIMIT")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%t\n", entry.Name, entry.UUID, entry.Level, entry.BypassesPlayerLimit)
+	}
+	w.Flush()
+}
+
+func printPlayerBans(bans []models.PlayerBan) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tUUID\tREASON\tSOURCE\tCREATED\tEXPIRES")
+	for _, ban := range bans {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", ban.Name, ban.UUID, ban.Reason, ban.Source, formatBanTime(ban.Created, "-"), formatBanTime(ban.Expires, "never"))
+	}
+	w.Flush()
+}
+
+func printIPBans(bans []models.IPBan) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "IP\tREASON\tSOURCE\tCREATED\tEXPIRES")
+	for _, ban := range bans {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", ban.IP, ban.Reason, ban.Source, formatBanTime(ban.Created, "-"), formatBanTime(ban.Expires, "never"))
+	}
+	w.Flush()
+}
+
+// formatBanTime formats an optional time of a ban
+func formatBanTime(t *time.Time, fallback string) string {
+	if t == nil {
+		return fallback
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+var serverWhitelistCmd = &cobra.Command{
+	Use:   "whitelist",
+	Short: "Manage the whitelist of a server",
+	Long: `Manage the whitelist of a server. Running servers are changed through the console,
+the whitelist.json of stopped servers is edited directly.
+
+The whitelist is only enforced if white-list is enabled, e.g. with "server properties set <server-id> white-list=true".`,
+}
+
+var serverWhitelistListCmd = &cobra.Command{
+	Use:     "list <server-id>",
+	Short:   "List the whitelisted players of a server",
+	Example: `  dockermc-cloud-manager server whitelist list abc123...`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runPlayerListCommand(cmd, func(ctx context.Context, s *service.PlayerListService) ([]models.WhitelistEntry, error) {
+			return s.Whitelist(ctx, args[0])
+		}, printWhitelist)
+	},
+}
+
+var serverWhitelistAddCmd = &cobra.Command{
+	Use:   "add <server-id> <player>",
+	Short: "Add a player to the whitelist of a server",
+	Example: `  dockermc-cloud-manager server whitelist add abc123... Notch
+  dockermc-cloud-manager server whitelist add abc123... Steve --uuid 8667ba71-b85a-4004-af54-457a9734eed7`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		playerUUID, _ := cmd.Flags().GetString("uuid")
+		runPlayerListCommand(cmd, func(ctx context.Context, s *service.PlayerListService) ([]models.WhitelistEntry, error) {
+			return s.AddToWhitelist(ctx, args[0], &models.PlayerRequest{Name: args[1], UUID: playerUUID})
+		}, printWhitelist)
+	},
+}
+
+var serverWhitelistRemoveCmd = &cobra.Command{
+	Use:     "remove <server-id> <player>",
+	Short:   "Remove a player from the whitelist of a server",
+	Example: `  dockermc-cloud-manager server whitelist remove abc123... Notch`,
+	Args:    cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		runPlayerListCommand(cmd, func(ctx context.Context, s *service.PlayerListService) ([]models.WhitelistEntry, error) {
+			return s.RemoveFromWhitelist(ctx, args[0], args[1])
+		}, printWhitelist)
+	},
+}
+
+var serverOpsCmd = &cobra.Command{
+	Use:   "ops",
+	Short: "Manage the operators of a server",
+	Long: `Manage the operators of a server. Running servers are changed through the console,
+the ops.json of stopped servers is edited directly. New operators get the level of op-permission-level.`,
+}
+
+var serverOpsListCmd = &cobra.Command{
+	Use:     "list <server-id>",
+	Short:   "List the operators of a server",
+	Example: `  dockermc-cloud-manager server ops list abc123...`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runPlayerListCommand(cmd, func(ctx context.Context, s *service.PlayerListService) ([]models.OperatorEntry, error) {
+			return s.Operators(ctx, args[0])
+		}, printOperators)
+	},
+}
+
+var serverOpsAddCmd = &cobra.Command{
+	Use:     "add <server-id> <player>",
+	Aliases: []string{"op"},
+	Short:   "Give a player operator permissions on a server",
+	Example: `  dockermc-cloud-manager server ops add abc123... Notch`,
+	Args:    cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		playerUUID, _ := cmd.Flags().GetString("uuid")
+		runPlayerListCommand(cmd, func(ctx context.Context, s *service.PlayerListService) ([]models.OperatorEntry, error) {
+			return s.AddOperator(ctx, args[0], &models.PlayerRequest{Name: args[1], UUID: playerUUID})
+		}, printOperators)
+	},
+}
+
+var serverOpsRemoveCmd = &cobra.Command{
+	Use:     "remove <server-id> <player>",
+	Aliases: []string{"deop"},
+	Short:   "Take the operator permissions of a player on a server",
+	Example: `  dockermc-cloud-manager server ops remove abc123... Notch`,
+	Args:    cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		runPlayerListCommand(cmd, func(ctx context.Context, s *service.PlayerListService) ([]models.OperatorEntry, error) {
+			return s.RemoveOperator(ctx, args[0], args[1])
+		}, printOperators)
+	},
+}
+
+var serverBansCmd = &cobra.Command{
+	Use:   "bans",
+	Short: "Manage the banned players and IP addresses of a server",
+	Long: `Manage the banned players and IP addresses of a server. Running servers are changed through
+the console and kick banned players, the ban lists of stopped servers are edited directly.`,
+}
+
+var serverBansListCmd = &cobra.Command{
+	Use:   "list <server-id>",
+	Short: "List the banned players or IP addresses of a server",
+	Example: `  dockermc-cloud-manager server bans list abc123...
+  dockermc-cloud-manager server bans list abc123... --ip`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if ip, _ := cmd.Flags().GetBool("ip"); ip {
+			runPlayerListCommand(cmd, func(ctx context.Context, s *service.PlayerListService) ([]models.IPBan, error) {
+				return s.IPBans(ctx, args[0])
+			}, printIPBans)
+			return
+		}
+		runPlayerListCommand(cmd, func(ctx context.Context, s *service.PlayerListService) ([]models.PlayerBan, error) {
+			return s.PlayerBans(ctx, args[0])
+		}, printPlayerBans)
+	},
+}
+
+var serverBansBanCmd = &cobra.Command{
+	Use:     "ban <server-id> <player>",
+	Short:   "Ban a player from a server",
+	Example: `  dockermc-cloud-manager server bans ban abc123... Griefer --reason "Destroyed spawn"`,
+	Args:    cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		playerUUID, _ := cmd.Flags().GetString("uuid")
+		reason, _ := cmd.Flags().GetString("reason")
+		runPlayerListCommand(cmd, func(ctx context.Context, s *service.PlayerListService) ([]models.PlayerBan, error) {
+			return s.BanPlayer(ctx, args[0], &models.BanPlayerRequest{Name: args[1], UUID: playerUUID, Reason: reason})
+		}, printPlayerBans)
+	},
+}
+
+var serverBansPardonCmd = &cobra.Command{
+	Use:     "pardon <server-id> <player>",
+	Short:   "Lift the ban of a player on a server",
+	Example: `  dockermc-cloud-manager server bans pardon abc123... Griefer`,
+	Args:    cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		runPlayerListCommand(cmd, func(ctx context.Context, s *service.PlayerListService) ([]models.PlayerBan, error) {
+			return s.PardonPlayer(ctx, args[0], args[1])
+		}, printPlayerBans)
+	},
+}
+
+var serverBansBanIPCmd = &cobra.Command{
+	Use:     "ban-ip <server-id> <ip>",
+	Short:   "Ban an IP address from a server",
+	Example: `  dockermc-cloud-manager server bans ban-ip abc123... 203.0.113.7 --reason "Bot attack"`,
+	Args:    cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		reason, _ := cmd.Flags().GetString("reason")
+		runPlayerListCommand(cmd, func(ctx context.Context, s *service.PlayerListService) ([]models.IPBan, error) {
+			return s.BanIP(ctx, args[0], &models.BanIPRequest{IP: args[1], Reason: reason})
+		}, printIPBans)
+	},
+}
+
+var serverBansPardonIPCmd = &cobra.Command{
+	Use:     "pardon-ip <server-id> <ip>",
+	Short:   "Lift the ban of an IP address on a server",
+	Example: `  dockermc-cloud-manager server bans pardon-ip abc123... 203.0.113.7`,
+	Args:    cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		runPlayerListCommand(cmd, func(ctx context.Context, s *service.PlayerListService) ([]models.IPBan, error) {
+			return s.PardonIP(ctx, args[0], args[1])
+		}, printIPBans)
+	},
+}
+
+func init() {
+	serverCmd.AddCommand(serverWhitelistCmd)
+	serverCmd.AddCommand(serverOpsCmd)
+	serverCmd.AddCommand(serverBansCmd)
+
+	serverWhitelistCmd.AddCommand(serverWhitelistListCmd, serverWhitelistAddCmd, serverWhitelistRemoveCmd)
+	serverOpsCmd.AddCommand(serverOpsListCmd, serverOpsAddCmd, serverOpsRemoveCmd)
+	serverBansCmd.AddCommand(serverBansListCmd, serverBansBanCmd, serverBansPardonCmd, serverBansBanIPCmd, serverBansPardonIPCmd)
+
+	for _, cmd := range []*cobra.Command{
+		serverWhitelistListCmd, serverWhitelistAddCmd, serverWhitelistRemoveCmd,
+		serverOpsListCmd, serverOpsAddCmd, serverOpsRemoveCmd,
+		serverBansListCmd, serverBansBanCmd, serverBansPardonCmd, serverBansBanIPCmd, serverBansPardonIPCmd,
+	} {
+		cmd.Flags().StringP("output", "o", "table", "Output format (table, json)")
+	}
+
+	for _, cmd := range []*cobra.Command{serverWhitelistAddCmd, serverOpsAddCmd, serverBansBanCmd} {
+		cmd.Flags().String("uuid", "", "UUID of the player, skips the Mojang lookup for stopped servers")
+	}
+	serverBansBanCmd.Flags().String("reason", "", "Reason shown to the player")
+	serverBansBanIPCmd.Flags().String("reason", "", "Reason shown to players connecting from the address")
+	serverBansListCmd.Flags().Bool("ip", false, "List banned IP addresses instead of players")
+}
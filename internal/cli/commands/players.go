@@ -0,0 +1,221 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/database"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/service"
+	"github.com/spf13/cobra"
+)
+
+// Helper function to initialize the player service for player commands
+func initializePlayerService(db *database.DB, mcService *service.MinecraftServerService) *service.PlayerService {
+	return service.NewPlayerService(database.NewPlayerSessionRepository(db), mcService, logger)
+}
+
+// playerFilterFromFlags reads the --server, --since and --until flags
+func playerFilterFromFlags(cmd *cobra.Command) models.PlayerSessionFilter {
+	filter := models.PlayerSessionFilter{}
+	filter.ServerID, _ = cmd.Flags().GetString("server")
+	for name, target := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		value, _ := cmd.Flags().GetString(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			logger.Error("Invalid --"+name+", use an RFC 3339 timestamp like 2006-01-02T15:04:05Z", "value", value)
+			os.Exit(1)
+		}
+		*target = t
+	}
+	return filter
+}
+
+// formatLeftAt formats the end of a session, open sessions are still online
+func formatLeftAt(leftAt *time.Time) string {
+	if leftAt == nil {
+		return "online"
+	}
+	return leftAt.Local().Format("2006-01-02 15:04")
+}
+
+var playersCmd = &cobra.Command{
+	Use:   "players",
+	Short: "Show the players seen on the servers",
+	Long: `List the players that were on the servers and their sessions.
+
+Sessions are recorded by 'serve', which follows the logs of running servers for joins and leaves.`,
+}
+
+var playersListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List players",
+	Long: `List the players with sessions in a time range, most recently seen first.
+
+--since and --until select the sessions that overlap the range, so players that joined
+before --since and were still online count as well.`,
+	Example: `  dockermc-cloud-manager players list
+  dockermc-cloud-manager players list --server abc123... --since 2026-10-15T18:00:00+02:00 --until 2026-10-16T06:00:00+02:00`,
+	Run: func(cmd *cobra.Command, args []string) {
+		outputFormat, _ := cmd.Flags().GetString("output")
+		filter := playerFilterFromFlags(cmd)
+		ctx := context.Background()
+
+		// Initialize services
+		db, _, mcService, cleanup := initializeServices()
+		defer cleanup()
+		playerService := initializePlayerService(db, mcService)
+
+		players, err := playerService.ListPlayers(ctx, filter)
+		if err != nil {
+			logger.Error("Failed to list players", "error", err)
+			os.Exit(1)
+		}
+
+		if outputFormat == "json" {
+			data, _ := json.MarshalIndent(players, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+
+		if len(players) == 0 {
+			fmt.Println("No players found.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "NAME\tUUID\tONLINE\tSESSIONS\tFIRST SEEN\tLAST SEEN")
+		for _, player := range players {
+			fmt.Fprintf(w, "%s\t%s\t%t\t%d\t%s\t%s\n",
+				player.Name,
+				player.UUID,
+				player.Online,
+				player.Sessions,
+				player.FirstSeen.Local().Format("2006-01-02 15:04"),
+				player.LastSeen.Local().Format("2006-01-02 15:04"),
+			)
+		}
+		w.Flush()
+	},
+}
+
+var playersShowCmd = &cobra.Command{
+	Use:     "show <player-uuid>",
+	Short:   "Show the sessions of a player",
+	Long:    `Show a player with its sessions, newest first.`,
+	Example: `  dockermc-cloud-manager players show 069a79f4-44e9-4726-a5be-fca90e38aaf5`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		outputFormat, _ := cmd.Flags().GetString("output")
+		filter := playerFilterFromFlags(cmd)
+		ctx := context.Background()
+
+		// Initialize services
+		db, _, mcService, cleanup := initializeServices()
+		defer cleanup()
+		playerService := initializePlayerService(db, mcService)
+
+		player, err := playerService.GetPlayer(ctx, args[0], filter)
+		if err != nil {
+			logger.Error("Failed to get player", "error", err)
+			os.Exit(1)
+		}
+
+		if outputFormat == "json" {
+			data, _ := json.MarshalIndent(player, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+
+		fmt.Printf("Name:       %s\n", player.Name)
+		fmt.Printf("UUID:       %s\n", player.UUID)
+		fmt.Printf("Online:     %t\n", player.Online)
+		fmt.Printf("First Seen: %s\n", player.FirstSeen.Local().Format(time.RFC1123))
+		fmt.Printf("Last Seen:  %s\n", player.LastSeen.Local().Format(time.RFC1123))
+		fmt.Printf("\nSessions:\n")
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "SERVER ID\tJOINED\tLEFT\tIP")
+		for _, session := range player.SessionList {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				session.ServerID,
+				session.JoinedAt.Local().Format("2006-01-02 15:04"),
+				formatLeftAt(session.LeftAt),
+				session.IP,
+			)
+		}
+		w.Flush()
+	},
+}
+
+var serverPlayersCmd = &cobra.Command{
+	Use:     "players <server-id>",
+	Short:   "List the players online on a server",
+	Long:    `List the players online on a server, from the sessions recorded by 'serve'.`,
+	Example: `  dockermc-cloud-manager server players abc123...`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		outputFormat, _ := cmd.Flags().GetString("output")
+		ctx := context.Background()
+
+		// Initialize services
+		db, _, mcService, cleanup := initializeServices()
+		defer cleanup()
+		playerService := initializePlayerService(db, mcService)
+
+		sessions, err := playerService.OnlinePlayers(ctx, args[0])
+		if err != nil {
+			logger.Error("Failed to list online players", "error", err)
+			os.Exit(1)
+		}
+
+		if outputFormat == "json" {
+			data, _ := json.MarshalIndent(sessions, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+
+		if len(sessions) == 0 {
+			fmt.Println("No players online.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "NAME\tUUID\tJOINED\tIP")
+		for _, session := range sessions {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				session.PlayerName,
+				session.PlayerUUID,
+				session.JoinedAt.Local().Format("2006-01-02 15:04"),
+				session.IP,
+			)
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(playersCmd)
+
+	playersCmd.AddCommand(playersListCmd)
+	playersListCmd.Flags().String("server", "", "Only show players of this server ID")
+	playersListCmd.Flags().String("since", "", "Only show players online at or after this RFC 3339 time")
+	playersListCmd.Flags().String("until", "", "Only show players online at or before this RFC 3339 time")
+	playersListCmd.Flags().StringP("output", "o", "table", "Output format (table, json)")
+
+	playersCmd.AddCommand(playersShowCmd)
+	playersShowCmd.Flags().String("server", "", "Only show sessions on this server ID")
+	playersShowCmd.Flags().String("since", "", "Only show sessions at or after this RFC 3339 time")
+	playersShowCmd.Flags().String("until", "", "Only show sessions at or before this RFC 3339 time")
+	playersShowCmd.Flags().StringP("output", "o", "table", "Output format (table, json)")
+
+	serverCmd.AddCommand(serverPlayersCmd)
+	serverPlayersCmd.Flags().StringP("output", "o", "table", "Output format (table, json)")
+}
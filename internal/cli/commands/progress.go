@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+)
+
+const (
+	progressBarWidth    = 30
+	progressRenderEvery = 100 * time.Millisecond
+)
+
+// pullProgressBar renders the progress of image pulls as a single updating line
+type pullProgressBar struct {
+	out        io.Writer
+	lastRender time.Time
+}
+
+// newPullProgressBar creates a progress bar writing to out
+func newPullProgressBar(out io.Writer) *pullProgressBar {
+	return &pullProgressBar{out: out}
+}
+
+// Update renders the progress, at most every progressRenderEvery unless the pull finished
+func (b *pullProgressBar) Update(pull *models.ImagePull) {
+	finished := pull.Status != models.ImagePullPulling
+	if !finished && time.Since(b.lastRender) < progressRenderEvery {
+		return
+	}
+	b.lastRender = time.Now()
+
+	filled := min(int(pull.Percent/100*progressBarWidth), progressBarWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+
+	fmt.Fprintf(b.out, "\rPulling %s [%s] %5.1f%% %s / %s ",
+		pull.Image, bar, pull.Percent, formatBytes(pull.DownloadedBytes), formatBytes(pull.TotalBytes))
+
+	switch pull.Status {
+	case models.ImagePullCompleted:
+		fmt.Fprintln(b.out, "done")
+	case models.ImagePullFailed:
+		fmt.Fprintln(b.out, "failed")
+	}
+}
+
+// formatBytes formats a byte count in MB
+func formatBytes(bytes int64) string {
+	return fmt.Sprintf("%.1f MB", float64(bytes)/(1024*1024))
+}
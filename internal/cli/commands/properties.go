@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/service"
+	"github.com/spf13/cobra"
+)
+
+// Helper function to initialize the properties service on top of the server services
+func initializePropertiesService(dockerService *service.DockerService, mcService *service.MinecraftServerService) *service.PropertiesService {
+	return service.NewPropertiesService(initializeFileService(dockerService, mcService), mcService, logger)
+}
+
+// printServerProperties prints the typed properties and the other keys of a server.properties
+func printServerProperties(properties *models.ServerPropertiesResponse) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE")
+	for _, property := range service.ListServerProperties(&properties.Properties) {
+		fmt.Fprintf(w, "%s\t%s\n", property.Key, property.Value)
+	}
+	w.Flush()
+
+	if len(properties.Other) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(properties.Other))
+	for key := range properties.Other {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	fmt.Println("\nOther (read-only):")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	for _, key := range keys {
+		fmt.Fprintf(w, "  %s\t%s\n", key, properties.Other[key])
+	}
+	w.Flush()
+}
+
+var serverPropertiesCmd = &cobra.Command{
+	Use:   "properties",
+	Short: "Show and change the server.properties of a server",
+}
+
+var serverPropertiesShowCmd = &cobra.Command{
+	Use:   "show <server-id>",
+	Short: "Show the server.properties of a server",
+	Example: `  dockermc-cloud-manager server properties show abc123...
+  dockermc-cloud-manager server properties show abc123... --output json`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		outputFormat, _ := cmd.Flags().GetString("output")
+		ctx := context.Background()
+
+		// Initialize services
+		_, dockerService, mcService, cleanup := initializeServices()
+		defer cleanup()
+		propertiesService := initializePropertiesService(dockerService, mcService)
+
+		properties, err := propertiesService.GetProperties(ctx, args[0])
+		if err != nil {
+			logger.Error("Failed to get server properties", "error", err)
+			os.Exit(1)
+		}
+
+		if outputFormat == "json" {
+			data, _ := json.MarshalIndent(properties, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+
+		printServerProperties(properties)
+	},
+}
+
+var serverPropertiesSetCmd = &cobra.Command{
+	Use:   "set <server-id> <key=value>...",
+	Short: "Change properties of a server",
+	Long: `Change properties in the server.properties of a server. Keys are written like in server.properties.
+Comments and other keys are kept. Changes to a running server apply on its next start.
+
+max-players and motd are set with "server update", online-mode is controlled by the manager.`,
+	Example: `  dockermc-cloud-manager server properties set abc123... difficulty=hard pvp=false view-distance=12`,
+	Args:    cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID := args[0]
+		ctx := context.Background()
+
+		values := make(map[string]string, len(args)-1)
+		for _, arg := range args[1:] {
+			key, value, ok := strings.Cut(arg, "=")
+			if !ok {
+				logger.Error("Invalid property, expected key=value", "property", arg)
+				os.Exit(1)
+			}
+			values[strings.TrimSpace(key)] = value
+		}
+
+		update, err := service.ParseServerProperties(values)
+		if err != nil {
+			logger.Error("Invalid properties", "error", err)
+			os.Exit(1)
+		}
+
+		// Initialize services
+		_, dockerService, mcService, cleanup := initializeServices()
+		defer cleanup()
+		propertiesService := initializePropertiesService(dockerService, mcService)
+
+		properties, err := propertiesService.UpdateProperties(ctx, serverID, update)
+		if err != nil {
+			logger.Error("Failed to update server properties", "error", err)
+			os.Exit(1)
+		}
+
+		if len(properties.Changed) == 0 {
+			fmt.Println("Nothing changed.")
+			return
+		}
+		fmt.Printf("✓ Changed %s\n", strings.Join(properties.Changed, ", "))
+		if properties.RestartRequired {
+			fmt.Println("\nThe server is running, restart it to apply the changes.")
+		}
+	},
+}
+
+func init() {
+	serverCmd.AddCommand(serverPropertiesCmd)
+
+	serverPropertiesCmd.AddCommand(serverPropertiesShowCmd)
+	serverPropertiesShowCmd.Flags().StringP("output", "o", "table", "Output format (table, json)")
+
+	serverPropertiesCmd.AddCommand(serverPropertiesSetCmd)
+}
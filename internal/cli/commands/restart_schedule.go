@@ -0,0 +1,165 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/database"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/service"
+	"github.com/spf13/cobra"
+)
+
+// Helper function to initialize the restart scheduler, the schedules are run by the serve command
+func initializeRestartScheduler(db *database.DB, mcService *service.MinecraftServerService) *service.RestartScheduler {
+	restartScheduleRepo := database.NewRestartScheduleRepository(db)
+	operationService := service.NewOperationService(database.NewOperationRepository(db), logger)
+	return service.NewRestartScheduler(mcService, operationService, restartScheduleRepo, logger)
+}
+
+var serverRestartScheduleCmd = &cobra.Command{
+	Use:   "restart-schedule",
+	Short: "Manage the restart schedule of a server",
+	Long: `Set, show, and remove the schedule a server is restarted on automatically.
+
+Scheduled restarts are run by the serve command. Only running servers are restarted,
+a stopped server stays stopped and the run is recorded as skipped.`,
+}
+
+var serverRestartScheduleSetCmd = &cobra.Command{
+	Use:   "set <server-id>",
+	Short: "Set the restart schedule of a server",
+	Long: `Create or replace the restart schedule of a server.
+
+The schedule is a standard 5 field cron expression (minute hour day-of-month month day-of-week)
+or a descriptor like @daily. Prefix it with CRON_TZ=<zone> to use a time zone other than the local one.
+
+With --warning, players see a countdown in chat and are moved to another server through the proxy
+before the restart. The restart happens when the countdown ends, not at the scheduled time.`,
+	Example: `  # Restart every night at 5:00 after a 5 minute warning
+  dockermc-cloud-manager server restart-schedule set abc123... --cron "0 5 * * *" --warning 5m`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID := args[0]
+		cronExpr, _ := cmd.Flags().GetString("cron")
+		warning, _ := cmd.Flags().GetDuration("warning")
+		disabled, _ := cmd.Flags().GetBool("disabled")
+		ctx := context.Background()
+
+		// Initialize services
+		db, _, mcService, cleanup := initializeServices()
+		defer cleanup()
+		scheduler := initializeRestartScheduler(db, mcService)
+
+		enabled := !disabled
+		schedule, err := scheduler.SetSchedule(ctx, serverID, &models.RestartScheduleRequest{
+			Cron:           cronExpr,
+			Enabled:        &enabled,
+			WarningSeconds: int(warning.Seconds()),
+		})
+		if err != nil {
+			logger.Error("Failed to set restart schedule", "error", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ Restart schedule of server %s set!\n\n", serverID)
+		printRestartSchedule(schedule)
+	},
+}
+
+var serverRestartScheduleShowCmd = &cobra.Command{
+	Use:     "show <server-id>",
+	Short:   "Show the restart schedule of a server",
+	Long:    `Show the restart schedule of a server and the outcome of its last run.`,
+	Example: `  dockermc-cloud-manager server restart-schedule show abc123...`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID := args[0]
+		outputFormat, _ := cmd.Flags().GetString("output")
+		ctx := context.Background()
+
+		// Initialize services
+		db, _, mcService, cleanup := initializeServices()
+		defer cleanup()
+		scheduler := initializeRestartScheduler(db, mcService)
+
+		schedule, err := scheduler.GetSchedule(ctx, serverID)
+		if err != nil {
+			logger.Error("Failed to get restart schedule", "error", err)
+			os.Exit(1)
+		}
+
+		if outputFormat == "json" {
+			data, _ := json.MarshalIndent(schedule, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+
+		printRestartSchedule(schedule)
+	},
+}
+
+var serverRestartScheduleRemoveCmd = &cobra.Command{
+	Use:     "remove <server-id>",
+	Short:   "Remove the restart schedule of a server",
+	Long:    `Remove the restart schedule of a server.`,
+	Example: `  dockermc-cloud-manager server restart-schedule remove abc123...`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID := args[0]
+		ctx := context.Background()
+
+		// Initialize services
+		db, _, mcService, cleanup := initializeServices()
+		defer cleanup()
+		scheduler := initializeRestartScheduler(db, mcService)
+
+		if err := scheduler.DeleteSchedule(ctx, serverID); err != nil {
+			logger.Error("Failed to remove restart schedule", "error", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ Restart schedule of server %s removed!\n", serverID)
+	},
+}
+
+// printRestartSchedule prints a restart schedule in a human readable form
+func printRestartSchedule(schedule *models.RestartSchedule) {
+	fmt.Printf("Cron:        %s\n", schedule.Cron)
+	fmt.Printf("Enabled:     %t\n", schedule.Enabled)
+	if schedule.WarningSeconds > 0 {
+		fmt.Printf("Warning:     %s\n", time.Duration(schedule.WarningSeconds)*time.Second)
+	} else {
+		fmt.Printf("Warning:     none\n")
+	}
+	if schedule.NextRunAt != nil && schedule.Enabled {
+		fmt.Printf("Next Run:    %s\n", schedule.NextRunAt.Local().Format("2006-01-02 15:04"))
+	}
+	if schedule.LastRunAt != nil {
+		fmt.Printf("Last Run:    %s (%s)\n", schedule.LastRunAt.Local().Format("2006-01-02 15:04"), schedule.LastStatus)
+	}
+	if schedule.LastError != "" {
+		fmt.Printf("Last Error:  %s\n", schedule.LastError)
+	}
+	if schedule.ConsecutiveFailures > 0 {
+		fmt.Printf("Failures:    %d in a row\n", schedule.ConsecutiveFailures)
+	}
+}
+
+func init() {
+	serverCmd.AddCommand(serverRestartScheduleCmd)
+
+	serverRestartScheduleCmd.AddCommand(serverRestartScheduleSetCmd)
+	serverRestartScheduleSetCmd.Flags().String("cron", "", "Cron expression or descriptor like @daily (required)")
+	serverRestartScheduleSetCmd.Flags().Duration("warning", 0, "Count down in chat and move players to another server before each restart (max 10m)")
+	serverRestartScheduleSetCmd.Flags().Bool("disabled", false, "Store the schedule without running it")
+	serverRestartScheduleSetCmd.MarkFlagRequired("cron")
+
+	serverRestartScheduleCmd.AddCommand(serverRestartScheduleShowCmd)
+	serverRestartScheduleShowCmd.Flags().StringP("output", "o", "table", "Output format (table, json)")
+
+	serverRestartScheduleCmd.AddCommand(serverRestartScheduleRemoveCmd)
+}
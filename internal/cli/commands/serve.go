@@ -69,16 +69,91 @@ If no port is specified, it uses the API_PORT environment variable or defaults t
 		// Initialize repositories
 		serverRepo := database.NewServerRepository(db)
 		proxyRepo := database.NewProxyRepository(db)
+		portRepo := database.NewPortRepository(db)
+		operationRepo := database.NewOperationRepository(db)
+		backupScheduleRepo := database.NewBackupScheduleRepository(db)
+		restartScheduleRepo := database.NewRestartScheduleRepository(db)
+		crashReportRepo := database.NewCrashReportRepository(db)
+		playerSessionRepo := database.NewPlayerSessionRepository(db)
 
 		// Initialize services
 		mcService := service.NewMinecraftServerService(dockerService, serverRepo, logger)
 		proxyService := service.NewProxyService(dockerService, proxyRepo, serverRepo, logger)
+		portAllocator := service.NewPortAllocator(portRepo, cfg.DirectPortRangeStart, cfg.DirectPortRangeEnd, logger)
+		operationService := service.NewOperationService(operationRepo, logger)
+		backupService := initializeBackupService(db, dockerService, mcService)
+		backupScheduler := service.NewBackupScheduler(backupService, backupScheduleRepo, logger)
+		restartScheduler := service.NewRestartScheduler(mcService, operationService, restartScheduleRepo, logger)
+		worldService := initializeWorldService(dockerService, mcService)
+		fileService := initializeFileService(dockerService, mcService)
+		propertiesService := service.NewPropertiesService(fileService, mcService, logger)
+		playerListService := service.NewPlayerListService(fileService, mcService, service.NewMojangClient(), logger)
+		playerService := service.NewPlayerService(playerSessionRepo, mcService, logger)
 
 		// Set proxy service in mcService to enable auto-linking
 		mcService.SetProxyService(proxyService)
+		mcService.SetPortAllocator(portAllocator)
+		mcService.SetCrashReportRepository(crashReportRepo)
+		mcService.SetServerPingEnabled(cfg.ServerPingInterval > 0)
+		mcService.SetSecretBox(loadSecretBox())
+		mcService.SetBackupDestinations(backupDestinationNames())
+
+		// Publish server and proxy changes for the event stream
+		eventBus := service.NewEventBus(cfg.EventHistorySize, logger)
+		mcService.SetEventBus(eventBus)
+		proxyService.SetEventBus(eventBus)
+		operationService.SetEventBus(eventBus)
+
+		// Operations of a previous run can't finish anymore
+		if err := operationService.FailInterrupted(context.Background()); err != nil {
+			logger.Error("Failed to mark interrupted operations", "error", err)
+		}
+
+		// Keep the stored state in line with Docker in the background
+		reconciler := service.NewReconciler(mcService, proxyService, cfg.ReconcileInterval, logger)
+		reconcileCtx, stopReconciler := context.WithCancel(context.Background())
+		defer stopReconciler()
+		go reconciler.Run(reconcileCtx)
+
+		// React to container state changes immediately instead of waiting for the next tick
+		eventWatcher := service.NewEventWatcher(dockerService, reconciler, logger)
+		go eventWatcher.Run(reconcileCtx)
+
+		// Run scheduled backups and apply their retention policies
+		go backupScheduler.Run(reconcileCtx)
+
+		// Restart servers on their restart schedules
+		go restartScheduler.Run(reconcileCtx)
+
+		// Tell starting servers from ones that accept logins
+		if cfg.ServerPingInterval > 0 {
+			healthChecker := service.NewHealthChecker(mcService, cfg.ServerPingInterval, logger)
+			go healthChecker.Run(reconcileCtx)
+		}
+
+		// Record player sessions from the server logs
+		playerTracker := service.NewPlayerTracker(mcService, playerSessionRepo, logger)
+		playerTracker.SetEventBus(eventBus)
+		go playerTracker.Run(reconcileCtx)
+
+		// Serve server volumes over SFTP
+		if cfg.SFTPEnabled {
+			hostKey, err := service.LoadOrCreateHostKey(cfg.SFTPHostKeyPath)
+			if err != nil {
+				logger.Error("Failed to load SFTP host key", "error", err)
+				os.Exit(1)
+			}
+			sftpAccounts := service.NewSFTPAccountService(database.NewSFTPAccountRepository(db), mcService, logger)
+			sftpServer := service.NewSFTPServer(sftpAccounts, fileService, mcService, hostKey, logger)
+			go func() {
+				if err := sftpServer.ListenAndServe(reconcileCtx, fmt.Sprintf(":%d", cfg.SFTPPort)); err != nil {
+					logger.Error("SFTP server failed", "error", err)
+				}
+			}()
+		}
 
 		// Setup router
-		router := routes.NewRouter(mcService, proxyService, logger)
+		router := routes.NewRouter(dockerService, mcService, proxyService, operationService, backupService, backupScheduler, restartScheduler, worldService, fileService, propertiesService, playerListService, playerService, eventBus, logger)
 
 		// Create HTTP server
 		srv := &http.Server{
@@ -111,6 +186,9 @@ If no port is specified, it uses the API_PORT environment variable or defaults t
 
 		case sig := <-shutdown:
 			logger.Info("Received shutdown signal, starting graceful shutdown", "signal", sig.String())
+			stopReconciler()
+			// End event streams, they would otherwise hold up the shutdown
+			eventBus.Close()
 
 			// Give outstanding requests a deadline for completion
 			ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
@@ -125,6 +203,11 @@ If no port is specified, it uses the API_PORT environment variable or defaults t
 				}
 			}
 
+			// Let running operations finish, so servers aren't left half created
+			if err := operationService.Wait(ctx); err != nil {
+				logger.Warn("Operations still running at shutdown", "error", err)
+			}
+
 			logger.Info("Server stopped gracefully")
 		}
 	},
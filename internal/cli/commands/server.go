@@ -4,7 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
+	"strings"
 	"text/tabwriter"
 	"time"
 
@@ -33,9 +36,25 @@ func initializeServices() (*database.DB, *service.DockerService, *service.Minecr
 
 	// Initialize repositories
 	serverRepo := database.NewServerRepository(db)
+	proxyRepo := database.NewProxyRepository(db)
+	portRepo := database.NewPortRepository(db)
 
 	// Initialize Minecraft server service
 	mcService := service.NewMinecraftServerService(dockerService, serverRepo, logger)
+	proxyService := service.NewProxyService(dockerService, proxyRepo, serverRepo, logger)
+	portAllocator := service.NewPortAllocator(portRepo, cfg.DirectPortRangeStart, cfg.DirectPortRangeEnd, logger)
+
+	// Servers join the proxy the API created, like servers created through the API. The CLI doesn't create
+	// the proxy itself, so without one servers are created standalone.
+	mcService.SetProxyService(proxyService)
+	mcService.SetCreateProxy(false)
+	mcService.SetPortAllocator(portAllocator)
+	mcService.SetCrashReportRepository(database.NewCrashReportRepository(db))
+	// The CLI doesn't run the health checker, so it can't wait for pings and running containers count as ready.
+	// A running API server corrects the status with its next ping.
+	mcService.SetServerPingEnabled(false)
+	mcService.SetSecretBox(loadSecretBox())
+	mcService.SetBackupDestinations(backupDestinationNames())
 
 	// Cleanup function
 	cleanup := func() {
@@ -46,10 +65,20 @@ func initializeServices() (*database.DB, *service.DockerService, *service.Minecr
 	return db, dockerService, mcService, cleanup
 }
 
+// loadSecretBox loads the master key secrets are encrypted with, both the API and the CLI need it
+func loadSecretBox() *service.SecretBox {
+	secrets, err := service.LoadSecretBox(cfg.MasterKey, cfg.MasterKeyPath)
+	if err != nil {
+		logger.Error("Failed to load master key", "error", err)
+		os.Exit(1)
+	}
+	return secrets
+}
+
 var serverCmd = &cobra.Command{
 	Use:   "server",
 	Short: "Manage Minecraft servers",
-	Long:  `Create, list, start, stop, and delete Minecraft servers.`,
+	Long:  `Create, list, update, start, stop, and delete Minecraft servers.`,
 }
 
 var serverCreateCmd = &cobra.Command{
@@ -60,7 +89,19 @@ var serverCreateCmd = &cobra.Command{
   dockermc-cloud-manager server create my-server
 
   # Create a server with custom settings
-  dockermc-cloud-manager server create survival --max-players 50 --motd "Welcome!" --version 1.20.1`,
+  dockermc-cloud-manager server create survival --max-players 50 --motd "Welcome!" --version 1.20.1
+
+  # Create a server limited to 4 GB of memory and 2 CPUs with a 3 GB heap
+  dockermc-cloud-manager server create modpack --memory 4096 --cpus 2 --heap 3072
+
+  # Create a server reachable on its own host port instead of through the proxy
+  dockermc-cloud-manager server create testing --expose
+
+  # Create a Fabric server with a pinned loader version
+  dockermc-cloud-manager server create modded --type fabric --version 1.21.1 --loader-version 0.16.5
+
+  # Create a server with Aikar's flags and a plugin from Spigot using itzg environment variables
+  dockermc-cloud-manager server create lobby --env USE_AIKAR_FLAGS=true --env SPIGET_RESOURCES=9089 --env TZ=Europe/Berlin`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		name := args[0]
@@ -68,8 +109,23 @@ var serverCreateCmd = &cobra.Command{
 		maxPlayers, _ := cmd.Flags().GetInt("max-players")
 		motd, _ := cmd.Flags().GetString("motd")
 		version, _ := cmd.Flags().GetString("version")
+		serverType, _ := cmd.Flags().GetString("type")
+		loaderVersion, _ := cmd.Flags().GetString("loader-version")
+		memoryMB, _ := cmd.Flags().GetInt("memory")
+		cpuLimit, _ := cmd.Flags().GetFloat64("cpus")
+		jvmHeapMB, _ := cmd.Flags().GetInt("heap")
+		exposeDirectly, _ := cmd.Flags().GetBool("expose")
+		envFlags, _ := cmd.Flags().GetStringArray("env")
+		backupDestination, _ := cmd.Flags().GetString("backup-destination")
+
+		env, err := parseEnvFlags(envFlags)
+		if err != nil {
+			logger.Error("Invalid environment variable", "error", err)
+			os.Exit(1)
+		}
 
-		ctx := context.Background()
+		// Show the image download, the first pull can take minutes
+		ctx := service.WithPullProgress(context.Background(), newPullProgressBar(os.Stdout).Update)
 
 		// Initialize services
 		_, _, mcService, cleanup := initializeServices()
@@ -77,10 +133,22 @@ var serverCreateCmd = &cobra.Command{
 
 		// Create server
 		req := &models.CreateServerRequest{
-			Name:       name,
-			MaxPlayers: maxPlayers,
-			MOTD:       motd,
-			Version:    version,
+			Name:              name,
+			MaxPlayers:        maxPlayers,
+			MOTD:              motd,
+			Version:           version,
+			Type:              models.ServerType(serverType),
+			LoaderVersion:     loaderVersion,
+			MemoryMB:          memoryMB,
+			CPULimit:          cpuLimit,
+			JVMHeapMB:         jvmHeapMB,
+			ExposeDirectly:    exposeDirectly,
+			Env:               env,
+			BackupDestination: backupDestination,
+		}
+		if crashPolicyFlagsChanged(cmd) {
+			req.CrashPolicy = &models.CrashPolicy{}
+			applyCrashPolicyFlags(cmd, req.CrashPolicy)
 		}
 
 		logger.Info("Creating server", "name", name)
@@ -97,8 +165,21 @@ var serverCreateCmd = &cobra.Command{
 		fmt.Printf("ID:           %s\n", server.ID)
 		fmt.Printf("Name:         %s\n", server.Name)
 		fmt.Printf("Status:       %s\n", server.Status)
+		if ping := server.Ping; ping != nil {
+			if ping.Ready {
+				fmt.Printf("Players:      %d/%d online\n", ping.OnlinePlayers, ping.MaxPlayers)
+				fmt.Printf("Reports:      %s, %d ms latency\n", ping.Version, ping.LatencyMS)
+			} else {
+				fmt.Printf("Ping:         %s\n", ping.Error)
+			}
+		}
 		fmt.Printf("Max Players:  %d\n", server.MaxPlayers)
 		fmt.Printf("MOTD:         %s\n", server.MOTD)
+		fmt.Printf("Version:      %s\n", server.Version)
+		fmt.Printf("Type:         %s\n", server.Type)
+		if server.ExposeDirectly {
+			fmt.Printf("Host Port:    %d\n", server.Port)
+		}
 		fmt.Printf("Container ID: %s\n", server.ContainerID)
 		fmt.Printf("Volume ID:    %s\n", server.VolumeID)
 		fmt.Printf("\nUse 'dockermc-cloud-manager server start %s' to start the server.\n", server.ID)
@@ -119,6 +200,11 @@ var serverListCmd = &cobra.Command{
 		_, _, mcService, cleanup := initializeServices()
 		defer cleanup()
 
+		// Refresh the stored state, the serve process may not be running
+		if err := mcService.ReconcileServers(ctx); err != nil {
+			logger.Warn("Failed to reconcile servers, showing last known state", "error", err)
+		}
+
 		// List servers
 		servers, err := mcService.ListServers(ctx)
 		if err != nil {
@@ -140,12 +226,13 @@ var serverListCmd = &cobra.Command{
 		}
 
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-		fmt.Fprintln(w, "ID\tNAME\tSTATUS\tMAX PLAYERS\tCREATED")
+		fmt.Fprintln(w, "ID\tNAME\tSTATUS\tTYPE\tMAX PLAYERS\tCREATED")
 		for _, server := range servers {
-			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n",
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n",
 				server.ID[:8]+"...",
 				server.Name,
 				server.Status,
+				server.Type,
 				server.MaxPlayers,
 				server.CreatedAt.Format("2006-01-02 15:04"),
 			)
@@ -181,14 +268,19 @@ var serverStartCmd = &cobra.Command{
 }
 
 var serverStopCmd = &cobra.Command{
-	Use:     "stop <server-id>",
-	Short:   "Stop a Minecraft server",
-	Long:    `Stop a running Minecraft server by its ID.`,
-	Example: `  dockermc-cloud-manager server stop abc123...`,
-	Args:    cobra.ExactArgs(1),
+	Use:   "stop <server-id>",
+	Short: "Stop a Minecraft server",
+	Long: `Stop a running Minecraft server by its ID.
+
+With --grace, players see a countdown in chat, are moved to another server through the proxy
+and the world is saved before the server stops.`,
+	Example: `  dockermc-cloud-manager server stop abc123...
+  dockermc-cloud-manager server stop abc123... --grace 60s`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		serverID := args[0]
 		ctx := context.Background()
+		grace, _ := cmd.Flags().GetDuration("grace")
 
 		// Initialize services
 		_, _, mcService, cleanup := initializeServices()
@@ -196,7 +288,7 @@ var serverStopCmd = &cobra.Command{
 
 		// Stop server
 		logger.Info("Stopping server", "id", serverID)
-		if err := mcService.StopServer(ctx, serverID); err != nil {
+		if err := mcService.StopServerGracefully(ctx, serverID, grace); err != nil {
 			logger.Error("Failed to stop server", "error", err)
 			os.Exit(1)
 		}
@@ -206,6 +298,156 @@ var serverStopCmd = &cobra.Command{
 	},
 }
 
+var serverRestartCmd = &cobra.Command{
+	Use:   "restart <server-id>",
+	Short: "Restart a Minecraft server",
+	Long: `Restart a Minecraft server by its ID. A stopped server is started.
+
+With --grace, players see a countdown in chat, are moved to another server through the proxy
+and the world is saved before the server restarts.`,
+	Example: `  dockermc-cloud-manager server restart abc123...
+  dockermc-cloud-manager server restart abc123... --grace 5m`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID := args[0]
+		ctx := context.Background()
+		grace, _ := cmd.Flags().GetDuration("grace")
+
+		// Initialize services
+		_, _, mcService, cleanup := initializeServices()
+		defer cleanup()
+
+		// Restart server
+		logger.Info("Restarting server", "id", serverID)
+		if err := mcService.RestartServer(ctx, serverID, grace); err != nil {
+			logger.Error("Failed to restart server", "error", err)
+			os.Exit(1)
+		}
+
+		logger.Info("Server restarted successfully", "id", serverID)
+		fmt.Printf("✓ Server %s restarted successfully!\n", serverID)
+	},
+}
+
+var serverRotateRCONPasswordCmd = &cobra.Command{
+	Use:   "rotate-rcon-password <server-id>",
+	Short: "Give a Minecraft server a new RCON password",
+	Long: `Generate a new RCON password for a Minecraft server by its ID.
+
+The server's container is recreated to pass the password to the server, so a running server restarts.`,
+	Example: `  dockermc-cloud-manager server rotate-rcon-password abc123...`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID := args[0]
+		ctx := context.Background()
+
+		// Initialize services
+		_, _, mcService, cleanup := initializeServices()
+		defer cleanup()
+
+		logger.Info("Rotating RCON password", "id", serverID)
+		if err := mcService.RotateRCONPassword(ctx, serverID); err != nil {
+			logger.Error("Failed to rotate RCON password", "error", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ RCON password of server %s rotated successfully!\n", serverID)
+	},
+}
+
+var serverUpdateCmd = &cobra.Command{
+	Use:   "update <server-id>",
+	Short: "Update a Minecraft server",
+	Long: `Update the settings of a Minecraft server by its ID.
+
+The server's container is recreated with the new settings while its volume is kept.
+A running server is restarted.`,
+	Example: `  dockermc-cloud-manager server update abc123... --motd "Welcome back!"
+  dockermc-cloud-manager server update abc123... --max-players 50
+  dockermc-cloud-manager server update abc123... --memory 6144 --heap 5120
+  dockermc-cloud-manager server update abc123... --expose=false
+  dockermc-cloud-manager server update abc123... --env SPIGET_RESOURCES=9089 --unset-env TZ
+  dockermc-cloud-manager server update abc123... --max-crashes 3 --crash-backoff 30s`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID := args[0]
+		ctx := context.Background()
+
+		req := &models.UpdateServerRequest{}
+		if cmd.Flags().Changed("max-players") {
+			maxPlayers, _ := cmd.Flags().GetInt("max-players")
+			req.MaxPlayers = &maxPlayers
+		}
+		if cmd.Flags().Changed("motd") {
+			motd, _ := cmd.Flags().GetString("motd")
+			req.MOTD = &motd
+		}
+		if cmd.Flags().Changed("memory") {
+			memoryMB, _ := cmd.Flags().GetInt("memory")
+			req.MemoryMB = &memoryMB
+		}
+		if cmd.Flags().Changed("cpus") {
+			cpuLimit, _ := cmd.Flags().GetFloat64("cpus")
+			req.CPULimit = &cpuLimit
+		}
+		if cmd.Flags().Changed("heap") {
+			jvmHeapMB, _ := cmd.Flags().GetInt("heap")
+			req.JVMHeapMB = &jvmHeapMB
+		}
+		if cmd.Flags().Changed("expose") {
+			exposeDirectly, _ := cmd.Flags().GetBool("expose")
+			req.ExposeDirectly = &exposeDirectly
+		}
+		if cmd.Flags().Changed("backup-destination") {
+			backupDestination, _ := cmd.Flags().GetString("backup-destination")
+			req.BackupDestination = &backupDestination
+		}
+		if cmd.Flags().Changed("env") || cmd.Flags().Changed("unset-env") {
+			envFlags, _ := cmd.Flags().GetStringArray("env")
+			unsetEnv, _ := cmd.Flags().GetStringArray("unset-env")
+
+			env, err := parseEnvFlags(envFlags)
+			if err != nil {
+				logger.Error("Invalid environment variable", "error", err)
+				os.Exit(1)
+			}
+			req.Env = make(map[string]*string, len(env)+len(unsetEnv))
+			for key, value := range env {
+				req.Env[key] = &value
+			}
+			for _, key := range unsetEnv {
+				req.Env[key] = nil
+			}
+		}
+
+		// Initialize services
+		_, _, mcService, cleanup := initializeServices()
+		defer cleanup()
+
+		// The policy is replaced as a whole, so start from the current one
+		if crashPolicyFlagsChanged(cmd) {
+			server, err := mcService.GetServer(ctx, serverID)
+			if err != nil {
+				logger.Error("Failed to get server", "error", err)
+				os.Exit(1)
+			}
+			req.CrashPolicy = &server.CrashPolicy
+			applyCrashPolicyFlags(cmd, req.CrashPolicy)
+		}
+
+		// Update server
+		logger.Info("Updating server", "id", serverID)
+		server, err := mcService.UpdateServer(ctx, serverID, req)
+		if err != nil {
+			logger.Error("Failed to update server", "error", err)
+			os.Exit(1)
+		}
+
+		logger.Info("Server updated successfully", "id", server.ID)
+		fmt.Printf("✓ Server %s updated successfully!\n", server.ID)
+	},
+}
+
 var serverDeleteCmd = &cobra.Command{
 	Use:   "delete <server-id>",
 	Short: "Delete a Minecraft server",
@@ -261,6 +503,11 @@ var serverInfoCmd = &cobra.Command{
 		_, _, mcService, cleanup := initializeServices()
 		defer cleanup()
 
+		// Refresh the stored state, the serve process may not be running
+		if err := mcService.ReconcileServer(ctx, serverID); err != nil {
+			logger.Warn("Failed to reconcile server, showing last known state", "error", err)
+		}
+
 		// Get server info
 		server, err := mcService.GetServer(ctx, serverID)
 		if err != nil {
@@ -280,8 +527,60 @@ var serverInfoCmd = &cobra.Command{
 		fmt.Printf("ID:           %s\n", server.ID)
 		fmt.Printf("Name:         %s\n", server.Name)
 		fmt.Printf("Status:       %s\n", server.Status)
+		if ping := server.Ping; ping != nil {
+			if ping.Ready {
+				fmt.Printf("Players:      %d/%d online\n", ping.OnlinePlayers, ping.MaxPlayers)
+				fmt.Printf("Reports:      %s, %d ms latency\n", ping.Version, ping.LatencyMS)
+			} else {
+				fmt.Printf("Ping:         %s\n", ping.Error)
+			}
+		}
 		fmt.Printf("Max Players:  %d\n", server.MaxPlayers)
 		fmt.Printf("MOTD:         %s\n", server.MOTD)
+		fmt.Printf("Version:      %s\n", server.Version)
+		fmt.Printf("Type:         %s\n", server.Type)
+		if server.LoaderVersion != "" {
+			fmt.Printf("Loader:       %s\n", server.LoaderVersion)
+		}
+		fmt.Printf("Memory:       %s\n", formatLimit(server.MemoryMB, "MB"))
+		fmt.Printf("CPUs:         %s\n", formatLimit(server.CPULimit, ""))
+		if server.JVMHeapMB > 0 {
+			fmt.Printf("JVM Heap:     %d MB\n", server.JVMHeapMB)
+		} else {
+			fmt.Printf("JVM Heap:     auto\n")
+		}
+		if server.ExposeDirectly {
+			fmt.Printf("Host Port:    %d\n", server.Port)
+		}
+		if server.BackupDestination != "" {
+			fmt.Printf("Backups:      %s\n", server.BackupDestination)
+		}
+		if len(server.Env) > 0 {
+			keys := slices.Sorted(maps.Keys(server.Env))
+			fmt.Printf("Environment:\n")
+			for _, key := range keys {
+				fmt.Printf("  %s=%s\n", key, server.Env[key])
+			}
+		}
+		if server.Crashes > 0 {
+			fmt.Printf("Crashes:      %d in the current window\n", server.Crashes)
+		}
+		if server.NextRestartAt != nil {
+			fmt.Printf("Next Start:   %s (after crash)\n", server.NextRestartAt.Local().Format("2006-01-02 15:04:05"))
+		}
+		if schedule := server.RestartSchedule; schedule != nil {
+			fmt.Printf("Restarts:     %s", schedule.Cron)
+			if !schedule.Enabled {
+				fmt.Printf(" (disabled)")
+			}
+			fmt.Println()
+			if schedule.NextRunAt != nil && schedule.Enabled {
+				fmt.Printf("Next Restart: %s\n", schedule.NextRunAt.Local().Format("2006-01-02 15:04"))
+			}
+			if schedule.LastRunAt != nil {
+				fmt.Printf("Last Restart: %s (%s)\n", schedule.LastRunAt.Local().Format("2006-01-02 15:04"), schedule.LastStatus)
+			}
+		}
 		fmt.Printf("Container ID: %s\n", server.ContainerID)
 		fmt.Printf("Volume ID:    %s\n", server.VolumeID)
 		fmt.Printf("Created:      %s\n", server.CreatedAt.Format(time.RFC1123))
@@ -290,6 +589,30 @@ var serverInfoCmd = &cobra.Command{
 	},
 }
 
+// parseEnvFlags parses KEY=VALUE flags into environment overrides
+func parseEnvFlags(flags []string) (map[string]string, error) {
+	if len(flags) == 0 {
+		return nil, nil
+	}
+	env := make(map[string]string, len(flags))
+	for _, flag := range flags {
+		key, value, ok := strings.Cut(flag, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("expected KEY=VALUE, got %q", flag)
+		}
+		env[key] = value
+	}
+	return env, nil
+}
+
+// formatLimit formats a resource limit where zero means no limit
+func formatLimit[T int | float64](value T, unit string) string {
+	if value == 0 {
+		return "unlimited"
+	}
+	return strings.TrimSpace(fmt.Sprintf("%v %s", value, unit))
+}
+
 func init() {
 	rootCmd.AddCommand(serverCmd)
 
@@ -298,6 +621,15 @@ func init() {
 	serverCreateCmd.Flags().IntP("max-players", "m", 20, "Maximum number of players")
 	serverCreateCmd.Flags().StringP("motd", "d", "", "Message of the day")
 	serverCreateCmd.Flags().StringP("version", "v", "LATEST", "Minecraft version")
+	serverCreateCmd.Flags().StringP("type", "t", string(models.DefaultServerType), "Server type (PAPER, PURPUR, FABRIC, FORGE, NEOFORGE, VANILLA)")
+	serverCreateCmd.Flags().String("loader-version", "", "Fabric/Forge/NeoForge loader version (default latest)")
+	serverCreateCmd.Flags().Int("memory", 0, "Container memory limit in MB (0 for unlimited)")
+	serverCreateCmd.Flags().Float64("cpus", 0, "Number of CPUs the server may use (0 for unlimited)")
+	serverCreateCmd.Flags().Int("heap", 0, "JVM heap size in MB, at least 256 (default 75% of --memory, leaving at least 256 MB)")
+	serverCreateCmd.Flags().Bool("expose", false, "Publish the server on its own host port instead of through the proxy")
+	serverCreateCmd.Flags().StringArrayP("env", "e", nil, "Environment variable override for the itzg image as KEY=VALUE (repeatable)")
+	serverCreateCmd.Flags().String("backup-destination", "", "Backup destination for backups that don't name one (local, s3; default BACKUP_DESTINATION)")
+	addCrashPolicyFlags(serverCreateCmd)
 
 	// List command
 	serverCmd.AddCommand(serverListCmd)
@@ -308,6 +640,27 @@ func init() {
 
 	// Stop command
 	serverCmd.AddCommand(serverStopCmd)
+	serverStopCmd.Flags().Duration("grace", 0, "Count down in chat, move players to another server and save before stopping (max 10m)")
+
+	// Restart command
+	serverCmd.AddCommand(serverRestartCmd)
+	serverRestartCmd.Flags().Duration("grace", 0, "Count down in chat, move players to another server and save before restarting (max 10m)")
+
+	// Rotate RCON password command
+	serverCmd.AddCommand(serverRotateRCONPasswordCmd)
+
+	// Update command
+	serverCmd.AddCommand(serverUpdateCmd)
+	serverUpdateCmd.Flags().IntP("max-players", "m", 20, "Maximum number of players")
+	serverUpdateCmd.Flags().StringP("motd", "d", "", "Message of the day")
+	serverUpdateCmd.Flags().Int("memory", 0, "Container memory limit in MB (0 for unlimited)")
+	serverUpdateCmd.Flags().Float64("cpus", 0, "Number of CPUs the server may use (0 for unlimited)")
+	serverUpdateCmd.Flags().Int("heap", 0, "JVM heap size in MB, at least 256 (0 for 75% of the memory limit, leaving at least 256 MB)")
+	serverUpdateCmd.Flags().Bool("expose", false, "Publish the server on its own host port instead of through the proxy")
+	serverUpdateCmd.Flags().StringArrayP("env", "e", nil, "Set an environment variable override as KEY=VALUE (repeatable)")
+	serverUpdateCmd.Flags().StringArray("unset-env", nil, "Remove an environment variable override (repeatable)")
+	serverUpdateCmd.Flags().String("backup-destination", "", "Backup destination for backups that don't name one (local, s3; empty for BACKUP_DESTINATION)")
+	addCrashPolicyFlags(serverUpdateCmd)
 
 	// Delete command
 	serverCmd.AddCommand(serverDeleteCmd)
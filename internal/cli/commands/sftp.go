@@ -0,0 +1,378 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/database"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/service"
+	"github.com/spf13/cobra"
+)
+
+// Helper function to initialize the SFTP account service
+func initializeSFTPAccountService(db *database.DB, mcService *service.MinecraftServerService) *service.SFTPAccountService {
+	return service.NewSFTPAccountService(database.NewSFTPAccountRepository(db), mcService, logger)
+}
+
+// readPassword returns the password given with --password or on the first line of stdin with --password-stdin
+func readPassword(cmd *cobra.Command) string {
+	password, _ := cmd.Flags().GetString("password")
+	fromStdin, _ := cmd.Flags().GetBool("password-stdin")
+	if !fromStdin {
+		return password
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		logger.Error("Failed to read password from stdin", "error", err)
+		os.Exit(1)
+	}
+	return strings.TrimRight(line, "\r\n")
+}
+
+var sftpCmd = &cobra.Command{
+	Use:   "sftp",
+	Short: "Manage the accounts of the SFTP server",
+	Long: `Manage the accounts of the embedded SFTP server, which is started by serve if SFTP_ENABLED is set.
+
+Accounts log in with a password or SSH keys and only see the servers they were granted access to,
+each as a directory named after the server that contains its /data.`,
+}
+
+var sftpAccountCmd = &cobra.Command{
+	Use:   "account",
+	Short: "Manage SFTP accounts",
+}
+
+var sftpAccountCreateCmd = &cobra.Command{
+	Use:   "create <username>",
+	Short: "Create an SFTP account",
+	Long:  `Create an SFTP account. Without a password the account can only log in with the keys added to it.`,
+	Example: `  echo "$PASSWORD" | dockermc-cloud-manager sftp account create alice --password-stdin
+  dockermc-cloud-manager sftp account create ci --key ~/.ssh/id_ed25519.pub`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		username := args[0]
+		keyFiles, _ := cmd.Flags().GetStringSlice("key")
+		password := readPassword(cmd)
+		ctx := context.Background()
+
+		// Initialize services
+		db, _, mcService, cleanup := initializeServices()
+		defer cleanup()
+		sftpAccounts := initializeSFTPAccountService(db, mcService)
+
+		if _, err := sftpAccounts.CreateAccount(ctx, username, password); err != nil {
+			logger.Error("Failed to create SFTP account", "error", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ SFTP account %s created!\n", username)
+
+		for _, keyFile := range keyFiles {
+			addKeyFile(ctx, sftpAccounts, username, keyFile)
+		}
+
+		if password == "" && len(keyFiles) == 0 {
+			fmt.Println("\nThe account has no password or key yet, add one to log in.")
+		}
+	},
+}
+
+var sftpAccountListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List SFTP accounts",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		outputFormat, _ := cmd.Flags().GetString("output")
+		ctx := context.Background()
+
+		// Initialize services
+		db, _, mcService, cleanup := initializeServices()
+		defer cleanup()
+		sftpAccounts := initializeSFTPAccountService(db, mcService)
+
+		accounts, err := sftpAccounts.ListAccounts(ctx)
+		if err != nil {
+			logger.Error("Failed to list SFTP accounts", "error", err)
+			os.Exit(1)
+		}
+
+		if outputFormat == "json" {
+			data, _ := json.MarshalIndent(accounts, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+
+		if len(accounts) == 0 {
+			fmt.Println("No SFTP accounts found.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "USERNAME\tPASSWORD\tKEYS\tSERVERS\tCREATED")
+		for _, account := range accounts {
+			fmt.Fprintf(w, "%s\t%t\t%d\t%d\t%s\n",
+				account.Username,
+				account.HasPassword(),
+				len(account.Keys),
+				len(account.Permissions),
+				account.CreatedAt.Format("2006-01-02 15:04"),
+			)
+		}
+		w.Flush()
+	},
+}
+
+var sftpAccountShowCmd = &cobra.Command{
+	Use:   "show <username>",
+	Short: "Show the keys and server permissions of an SFTP account",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		// Initialize services
+		db, _, mcService, cleanup := initializeServices()
+		defer cleanup()
+		sftpAccounts := initializeSFTPAccountService(db, mcService)
+
+		account, err := sftpAccounts.GetAccount(ctx, args[0])
+		if err != nil {
+			logger.Error("Failed to get SFTP account", "error", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Username:  %s\n", account.Username)
+		fmt.Printf("Password:  %t\n", account.HasPassword())
+		fmt.Printf("Created:   %s\n", account.CreatedAt.Format("2006-01-02 15:04:05"))
+
+		fmt.Println("\nKeys:")
+		if len(account.Keys) == 0 {
+			fmt.Println("  none")
+		}
+		for _, key := range account.Keys {
+			fmt.Printf("  %s %s %s\n", key.Type, key.Fingerprint, key.Comment)
+		}
+
+		fmt.Println("\nServers:")
+		if len(account.Permissions) == 0 {
+			fmt.Println("  none")
+		}
+		for _, permission := range account.Permissions {
+			name := "(deleted)"
+			if server, err := mcService.GetServer(ctx, permission.ServerID); err == nil {
+				name = server.Name
+			}
+			fmt.Printf("  %s  %s  %s\n", permission.ServerID, permission.Access, name)
+		}
+	},
+}
+
+var sftpAccountPasswdCmd = &cobra.Command{
+	Use:   "passwd <username>",
+	Short: "Change the password of an SFTP account",
+	Long:  `Change the password of an SFTP account. With --remove the account can only log in with keys.`,
+	Example: `  echo "$PASSWORD" | dockermc-cloud-manager sftp account passwd alice --password-stdin
+  dockermc-cloud-manager sftp account passwd ci --remove`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		username := args[0]
+		remove, _ := cmd.Flags().GetBool("remove")
+		ctx := context.Background()
+
+		password := ""
+		if !remove {
+			if password = readPassword(cmd); password == "" {
+				logger.Error("No password given, use --password, --password-stdin or --remove")
+				os.Exit(1)
+			}
+		}
+
+		// Initialize services
+		db, _, mcService, cleanup := initializeServices()
+		defer cleanup()
+		sftpAccounts := initializeSFTPAccountService(db, mcService)
+
+		if err := sftpAccounts.SetPassword(ctx, username, password); err != nil {
+			logger.Error("Failed to change SFTP password", "error", err)
+			os.Exit(1)
+		}
+
+		if remove {
+			fmt.Printf("✓ Password of SFTP account %s removed\n", username)
+		} else {
+			fmt.Printf("✓ Password of SFTP account %s changed\n", username)
+		}
+	},
+}
+
+var sftpAccountDeleteCmd = &cobra.Command{
+	Use:   "delete <username>",
+	Short: "Delete an SFTP account",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		// Initialize services
+		db, _, mcService, cleanup := initializeServices()
+		defer cleanup()
+		sftpAccounts := initializeSFTPAccountService(db, mcService)
+
+		if err := sftpAccounts.DeleteAccount(ctx, args[0]); err != nil {
+			logger.Error("Failed to delete SFTP account", "error", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ SFTP account %s deleted\n", args[0])
+	},
+}
+
+var sftpKeyCmd = &cobra.Command{
+	Use:   "key",
+	Short: "Manage the SSH keys of SFTP accounts",
+}
+
+var sftpKeyAddCmd = &cobra.Command{
+	Use:     "add <username> <public-key-file>",
+	Short:   "Add an SSH public key to an SFTP account",
+	Example: `  dockermc-cloud-manager sftp key add alice ~/.ssh/id_ed25519.pub`,
+	Args:    cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		// Initialize services
+		db, _, mcService, cleanup := initializeServices()
+		defer cleanup()
+		sftpAccounts := initializeSFTPAccountService(db, mcService)
+
+		addKeyFile(ctx, sftpAccounts, args[0], args[1])
+	},
+}
+
+// addKeyFile adds the public key in a file like id_ed25519.pub to an SFTP account
+func addKeyFile(ctx context.Context, sftpAccounts *service.SFTPAccountService, username, keyFile string) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		logger.Error("Failed to read public key", "error", err)
+		os.Exit(1)
+	}
+
+	key, err := sftpAccounts.AddKey(ctx, username, string(data))
+	if err != nil {
+		logger.Error("Failed to add SFTP key", "error", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✓ Key %s added to SFTP account %s\n", key.Fingerprint, username)
+}
+
+var sftpKeyRemoveCmd = &cobra.Command{
+	Use:     "remove <username> <fingerprint>",
+	Short:   "Remove an SSH public key from an SFTP account",
+	Example: `  dockermc-cloud-manager sftp key remove alice SHA256:uNiVztksCsDhcc0u9e8BujQXVUpKZIDTMczCvj3tD2s`,
+	Args:    cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		// Initialize services
+		db, _, mcService, cleanup := initializeServices()
+		defer cleanup()
+		sftpAccounts := initializeSFTPAccountService(db, mcService)
+
+		if err := sftpAccounts.RemoveKey(ctx, args[0], args[1]); err != nil {
+			logger.Error("Failed to remove SFTP key", "error", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ Key %s removed from SFTP account %s\n", args[1], args[0])
+	},
+}
+
+var sftpGrantCmd = &cobra.Command{
+	Use:   "grant <username> <server-id>",
+	Short: "Give an SFTP account access to a server",
+	Long: `Give an SFTP account access to the files of a server. Accounts can write unless --read-only is set.
+Granting again changes the access of the account.`,
+	Example: `  dockermc-cloud-manager sftp grant alice abc123...
+  dockermc-cloud-manager sftp grant auditor abc123... --read-only`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		readOnly, _ := cmd.Flags().GetBool("read-only")
+		ctx := context.Background()
+
+		access := models.SFTPAccessWrite
+		if readOnly {
+			access = models.SFTPAccessRead
+		}
+
+		// Initialize services
+		db, _, mcService, cleanup := initializeServices()
+		defer cleanup()
+		sftpAccounts := initializeSFTPAccountService(db, mcService)
+
+		if _, err := sftpAccounts.Grant(ctx, args[0], args[1], access); err != nil {
+			logger.Error("Failed to grant SFTP access", "error", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ SFTP account %s has %s access to server %s\n", args[0], access, args[1])
+	},
+}
+
+var sftpRevokeCmd = &cobra.Command{
+	Use:   "revoke <username> <server-id>",
+	Short: "Remove the access of an SFTP account to a server",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		// Initialize services
+		db, _, mcService, cleanup := initializeServices()
+		defer cleanup()
+		sftpAccounts := initializeSFTPAccountService(db, mcService)
+
+		if err := sftpAccounts.Revoke(ctx, args[0], args[1]); err != nil {
+			logger.Error("Failed to revoke SFTP access", "error", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ SFTP account %s has no access to server %s anymore\n", args[0], args[1])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sftpCmd)
+
+	sftpCmd.AddCommand(sftpAccountCmd)
+
+	sftpAccountCmd.AddCommand(sftpAccountCreateCmd)
+	sftpAccountCreateCmd.Flags().String("password", "", "Password of the account, prefer --password-stdin")
+	sftpAccountCreateCmd.Flags().Bool("password-stdin", false, "Read the password from the first line of stdin")
+	sftpAccountCreateCmd.Flags().StringSlice("key", nil, "SSH public key file to add, can be repeated")
+
+	sftpAccountCmd.AddCommand(sftpAccountListCmd)
+	sftpAccountListCmd.Flags().StringP("output", "o", "table", "Output format (table, json)")
+
+	sftpAccountCmd.AddCommand(sftpAccountShowCmd)
+
+	sftpAccountCmd.AddCommand(sftpAccountPasswdCmd)
+	sftpAccountPasswdCmd.Flags().String("password", "", "New password, prefer --password-stdin")
+	sftpAccountPasswdCmd.Flags().Bool("password-stdin", false, "Read the new password from the first line of stdin")
+	sftpAccountPasswdCmd.Flags().Bool("remove", false, "Remove the password, so the account only logs in with keys")
+
+	sftpAccountCmd.AddCommand(sftpAccountDeleteCmd)
+
+	sftpCmd.AddCommand(sftpKeyCmd)
+	sftpKeyCmd.AddCommand(sftpKeyAddCmd)
+	sftpKeyCmd.AddCommand(sftpKeyRemoveCmd)
+
+	sftpCmd.AddCommand(sftpGrantCmd)
+	sftpGrantCmd.Flags().Bool("read-only", false, "Only allow reading files")
+
+	sftpCmd.AddCommand(sftpRevokeCmd)
+}
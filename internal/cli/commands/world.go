@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/service"
+	"github.com/spf13/cobra"
+)
+
+// Helper function to initialize the world service on top of the server services
+func initializeWorldService(dockerService *service.DockerService, mcService *service.MinecraftServerService) *service.WorldService {
+	return service.NewWorldService(dockerService, mcService, int64(cfg.WorldImportMaxSizeMB)*1024*1024, logger)
+}
+
+var serverWorldCmd = &cobra.Command{
+	Use:   "world",
+	Short: "Import and export server worlds",
+	Long:  `Move worlds between servers, or from singleplayer saves onto a server.`,
+}
+
+var serverWorldImportCmd = &cobra.Command{
+	Use:   "import <server-id> <archive>",
+	Short: "Import a world into a stopped server",
+	Long: `Replace the worlds of a stopped server with the ones in a zip, tar or tar.gz archive.
+
+Server worlds (world/, world_nether/, world_the_end/) and singleplayer saves with level.dat at their root
+are recognized, also when wrapped in a folder. A singleplayer save becomes the server's world/.
+Existing world folders are replaced, everything else in the server's volume is kept.`,
+	Example: `  dockermc-cloud-manager server world import abc123... ./spawn-map.zip
+  dockermc-cloud-manager server world import abc123... ./world-export.tar.gz`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID := args[0]
+		ctx := context.Background()
+
+		archive, err := os.Open(args[1])
+		if err != nil {
+			logger.Error("Failed to open world archive", "error", err)
+			os.Exit(1)
+		}
+		defer archive.Close()
+
+		// Initialize services
+		_, dockerService, mcService, cleanup := initializeServices()
+		defer cleanup()
+		worldService := initializeWorldService(dockerService, mcService)
+
+		logger.Info("Importing world", "id", serverID, "archive", args[1])
+		result, err := worldService.ImportWorld(ctx, serverID, archive)
+		if err != nil {
+			logger.Error("Failed to import world", "error", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ World imported into server %s!\n\n", serverID)
+		fmt.Printf("Layout:   %s\n", result.Layout)
+		fmt.Printf("Worlds:   %s\n", strings.Join(result.Worlds, ", "))
+		fmt.Printf("Files:    %d (%s)\n", result.Files, formatBytes(result.SizeBytes))
+		if result.Skipped > 0 {
+			fmt.Printf("Skipped:  %d entries outside of the world folders\n", result.Skipped)
+		}
+	},
+}
+
+var serverWorldExportCmd = &cobra.Command{
+	Use:   "export <server-id> [file]",
+	Short: "Export the world of a server",
+	Long: `Write the world folders of a server to a zip archive, which can be imported into another server.
+
+With --save-world a running server stops saving and flushes the world to disk first,
+so the archive is consistent. Saving is turned back on afterwards.`,
+	Example: `  dockermc-cloud-manager server world export abc123...
+  dockermc-cloud-manager server world export abc123... ./lobby.zip --save-world`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID := args[0]
+		saveWorld, _ := cmd.Flags().GetBool("save-world")
+		ctx := context.Background()
+
+		// Initialize services
+		_, dockerService, mcService, cleanup := initializeServices()
+		defer cleanup()
+		worldService := initializeWorldService(dockerService, mcService)
+
+		export, err := worldService.OpenWorldExport(ctx, serverID, saveWorld)
+		if err != nil {
+			logger.Error("Failed to export world", "error", err)
+			os.Exit(1)
+		}
+		defer export.Close()
+
+		fileName := export.FileName()
+		if len(args) > 1 {
+			fileName = args[1]
+		}
+
+		file, err := os.Create(fileName)
+		if err != nil {
+			logger.Error("Failed to create world archive", "error", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+
+		if err := export.WriteZip(file); err != nil {
+			file.Close()
+			os.Remove(fileName)
+			logger.Error("Failed to export world", "error", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ World of server %s exported to %s (%s)\n", serverID, fileName, strings.Join(export.Worlds, ", "))
+	},
+}
+
+func init() {
+	serverCmd.AddCommand(serverWorldCmd)
+
+	serverWorldCmd.AddCommand(serverWorldImportCmd)
+
+	serverWorldCmd.AddCommand(serverWorldExportCmd)
+	serverWorldExportCmd.Flags().Bool("save-world", false, "Flush the world with save-off/save-all before exporting a running server")
+}
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"time"
 )
 
 // Config holds the application configuration
@@ -12,6 +14,41 @@ type Config struct {
 	VelocityImage  string
 	MinecraftImage string
 	DatabasePath   string
+	// Master key secrets like RCON passwords are encrypted with in the database, base64 encoded 32 bytes.
+	// Without MasterKey, the key is read from MasterKeyPath and created there on first start.
+	MasterKey     string
+	MasterKeyPath string
+	// Directory backup archives are stored in
+	BackupDir string
+	// Destination backups are written to unless a request or schedule names one, "local" or "s3"
+	BackupDestination string
+	// S3-compatible backup destination, only available if a bucket is set
+	BackupS3Endpoint        string
+	BackupS3Bucket          string
+	BackupS3Region          string
+	BackupS3AccessKeyID     string
+	BackupS3SecretAccessKey string
+	BackupS3Prefix          string
+	BackupS3UseSSL          bool
+	BackupS3PartSizeMB      int
+	// Maximum size of uploaded world archives in MB
+	WorldImportMaxSizeMB int
+	// Maximum size of files read or written through the file manager in MB
+	FileMaxSizeMB int
+	// Embedded SFTP server exposing server volumes, disabled unless SFTPEnabled is set
+	SFTPEnabled     bool
+	SFTPPort        int
+	SFTPHostKeyPath string
+	// Host port range for servers that are exposed directly instead of through the proxy
+	DirectPortRangeStart int
+	DirectPortRangeEnd   int
+	// How often the reconciler compares servers and the proxy with Docker
+	ReconcileInterval time.Duration
+	// How often starting and running servers are pinged to tell if they accept logins, 0 disables the ping
+	// and running servers count as ready right away
+	ServerPingInterval time.Duration
+	// Number of recent events kept so event stream clients can resume after reconnecting
+	EventHistorySize int
 }
 
 // Load reads configuration from environment variables with defaults
@@ -43,11 +80,145 @@ func Load() (*Config, error) {
 		databasePath = "./data/dockermc.db"
 	}
 
+	masterKeyPath := os.Getenv("MASTER_KEY_PATH")
+	if masterKeyPath == "" {
+		masterKeyPath = "./data/master.key"
+	}
+
+	backupDir := os.Getenv("BACKUP_DIR")
+	if backupDir == "" {
+		backupDir = "./data/backups"
+	}
+
+	backupDestination := os.Getenv("BACKUP_DESTINATION")
+	if backupDestination == "" {
+		backupDestination = "local"
+	}
+
+	backupS3UseSSL := true
+	if envSSL := os.Getenv("BACKUP_S3_USE_SSL"); envSSL != "" {
+		if b, err := strconv.ParseBool(envSSL); err == nil {
+			backupS3UseSSL = b
+		}
+	}
+
+	backupS3PartSizeMB := 64
+	if envPartSize := os.Getenv("BACKUP_S3_PART_SIZE_MB"); envPartSize != "" {
+		if n, err := strconv.Atoi(envPartSize); err == nil && n >= 5 {
+			backupS3PartSizeMB = n
+		}
+	}
+
+	backupS3Bucket := os.Getenv("BACKUP_S3_BUCKET")
+	switch backupDestination {
+	case "local":
+	case "s3":
+		if backupS3Bucket == "" {
+			return nil, fmt.Errorf("BACKUP_DESTINATION is s3 but BACKUP_S3_BUCKET is not set")
+		}
+	default:
+		return nil, fmt.Errorf("invalid backup destination %q, must be local or s3", backupDestination)
+	}
+
+	worldImportMaxSizeMB := 4096
+	if envSize := os.Getenv("WORLD_IMPORT_MAX_SIZE_MB"); envSize != "" {
+		if n, err := strconv.Atoi(envSize); err == nil && n > 0 {
+			worldImportMaxSizeMB = n
+		}
+	}
+
+	fileMaxSizeMB := 512
+	if envSize := os.Getenv("FILE_MAX_SIZE_MB"); envSize != "" {
+		if n, err := strconv.Atoi(envSize); err == nil && n > 0 {
+			fileMaxSizeMB = n
+		}
+	}
+
+	sftpEnabled := false
+	if envEnabled := os.Getenv("SFTP_ENABLED"); envEnabled != "" {
+		if b, err := strconv.ParseBool(envEnabled); err == nil {
+			sftpEnabled = b
+		}
+	}
+
+	sftpPort := 2022
+	if envPort := os.Getenv("SFTP_PORT"); envPort != "" {
+		if p, err := strconv.Atoi(envPort); err == nil {
+			sftpPort = p
+		}
+	}
+
+	sftpHostKeyPath := os.Getenv("SFTP_HOST_KEY_PATH")
+	if sftpHostKeyPath == "" {
+		sftpHostKeyPath = "./data/sftp_host_key"
+	}
+
+	directPortRangeStart := 25566
+	if envStart := os.Getenv("DIRECT_PORT_RANGE_START"); envStart != "" {
+		if p, err := strconv.Atoi(envStart); err == nil {
+			directPortRangeStart = p
+		}
+	}
+
+	directPortRangeEnd := 25665
+	if envEnd := os.Getenv("DIRECT_PORT_RANGE_END"); envEnd != "" {
+		if p, err := strconv.Atoi(envEnd); err == nil {
+			directPortRangeEnd = p
+		}
+	}
+
+	if directPortRangeStart < 1 || directPortRangeEnd > 65535 || directPortRangeStart > directPortRangeEnd {
+		return nil, fmt.Errorf("invalid direct port range %d-%d", directPortRangeStart, directPortRangeEnd)
+	}
+
+	reconcileInterval := 30 * time.Second
+	if envInterval := os.Getenv("RECONCILE_INTERVAL"); envInterval != "" {
+		if d, err := time.ParseDuration(envInterval); err == nil && d > 0 {
+			reconcileInterval = d
+		}
+	}
+
+	serverPingInterval := 10 * time.Second
+	if envInterval := os.Getenv("SERVER_PING_INTERVAL"); envInterval != "" {
+		if d, err := time.ParseDuration(envInterval); err == nil && d >= 0 {
+			serverPingInterval = d
+		}
+	}
+
+	eventHistorySize := 1000
+	if envSize := os.Getenv("EVENT_HISTORY_SIZE"); envSize != "" {
+		if n, err := strconv.Atoi(envSize); err == nil && n > 0 {
+			eventHistorySize = n
+		}
+	}
+
 	return &Config{
-		Port:           port,
-		DockerNetwork:  dockerNetwork,
-		VelocityImage:  velocityImage,
-		MinecraftImage: minecraftImage,
-		DatabasePath:   databasePath,
+		Port:                    port,
+		DockerNetwork:           dockerNetwork,
+		VelocityImage:           velocityImage,
+		MinecraftImage:          minecraftImage,
+		DatabasePath:            databasePath,
+		MasterKey:               os.Getenv("MASTER_KEY"),
+		MasterKeyPath:           masterKeyPath,
+		BackupDir:               backupDir,
+		BackupDestination:       backupDestination,
+		BackupS3Endpoint:        os.Getenv("BACKUP_S3_ENDPOINT"),
+		BackupS3Bucket:          backupS3Bucket,
+		BackupS3Region:          os.Getenv("BACKUP_S3_REGION"),
+		BackupS3AccessKeyID:     os.Getenv("BACKUP_S3_ACCESS_KEY_ID"),
+		BackupS3SecretAccessKey: os.Getenv("BACKUP_S3_SECRET_ACCESS_KEY"),
+		BackupS3Prefix:          os.Getenv("BACKUP_S3_PREFIX"),
+		BackupS3UseSSL:          backupS3UseSSL,
+		BackupS3PartSizeMB:      backupS3PartSizeMB,
+		WorldImportMaxSizeMB:    worldImportMaxSizeMB,
+		FileMaxSizeMB:           fileMaxSizeMB,
+		SFTPEnabled:             sftpEnabled,
+		SFTPPort:                sftpPort,
+		SFTPHostKeyPath:         sftpHostKeyPath,
+		DirectPortRangeStart:    directPortRangeStart,
+		DirectPortRangeEnd:      directPortRangeEnd,
+		ReconcileInterval:       reconcileInterval,
+		ServerPingInterval:      serverPingInterval,
+		EventHistorySize:        eventHistorySize,
 	}, nil
 }
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrBackupNotFound is returned when a backup does not exist in the database
+var ErrBackupNotFound = errors.New("backup not found")
+
+// BackupRepository provides database operations for Backup
+type BackupRepository struct {
+	db     *gorm.DB
+	logger *slog.Logger
+}
+
+// NewBackupRepository creates a new backup repository
+func NewBackupRepository(db *DB) *BackupRepository {
+	return &BackupRepository{
+		db:     db.DB,
+		logger: db.logger,
+	}
+}
+
+// Create inserts a new backup into the database
+func (r *BackupRepository) Create(backup *models.Backup) error {
+	result := r.db.Create(backup)
+	if result.Error != nil {
+		r.logger.Error("Failed to create backup in database", "error", result.Error)
+		return result.Error
+	}
+	r.logger.Debug("Backup created in database", "id", backup.ID, "server_id", backup.ServerID)
+	return nil
+}
+
+// FindByID retrieves a backup by its ID
+func (r *BackupRepository) FindByID(id string) (*models.Backup, error) {
+	var backup models.Backup
+	result := r.db.First(&backup, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrBackupNotFound
+		}
+		r.logger.Error("Failed to find backup by ID", "id", id, "error", result.Error)
+		return nil, result.Error
+	}
+	return &backup, nil
+}
+
+// FindByServerID retrieves all backups of a server, newest first
+func (r *BackupRepository) FindByServerID(serverID string) ([]*models.Backup, error) {
+	var backups []*models.Backup
+	result := r.db.Where("server_id = ?", serverID).Order("created_at DESC").Find(&backups)
+	if result.Error != nil {
+		r.logger.Error("Failed to retrieve backups", "server_id", serverID, "error", result.Error)
+		return nil, result.Error
+	}
+	return backups, nil
+}
+
+// Delete removes a backup from the database
+func (r *BackupRepository) Delete(id string) error {
+	result := r.db.Delete(&models.Backup{}, "id = ?", id)
+	if result.Error != nil {
+		r.logger.Error("Failed to delete backup", "id", id, "error", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBackupNotFound
+	}
+	r.logger.Debug("Backup deleted from database", "id", id)
+	return nil
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrBackupScheduleNotFound is returned when a server has no backup schedule
+var ErrBackupScheduleNotFound = errors.New("backup schedule not found")
+
+// BackupScheduleRepository provides database operations for BackupSchedule
+type BackupScheduleRepository struct {
+	db     *gorm.DB
+	logger *slog.Logger
+}
+
+// NewBackupScheduleRepository creates a new backup schedule repository
+func NewBackupScheduleRepository(db *DB) *BackupScheduleRepository {
+	return &BackupScheduleRepository{
+		db:     db.DB,
+		logger: db.logger,
+	}
+}
+
+// Save creates or replaces the backup schedule of a server
+func (r *BackupScheduleRepository) Save(schedule *models.BackupSchedule) error {
+	result := r.db.Save(schedule)
+	if result.Error != nil {
+		r.logger.Error("Failed to save backup schedule", "server_id", schedule.ServerID, "error", result.Error)
+		return result.Error
+	}
+	return nil
+}
+
+// FindByServerID retrieves the backup schedule of a server
+func (r *BackupScheduleRepository) FindByServerID(serverID string) (*models.BackupSchedule, error) {
+	var schedule models.BackupSchedule
+	result := r.db.First(&schedule, "server_id = ?", serverID)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrBackupScheduleNotFound
+		}
+		r.logger.Error("Failed to find backup schedule", "server_id", serverID, "error", result.Error)
+		return nil, result.Error
+	}
+	return &schedule, nil
+}
+
+// FindDue retrieves the enabled schedules whose next run is due
+func (r *BackupScheduleRepository) FindDue(now time.Time) ([]*models.BackupSchedule, error) {
+	var schedules []*models.BackupSchedule
+	result := r.db.Where("enabled = ? AND next_run_at <= ?", true, now).Find(&schedules)
+	if result.Error != nil {
+		r.logger.Error("Failed to retrieve due backup schedules", "error", result.Error)
+		return nil, result.Error
+	}
+	return schedules, nil
+}
+
+// UpdateNextRun only updates when the schedule runs next, so concurrent edits of the schedule are kept
+func (r *BackupScheduleRepository) UpdateNextRun(serverID string, nextRunAt time.Time) error {
+	result := r.db.Model(&models.BackupSchedule{}).
+		Where("server_id = ?", serverID).
+		Update("next_run_at", nextRunAt)
+	if result.Error != nil {
+		r.logger.Error("Failed to update next backup run", "server_id", serverID, "error", result.Error)
+		return result.Error
+	}
+	return nil
+}
+
+// UpdateLastRun only updates the outcome of the last run, so concurrent edits of the schedule are kept
+func (r *BackupScheduleRepository) UpdateLastRun(schedule *models.BackupSchedule) error {
+	result := r.db.Model(&models.BackupSchedule{}).
+		Where("server_id = ?", schedule.ServerID).
+		Updates(map[string]interface{}{
+			"last_run_at":          schedule.LastRunAt,
+			"last_status":          schedule.LastStatus,
+			"last_error":           schedule.LastError,
+			"consecutive_failures": schedule.ConsecutiveFailures,
+		})
+	if result.Error != nil {
+		r.logger.Error("Failed to update last backup run", "server_id", schedule.ServerID, "error", result.Error)
+		return result.Error
+	}
+	return nil
+}
+
+// Delete removes the backup schedule of a server
+func (r *BackupScheduleRepository) Delete(serverID string) error {
+	result := r.db.Delete(&models.BackupSchedule{}, "server_id = ?", serverID)
+	if result.Error != nil {
+		r.logger.Error("Failed to delete backup schedule", "server_id", serverID, "error", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBackupScheduleNotFound
+	}
+	return nil
+}
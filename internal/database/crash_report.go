@@ -0,0 +1,74 @@
+package database
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrCrashReportNotFound is returned when a crash report does not exist in the database
+var ErrCrashReportNotFound = errors.New("crash report not found")
+
+// CrashReportRepository provides database operations for CrashReport
+type CrashReportRepository struct {
+	db     *gorm.DB
+	logger *slog.Logger
+}
+
+// NewCrashReportRepository creates a new crash report repository
+func NewCrashReportRepository(db *DB) *CrashReportRepository {
+	return &CrashReportRepository{
+		db:     db.DB,
+		logger: db.logger,
+	}
+}
+
+// Create inserts a new crash report into the database
+func (r *CrashReportRepository) Create(report *models.CrashReport) error {
+	result := r.db.Create(report)
+	if result.Error != nil {
+		r.logger.Error("Failed to create crash report in database", "error", result.Error)
+		return result.Error
+	}
+	r.logger.Debug("Crash report created in database", "id", report.ID, "server_id", report.ServerID)
+	return nil
+}
+
+// FindByID retrieves a crash report by its ID
+func (r *CrashReportRepository) FindByID(id string) (*models.CrashReport, error) {
+	var report models.CrashReport
+	result := r.db.First(&report, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrCrashReportNotFound
+		}
+		r.logger.Error("Failed to find crash report by ID", "id", id, "error", result.Error)
+		return nil, result.Error
+	}
+	return &report, nil
+}
+
+// FindByServerID retrieves all crash reports of a server, newest first
+func (r *CrashReportRepository) FindByServerID(serverID string) ([]*models.CrashReport, error) {
+	var reports []*models.CrashReport
+	result := r.db.Where("server_id = ?", serverID).Order("created_at DESC").Find(&reports)
+	if result.Error != nil {
+		r.logger.Error("Failed to retrieve crash reports", "server_id", serverID, "error", result.Error)
+		return nil, result.Error
+	}
+	return reports, nil
+}
+
+// Prune deletes all but the newest keep crash reports of a server
+func (r *CrashReportRepository) Prune(serverID string, keep int) error {
+	newest := r.db.Model(&models.CrashReport{}).Select("id").
+		Where("server_id = ?", serverID).Order("created_at DESC").Limit(keep)
+	result := r.db.Where("server_id = ? AND id NOT IN (?)", serverID, newest).Delete(&models.CrashReport{})
+	if result.Error != nil {
+		r.logger.Error("Failed to prune crash reports", "server_id", serverID, "error", result.Error)
+		return result.Error
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -12,6 +13,9 @@ import (
 	"gorm.io/gorm/logger"
 )
 
+// ErrServerNotFound is returned when a server does not exist in the database
+var ErrServerNotFound = errors.New("server not found")
+
 // DB wraps the GORM database connection
 type DB struct {
 	*gorm.DB
@@ -40,7 +44,21 @@ func New(dbPath string, log *slog.Logger) (*DB, error) {
 	log.Info("Database connection established", "path", dbPath)
 
 	// Auto-migrate schemas
-	if err := db.AutoMigrate(&models.MinecraftServer{}, &models.ProxyServer{}); err != nil {
+	if err := db.AutoMigrate(
+		&models.MinecraftServer{},
+		&models.ProxyServer{},
+		&models.PortAllocation{},
+		&models.Operation{},
+		&models.Backup{},
+		&models.BackupSchedule{},
+		&models.RestartSchedule{},
+		&models.CrashReport{},
+		&models.PlayerSession{},
+		&models.ServerEnvVar{},
+		&models.SFTPAccount{},
+		&models.SFTPKey{},
+		&models.SFTPPermission{},
+	); err != nil {
 		return nil, fmt.Errorf("failed to auto-migrate schemas: %w", err)
 	}
 
@@ -75,12 +93,17 @@ func NewServerRepository(db *DB) *ServerRepository {
 	}
 }
 
-// Create inserts a new server into the database
+// Create inserts a new server and its environment overrides into the database
 func (r *ServerRepository) Create(server *models.MinecraftServer) error {
-	result := r.db.Create(server)
-	if result.Error != nil {
-		r.logger.Error("Failed to create server in database", "error", result.Error)
-		return result.Error
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(server).Error; err != nil {
+			return err
+		}
+		return saveEnv(tx, server)
+	})
+	if err != nil {
+		r.logger.Error("Failed to create server in database", "error", err)
+		return err
 	}
 	r.logger.Debug("Server created in database", "id", server.ID, "name", server.Name)
 	return nil
@@ -92,11 +115,14 @@ func (r *ServerRepository) FindByID(id string) (*models.MinecraftServer, error)
 	result := r.db.First(&server, "id = ?", id)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("server not found")
+			return nil, ErrServerNotFound
 		}
 		r.logger.Error("Failed to find server by ID", "id", id, "error", result.Error)
 		return nil, result.Error
 	}
+	if err := r.loadDetails(&server); err != nil {
+		return nil, err
+	}
 	return &server, nil
 }
 
@@ -106,11 +132,14 @@ func (r *ServerRepository) FindByName(name string) (*models.MinecraftServer, err
 	result := r.db.First(&server, "name = ?", name)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("server not found")
+			return nil, ErrServerNotFound
 		}
 		r.logger.Error("Failed to find server by name", "name", name, "error", result.Error)
 		return nil, result.Error
 	}
+	if err := r.loadDetails(&server); err != nil {
+		return nil, err
+	}
 	return &server, nil
 }
 
@@ -122,30 +151,154 @@ func (r *ServerRepository) FindAll() ([]*models.MinecraftServer, error) {
 		r.logger.Error("Failed to find all servers", "error", result.Error)
 		return nil, result.Error
 	}
+	if err := r.loadDetails(servers...); err != nil {
+		return nil, err
+	}
 	return servers, nil
 }
 
-// Update updates a server in the database
+// Update updates a server and replaces its environment overrides in the database
 func (r *ServerRepository) Update(server *models.MinecraftServer) error {
-	result := r.db.Save(server)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(server).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.ServerEnvVar{}, "server_id = ?", server.ID).Error; err != nil {
+			return err
+		}
+		return saveEnv(tx, server)
+	})
+	if err != nil {
+		r.logger.Error("Failed to update server", "id", server.ID, "error", err)
+		return err
+	}
+	r.logger.Debug("Server updated in database", "id", server.ID, "name", server.Name)
+	return nil
+}
+
+// UpdateState updates only the container state columns of a server,
+// so concurrent changes to its settings are not overwritten
+func (r *ServerRepository) UpdateState(server *models.MinecraftServer) error {
+	result := r.db.Model(&models.MinecraftServer{}).Where("id = ?", server.ID).Updates(map[string]interface{}{
+		"container_id":       server.ContainerID,
+		"status":             server.Status,
+		"exit_code":          server.ExitCode,
+		"oom_killed":         server.OOMKilled,
+		"restart_count":      server.RestartCount,
+		"crashes":            server.Crashes,
+		"crash_window_start": server.CrashWindowStart,
+		"next_restart_at":    server.NextRestartAt,
+	})
 	if result.Error != nil {
-		r.logger.Error("Failed to update server", "id", server.ID, "error", result.Error)
+		r.logger.Error("Failed to update server state", "id", server.ID, "error", result.Error)
 		return result.Error
 	}
-	r.logger.Debug("Server updated in database", "id", server.ID, "name", server.Name)
+	r.logger.Debug("Server state updated in database", "id", server.ID, "status", server.Status)
 	return nil
 }
 
-// Delete removes a server from the database
-func (r *ServerRepository) Delete(id string) error {
-	result := r.db.Unscoped().Delete(&models.MinecraftServer{}, "id = ?", id)
+// UpdateRCONPassword stores the RCON password of a server
+func (r *ServerRepository) UpdateRCONPassword(id, password string) error {
+	result := r.db.Model(&models.MinecraftServer{}).Where("id = ?", id).Update("rcon_password", password)
 	if result.Error != nil {
-		r.logger.Error("Failed to delete server", "id", id, "error", result.Error)
+		r.logger.Error("Failed to update RCON password", "id", id, "error", result.Error)
 		return result.Error
 	}
-	if result.RowsAffected == 0 {
-		return fmt.Errorf("server not found")
+	return nil
+}
+
+// Delete removes a server with its environment overrides, restart schedule, crash reports and player sessions from the database
+func (r *ServerRepository) Delete(id string) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.ServerEnvVar{}, "server_id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.RestartSchedule{}, "server_id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.CrashReport{}, "server_id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.PlayerSession{}, "server_id = ?", id).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Delete(&models.MinecraftServer{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrServerNotFound
+		}
+		return nil
+	})
+	if errors.Is(err, ErrServerNotFound) {
+		return err
+	}
+	if err != nil {
+		r.logger.Error("Failed to delete server", "id", id, "error", err)
+		return err
 	}
 	r.logger.Debug("Server deleted from database", "id", id)
 	return nil
 }
+
+// loadDetails fills in the data of servers stored in other tables
+func (r *ServerRepository) loadDetails(servers ...*models.MinecraftServer) error {
+	if len(servers) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*models.MinecraftServer, len(servers))
+	ids := make([]string, 0, len(servers))
+	for _, server := range servers {
+		byID[server.ID] = server
+		ids = append(ids, server.ID)
+	}
+
+	if err := r.loadEnv(byID, ids); err != nil {
+		return err
+	}
+	return r.loadRestartSchedules(byID, ids)
+}
+
+// loadEnv fills in the environment overrides of servers
+func (r *ServerRepository) loadEnv(byID map[string]*models.MinecraftServer, ids []string) error {
+	var vars []models.ServerEnvVar
+	if err := r.db.Where("server_id IN ?", ids).Find(&vars).Error; err != nil {
+		r.logger.Error("Failed to load server environment overrides", "error", err)
+		return err
+	}
+	for _, v := range vars {
+		server := byID[v.ServerID]
+		if server.Env == nil {
+			server.Env = make(map[string]string)
+		}
+		server.Env[v.Key] = v.Value
+	}
+	return nil
+}
+
+// saveEnv inserts the environment overrides of a server
+func saveEnv(tx *gorm.DB, server *models.MinecraftServer) error {
+	if len(server.Env) == 0 {
+		return nil
+	}
+	vars := make([]models.ServerEnvVar, 0, len(server.Env))
+	for key, value := range server.Env {
+		vars = append(vars, models.ServerEnvVar{ServerID: server.ID, Key: key, Value: value})
+	}
+	return tx.Create(&vars).Error
+}
+
+// loadRestartSchedules fills in the restart schedules of servers
+func (r *ServerRepository) loadRestartSchedules(byID map[string]*models.MinecraftServer, ids []string) error {
+	var schedules []*models.RestartSchedule
+	if err := r.db.Where("server_id IN ?", ids).Find(&schedules).Error; err != nil {
+		r.logger.Error("Failed to load server restart schedules", "error", err)
+		return err
+	}
+	for _, schedule := range schedules {
+		byID[schedule.ServerID].RestartSchedule = schedule
+	}
+	return nil
+}
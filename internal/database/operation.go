@@ -0,0 +1,94 @@
+package database
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrOperationNotFound is returned when an operation does not exist in the database
+var ErrOperationNotFound = errors.New("operation not found")
+
+// OperationRepository provides database operations for Operation
+type OperationRepository struct {
+	db     *gorm.DB
+	logger *slog.Logger
+}
+
+// NewOperationRepository creates a new operation repository
+func NewOperationRepository(db *DB) *OperationRepository {
+	return &OperationRepository{
+		db:     db.DB,
+		logger: db.logger,
+	}
+}
+
+// Create inserts a new operation into the database
+func (r *OperationRepository) Create(operation *models.Operation) error {
+	result := r.db.Create(operation)
+	if result.Error != nil {
+		r.logger.Error("Failed to create operation in database", "error", result.Error)
+		return result.Error
+	}
+	r.logger.Debug("Operation created in database", "id", operation.ID, "type", operation.Type)
+	return nil
+}
+
+// Update updates an existing operation in the database
+func (r *OperationRepository) Update(operation *models.Operation) error {
+	result := r.db.Save(operation)
+	if result.Error != nil {
+		r.logger.Error("Failed to update operation in database", "id", operation.ID, "error", result.Error)
+		return result.Error
+	}
+	return nil
+}
+
+// FindByID retrieves an operation by its ID
+func (r *OperationRepository) FindByID(id string) (*models.Operation, error) {
+	var operation models.Operation
+	result := r.db.First(&operation, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrOperationNotFound
+		}
+		r.logger.Error("Failed to find operation by ID", "id", id, "error", result.Error)
+		return nil, result.Error
+	}
+	return &operation, nil
+}
+
+// FindRecent retrieves the most recent operations, optionally only those of one server
+func (r *OperationRepository) FindRecent(serverID string, limit int) ([]*models.Operation, error) {
+	var operations []*models.Operation
+	query := r.db.Order("created_at DESC").Limit(limit)
+	if serverID != "" {
+		query = query.Where("server_id = ?", serverID)
+	}
+	result := query.Find(&operations)
+	if result.Error != nil {
+		r.logger.Error("Failed to retrieve operations", "error", result.Error)
+		return nil, result.Error
+	}
+	return operations, nil
+}
+
+// FailUnfinished marks operations that were still pending or running as failed.
+// Operations run in the manager process, so after a restart they can never finish.
+func (r *OperationRepository) FailUnfinished(reason string) (int64, error) {
+	result := r.db.Model(&models.Operation{}).
+		Where("status IN ?", []models.OperationStatus{models.OperationPending, models.OperationRunning}).
+		Updates(map[string]interface{}{
+			"status":      models.OperationFailed,
+			"error":       reason,
+			"finished_at": time.Now(),
+		})
+	if result.Error != nil {
+		r.logger.Error("Failed to mark unfinished operations as failed", "error", result.Error)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
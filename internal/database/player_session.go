@@ -0,0 +1,125 @@
+package database
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrPlayerNotFound is returned when no session of a player exists in the database
+var ErrPlayerNotFound = errors.New("player not found")
+
+// PlayerSessionRepository provides database operations for PlayerSession
+type PlayerSessionRepository struct {
+	db     *gorm.DB
+	logger *slog.Logger
+}
+
+// NewPlayerSessionRepository creates a new player session repository
+func NewPlayerSessionRepository(db *DB) *PlayerSessionRepository {
+	return &PlayerSessionRepository{
+		db:     db.DB,
+		logger: db.logger,
+	}
+}
+
+// CreateIfMissing inserts a session unless the server already has one of the player starting at the same time.
+// Logs are read again after restarts of the manager, so a join is seen more than once.
+// Times are stored in UTC, SQLite compares them as text.
+func (r *PlayerSessionRepository) CreateIfMissing(session *models.PlayerSession) error {
+	session.JoinedAt = session.JoinedAt.UTC()
+
+	var existing []*models.PlayerSession
+	result := r.db.Where("server_id = ? AND player_name = ? AND joined_at = ?", session.ServerID, session.PlayerName, session.JoinedAt).
+		Limit(1).Find(&existing)
+	if result.Error != nil {
+		r.logger.Error("Failed to retrieve player session", "error", result.Error)
+		return result.Error
+	}
+	if len(existing) > 0 {
+		*session = *existing[0]
+		return nil
+	}
+
+	if result := r.db.Create(session); result.Error != nil {
+		r.logger.Error("Failed to create player session in database", "error", result.Error)
+		return result.Error
+	}
+	return nil
+}
+
+// Close ends the open sessions of a player on a server
+func (r *PlayerSessionRepository) Close(serverID, playerName string, leftAt time.Time) error {
+	result := r.db.Model(&models.PlayerSession{}).
+		Where("server_id = ? AND player_name = ? AND left_at IS NULL AND joined_at <= ?", serverID, playerName, leftAt.UTC()).
+		Update("left_at", leftAt.UTC())
+	if result.Error != nil {
+		r.logger.Error("Failed to close player session", "server_id", serverID, "player", playerName, "error", result.Error)
+		return result.Error
+	}
+	return nil
+}
+
+// CloseAll ends all open sessions on a server, e.g. after it stopped
+func (r *PlayerSessionRepository) CloseAll(serverID string, leftAt time.Time) error {
+	result := r.db.Model(&models.PlayerSession{}).
+		Where("server_id = ? AND left_at IS NULL", serverID).
+		Update("left_at", leftAt.UTC())
+	if result.Error != nil {
+		r.logger.Error("Failed to close player sessions", "server_id", serverID, "error", result.Error)
+		return result.Error
+	}
+	return nil
+}
+
+// FindOpen retrieves the open sessions on a server, i.e. the players online, ordered by join time
+func (r *PlayerSessionRepository) FindOpen(serverID string) ([]*models.PlayerSession, error) {
+	var sessions []*models.PlayerSession
+	result := r.db.Where("server_id = ? AND left_at IS NULL", serverID).Order("joined_at").Find(&sessions)
+	if result.Error != nil {
+		r.logger.Error("Failed to retrieve open player sessions", "server_id", serverID, "error", result.Error)
+		return nil, result.Error
+	}
+	return sessions, nil
+}
+
+// Find retrieves the sessions matching a filter, newest first
+func (r *PlayerSessionRepository) Find(filter models.PlayerSessionFilter) ([]*models.PlayerSession, error) {
+	query := r.db.Model(&models.PlayerSession{})
+	if filter.ServerID != "" {
+		query = query.Where("server_id = ?", filter.ServerID)
+	}
+	if filter.PlayerUUID != "" {
+		query = query.Where("player_uuid = ?", filter.PlayerUUID)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("left_at IS NULL OR left_at >= ?", filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("joined_at <= ?", filter.Until.UTC())
+	}
+
+	var sessions []*models.PlayerSession
+	if result := query.Order("joined_at DESC").Find(&sessions); result.Error != nil {
+		r.logger.Error("Failed to retrieve player sessions", "error", result.Error)
+		return nil, result.Error
+	}
+	return sessions, nil
+}
+
+// LastJoinTime returns the latest join on a server, or the zero time if it has no sessions
+func (r *PlayerSessionRepository) LastJoinTime(serverID string) (time.Time, error) {
+	var sessions []*models.PlayerSession
+	result := r.db.Where("server_id = ?", serverID).Order("joined_at DESC").Limit(1).Find(&sessions)
+	if result.Error != nil {
+		r.logger.Error("Failed to retrieve last player session", "server_id", serverID, "error", result.Error)
+		return time.Time{}, result.Error
+	}
+	if len(sessions) == 0 {
+		return time.Time{}, nil
+	}
+	return sessions[0].JoinedAt, nil
+}
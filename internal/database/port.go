@@ -0,0 +1,84 @@
+package database
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrNoFreePort is returned when every port in the allocation range is taken
+var ErrNoFreePort = errors.New("no free port in range")
+
+// PortRepository provides database operations for PortAllocation
+type PortRepository struct {
+	db     *gorm.DB
+	logger *slog.Logger
+}
+
+// NewPortRepository creates a new port repository
+func NewPortRepository(db *DB) *PortRepository {
+	return &PortRepository{
+		db:     db.DB,
+		logger: db.logger,
+	}
+}
+
+// Allocate reserves the lowest free port in [start, end] for a server.
+// If the server already holds a port, that port is returned.
+func (r *PortRepository) Allocate(serverID string, start, end int) (int, error) {
+	var port int
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.PortAllocation
+		result := tx.First(&existing, "server_id = ?", serverID)
+		if result.Error == nil {
+			port = existing.Port
+			return nil
+		}
+		if result.Error != gorm.ErrRecordNotFound {
+			return result.Error
+		}
+
+		var used []int
+		if err := tx.Model(&models.PortAllocation{}).
+			Where("port BETWEEN ? AND ?", start, end).
+			Order("port").
+			Pluck("port", &used).Error; err != nil {
+			return err
+		}
+
+		// used is sorted, so the first gap is the lowest free port
+		port = start
+		for _, p := range used {
+			if p != port {
+				break
+			}
+			port++
+		}
+		if port > end {
+			return ErrNoFreePort
+		}
+
+		// The port is the primary key, so a concurrent allocation of the same port fails here
+		return tx.Create(&models.PortAllocation{Port: port, ServerID: serverID}).Error
+	})
+	if err != nil {
+		r.logger.Error("Failed to allocate port", "server_id", serverID, "error", err)
+		return 0, err
+	}
+
+	r.logger.Debug("Port allocated", "server_id", serverID, "port", port)
+	return port, nil
+}
+
+// Release frees the port held by a server. Releasing a server without a port is not an error.
+func (r *PortRepository) Release(serverID string) error {
+	result := r.db.Delete(&models.PortAllocation{}, "server_id = ?", serverID)
+	if result.Error != nil {
+		r.logger.Error("Failed to release port", "server_id", serverID, "error", result.Error)
+		return result.Error
+	}
+	r.logger.Debug("Port released", "server_id", serverID, "released", result.RowsAffected > 0)
+	return nil
+}
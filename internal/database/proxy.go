@@ -1,13 +1,16 @@
 package database
 
 import (
-	"fmt"
+	"errors"
 	"log/slog"
 
 	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
 	"gorm.io/gorm"
 )
 
+// ErrProxyNotFound is returned when the proxy does not exist in the database
+var ErrProxyNotFound = errors.New("proxy not found")
+
 // ProxyRepository provides database operations for ProxyServer
 type ProxyRepository struct {
 	db     *gorm.DB
@@ -39,7 +42,7 @@ func (r *ProxyRepository) FindByID(id string) (*models.ProxyServer, error) {
 	result := r.db.First(&proxy, "id = ?", id)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("proxy not found")
+			return nil, ErrProxyNotFound
 		}
 		r.logger.Error("Failed to find proxy by ID", "id", id, "error", result.Error)
 		return nil, result.Error
@@ -53,7 +56,7 @@ func (r *ProxyRepository) FindByName(name string) (*models.ProxyServer, error) {
 	result := r.db.First(&proxy, "name = ?", name)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("proxy not found")
+			return nil, ErrProxyNotFound
 		}
 		r.logger.Error("Failed to find proxy by name", "name", name, "error", result.Error)
 		return nil, result.Error
@@ -83,6 +86,23 @@ func (r *ProxyRepository) Update(proxy *models.ProxyServer) error {
 	return nil
 }
 
+// UpdateState updates only the container state columns of a proxy,
+// so concurrent changes to its settings are not overwritten
+func (r *ProxyRepository) UpdateState(proxy *models.ProxyServer) error {
+	result := r.db.Model(&models.ProxyServer{}).Where("id = ?", proxy.ID).Updates(map[string]interface{}{
+		"container_id": proxy.ContainerID,
+		"status":       proxy.Status,
+		"exit_code":    proxy.ExitCode,
+		"oom_killed":   proxy.OOMKilled,
+	})
+	if result.Error != nil {
+		r.logger.Error("Failed to update proxy state", "id", proxy.ID, "error", result.Error)
+		return result.Error
+	}
+	r.logger.Debug("Proxy state updated in database", "id", proxy.ID, "status", proxy.Status)
+	return nil
+}
+
 // Delete removes a proxy from the database
 func (r *ProxyRepository) Delete(id string) error {
 	result := r.db.Unscoped().Delete(&models.ProxyServer{}, "id = ?", id)
@@ -91,7 +111,7 @@ func (r *ProxyRepository) Delete(id string) error {
 		return result.Error
 	}
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("proxy not found")
+		return ErrProxyNotFound
 	}
 	r.logger.Debug("Proxy deleted from database", "id", id)
 	return nil
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrRestartScheduleNotFound is returned when a server has no restart schedule
+var ErrRestartScheduleNotFound = errors.New("restart schedule not found")
+
+// RestartScheduleRepository provides database operations for RestartSchedule
+type RestartScheduleRepository struct {
+	db     *gorm.DB
+	logger *slog.Logger
+}
+
+// NewRestartScheduleRepository creates a new restart schedule repository
+func NewRestartScheduleRepository(db *DB) *RestartScheduleRepository {
+	return &RestartScheduleRepository{
+		db:     db.DB,
+		logger: db.logger,
+	}
+}
+
+// Save creates or replaces the restart schedule of a server
+func (r *RestartScheduleRepository) Save(schedule *models.RestartSchedule) error {
+	result := r.db.Save(schedule)
+	if result.Error != nil {
+		r.logger.Error("Failed to save restart schedule", "server_id", schedule.ServerID, "error", result.Error)
+		return result.Error
+	}
+	return nil
+}
+
+// FindByServerID retrieves the restart schedule of a server
+func (r *RestartScheduleRepository) FindByServerID(serverID string) (*models.RestartSchedule, error) {
+	var schedule models.RestartSchedule
+	result := r.db.First(&schedule, "server_id = ?", serverID)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrRestartScheduleNotFound
+		}
+		r.logger.Error("Failed to find restart schedule", "server_id", serverID, "error", result.Error)
+		return nil, result.Error
+	}
+	return &schedule, nil
+}
+
+// FindDue retrieves the enabled schedules whose next run is due
+func (r *RestartScheduleRepository) FindDue(now time.Time) ([]*models.RestartSchedule, error) {
+	var schedules []*models.RestartSchedule
+	result := r.db.Where("enabled = ? AND next_run_at <= ?", true, now).Find(&schedules)
+	if result.Error != nil {
+		r.logger.Error("Failed to retrieve due restart schedules", "error", result.Error)
+		return nil, result.Error
+	}
+	return schedules, nil
+}
+
+// UpdateNextRun only updates when the schedule runs next, so concurrent edits of the schedule are kept
+func (r *RestartScheduleRepository) UpdateNextRun(serverID string, nextRunAt time.Time) error {
+	result := r.db.Model(&models.RestartSchedule{}).
+		Where("server_id = ?", serverID).
+		Update("next_run_at", nextRunAt)
+	if result.Error != nil {
+		r.logger.Error("Failed to update next restart run", "server_id", serverID, "error", result.Error)
+		return result.Error
+	}
+	return nil
+}
+
+// UpdateLastRun only updates the outcome of the last run, so concurrent edits of the schedule are kept
+func (r *RestartScheduleRepository) UpdateLastRun(schedule *models.RestartSchedule) error {
+	result := r.db.Model(&models.RestartSchedule{}).
+		Where("server_id = ?", schedule.ServerID).
+		Updates(map[string]interface{}{
+			"last_run_at":          schedule.LastRunAt,
+			"last_status":          schedule.LastStatus,
+			"last_error":           schedule.LastError,
+			"consecutive_failures": schedule.ConsecutiveFailures,
+		})
+	if result.Error != nil {
+		r.logger.Error("Failed to update last restart run", "server_id", schedule.ServerID, "error", result.Error)
+		return result.Error
+	}
+	return nil
+}
+
+// Delete removes the restart schedule of a server
+func (r *RestartScheduleRepository) Delete(serverID string) error {
+	result := r.db.Delete(&models.RestartSchedule{}, "server_id = ?", serverID)
+	if result.Error != nil {
+		r.logger.Error("Failed to delete restart schedule", "server_id", serverID, "error", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRestartScheduleNotFound
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package database
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrSFTPAccountNotFound is returned when an SFTP account does not exist in the database
+var ErrSFTPAccountNotFound = errors.New("sftp account not found")
+
+// ErrSFTPKeyNotFound is returned when an SFTP account has no key with the given fingerprint
+var ErrSFTPKeyNotFound = errors.New("sftp key not found")
+
+// ErrSFTPPermissionNotFound is returned when an SFTP account has no permission for a server
+var ErrSFTPPermissionNotFound = errors.New("sftp permission not found")
+
+// SFTPAccountRepository provides database operations for SFTPAccount, its keys and permissions
+type SFTPAccountRepository struct {
+	db     *gorm.DB
+	logger *slog.Logger
+}
+
+// NewSFTPAccountRepository creates a new SFTP account repository
+func NewSFTPAccountRepository(db *DB) *SFTPAccountRepository {
+	return &SFTPAccountRepository{
+		db:     db.DB,
+		logger: db.logger,
+	}
+}
+
+// Create inserts a new SFTP account into the database
+func (r *SFTPAccountRepository) Create(account *models.SFTPAccount) error {
+	result := r.db.Create(account)
+	if result.Error != nil {
+		r.logger.Error("Failed to create SFTP account in database", "username", account.Username, "error", result.Error)
+		return result.Error
+	}
+	r.logger.Debug("SFTP account created in database", "username", account.Username)
+	return nil
+}
+
+// FindByUsername retrieves an SFTP account with its keys and permissions
+func (r *SFTPAccountRepository) FindByUsername(username string) (*models.SFTPAccount, error) {
+	var account models.SFTPAccount
+	result := r.db.Preload("Keys").Preload("Permissions").First(&account, "username = ?", username)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrSFTPAccountNotFound
+		}
+		r.logger.Error("Failed to find SFTP account", "username", username, "error", result.Error)
+		return nil, result.Error
+	}
+	return &account, nil
+}
+
+// FindAll retrieves all SFTP accounts with their keys and permissions
+func (r *SFTPAccountRepository) FindAll() ([]*models.SFTPAccount, error) {
+	var accounts []*models.SFTPAccount
+	result := r.db.Preload("Keys").Preload("Permissions").Order("username").Find(&accounts)
+	if result.Error != nil {
+		r.logger.Error("Failed to retrieve SFTP accounts", "error", result.Error)
+		return nil, result.Error
+	}
+	return accounts, nil
+}
+
+// UpdatePasswordHash replaces the password of an SFTP account
+func (r *SFTPAccountRepository) UpdatePasswordHash(username, passwordHash string) error {
+	result := r.db.Model(&models.SFTPAccount{}).
+		Where("username = ?", username).
+		Update("password_hash", passwordHash)
+	if result.Error != nil {
+		r.logger.Error("Failed to update SFTP password", "username", username, "error", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSFTPAccountNotFound
+	}
+	return nil
+}
+
+// Delete removes an SFTP account together with its keys and permissions
+func (r *SFTPAccountRepository) Delete(username string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.SFTPKey{}, "username = ?", username).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.SFTPPermission{}, "username = ?", username).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.SFTPAccount{}, "username = ?", username)
+		if result.Error != nil {
+			r.logger.Error("Failed to delete SFTP account", "username", username, "error", result.Error)
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrSFTPAccountNotFound
+		}
+		return nil
+	})
+}
+
+// AddKey stores a public key of an SFTP account
+func (r *SFTPAccountRepository) AddKey(key *models.SFTPKey) error {
+	result := r.db.Create(key)
+	if result.Error != nil {
+		r.logger.Error("Failed to add SFTP key", "username", key.Username, "error", result.Error)
+		return result.Error
+	}
+	return nil
+}
+
+// FindKey retrieves a public key by its fingerprint
+func (r *SFTPAccountRepository) FindKey(fingerprint string) (*models.SFTPKey, error) {
+	var key models.SFTPKey
+	result := r.db.First(&key, "fingerprint = ?", fingerprint)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrSFTPKeyNotFound
+		}
+		r.logger.Error("Failed to find SFTP key", "fingerprint", fingerprint, "error", result.Error)
+		return nil, result.Error
+	}
+	return &key, nil
+}
+
+// DeleteKey removes a public key of an SFTP account
+func (r *SFTPAccountRepository) DeleteKey(username, fingerprint string) error {
+	result := r.db.Delete(&models.SFTPKey{}, "username = ? AND fingerprint = ?", username, fingerprint)
+	if result.Error != nil {
+		r.logger.Error("Failed to delete SFTP key", "username", username, "error", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSFTPKeyNotFound
+	}
+	return nil
+}
+
+// SavePermission creates or replaces the permission of an SFTP account for a server
+func (r *SFTPAccountRepository) SavePermission(permission *models.SFTPPermission) error {
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "username"}, {Name: "server_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"access", "updated_at"}),
+	}).Create(permission)
+	if result.Error != nil {
+		r.logger.Error("Failed to save SFTP permission", "username", permission.Username, "server_id", permission.ServerID, "error", result.Error)
+		return result.Error
+	}
+	return nil
+}
+
+// FindPermission retrieves the permission of an SFTP account for a server
+func (r *SFTPAccountRepository) FindPermission(username, serverID string) (*models.SFTPPermission, error) {
+	var permission models.SFTPPermission
+	result := r.db.First(&permission, "username = ? AND server_id = ?", username, serverID)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrSFTPPermissionNotFound
+		}
+		r.logger.Error("Failed to find SFTP permission", "username", username, "server_id", serverID, "error", result.Error)
+		return nil, result.Error
+	}
+	return &permission, nil
+}
+
+// FindPermissions retrieves all permissions of an SFTP account
+func (r *SFTPAccountRepository) FindPermissions(username string) ([]*models.SFTPPermission, error) {
+	var permissions []*models.SFTPPermission
+	result := r.db.Where("username = ?", username).Find(&permissions)
+	if result.Error != nil {
+		r.logger.Error("Failed to retrieve SFTP permissions", "username", username, "error", result.Error)
+		return nil, result.Error
+	}
+	return permissions, nil
+}
+
+// DeletePermission revokes the permission of an SFTP account for a server
+func (r *SFTPAccountRepository) DeletePermission(username, serverID string) error {
+	result := r.db.Delete(&models.SFTPPermission{}, "username = ? AND server_id = ?", username, serverID)
+	if result.Error != nil {
+		r.logger.Error("Failed to delete SFTP permission", "username", username, "server_id", serverID, "error", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSFTPPermissionNotFound
+	}
+	return nil
+}
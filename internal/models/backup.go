@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// Backup is an archive of a server's /data volume
+type Backup struct {
+	ID               string     `json:"id" gorm:"primaryKey"`
+	ServerID         string     `json:"server_id" gorm:"index;not null"`
+	Destination      string     `json:"destination" gorm:"not null;default:local"` // Name of the storage the archive is in
+	FileName         string     `json:"file_name" gorm:"not null"`                 // Key of the archive in the destination
+	SizeBytes        int64      `json:"size_bytes"`
+	Checksum         string     `json:"checksum"` // SHA-256 of the archive, hex encoded
+	MinecraftVersion string     `json:"minecraft_version"`
+	Type             ServerType `json:"type" gorm:"type:varchar(20)"`
+	WorldSaved       bool       `json:"world_saved"` // Whether the world was flushed with save-all before archiving
+	Scheduled        bool       `json:"scheduled"`   // Created by the backup schedule, only those are pruned
+	CreatedAt        time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// CreateBackupRequest represents the request to back up a server
+type CreateBackupRequest struct {
+	// Disable saving and flush the world with save-off/save-all before archiving.
+	// Only has an effect while the server is running, a stopped server is consistent on disk.
+	SaveWorld bool `json:"save_world"`
+	// Name of the storage to write the archive to, the destination of the server if empty
+	Destination string `json:"destination,omitempty"`
+}
+
+// BackupDestination is a configured storage for backup archives
+type BackupDestination struct {
+	Name     string `json:"name"`
+	Location string `json:"location"` // Directory or bucket URL archives are stored in
+	Default  bool   `json:"default"`  // Used when neither a backup, its schedule nor its server name a destination
+}
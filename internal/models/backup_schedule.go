@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+)
+
+// ScheduleRunStatus is the outcome of the last scheduled run
+type ScheduleRunStatus string
+
+const (
+	ScheduleRunSucceeded ScheduleRunStatus = "succeeded"
+	ScheduleRunFailed    ScheduleRunStatus = "failed"
+	ScheduleRunSkipped   ScheduleRunStatus = "skipped" // e.g. a scheduled restart of a stopped server
+)
+
+// RetentionPolicy decides which scheduled backups are kept, a backup is kept if any rule keeps it.
+// Manual backups are never pruned. If all rules are zero, all backups are kept.
+type RetentionPolicy struct {
+	Last   int `json:"last"`   // Keep the N most recent scheduled backups
+	Daily  int `json:"daily"`  // Keep the newest backup of each of the last N days
+	Weekly int `json:"weekly"` // Keep the newest backup of each of the last N weeks
+}
+
+// IsZero reports whether the policy keeps all backups
+func (p RetentionPolicy) IsZero() bool {
+	return p.Last == 0 && p.Daily == 0 && p.Weekly == 0
+}
+
+// BackupSchedule defines when a server is backed up automatically and how long the backups are kept
+type BackupSchedule struct {
+	ServerID            string            `json:"server_id" gorm:"primaryKey"`
+	Cron                string            `json:"cron" gorm:"not null"` // Standard 5 field cron expression or descriptor like @daily
+	Enabled             bool              `json:"enabled"`
+	SaveWorld           bool              `json:"save_world"`
+	Destination         string            `json:"destination,omitempty"` // Storage the backups are written to, the destination of the server if empty
+	Retention           RetentionPolicy   `json:"retention" gorm:"embedded;embeddedPrefix:keep_"`
+	NextRunAt           *time.Time        `json:"next_run_at,omitempty" gorm:"index"`
+	LastRunAt           *time.Time        `json:"last_run_at,omitempty"`
+	LastStatus          ScheduleRunStatus `json:"last_status,omitempty" gorm:"type:varchar(20)"`
+	LastError           string            `json:"last_error,omitempty"`
+	ConsecutiveFailures int               `json:"consecutive_failures"`
+	CreatedAt           time.Time         `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt           time.Time         `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// BackupScheduleRequest represents the request to set the backup schedule of a server
+type BackupScheduleRequest struct {
+	Cron        string          `json:"cron"`
+	Enabled     *bool           `json:"enabled,omitempty"` // Defaults to true
+	SaveWorld   bool            `json:"save_world"`
+	Destination string          `json:"destination,omitempty"`
+	Retention   RetentionPolicy `json:"retention"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// CrashPolicy decides how a crashing server is restarted and when the manager gives up on it.
+// Fields that are 0 use the manager's defaults.
+type CrashPolicy struct {
+	MaxCrashes    int `json:"max_crashes"`    // Crashes within the window that put the server into crash_loop
+	WindowSeconds int `json:"window_seconds"` // Length of the window crashes are counted in
+	// BackoffSeconds is the delay before the first restart, it doubles with every further crash in the window
+	BackoffSeconds    int `json:"backoff_seconds"`
+	MaxBackoffSeconds int `json:"max_backoff_seconds"`
+}
+
+// CrashReport is captured when a server is stopped because it kept crashing
+type CrashReport struct {
+	ID        string `json:"id" gorm:"primaryKey"`
+	ServerID  string `json:"server_id" gorm:"index;not null"`
+	ExitCode  int    `json:"exit_code"` // Exit code of the last crash
+	OOMKilled bool   `json:"oom_killed"`
+	Crashes   int    `json:"crashes"` // Crashes within the window that led to the report
+	Logs      string `json:"logs"`    // Last lines of the container log
+	// CrashReportFile is the newest file in the server's crash-reports directory, if there is one
+	CrashReportFile string    `json:"crash_report_file,omitempty"`
+	CrashReport     string    `json:"crash_report,omitempty"`
+	CreatedAt       time.Time `json:"created_at" gorm:"autoCreateTime;index"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// EventType identifies what happened in an Event
+type EventType string
+
+const (
+	EventServerCreated          EventType = "server.created"
+	EventServerUpdated          EventType = "server.updated"
+	EventServerDeleted          EventType = "server.deleted"
+	EventServerStatusChanged    EventType = "server.status_changed"
+	EventProxyUpdated           EventType = "proxy.updated"
+	EventProxyStatusChanged     EventType = "proxy.status_changed"
+	EventProxyConfigRegenerated EventType = "proxy.config_regenerated"
+	EventOperationUpdated       EventType = "operation.updated"
+	EventPlayerJoined           EventType = "player.joined"
+	EventPlayerLeft             EventType = "player.left"
+	// EventResync tells a client that events were missed and it has to refetch all resources
+	EventResync EventType = "resync"
+)
+
+// Event is a change to a server or the proxy that is streamed to clients
+type Event struct {
+	ID        string    `json:"id"` // Cursor to resume the stream after this event
+	Type      EventType `json:"type"`
+	ServerID  string    `json:"server_id,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      any       `json:"data,omitempty"`
+}
+
+// StatusChange is the data of status_changed events
+type StatusChange struct {
+	PreviousStatus ContainerStatus `json:"previous_status"`
+	Status         ContainerStatus `json:"status"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// FileInfo describes a file or directory in a server's volume
+type FileInfo struct {
+	Name    string    `json:"name"`
+	Path    string    `json:"path"` // Absolute path inside the volume, e.g. /plugins/example.jar
+	IsDir   bool      `json:"is_dir"`
+	Size    int64     `json:"size"`
+	Mode    string    `json:"mode"` // Unix permissions like -rw-r--r--
+	ModTime time.Time `json:"mod_time"`
+}
+
+// DirectoryListing is the content of a directory in a server's volume
+type DirectoryListing struct {
+	Path    string     `json:"path"`
+	Entries []FileInfo `json:"entries"` // Directories first, then files, each sorted by name
+}
+
+// MoveFileRequest represents the request to rename or move a file or directory
+type MoveFileRequest struct {
+	Path string `json:"path"` // New absolute path inside the volume
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// ImagePullStatus represents the state of an image pull
+type ImagePullStatus string
+
+const (
+	ImagePullPulling   ImagePullStatus = "pulling"
+	ImagePullCompleted ImagePullStatus = "completed"
+	ImagePullFailed    ImagePullStatus = "failed"
+)
+
+// ImagePull reports the progress of pulling a Docker image
+type ImagePull struct {
+	Image           string          `json:"image"`
+	Status          ImagePullStatus `json:"status"`
+	Layers          []LayerProgress `json:"layers"`
+	DownloadedBytes int64           `json:"downloaded_bytes"`
+	TotalBytes      int64           `json:"total_bytes"` // Sum of the layer sizes known so far
+	Percent         float64         `json:"percent"`
+	Error           string          `json:"error,omitempty"`
+	StartedAt       time.Time       `json:"started_at"`
+	FinishedAt      *time.Time      `json:"finished_at,omitempty"`
+}
+
+// LayerProgress reports the progress of a single image layer
+type LayerProgress struct {
+	ID              string `json:"id"`
+	Status          string `json:"status"` // As reported by Docker, e.g. "Downloading" or "Pull complete"
+	DownloadedBytes int64  `json:"downloaded_bytes"`
+	TotalBytes      int64  `json:"total_bytes"`
+}
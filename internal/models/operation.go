@@ -0,0 +1,61 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OperationType identifies the kind of long running operation
+type OperationType string
+
+const (
+	OperationCreateServer  OperationType = "server.create"
+	OperationStartServer   OperationType = "server.start"
+	OperationStopServer    OperationType = "server.stop"
+	OperationRestartServer OperationType = "server.restart"
+	OperationUpdateServer  OperationType = "server.update"
+	OperationDeleteServer  OperationType = "server.delete"
+	OperationCreateBackup  OperationType = "backup.create"
+	OperationRestoreBackup OperationType = "backup.restore"
+
+	// OperationRotateRCONPassword gives a server a new RCON password and recreates its container
+	OperationRotateRCONPassword OperationType = "server.rotate_rcon_password"
+)
+
+// OperationStatus represents the state of an operation or one of its steps
+type OperationStatus string
+
+const (
+	OperationPending   OperationStatus = "pending"
+	OperationRunning   OperationStatus = "running"
+	OperationSucceeded OperationStatus = "succeeded"
+	OperationFailed    OperationStatus = "failed"
+)
+
+// Operation tracks a long running server operation that is executed in the background
+type Operation struct {
+	ID         string          `json:"id" gorm:"primaryKey"`
+	Type       OperationType   `json:"type" gorm:"type:varchar(50);not null"`
+	ServerID   string          `json:"server_id,omitempty" gorm:"index"` // Set as soon as the server is known
+	Status     OperationStatus `json:"status" gorm:"type:varchar(20)"`
+	Progress   int             `json:"progress"` // Overall progress in percent
+	Steps      []OperationStep `json:"steps" gorm:"serializer:json"`
+	Result     json.RawMessage `json:"result,omitempty" gorm:"serializer:json"` // e.g. the created server
+	Error      string          `json:"error,omitempty"`
+	CreatedAt  time.Time       `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time       `json:"updated_at" gorm:"autoUpdateTime"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+}
+
+// OperationStep is a single step of an operation
+type OperationStep struct {
+	Name       string          `json:"name"`
+	Status     OperationStatus `json:"status"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+}
+
+// IsFinished reports whether the operation succeeded or failed
+func (o *Operation) IsFinished() bool {
+	return o.Status == OperationSucceeded || o.Status == OperationFailed
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// WhitelistEntry is a player on the whitelist of a server
+type WhitelistEntry struct {
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+}
+
+// OperatorEntry is a player with operator permissions on a server
+type OperatorEntry struct {
+	UUID                string `json:"uuid"`
+	Name                string `json:"name"`
+	Level               int    `json:"level"` // Permission level, 1-4
+	BypassesPlayerLimit bool   `json:"bypasses_player_limit"`
+}
+
+// PlayerBan is a player banned from a server
+type PlayerBan struct {
+	UUID    string     `json:"uuid"`
+	Name    string     `json:"name"`
+	Reason  string     `json:"reason"`
+	Source  string     `json:"source"` // Who issued the ban, e.g. Server or a player name
+	Created *time.Time `json:"created,omitempty"`
+	Expires *time.Time `json:"expires,omitempty"` // Not set for permanent bans
+}
+
+// IPBan is an IP address banned from a server
+type IPBan struct {
+	IP      string     `json:"ip"`
+	Reason  string     `json:"reason"`
+	Source  string     `json:"source"`
+	Created *time.Time `json:"created,omitempty"`
+	Expires *time.Time `json:"expires,omitempty"` // Not set for permanent bans
+}
+
+// PlayerRequest names a player to add to the whitelist or operators of a server
+type PlayerRequest struct {
+	Name string `json:"name"`
+	// UUID skips the profile lookup for stopped servers, e.g. for players unknown to Mojang
+	UUID string `json:"uuid,omitempty"`
+}
+
+// BanPlayerRequest is the request body for banning a player
+type BanPlayerRequest struct {
+	Name   string `json:"name"`
+	UUID   string `json:"uuid,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// BanIPRequest is the request body for banning an IP address
+type BanIPRequest struct {
+	IP     string `json:"ip"`
+	Reason string `json:"reason,omitempty"`
+}
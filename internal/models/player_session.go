@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+)
+
+// PlayerSession is a stay of a player on a server, parsed from the server log
+type PlayerSession struct {
+	ID         string     `json:"id" gorm:"primaryKey"`
+	ServerID   string     `json:"server_id" gorm:"index;not null"`
+	PlayerUUID string     `json:"player_uuid" gorm:"index"` // Empty if the log and usercache.json didn't reveal it
+	PlayerName string     `json:"player_name" gorm:"index;not null"`
+	IP         string     `json:"ip,omitempty"` // Address the player connected from, forwarded by the proxy
+	JoinedAt   time.Time  `json:"joined_at" gorm:"index;not null"`
+	LeftAt     *time.Time `json:"left_at,omitempty"` // Not set while the player is online
+}
+
+// Player summarizes the sessions of a player
+type Player struct {
+	UUID      string    `json:"uuid"`
+	Name      string    `json:"name"` // Name of the latest session
+	Online    bool      `json:"online"`
+	ServerID  string    `json:"server_id,omitempty"` // Server the player is on while online
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Sessions  int       `json:"sessions"`
+}
+
+// PlayerDetail is a player with its sessions, newest first
+type PlayerDetail struct {
+	Player
+	SessionList []*PlayerSession `json:"session_list"`
+}
+
+// PlayerSessionFilter narrows down player sessions, zero values don't filter
+type PlayerSessionFilter struct {
+	ServerID   string
+	PlayerUUID string
+	Since      time.Time // Sessions that ended after Since or are still open
+	Until      time.Time // Sessions that started before Until
+}
@@ -0,0 +1,12 @@
+package models
+
+import (
+	"time"
+)
+
+// PortAllocation reserves a host port for a server that is exposed directly
+type PortAllocation struct {
+	Port      int       `json:"port" gorm:"primaryKey;autoIncrement:false"`
+	ServerID  string    `json:"server_id" gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
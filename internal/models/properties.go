@@ -0,0 +1,43 @@
+package models
+
+// ServerProperties are the settings of server.properties that can be changed through the API.
+// Fields missing in the file are nil. On updates only the fields that are set are changed.
+// Settings the manager controls, like max-players, motd and online-mode, are not part of it.
+type ServerProperties struct {
+	Difficulty                     *string `json:"difficulty,omitempty" property:"difficulty"` // peaceful, easy, normal or hard
+	Gamemode                       *string `json:"gamemode,omitempty" property:"gamemode"`     // survival, creative, adventure or spectator
+	ForceGamemode                  *bool   `json:"force_gamemode,omitempty" property:"force-gamemode"`
+	Hardcore                       *bool   `json:"hardcore,omitempty" property:"hardcore"`
+	PVP                            *bool   `json:"pvp,omitempty" property:"pvp"`
+	ViewDistance                   *int    `json:"view_distance,omitempty" property:"view-distance"`             // Chunks, 3-32
+	SimulationDistance             *int    `json:"simulation_distance,omitempty" property:"simulation-distance"` // Chunks, 3-32
+	SpawnProtection                *int    `json:"spawn_protection,omitempty" property:"spawn-protection"`       // Radius in blocks, 0 disables it
+	LevelSeed                      *string `json:"level_seed,omitempty" property:"level-seed"`                   // Only used when the world is generated
+	LevelType                      *string `json:"level_type,omitempty" property:"level-type"`                   // e.g. minecraft:normal, minecraft:flat
+	GenerateStructures             *bool   `json:"generate_structures,omitempty" property:"generate-structures"`
+	AllowNether                    *bool   `json:"allow_nether,omitempty" property:"allow-nether"`
+	AllowFlight                    *bool   `json:"allow_flight,omitempty" property:"allow-flight"`
+	SpawnMonsters                  *bool   `json:"spawn_monsters,omitempty" property:"spawn-monsters"`
+	SpawnAnimals                   *bool   `json:"spawn_animals,omitempty" property:"spawn-animals"`
+	SpawnNPCs                      *bool   `json:"spawn_npcs,omitempty" property:"spawn-npcs"`
+	Whitelist                      *bool   `json:"white_list,omitempty" property:"white-list"`
+	EnforceWhitelist               *bool   `json:"enforce_whitelist,omitempty" property:"enforce-whitelist"`
+	EnableCommandBlock             *bool   `json:"enable_command_block,omitempty" property:"enable-command-block"`
+	OpPermissionLevel              *int    `json:"op_permission_level,omitempty" property:"op-permission-level"` // 1-4
+	PlayerIdleTimeout              *int    `json:"player_idle_timeout,omitempty" property:"player-idle-timeout"` // Minutes, 0 disables it
+	MaxWorldSize                   *int    `json:"max_world_size,omitempty" property:"max-world-size"`           // Radius of the world border in blocks
+	EntityBroadcastRangePercentage *int    `json:"entity_broadcast_range_percentage,omitempty" property:"entity-broadcast-range-percentage"`
+	HideOnlinePlayers              *bool   `json:"hide_online_players,omitempty" property:"hide-online-players"`
+	ResourcePack                   *string `json:"resource_pack,omitempty" property:"resource-pack"` // URL of the resource pack
+	ResourcePackSHA1               *string `json:"resource_pack_sha1,omitempty" property:"resource-pack-sha1"`
+	RequireResourcePack            *bool   `json:"require_resource_pack,omitempty" property:"require-resource-pack"`
+}
+
+// ServerPropertiesResponse is the content of a server's server.properties
+type ServerPropertiesResponse struct {
+	Properties ServerProperties  `json:"properties"`
+	Other      map[string]string `json:"other"`             // Keys without a typed field, they are kept as they are
+	Changed    []string          `json:"changed,omitempty"` // Keys changed by the update
+	// RestartRequired is set if the update changed settings of a running server, they apply on its next start
+	RestartRequired bool `json:"restart_required"`
+}
@@ -17,6 +17,8 @@ type ProxyServer struct {
 	VolumeID        string          `json:"volume_id"`
 	DefaultServerID string          `json:"default_server_id"`
 	Status          ContainerStatus `json:"status" gorm:"type:varchar(20)"`
+	ExitCode        int             `json:"exit_code"`            // Exit code of the last container run
+	OOMKilled       bool            `json:"oom_killed"`           // Whether the last container run was killed due to OOM
 	Port            int             `json:"port" gorm:"not null"` // Public port (typically 25565)
 	CreatedAt       time.Time       `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt       time.Time       `json:"updated_at" gorm:"autoUpdateTime"`
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// RestartSchedule defines when a running server is restarted automatically
+type RestartSchedule struct {
+	ServerID string `json:"server_id" gorm:"primaryKey"`
+	Cron     string `json:"cron" gorm:"not null"` // Standard 5 field cron expression or descriptor like @daily
+	Enabled  bool   `json:"enabled"`
+	// WarningSeconds is the countdown players see before the restart, 0 restarts without warning
+	WarningSeconds      int               `json:"warning_seconds"`
+	NextRunAt           *time.Time        `json:"next_run_at,omitempty" gorm:"index"`
+	LastRunAt           *time.Time        `json:"last_run_at,omitempty"`
+	LastStatus          ScheduleRunStatus `json:"last_status,omitempty" gorm:"type:varchar(20)"`
+	LastError           string            `json:"last_error,omitempty"`
+	ConsecutiveFailures int               `json:"consecutive_failures"`
+	CreatedAt           time.Time         `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt           time.Time         `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// RestartScheduleRequest represents the request to set the restart schedule of a server
+type RestartScheduleRequest struct {
+	Cron           string `json:"cron"`
+	Enabled        *bool  `json:"enabled,omitempty"` // Defaults to true
+	WarningSeconds int    `json:"warning_seconds"`
+}
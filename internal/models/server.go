@@ -6,28 +6,80 @@ import (
 
 // MinecraftServer represents a Minecraft server instance
 type MinecraftServer struct {
-	ID          string          `json:"id" gorm:"primaryKey"`
-	Name        string          `json:"name" gorm:"uniqueIndex;not null"`
-	ContainerID string          `json:"container_id" gorm:"index"`
-	VolumeID    string          `json:"volume_id"`
-	Status      ContainerStatus `json:"status" gorm:"type:varchar(20)"`
-	Port        int             `json:"port"`
-	MaxPlayers  int             `json:"max_players" gorm:"not null"`
-	MOTD        string          `json:"motd"`
-	CreatedAt   time.Time       `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt   time.Time       `json:"updated_at" gorm:"autoUpdateTime"`
+	ID            string          `json:"id" gorm:"primaryKey"`
+	Name          string          `json:"name" gorm:"uniqueIndex;not null"`
+	ContainerID   string          `json:"container_id" gorm:"index"`
+	VolumeID      string          `json:"volume_id"`
+	Status        ContainerStatus `json:"status" gorm:"type:varchar(20)"`
+	ExitCode      int             `json:"exit_code"`  // Exit code of the last container run
+	OOMKilled     bool            `json:"oom_killed"` // Whether the last container run was killed due to OOM
+	Port          int             `json:"port"`       // Host port, only set when ExposeDirectly is enabled
+	MaxPlayers    int             `json:"max_players" gorm:"not null"`
+	MOTD          string          `json:"motd"`
+	Version       string          `json:"version"`
+	Type          ServerType      `json:"type" gorm:"type:varchar(20)"`
+	LoaderVersion string          `json:"loader_version,omitempty"`
+	MemoryMB      int             `json:"memory_mb"`   // Container memory limit, 0 for unlimited
+	CPULimit      float64         `json:"cpu_limit"`   // Number of CPUs, 0 for unlimited
+	JVMHeapMB     int             `json:"jvm_heap_mb"` // JVM heap size, 0 to derive it from MemoryMB
+	// ExposeDirectly publishes the server on its own host port and keeps it out of the proxy
+	ExposeDirectly bool `json:"expose_directly"`
+	// Env holds environment variable overrides for the itzg image, stored as ServerEnvVar rows
+	Env map[string]string `json:"env,omitempty" gorm:"-"`
+	// CrashPolicy decides how the server is restarted after crashes and when it is put into crash_loop
+	CrashPolicy CrashPolicy `json:"crash_policy" gorm:"embedded;embeddedPrefix:crash_"`
+	// BackupDestination is where backups and schedules that don't name a destination are written,
+	// the default destination if empty
+	BackupDestination string `json:"backup_destination,omitempty"`
+	// RestartCount is how often Docker restarted the container since the manager last started it
+	RestartCount int `json:"restart_count"`
+	// Crashes counts the crashes in the current crash window, which started at CrashWindowStart
+	Crashes          int        `json:"crashes"`
+	CrashWindowStart *time.Time `json:"crash_window_start,omitempty"`
+	// NextRestartAt is when a crashed server is started again after its backoff
+	NextRestartAt *time.Time `json:"next_restart_at,omitempty"`
+	// RestartSchedule is the schedule the server is restarted on, if it has one
+	RestartSchedule *RestartSchedule `json:"restart_schedule,omitempty" gorm:"-"`
+	// RCONPassword is the RCON password of the server, encrypted with the master key
+	RCONPassword string `json:"-"`
+	// Ping is the last Server List Ping of the running server, only kept in memory
+	Ping      *ServerPing `json:"ping,omitempty" gorm:"-"`
+	CreatedAt time.Time   `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time   `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
 // CreateServerRequest represents the request body for creating a new server
 type CreateServerRequest struct {
-	Name       string `json:"name" binding:"required"`
-	MaxPlayers int    `json:"max_players"`
-	MOTD       string `json:"motd"`
-	Version    string `json:"version"`
+	Name           string     `json:"name" binding:"required"`
+	MaxPlayers     int        `json:"max_players"`
+	MOTD           string     `json:"motd"`
+	Version        string     `json:"version"`
+	Type           ServerType `json:"type"`
+	LoaderVersion  string     `json:"loader_version"`
+	MemoryMB       int        `json:"memory_mb"`
+	CPULimit       float64    `json:"cpu_limit"`
+	JVMHeapMB      int        `json:"jvm_heap_mb"`
+	ExposeDirectly bool       `json:"expose_directly"`
+	// Env overrides environment variables of the itzg image, e.g. TZ or USE_AIKAR_FLAGS
+	Env map[string]string `json:"env,omitempty"`
+	// CrashPolicy overrides the default crash policy, fields that are 0 use the defaults
+	CrashPolicy *CrashPolicy `json:"crash_policy,omitempty"`
+	// BackupDestination is where backups of the server are written, the default destination if empty
+	BackupDestination string `json:"backup_destination,omitempty"`
 }
 
 // UpdateServerRequest represents the request body for updating a server
 type UpdateServerRequest struct {
-	MaxPlayers *int    `json:"max_players,omitempty"`
-	MOTD       *string `json:"motd,omitempty"`
+	MaxPlayers     *int     `json:"max_players,omitempty"`
+	MOTD           *string  `json:"motd,omitempty"`
+	MemoryMB       *int     `json:"memory_mb,omitempty"`
+	CPULimit       *float64 `json:"cpu_limit,omitempty"`
+	JVMHeapMB      *int     `json:"jvm_heap_mb,omitempty"`
+	ExposeDirectly *bool    `json:"expose_directly,omitempty"`
+	// Env sets environment variable overrides, a null value removes the override
+	Env map[string]*string `json:"env,omitempty"`
+	// CrashPolicy replaces the crash policy of the server
+	CrashPolicy *CrashPolicy `json:"crash_policy,omitempty"`
+	// BackupDestination replaces the backup destination of the server, an empty string uses the default again
+	BackupDestination *string `json:"backup_destination,omitempty"`
 }
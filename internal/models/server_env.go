@@ -0,0 +1,9 @@
+package models
+
+// ServerEnvVar is an environment variable override of a server, passed to the itzg image
+// together with the variables the manager sets itself
+type ServerEnvVar struct {
+	ServerID string `gorm:"primaryKey"`
+	Key      string `gorm:"primaryKey"`
+	Value    string `gorm:"not null"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// ServerPing is the result of the last Server List Ping of a running server
+type ServerPing struct {
+	Ready         bool      `json:"ready"` // Whether the server answered, i.e. accepts logins
+	OnlinePlayers int       `json:"online_players"`
+	MaxPlayers    int       `json:"max_players"`
+	Version       string    `json:"version,omitempty"`  // Version name the server reports, e.g. "Paper 1.21.1"
+	Protocol      int       `json:"protocol,omitempty"` // Protocol number of the version
+	MOTD          string    `json:"motd,omitempty"`     // Description without formatting codes
+	LatencyMS     int64     `json:"latency_ms"`
+	CheckedAt     time.Time `json:"checked_at"`
+	Error         string    `json:"error,omitempty"` // Why the last ping failed
+	// Unreachable is set if the manager can't reach the container network, the server then counts as ready
+	// while its container runs
+	Unreachable bool `json:"unreachable,omitempty"`
+}
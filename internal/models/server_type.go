@@ -0,0 +1,46 @@
+package models
+
+// ServerType is the server software run by the itzg/minecraft-server image (its TYPE variable)
+type ServerType string
+
+const (
+	ServerTypePaper    ServerType = "PAPER"
+	ServerTypePurpur   ServerType = "PURPUR"
+	ServerTypeFabric   ServerType = "FABRIC"
+	ServerTypeForge    ServerType = "FORGE"
+	ServerTypeNeoForge ServerType = "NEOFORGE"
+	ServerTypeVanilla  ServerType = "VANILLA"
+)
+
+// DefaultServerType is used when no type is requested
+const DefaultServerType = ServerTypePaper
+
+// SupportedServerTypes lists all server types that can be selected
+var SupportedServerTypes = []ServerType{
+	ServerTypePaper,
+	ServerTypePurpur,
+	ServerTypeFabric,
+	ServerTypeForge,
+	ServerTypeNeoForge,
+	ServerTypeVanilla,
+}
+
+// IsSupported reports whether the server type can be selected
+func (t ServerType) IsSupported() bool {
+	for _, supported := range SupportedServerTypes {
+		if t == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPaperFamily reports whether the server type reads spigot.yml
+func (t ServerType) IsPaperFamily() bool {
+	return t == ServerTypePaper || t == ServerTypePurpur
+}
+
+// HasModLoader reports whether the server type uses a mod loader with its own version
+func (t ServerType) HasModLoader() bool {
+	return t == ServerTypeFabric || t == ServerTypeForge || t == ServerTypeNeoForge
+}
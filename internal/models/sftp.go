@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+)
+
+// SFTPAccess is the level of access an SFTP account has to a server's files
+type SFTPAccess string
+
+const (
+	SFTPAccessRead  SFTPAccess = "read"
+	SFTPAccessWrite SFTPAccess = "write"
+)
+
+// SFTPAccount is an account of the embedded SFTP server, it only sees the servers it has permissions for
+type SFTPAccount struct {
+	Username     string           `json:"username" gorm:"primaryKey"`
+	PasswordHash string           `json:"-"` // bcrypt hash, empty if the account only logs in with keys
+	Keys         []SFTPKey        `json:"keys" gorm:"foreignKey:Username"`
+	Permissions  []SFTPPermission `json:"permissions" gorm:"foreignKey:Username"`
+	CreatedAt    time.Time        `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time        `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// HasPassword reports whether the account can log in with a password
+func (a *SFTPAccount) HasPassword() bool {
+	return a.PasswordHash != ""
+}
+
+// SFTPKey is an SSH public key an SFTP account can log in with
+type SFTPKey struct {
+	Fingerprint string    `json:"fingerprint" gorm:"primaryKey"` // SHA256 fingerprint like ssh-keygen -l prints it
+	Username    string    `json:"username" gorm:"index;not null"`
+	Type        string    `json:"type"`       // Key algorithm, e.g. ssh-ed25519
+	PublicKey   string    `json:"public_key"` // Key in authorized_keys format, without comment
+	Comment     string    `json:"comment,omitempty"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// SFTPPermission grants an SFTP account access to the files of a server
+type SFTPPermission struct {
+	Username  string     `json:"username" gorm:"primaryKey"`
+	ServerID  string     `json:"server_id" gorm:"primaryKey"`
+	Access    SFTPAccess `json:"access" gorm:"type:varchar(10);not null"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
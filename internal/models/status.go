@@ -4,7 +4,11 @@ type ContainerStatus string
 
 const (
 	StatusCreating ContainerStatus = "creating"
+	StatusStarting ContainerStatus = "starting" // Container runs, but the server doesn't accept logins yet
 	StatusRunning  ContainerStatus = "running"
+	StatusStopping ContainerStatus = "stopping" // Set by the manager during a stop, Docker doesn't report it
 	StatusStopped  ContainerStatus = "stopped"
 	StatusError    ContainerStatus = "error"
+	// StatusCrashLoop is set by the manager when it stopped a server that kept crashing
+	StatusCrashLoop ContainerStatus = "crash_loop"
 )
@@ -0,0 +1,23 @@
+package models
+
+// WorldLayout describes how the worlds in an uploaded archive were laid out
+type WorldLayout string
+
+const (
+	// WorldLayoutServer is a server world with world/, world_nether/ and world_the_end/ folders
+	WorldLayoutServer WorldLayout = "server"
+	// WorldLayoutSingleplayer is a single save folder with level.dat at its root, it becomes world/
+	WorldLayoutSingleplayer WorldLayout = "singleplayer"
+)
+
+// WorldFolders are the folders of the server's volume a world import replaces
+var WorldFolders = []string{"world", "world_nether", "world_the_end"}
+
+// WorldImport is the result of importing a world archive into a server
+type WorldImport struct {
+	Layout    WorldLayout `json:"layout"`
+	Worlds    []string    `json:"worlds"`     // World folders written to the volume
+	Files     int         `json:"files"`      // Number of imported files
+	SizeBytes int64       `json:"size_bytes"` // Uncompressed size of the imported files
+	Skipped   int         `json:"skipped"`    // Archive entries outside of the world folders, e.g. server.properties
+}
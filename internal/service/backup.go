@@ -0,0 +1,447 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/database"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+)
+
+// BackupService archives server volumes to tar.gz files in a backup storage and restores them
+type BackupService struct {
+	dockerService      *DockerService
+	mcService          *MinecraftServerService
+	repo               *database.BackupRepository
+	storages           map[string]BackupStorage
+	defaultDestination string
+	logger             *slog.Logger
+}
+
+// NewBackupService creates a new backup service, backups without a destination are written to defaultStorage
+func NewBackupService(
+	dockerService *DockerService,
+	mcService *MinecraftServerService,
+	repo *database.BackupRepository,
+	defaultStorage BackupStorage,
+	logger *slog.Logger,
+) *BackupService {
+	return &BackupService{
+		dockerService:      dockerService,
+		mcService:          mcService,
+		repo:               repo,
+		storages:           map[string]BackupStorage{defaultStorage.Name(): defaultStorage},
+		defaultDestination: defaultStorage.Name(),
+		logger:             logger,
+	}
+}
+
+// AddStorage makes an additional backup destination available
+func (s *BackupService) AddStorage(storage BackupStorage) {
+	s.storages[storage.Name()] = storage
+}
+
+// ListDestinations returns the configured backup destinations
+func (s *BackupService) ListDestinations() []models.BackupDestination {
+	destinations := make([]models.BackupDestination, 0, len(s.storages))
+	for name, storage := range s.storages {
+		destinations = append(destinations, models.BackupDestination{
+			Name:     name,
+			Location: storage.Location(),
+			Default:  name == s.defaultDestination,
+		})
+	}
+	slices.SortFunc(destinations, func(a, b models.BackupDestination) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return destinations
+}
+
+// storage returns the storage of a destination, the default one if name is empty
+func (s *BackupService) storage(name string) (BackupStorage, error) {
+	if name == "" {
+		name = s.defaultDestination
+	}
+	storage, ok := s.storages[name]
+	if !ok {
+		return nil, validationError("backup destination %q is not configured", name)
+	}
+	return storage, nil
+}
+
+// serverStorage returns the storage a backup of a server is written to: the requested destination,
+// otherwise the one of the server, otherwise the default one
+func (s *BackupService) serverStorage(server *models.MinecraftServer, destination string) (BackupStorage, error) {
+	if destination == "" {
+		destination = server.BackupDestination
+	}
+	return s.storage(destination)
+}
+
+// ValidateCreateBackupRequest checks that the server exists and its backup can be written to the destination,
+// so invalid requests can be rejected before the backup runs in the background
+func (s *BackupService) ValidateCreateBackupRequest(ctx context.Context, serverID string, req *models.CreateBackupRequest) error {
+	server, err := s.mcService.GetServer(ctx, serverID)
+	if err != nil {
+		return err
+	}
+	_, err = s.serverStorage(server, req.Destination)
+	return err
+}
+
+// CreateBackup archives the volume of a server
+func (s *BackupService) CreateBackup(ctx context.Context, serverID string, req *models.CreateBackupRequest) (*models.Backup, error) {
+	return s.createBackup(ctx, serverID, req.Destination, req.SaveWorld, false)
+}
+
+// createBackup archives the volume of a server, scheduled backups are subject to the retention policy
+func (s *BackupService) createBackup(ctx context.Context, serverID, destination string, saveWorld, scheduled bool) (*models.Backup, error) {
+	// Keep the server from being recreated or deleted while its volume is archived
+	unlock, err := s.mcService.locks.LockOrConflict(serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	server, err := s.mcService.repo.FindByID(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	storage, err := s.serverStorage(server, destination)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := s.dockerService.GetContainerState(ctx, server.ContainerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container state: %w", err)
+	}
+
+	worldSaved := false
+	if saveWorld && state.Running {
+		reportStep(ctx, "Saving world", 5)
+		resumeSaving, err := s.mcService.suspendSaving(ctx, server)
+		if err != nil {
+			return nil, err
+		}
+		// Turn saving back on no matter how the backup ends
+		defer resumeSaving()
+		worldSaved = true
+	}
+
+	backupID := uuid.New().String()
+	createdAt := time.Now().UTC()
+	fileName := path.Join(server.ID, fmt.Sprintf("%s-%s.tar.gz", createdAt.Format("20060102-150405"), backupID))
+
+	s.logger.InfoContext(ctx, "Creating backup",
+		"server_id", server.ID,
+		"backup_id", backupID,
+		"destination", storage.Name(),
+		"file", fileName,
+		"world_saved", worldSaved,
+		"scheduled", scheduled)
+
+	reportStep(ctx, "Archiving volume", 20)
+	size, checksum, err := s.archiveVolume(ctx, server.VolumeID, storage, fileName)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to archive volume",
+			"server_id", server.ID,
+			"backup_id", backupID,
+			"error", err)
+		return nil, err
+	}
+
+	reportStep(ctx, "Saving backup", 95)
+	backup := &models.Backup{
+		ID:               backupID,
+		ServerID:         server.ID,
+		Destination:      storage.Name(),
+		FileName:         fileName,
+		SizeBytes:        size,
+		Checksum:         checksum,
+		MinecraftVersion: server.Version,
+		Type:             server.Type,
+		WorldSaved:       worldSaved,
+		Scheduled:        scheduled,
+		CreatedAt:        createdAt,
+	}
+	if err := s.repo.Create(backup); err != nil {
+		if err := storage.Delete(context.WithoutCancel(ctx), fileName); err != nil {
+			s.logger.WarnContext(ctx, "Failed to delete archive of unsaved backup", "backup_id", backupID, "error", err)
+		}
+		return nil, fmt.Errorf("failed to save backup to database: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "Backup created successfully",
+		"server_id", server.ID,
+		"backup_id", backupID,
+		"size_bytes", size)
+
+	return backup, nil
+}
+
+// archiveVolume streams the volume as tar.gz into the storage and returns the size and checksum of the archive
+func (s *BackupService) archiveVolume(ctx context.Context, volumeName string, storage BackupStorage, key string) (int64, string, error) {
+	export, err := s.dockerService.ExportVolume(ctx, volumeName)
+	if err != nil {
+		return 0, "", err
+	}
+	defer export.Close()
+
+	// Compress while the storage reads, so the archive is never held in memory or staged on disk
+	hash := sha256.New()
+	counter := &countingWriter{}
+	pr, pw := io.Pipe()
+	go func() {
+		gz := gzip.NewWriter(io.MultiWriter(pw, hash, counter))
+		_, err := io.Copy(gz, export)
+		if err == nil {
+			err = gz.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	if err := storage.Put(ctx, key, pr); err != nil {
+		pr.CloseWithError(err)
+		return 0, "", err
+	}
+
+	return counter.n, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// ListBackups returns the backups of a server, newest first
+func (s *BackupService) ListBackups(ctx context.Context, serverID string) ([]*models.Backup, error) {
+	if _, err := s.mcService.repo.FindByID(serverID); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByServerID(serverID)
+}
+
+// GetBackup returns a backup of a server
+func (s *BackupService) GetBackup(ctx context.Context, serverID, backupID string) (*models.Backup, error) {
+	backup, err := s.repo.FindByID(backupID)
+	if err != nil {
+		return nil, err
+	}
+	if backup.ServerID != serverID {
+		return nil, database.ErrBackupNotFound
+	}
+	return backup, nil
+}
+
+// OpenBackup opens the archive of a backup for reading. The caller must close the archive.
+func (s *BackupService) OpenBackup(ctx context.Context, serverID, backupID string) (*models.Backup, io.ReadSeekCloser, error) {
+	backup, err := s.GetBackup(ctx, serverID, backupID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	storage, err := s.storage(backup.Destination)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	archive, err := storage.Open(ctx, backup.FileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return backup, archive, nil
+}
+
+// RestoreBackup replaces the volume content of a stopped server with a backup
+func (s *BackupService) RestoreBackup(ctx context.Context, serverID, backupID string) error {
+	unlock, err := s.mcService.locks.LockOrConflict(serverID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	server, err := s.mcService.repo.FindByID(serverID)
+	if err != nil {
+		return err
+	}
+
+	state, err := s.dockerService.GetContainerState(ctx, server.ContainerID)
+	if err != nil {
+		return fmt.Errorf("failed to get container state: %w", err)
+	}
+	if state.Running || state.Restarting {
+		return conflictError("server must be stopped to restore a backup")
+	}
+
+	backup, archive, err := s.OpenBackup(ctx, serverID, backupID)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	s.logger.InfoContext(ctx, "Restoring backup",
+		"server_id", server.ID,
+		"backup_id", backup.ID,
+		"destination", backup.Destination)
+
+	// The checksum is verified while the archive is extracted to the staging directory,
+	// a mismatch fails the extraction before the current content is replaced
+	reportStep(ctx, "Restoring volume", 10)
+	gz, err := gzip.NewReader(&checksumReader{r: archive, hash: sha256.New(), expected: backup.Checksum})
+	if err != nil {
+		return fmt.Errorf("failed to read backup archive: %w", err)
+	}
+	defer gz.Close()
+
+	if err := s.dockerService.RestoreVolume(ctx, server.VolumeID, gz); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to restore backup",
+			"server_id", server.ID,
+			"backup_id", backup.ID,
+			"error", err)
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "Backup restored successfully",
+		"server_id", server.ID,
+		"backup_id", backup.ID)
+
+	return nil
+}
+
+// checksumReader fails at EOF if the SHA-256 of everything read doesn't match the expected hex encoded checksum
+type checksumReader struct {
+	r        io.Reader
+	hash     hash.Hash
+	expected string
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.hash.Write(p[:n])
+	if errors.Is(err, io.EOF) {
+		if actual := hex.EncodeToString(c.hash.Sum(nil)); actual != c.expected {
+			return n, fmt.Errorf("backup archive is corrupted: checksum %s does not match %s", actual, c.expected)
+		}
+	}
+	return n, err
+}
+
+// DeleteBackup removes a backup and its archive
+func (s *BackupService) DeleteBackup(ctx context.Context, serverID, backupID string) error {
+	backup, err := s.GetBackup(ctx, serverID, backupID)
+	if err != nil {
+		return err
+	}
+
+	storage, err := s.storage(backup.Destination)
+	if err != nil {
+		return err
+	}
+	if err := storage.Delete(ctx, backup.FileName); err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "Backup deleted", "server_id", serverID, "backup_id", backupID)
+	return s.repo.Delete(backupID)
+}
+
+// pruneBackups deletes the scheduled backups of a server that the retention policy doesn't keep
+func (s *BackupService) pruneBackups(ctx context.Context, serverID string, policy models.RetentionPolicy) error {
+	backups, err := s.repo.FindByServerID(serverID)
+	if err != nil {
+		return err
+	}
+
+	scheduled := make([]*models.Backup, 0, len(backups))
+	for _, backup := range backups {
+		if backup.Scheduled {
+			scheduled = append(scheduled, backup)
+		}
+	}
+
+	var errs []error
+	for _, backup := range expiredBackups(scheduled, policy, time.Now()) {
+		s.logger.InfoContext(ctx, "Pruning backup",
+			"server_id", serverID,
+			"backup_id", backup.ID,
+			"created_at", backup.CreatedAt)
+		if err := s.DeleteBackup(ctx, serverID, backup.ID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to prune backup %s: %w", backup.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// expiredBackups returns the backups the retention policy doesn't keep. backups must be sorted newest first.
+func expiredBackups(backups []*models.Backup, policy models.RetentionPolicy, now time.Time) []*models.Backup {
+	if policy.IsZero() {
+		return nil
+	}
+
+	keep := make(map[string]bool, len(backups))
+	for i, backup := range backups {
+		if i < policy.Last {
+			keep[backup.ID] = true
+		}
+	}
+
+	// Day and week boundaries follow the manager's local time
+	now = now.In(time.Local)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	if policy.Daily > 0 {
+		cutoff := today.AddDate(0, 0, -(policy.Daily - 1))
+		days := make(map[string]bool)
+		for _, backup := range backups {
+			createdAt := backup.CreatedAt.In(time.Local)
+			day := createdAt.Format("2006-01-02")
+			if createdAt.Before(cutoff) || days[day] {
+				continue
+			}
+			days[day] = true
+			keep[backup.ID] = true
+		}
+	}
+
+	if policy.Weekly > 0 {
+		cutoff := today.AddDate(0, 0, -7*policy.Weekly)
+		weeks := make(map[string]bool)
+		for _, backup := range backups {
+			createdAt := backup.CreatedAt.In(time.Local)
+			year, week := createdAt.ISOWeek()
+			key := fmt.Sprintf("%d-%d", year, week)
+			if createdAt.Before(cutoff) || weeks[key] {
+				continue
+			}
+			weeks[key] = true
+			keep[backup.ID] = true
+		}
+	}
+
+	var expired []*models.Backup
+	for _, backup := range backups {
+		if !keep[backup.ID] {
+			expired = append(expired, backup)
+		}
+	}
+	return expired
+}
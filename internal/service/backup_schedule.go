@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/database"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"github.com/robfig/cron/v3"
+)
+
+// scheduleCheckInterval is how often due schedules are looked up, cron has a resolution of one minute
+const scheduleCheckInterval = 15 * time.Second
+
+// cronParser parses standard 5 field cron expressions, descriptors like @daily and CRON_TZ= prefixes
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// BackupScheduler manages backup schedules and runs them from the serve process.
+// Schedules are read from the database on every check, so changes made by the CLI are picked up as well.
+type BackupScheduler struct {
+	backupService *BackupService
+	repo          *database.BackupScheduleRepository
+	mu            sync.Mutex
+	running       map[string]bool // Servers with a scheduled backup in progress
+	logger        *slog.Logger
+}
+
+// NewBackupScheduler creates a new backup scheduler
+func NewBackupScheduler(backupService *BackupService, repo *database.BackupScheduleRepository, logger *slog.Logger) *BackupScheduler {
+	return &BackupScheduler{
+		backupService: backupService,
+		repo:          repo,
+		running:       make(map[string]bool),
+		logger:        logger,
+	}
+}
+
+// GetSchedule returns the backup schedule of a server
+func (s *BackupScheduler) GetSchedule(ctx context.Context, serverID string) (*models.BackupSchedule, error) {
+	return s.repo.FindByServerID(serverID)
+}
+
+// SetSchedule creates or replaces the backup schedule of a server
+func (s *BackupScheduler) SetSchedule(ctx context.Context, serverID string, req *models.BackupScheduleRequest) (*models.BackupSchedule, error) {
+	schedule, err := cronParser.Parse(req.Cron)
+	if err != nil {
+		return nil, validationError("invalid cron expression: %v", err)
+	}
+	if _, err := s.backupService.storage(req.Destination); err != nil {
+		return nil, err
+	}
+	if req.Retention.Last < 0 || req.Retention.Daily < 0 || req.Retention.Weekly < 0 {
+		return nil, validationError("retention values must not be negative")
+	}
+
+	if _, err := s.backupService.mcService.repo.FindByID(serverID); err != nil {
+		return nil, err
+	}
+
+	// Keep the outcome of previous runs when the schedule is changed
+	backupSchedule, err := s.repo.FindByServerID(serverID)
+	if err != nil {
+		if !errors.Is(err, database.ErrBackupScheduleNotFound) {
+			return nil, err
+		}
+		backupSchedule = &models.BackupSchedule{ServerID: serverID}
+	}
+
+	backupSchedule.Cron = req.Cron
+	backupSchedule.Enabled = req.Enabled == nil || *req.Enabled
+	backupSchedule.SaveWorld = req.SaveWorld
+	backupSchedule.Destination = req.Destination
+	backupSchedule.Retention = req.Retention
+	nextRunAt := schedule.Next(time.Now())
+	backupSchedule.NextRunAt = &nextRunAt
+
+	if err := s.repo.Save(backupSchedule); err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "Backup schedule set",
+		"server_id", serverID,
+		"cron", req.Cron,
+		"enabled", backupSchedule.Enabled,
+		"next_run_at", nextRunAt)
+
+	return backupSchedule, nil
+}
+
+// DeleteSchedule removes the backup schedule of a server. Existing backups are kept.
+func (s *BackupScheduler) DeleteSchedule(ctx context.Context, serverID string) error {
+	if err := s.repo.Delete(serverID); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "Backup schedule deleted", "server_id", serverID)
+	return nil
+}
+
+// Run starts due backups until ctx is cancelled.
+// Runs that were missed while the manager was down are started once on startup.
+func (s *BackupScheduler) Run(ctx context.Context) {
+	s.logger.InfoContext(ctx, "Starting backup scheduler")
+
+	ticker := time.NewTicker(scheduleCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		s.startDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			s.logger.InfoContext(ctx, "Stopping backup scheduler")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// startDue starts the backups of all due schedules that are not running yet
+func (s *BackupScheduler) startDue(ctx context.Context) {
+	now := time.Now()
+	schedules, err := s.repo.FindDue(now)
+	if err != nil {
+		s.logger.WarnContext(ctx, "Failed to look up due backup schedules", "error", err)
+		return
+	}
+
+	for _, schedule := range schedules {
+		// Move the schedule forward before running, so a long backup isn't started twice
+		cronSchedule, err := cronParser.Parse(schedule.Cron)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "Invalid cron expression in backup schedule",
+				"server_id", schedule.ServerID,
+				"cron", schedule.Cron,
+				"error", err)
+			continue
+		}
+		if err := s.repo.UpdateNextRun(schedule.ServerID, cronSchedule.Next(now)); err != nil {
+			continue
+		}
+
+		s.mu.Lock()
+		if s.running[schedule.ServerID] {
+			s.mu.Unlock()
+			s.logger.WarnContext(ctx, "Previous scheduled backup still running, skipping this run",
+				"server_id", schedule.ServerID)
+			continue
+		}
+		s.running[schedule.ServerID] = true
+		s.mu.Unlock()
+
+		go func(schedule *models.BackupSchedule) {
+			defer func() {
+				s.mu.Lock()
+				delete(s.running, schedule.ServerID)
+				s.mu.Unlock()
+			}()
+			s.runSchedule(ctx, schedule)
+		}(schedule)
+	}
+}
+
+// runSchedule creates a backup, prunes old ones and records the outcome on the schedule
+func (s *BackupScheduler) runSchedule(ctx context.Context, schedule *models.BackupSchedule) {
+	s.logger.InfoContext(ctx, "Running scheduled backup", "server_id", schedule.ServerID)
+
+	// A backup that started should finish even if the manager shuts down
+	runCtx := context.WithoutCancel(ctx)
+
+	backup, err := s.backupService.createBackup(runCtx, schedule.ServerID, schedule.Destination, schedule.SaveWorld, true)
+	if errors.Is(err, database.ErrServerNotFound) {
+		s.logger.WarnContext(ctx, "Server of backup schedule no longer exists, deleting schedule",
+			"server_id", schedule.ServerID)
+		if err := s.repo.Delete(schedule.ServerID); err != nil && !errors.Is(err, database.ErrBackupScheduleNotFound) {
+			s.logger.ErrorContext(ctx, "Failed to delete backup schedule", "server_id", schedule.ServerID, "error", err)
+		}
+		return
+	}
+	if err == nil {
+		if pruneErr := s.backupService.pruneBackups(runCtx, schedule.ServerID, schedule.Retention); pruneErr != nil {
+			err = pruneErr
+		}
+	}
+
+	now := time.Now()
+	schedule.LastRunAt = &now
+	if err != nil {
+		schedule.LastStatus = models.ScheduleRunFailed
+		schedule.LastError = err.Error()
+		schedule.ConsecutiveFailures++
+		s.logger.ErrorContext(ctx, "Scheduled backup failed",
+			"server_id", schedule.ServerID,
+			"consecutive_failures", schedule.ConsecutiveFailures,
+			"error", err)
+	} else {
+		schedule.LastStatus = models.ScheduleRunSucceeded
+		schedule.LastError = ""
+		schedule.ConsecutiveFailures = 0
+		s.logger.InfoContext(ctx, "Scheduled backup succeeded",
+			"server_id", schedule.ServerID,
+			"backup_id", backup.ID)
+	}
+
+	if err := s.repo.UpdateLastRun(schedule); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to record scheduled backup run", "server_id", schedule.ServerID, "error", err)
+	}
+}
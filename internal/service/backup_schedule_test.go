@@ -0,0 +1,68 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiredBackups(t *testing.T) {
+	// Friday, the ISO week started on Monday the 12th
+	now := time.Date(2026, time.October, 16, 18, 0, 0, 0, time.Local)
+	at := func(day, hour int) time.Time {
+		return time.Date(2026, time.October, day, hour, 0, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name    string
+		policy  models.RetentionPolicy
+		created []time.Time // Newest first
+		expired []int       // Indexes into created
+	}{
+		{
+			name:    "zero policy keeps everything",
+			created: []time.Time{at(16, 12), at(15, 12), at(1, 12)},
+		},
+		{
+			name:    "last",
+			policy:  models.RetentionPolicy{Last: 2},
+			created: []time.Time{at(16, 12), at(16, 6), at(15, 12), at(14, 12)},
+			expired: []int{2, 3},
+		},
+		{
+			name:    "daily keeps the newest backup of each day",
+			policy:  models.RetentionPolicy{Daily: 2},
+			created: []time.Time{at(16, 12), at(16, 6), at(15, 20), at(15, 8), at(14, 12)},
+			expired: []int{1, 3, 4},
+		},
+		{
+			name:    "weekly keeps the newest backup of each week",
+			policy:  models.RetentionPolicy{Weekly: 2},
+			created: []time.Time{at(16, 12), at(13, 12), at(8, 12), at(6, 12), at(1, 12)},
+			expired: []int{1, 3, 4},
+		},
+		{
+			name:    "a backup is kept if any rule keeps it",
+			policy:  models.RetentionPolicy{Last: 1, Daily: 2},
+			created: []time.Time{at(16, 12), at(16, 6), at(15, 12), at(14, 12)},
+			expired: []int{1, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backups := make([]*models.Backup, len(tt.created))
+			for i, createdAt := range tt.created {
+				backups[i] = &models.Backup{ID: createdAt.Format(time.RFC3339), CreatedAt: createdAt}
+			}
+
+			var want []*models.Backup
+			for _, i := range tt.expired {
+				want = append(want, backups[i])
+			}
+			assert.Equal(t, want, expiredBackups(backups, tt.policy, now))
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+const (
+	// LocalDestination is the name of the backup destination on the manager's disk
+	LocalDestination = "local"
+	// S3Destination is the name of the backup destination in an S3-compatible bucket
+	S3Destination = "s3"
+)
+
+// BackupStorage stores backup archives under keys like "<server-id>/<file>.tar.gz"
+type BackupStorage interface {
+	// Name identifies the destination in requests and on stored backups
+	Name() string
+	// Location describes where archives are stored, e.g. a directory or bucket URL
+	Location() string
+	// Put stores the content of r under key. A failed Put must not leave a partial archive behind.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open returns the archive stored under key. The caller must close it.
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	// Delete removes the archive stored under key, a missing archive is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// LocalBackupStorage stores backup archives in a directory
+type LocalBackupStorage struct {
+	dir string
+}
+
+// NewLocalBackupStorage creates a backup storage writing to dir
+func NewLocalBackupStorage(dir string) *LocalBackupStorage {
+	return &LocalBackupStorage{dir: dir}
+}
+
+func (s *LocalBackupStorage) Name() string {
+	return LocalDestination
+}
+
+func (s *LocalBackupStorage) Location() string {
+	return s.dir
+}
+
+func (s *LocalBackupStorage) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(key))
+}
+
+func (s *LocalBackupStorage) Put(ctx context.Context, key string, r io.Reader) error {
+	target := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	// Write to a temporary file first, so a failed backup never looks like a complete archive
+	tmpPath := target + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer os.Remove(tmpPath)
+	defer file.Close()
+
+	if _, err := io.Copy(file, r); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := os.Rename(tmpPath, target); err != nil {
+		return fmt.Errorf("failed to move archive into place: %w", err)
+	}
+	return nil
+}
+
+func (s *LocalBackupStorage) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	file, err := os.Open(s.path(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup archive: %w", err)
+	}
+	return file, nil
+}
+
+func (s *LocalBackupStorage) Delete(ctx context.Context, key string) error {
+	if err := os.Remove(s.path(key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete backup archive: %w", err)
+	}
+	return nil
+}
+
+// S3Config configures an S3-compatible backup destination like AWS S3 or MinIO
+type S3Config struct {
+	Endpoint        string // Host and optional port, e.g. s3.eu-central-1.amazonaws.com or localhost:9000
+	Bucket          string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	Prefix          string // Prepended to all keys, e.g. "dockermc/"
+	UseSSL          bool
+	PartSizeMB      int // Size of the parts of multipart uploads
+}
+
+// S3BackupStorage stores backup archives in an S3-compatible bucket.
+// Archives are uploaded in parts while they are created, so they never have to fit in memory or on disk.
+type S3BackupStorage struct {
+	client   *minio.Client
+	bucket   string
+	prefix   string
+	partSize uint64
+}
+
+// NewS3BackupStorage creates a backup storage writing to an S3-compatible bucket
+func NewS3BackupStorage(cfg S3Config) (*S3BackupStorage, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("S3 endpoint and bucket are required")
+	}
+	// S3 requires parts of at least 5 MiB, except for the last one
+	if cfg.PartSizeMB < 5 {
+		return nil, fmt.Errorf("S3 part size must be at least 5 MB")
+	}
+
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+
+	return &S3BackupStorage{
+		client:   client,
+		bucket:   cfg.Bucket,
+		prefix:   strings.TrimPrefix(cfg.Prefix, "/"),
+		partSize: uint64(cfg.PartSizeMB) * 1024 * 1024,
+	}, nil
+}
+
+func (s *S3BackupStorage) Name() string {
+	return S3Destination
+}
+
+func (s *S3BackupStorage) Location() string {
+	return fmt.Sprintf("s3://%s/%s", s.bucket, s.prefix)
+}
+
+func (s *S3BackupStorage) objectName(key string) string {
+	return path.Join(s.prefix, key)
+}
+
+func (s *S3BackupStorage) Put(ctx context.Context, key string, r io.Reader) error {
+	// With an unknown size the archive is uploaded in parts of partSize,
+	// a failed upload is aborted so no partial object is left behind
+	_, err := s.client.PutObject(ctx, s.bucket, s.objectName(key), r, -1, minio.PutObjectOptions{
+		ContentType: "application/gzip",
+		PartSize:    s.partSize,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload archive to s3://%s/%s: %w", s.bucket, s.objectName(key), err)
+	}
+	return nil
+}
+
+func (s *S3BackupStorage) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, s.objectName(key), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup archive: %w", err)
+	}
+	// GetObject doesn't send a request yet, check that the archive exists before it is streamed
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, fmt.Errorf("failed to open backup archive: %w", os.ErrNotExist)
+		}
+		return nil, fmt.Errorf("failed to open backup archive: %w", err)
+	}
+	return object, nil
+}
+
+func (s *S3BackupStorage) Delete(ctx context.Context, key string) error {
+	err := s.client.RemoveObject(ctx, s.bucket, s.objectName(key), minio.RemoveObjectOptions{})
+	if err != nil && minio.ToErrorResponse(err).Code != "NoSuchKey" {
+		return fmt.Errorf("failed to delete backup archive: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingReader returns an error once n bytes were read, like an archive whose creation failed
+type failingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	if f.n <= 0 {
+		return 0, errors.New("archive creation failed")
+	}
+	if int64(len(p)) > f.n {
+		p = p[:f.n]
+	}
+	n, err := f.r.Read(p)
+	f.n -= int64(n)
+	return n, err
+}
+
+// testBackupStorage puts, opens and deletes an archive of size bytes in storage
+func testBackupStorage(t *testing.T, storage BackupStorage, size int64) {
+	ctx := context.Background()
+	key := "test-" + uuid.New().String() + "/backup.tar.gz"
+
+	content := make([]byte, size)
+	_, err := rand.Read(content)
+	require.NoError(t, err)
+
+	// The size is unknown to the storage, like for an archive that is created while it is stored
+	require.NoError(t, storage.Put(ctx, key, io.MultiReader(bytes.NewReader(content))))
+	t.Cleanup(func() {
+		storage.Delete(context.Background(), key)
+	})
+
+	archive, err := storage.Open(ctx, key)
+	require.NoError(t, err)
+	stored, err := io.ReadAll(archive)
+	require.NoError(t, err)
+	assert.Equal(t, sha256.Sum256(content), sha256.Sum256(stored))
+
+	// Downloads seek to serve ranges
+	_, err = archive.Seek(size-10, io.SeekStart)
+	require.NoError(t, err)
+	tail, err := io.ReadAll(archive)
+	require.NoError(t, err)
+	assert.Equal(t, content[size-10:], tail)
+	require.NoError(t, archive.Close())
+
+	require.NoError(t, storage.Delete(ctx, key))
+	_, err = storage.Open(ctx, key)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// Deleting a missing archive is not an error
+	assert.NoError(t, storage.Delete(ctx, key))
+
+	// A failed upload leaves nothing behind
+	failedKey := "test-" + uuid.New().String() + "/failed.tar.gz"
+	err = storage.Put(ctx, failedKey, &failingReader{r: rand.Reader, n: size / 2})
+	require.Error(t, err)
+	_, err = storage.Open(ctx, failedKey)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestLocalBackupStorage(t *testing.T) {
+	testBackupStorage(t, NewLocalBackupStorage(t.TempDir()), 1<<20)
+}
+
+// TestS3BackupStorage runs against an S3-compatible endpoint like a local MinIO, e.g.
+//
+//	docker run -d -p 9000:9000 minio/minio server /data
+//	BACKUP_S3_TEST_ENDPOINT=localhost:9000 go test ./internal/service -run S3
+//
+// The credentials default to the ones of MinIO and the bucket is created if it doesn't exist.
+func TestS3BackupStorage(t *testing.T) {
+	endpoint := os.Getenv("BACKUP_S3_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("BACKUP_S3_TEST_ENDPOINT is not set")
+	}
+	getenv := func(key, fallback string) string {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+		return fallback
+	}
+
+	cfg := S3Config{
+		Endpoint:        endpoint,
+		Bucket:          getenv("BACKUP_S3_TEST_BUCKET", "dockermc-backups-test"),
+		Region:          getenv("BACKUP_S3_TEST_REGION", "us-east-1"),
+		AccessKeyID:     getenv("BACKUP_S3_TEST_ACCESS_KEY_ID", "minioadmin"),
+		SecretAccessKey: getenv("BACKUP_S3_TEST_SECRET_ACCESS_KEY", "minioadmin"),
+		Prefix:          "dockermc/",
+		UseSSL:          os.Getenv("BACKUP_S3_TEST_USE_SSL") == "true",
+		PartSizeMB:      5,
+	}
+	storage, err := NewS3BackupStorage(cfg)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	exists, err := storage.client.BucketExists(ctx, cfg.Bucket)
+	require.NoError(t, err)
+	if !exists {
+		require.NoError(t, storage.client.MakeBucket(ctx, cfg.Bucket, minio.MakeBucketOptions{Region: cfg.Region}))
+	}
+
+	t.Run("single part", func(t *testing.T) {
+		testBackupStorage(t, storage, 1<<20)
+	})
+	t.Run("multipart", func(t *testing.T) {
+		// More than two parts of PartSizeMB
+		testBackupStorage(t, storage, int64(2*cfg.PartSizeMB+1)<<20)
+	})
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"testing"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gzipArchive compresses content and returns the archive with its checksum
+func gzipArchive(t *testing.T, content string) ([]byte, string) {
+	t.Helper()
+
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	_, err := gz.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	sum := sha256.Sum256(archive.Bytes())
+	return archive.Bytes(), hex.EncodeToString(sum[:])
+}
+
+func TestChecksumReader(t *testing.T) {
+	archive, checksum := gzipArchive(t, "level-name=world\n")
+
+	// Restoring reads the archive through the checksum reader like this
+	gz, err := gzip.NewReader(&checksumReader{r: bytes.NewReader(archive), hash: sha256.New(), expected: checksum})
+	require.NoError(t, err)
+	content, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, "level-name=world\n", string(content))
+}
+
+func TestChecksumReaderMismatch(t *testing.T) {
+	archive, _ := gzipArchive(t, "level-name=world\n")
+	_, otherChecksum := gzipArchive(t, "level-name=other\n")
+
+	gz, err := gzip.NewReader(&checksumReader{r: bytes.NewReader(archive), hash: sha256.New(), expected: otherChecksum})
+	require.NoError(t, err)
+	_, err = io.ReadAll(gz)
+	assert.ErrorContains(t, err, "backup archive is corrupted")
+}
+
+// namedBackupStorage is a local storage that is offered under another destination name
+type namedBackupStorage struct {
+	*LocalBackupStorage
+	name string
+}
+
+func (s namedBackupStorage) Name() string {
+	return s.name
+}
+
+func TestServerStorage(t *testing.T) {
+	backupService := NewBackupService(nil, nil, nil, NewLocalBackupStorage(t.TempDir()), nil)
+	backupService.AddStorage(namedBackupStorage{LocalBackupStorage: NewLocalBackupStorage(t.TempDir()), name: S3Destination})
+
+	tests := []struct {
+		name              string
+		serverDestination string
+		requested         string
+		want              string
+	}{
+		{name: "default destination", want: LocalDestination},
+		{name: "destination of the server", serverDestination: S3Destination, want: S3Destination},
+		{name: "requested destination wins", serverDestination: S3Destination, requested: LocalDestination, want: LocalDestination},
+		{name: "requested destination without server destination", requested: S3Destination, want: S3Destination},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &models.MinecraftServer{ID: "server", BackupDestination: tt.serverDestination}
+			storage, err := backupService.serverStorage(server, tt.requested)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, storage.Name())
+		})
+	}
+
+	// A destination that was removed from the configuration fails instead of silently using another one
+	_, err := backupService.serverStorage(&models.MinecraftServer{ID: "server", BackupDestination: "gone"}, "")
+	assert.ErrorIs(t, err, ErrValidation)
+}
+
+func TestValidateBackupDestination(t *testing.T) {
+	mcService := &MinecraftServerService{}
+	assert.NoError(t, mcService.validateBackupDestination("anything"), "any destination without configured ones")
+
+	mcService.SetBackupDestinations([]string{LocalDestination})
+	assert.NoError(t, mcService.validateBackupDestination(""))
+	assert.NoError(t, mcService.validateBackupDestination(LocalDestination))
+	assert.ErrorIs(t, mcService.validateBackupDestination(S3Destination), ErrValidation)
+
+	destination := S3Destination
+	assert.ErrorIs(t, mcService.ValidateUpdateServerRequest(&models.UpdateServerRequest{BackupDestination: &destination}), ErrValidation)
+	assert.ErrorIs(t, mcService.ValidateCreateServerRequest(&models.CreateServerRequest{Name: "lobby", BackupDestination: destination}), ErrValidation)
+}
@@ -0,0 +1,413 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/google/uuid"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/database"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+)
+
+// Defaults of the crash policy, used for the fields of a server's policy that are 0
+const (
+	defaultMaxCrashes      = 5
+	defaultCrashWindow     = 10 * time.Minute
+	defaultCrashBackoff    = 10 * time.Second
+	defaultMaxCrashBackoff = 5 * time.Minute
+)
+
+const (
+	crashLogLines       = "200"            // Lines of the container log kept in a crash report
+	crashReportsDir     = "/crash-reports" // Where Minecraft writes its crash reports inside the volume
+	maxCrashReportBytes = 256 << 10
+	crashReportsKept    = 10 // Crash reports kept per server, older ones are deleted
+)
+
+// SetCrashReportRepository sets the repository crash reports are stored in
+func (s *MinecraftServerService) SetCrashReportRepository(crashReports *database.CrashReportRepository) {
+	s.crashReports = crashReports
+}
+
+// CrashReports returns the crash reports of a server, newest first
+func (s *MinecraftServerService) CrashReports(ctx context.Context, serverID string) ([]*models.CrashReport, error) {
+	if _, err := s.repo.FindByID(serverID); err != nil {
+		return nil, err
+	}
+	return s.crashReports.FindByServerID(serverID)
+}
+
+// GetCrashReport returns a crash report of a server
+func (s *MinecraftServerService) GetCrashReport(ctx context.Context, serverID, reportID string) (*models.CrashReport, error) {
+	report, err := s.crashReports.FindByID(reportID)
+	if err != nil {
+		return nil, err
+	}
+	if report.ServerID != serverID {
+		return nil, database.ErrCrashReportNotFound
+	}
+	return report, nil
+}
+
+// crashPolicy returns the crash policy of a server with the defaults filled in
+func crashPolicy(server *models.MinecraftServer) models.CrashPolicy {
+	policy := server.CrashPolicy
+	if policy.MaxCrashes == 0 {
+		policy.MaxCrashes = defaultMaxCrashes
+	}
+	if policy.WindowSeconds == 0 {
+		policy.WindowSeconds = int(defaultCrashWindow.Seconds())
+	}
+	if policy.BackoffSeconds == 0 {
+		policy.BackoffSeconds = int(defaultCrashBackoff.Seconds())
+	}
+	if policy.MaxBackoffSeconds == 0 {
+		policy.MaxBackoffSeconds = int(defaultMaxCrashBackoff.Seconds())
+	}
+	return policy
+}
+
+// validateCrashPolicy checks the values of a crash policy
+func validateCrashPolicy(policy *models.CrashPolicy) error {
+	if policy == nil {
+		return nil
+	}
+	if policy.MaxCrashes < 0 || policy.MaxCrashes > 100 {
+		return validationError("crash_policy max_crashes must be between 0 and 100")
+	}
+	for name, seconds := range map[string]int{
+		"window_seconds":      policy.WindowSeconds,
+		"backoff_seconds":     policy.BackoffSeconds,
+		"max_backoff_seconds": policy.MaxBackoffSeconds,
+	} {
+		if seconds < 0 || seconds > 86400 {
+			return validationError("crash_policy %s must be between 0 and 86400", name)
+		}
+	}
+	effective := crashPolicy(&models.MinecraftServer{CrashPolicy: *policy})
+	if effective.BackoffSeconds > effective.MaxBackoffSeconds {
+		return validationError("crash_policy backoff_seconds must not be greater than max_backoff_seconds")
+	}
+	return nil
+}
+
+// crashBackoff returns how long a server waits before it is started after its nth crash in the window
+func crashBackoff(policy models.CrashPolicy, crashes int) time.Duration {
+	backoff := time.Duration(policy.BackoffSeconds) * time.Second
+	maxBackoff := time.Duration(policy.MaxBackoffSeconds) * time.Second
+	for i := 1; i < crashes && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxBackoff)
+}
+
+// inCrashLoop reports whether the manager stopped a server because it crashed too often
+func inCrashLoop(server *models.MinecraftServer) bool {
+	return server.Crashes >= crashPolicy(server).MaxCrashes
+}
+
+// serverStatus maps the container state of a server to its status. A stopped container keeps the status
+// the crash handling gave it, since the manager stopped it itself.
+func serverStatus(server *models.MinecraftServer, state *ContainerState) models.ContainerStatus {
+	if state.Exists && !state.Running && !state.Restarting {
+		switch {
+		case inCrashLoop(server):
+			return models.StatusCrashLoop
+		case server.NextRestartAt != nil:
+			// Waiting for the backoff, like Docker's restarting state
+			return models.StatusCreating
+		}
+	}
+	return statusFromContainerState(state)
+}
+
+// handleCrashes counts the restarts Docker made after a failed run since the last reconciliation as crashes
+// and applies the crash policy of the server. Docker restarts a crashed container right away, so the manager
+// stops it and starts it again once the backoff is over. After too many crashes within the window, the container
+// stays stopped and a crash report is captured. Stopping and capturing the report run in the background, so
+// they don't hold up the reconciliation of other servers. It returns the container state after the actions
+// and whether the crash state of the server changed. The caller must hold the server lock.
+func (s *MinecraftServerService) handleCrashes(ctx context.Context, server *models.MinecraftServer, state *ContainerState) (*ContainerState, bool) {
+	if !state.Exists {
+		return state, false
+	}
+
+	// The container counts as stopped while the manager stops it after a crash
+	if s.crashStopInProgress(server.ID) {
+		state = stoppedState(state)
+	}
+
+	policy := crashPolicy(server)
+	changed := false
+	acted := false
+
+	// Docker resets the restart count whenever the container is started through the API
+	if state.RestartCount < server.RestartCount {
+		server.RestartCount = state.RestartCount
+		changed = true
+	}
+
+	// A server in crash loop that runs again was started outside the manager
+	if inCrashLoop(server) && state.Running {
+		server.Crashes = 0
+		server.CrashWindowStart = nil
+		changed = true
+	}
+
+	if restarts := state.RestartCount - server.RestartCount; restarts > 0 {
+		server.RestartCount = state.RestartCount
+		changed = true
+
+		// Docker also restarts a server that exited cleanly, e.g. after /stop in game. That is no crash.
+		if state.ExitCode == 0 && !state.OOMKilled {
+			s.logger.InfoContext(ctx, "Server restarted after a clean exit",
+				"server_id", server.ID,
+				"server_name", server.Name)
+		} else {
+			s.countCrashes(ctx, server, state, policy, restarts)
+			state = stoppedState(state)
+		}
+	}
+
+	// Start a crashed server again once its backoff is over. It is started once its container stopped
+	// if that is still in progress.
+	if server.NextRestartAt != nil && !time.Now().Before(*server.NextRestartAt) && !s.crashStopInProgress(server.ID) {
+		server.NextRestartAt = nil
+		changed = true
+		if !state.Running {
+			s.logger.InfoContext(ctx, "Starting crashed server after backoff", "server_id", server.ID)
+			if err := s.dockerService.client.ContainerStart(ctx, server.ContainerID, container.StartOptions{}); err != nil {
+				s.logger.ErrorContext(ctx, "Failed to start crashed server",
+					"server_id", server.ID,
+					"error", err)
+			}
+			acted = true
+		}
+	}
+
+	if acted {
+		newState, err := s.dockerService.GetContainerState(ctx, server.ContainerID)
+		if err != nil {
+			s.logger.WarnContext(ctx, "Failed to get container state after crash handling",
+				"server_id", server.ID,
+				"error", err)
+			return state, changed
+		}
+		return newState, changed
+	}
+	return state, changed
+}
+
+// stoppedState returns a copy of a container state as if the container was stopped
+func stoppedState(state *ContainerState) *ContainerState {
+	stopped := *state
+	stopped.Running = false
+	stopped.Restarting = false
+	return &stopped
+}
+
+// crashStopInProgress reports whether the crashed container of a server is being stopped
+func (s *MinecraftServerService) crashStopInProgress(serverID string) bool {
+	_, ok := s.crashStops.Load(serverID)
+	return ok
+}
+
+// countCrashes adds crashes to the crash window of a server and stops its container, either until the backoff
+// is over or for good if the server is in crash loop. The caller must hold the server lock.
+func (s *MinecraftServerService) countCrashes(ctx context.Context, server *models.MinecraftServer, state *ContainerState, policy models.CrashPolicy, crashes int) {
+	now := time.Now()
+	if !state.Running {
+		server.ExitCode = state.ExitCode
+		server.OOMKilled = state.OOMKilled
+	}
+	if server.CrashWindowStart == nil || now.Sub(*server.CrashWindowStart) > time.Duration(policy.WindowSeconds)*time.Second {
+		server.CrashWindowStart = &now
+		server.Crashes = 0
+	}
+	server.Crashes += crashes
+
+	s.logger.WarnContext(ctx, "Server crashed",
+		"server_id", server.ID,
+		"server_name", server.Name,
+		"crashes", server.Crashes,
+		"max_crashes", policy.MaxCrashes,
+		"exit_code", server.ExitCode,
+		"oom_killed", server.OOMKilled)
+
+	if server.Crashes >= policy.MaxCrashes {
+		s.logger.ErrorContext(ctx, "Server keeps crashing, stopping it",
+			"server_id", server.ID,
+			"server_name", server.Name,
+			"crashes", server.Crashes,
+			"window", time.Duration(policy.WindowSeconds)*time.Second)
+		server.NextRestartAt = nil
+		s.stopCrashedContainer(ctx, server, true)
+		return
+	}
+
+	backoff := crashBackoff(policy, server.Crashes)
+	nextRestartAt := now.Add(backoff)
+	server.NextRestartAt = &nextRestartAt
+	s.logger.InfoContext(ctx, "Restarting crashed server after backoff",
+		"server_id", server.ID,
+		"backoff", backoff)
+	s.stopCrashedContainer(ctx, server, false)
+
+	// The periodic reconciliation starts the server as well, the timer just makes it punctual
+	serverID := server.ID
+	time.AfterFunc(backoff, func() {
+		if err := s.ReconcileServer(context.Background(), serverID); err != nil && !errors.Is(err, database.ErrServerNotFound) {
+			s.logger.Warn("Failed to reconcile server after crash backoff", "server_id", serverID, "error", err)
+		}
+	})
+}
+
+// stopCrashedContainer stops a container Docker is restarting after a crash in the background and captures
+// a crash report afterwards if requested. The server is reconciled once the container stopped, so a backoff
+// that is over by then starts it again. Failures are only logged, the next crash is handled the same way.
+func (s *MinecraftServerService) stopCrashedContainer(ctx context.Context, server *models.MinecraftServer, report bool) {
+	if _, inProgress := s.crashStops.LoadOrStore(server.ID, struct{}{}); inProgress {
+		return
+	}
+
+	// The caller keeps changing the server, so work on a copy
+	crashed := *server
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		timeout := 30
+		if err := s.dockerService.client.ContainerStop(ctx, crashed.ContainerID, container.StopOptions{
+			Timeout: &timeout,
+		}); err != nil {
+			s.logger.ErrorContext(ctx, "Failed to stop crashed server",
+				"server_id", crashed.ID,
+				"error", err)
+		}
+		if report {
+			s.captureCrashReport(ctx, &crashed)
+		}
+		s.crashStops.Delete(crashed.ID)
+
+		if err := s.ReconcileServer(ctx, crashed.ID); err != nil && !errors.Is(err, database.ErrServerNotFound) {
+			s.logger.WarnContext(ctx, "Failed to reconcile server after stopping it", "server_id", crashed.ID, "error", err)
+		}
+	}()
+}
+
+// resetCrashes forgets the crashes of a server, e.g. when it is started by hand after a crash loop.
+// The caller must hold the server lock.
+func (s *MinecraftServerService) resetCrashes(ctx context.Context, server *models.MinecraftServer) {
+	if server.Crashes == 0 && server.CrashWindowStart == nil && server.NextRestartAt == nil {
+		return
+	}
+
+	server.Crashes = 0
+	server.CrashWindowStart = nil
+	server.NextRestartAt = nil
+	if err := s.repo.UpdateState(server); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to reset crashes of server",
+			"server_id", server.ID,
+			"error", err)
+	}
+}
+
+// captureCrashReport stores the last log lines and the newest file of crash-reports/ of a server that
+// was stopped because it kept crashing. Failures are only logged.
+func (s *MinecraftServerService) captureCrashReport(ctx context.Context, server *models.MinecraftServer) {
+	if s.crashReports == nil {
+		return
+	}
+
+	report := &models.CrashReport{
+		ID:        uuid.New().String(),
+		ServerID:  server.ID,
+		ExitCode:  server.ExitCode,
+		OOMKilled: server.OOMKilled,
+		Crashes:   server.Crashes,
+	}
+
+	if logs, err := s.GetServerLogs(ctx, server.ContainerID, false, crashLogLines); err != nil {
+		s.logger.WarnContext(ctx, "Failed to read logs for crash report",
+			"server_id", server.ID,
+			"error", err)
+	} else {
+		var text bytes.Buffer
+		if _, err := stdcopy.StdCopy(&text, &text, logs); err != nil {
+			s.logger.WarnContext(ctx, "Failed to read logs for crash report",
+				"server_id", server.ID,
+				"error", err)
+		}
+		logs.Close()
+		report.Logs = text.String()
+	}
+
+	name, content, err := s.newestCrashReportFile(ctx, server)
+	if err != nil {
+		s.logger.WarnContext(ctx, "Failed to read crash-reports directory",
+			"server_id", server.ID,
+			"error", err)
+	}
+	report.CrashReportFile = name
+	report.CrashReport = content
+
+	if err := s.crashReports.Create(report); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to store crash report",
+			"server_id", server.ID,
+			"error", err)
+		return
+	}
+	if err := s.crashReports.Prune(server.ID, crashReportsKept); err != nil {
+		s.logger.WarnContext(ctx, "Failed to prune crash reports",
+			"server_id", server.ID,
+			"error", err)
+	}
+
+	s.logger.InfoContext(ctx, "Crash report captured",
+		"server_id", server.ID,
+		"report_id", report.ID,
+		"crash_report_file", report.CrashReportFile)
+}
+
+// newestCrashReportFile returns the name and content of the file Minecraft wrote to crash-reports/ during the crash window.
+// Content beyond maxCrashReportBytes is cut off. A missing directory is no error.
+func (s *MinecraftServerService) newestCrashReportFile(ctx context.Context, server *models.MinecraftServer) (string, string, error) {
+	volume := s.dockerService.VolumeFS(server.VolumeID)
+	entries, err := volume.ReadDir(ctx, crashReportsDir)
+	if err != nil {
+		if errors.Is(err, ErrFileNotFound) {
+			return "", "", nil
+		}
+		return "", "", err
+	}
+
+	var newest *models.FileInfo
+	for i := range entries {
+		if entries[i].IsDir {
+			continue
+		}
+		if newest == nil || entries[i].ModTime.After(newest.ModTime) {
+			newest = &entries[i]
+		}
+	}
+	// A report older than the crash window belongs to an earlier crash
+	if newest == nil || (server.CrashWindowStart != nil && newest.ModTime.Before(*server.CrashWindowStart)) {
+		return "", "", nil
+	}
+
+	file, _, err := volume.Open(ctx, path.Join(crashReportsDir, newest.Name))
+	if err != nil {
+		return newest.Name, "", err
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, maxCrashReportBytes))
+	if err != nil {
+		return newest.Name, "", err
+	}
+	return newest.Name, string(content), nil
+}
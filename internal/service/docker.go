@@ -2,18 +2,26 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"sort"
+	"sync"
 
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
 )
 
 // DockerService handles Docker operations
 type DockerService struct {
-	client *client.Client
-	logger *slog.Logger
+	client  *client.Client
+	pullsMu sync.Mutex
+	pulls   map[string]*models.ImagePull // Latest progress per image
+	logger  *slog.Logger
 }
 
 // NewDockerService creates a new Docker service
@@ -25,6 +33,7 @@ func NewDockerService(logger *slog.Logger) (*DockerService, error) {
 
 	return &DockerService{
 		client: cli,
+		pulls:  make(map[string]*models.ImagePull),
 		logger: logger,
 	}, nil
 }
@@ -57,32 +66,84 @@ func (s *DockerService) PullImage(ctx context.Context, imageName string) error {
 
 	// Image doesn't exist, pull it
 	s.logger.InfoContext(ctx, "Pulling Docker image", "image", imageName)
-	reader, err := s.client.ImagePull(ctx, imageName, image.PullOptions{})
-	if err != nil {
+	tracker := newPullTracker(imageName)
+	s.recordPull(ctx, tracker.snapshot())
+
+	if err := s.pullImage(ctx, imageName, tracker); err != nil {
+		tracker.finish(err)
+		s.recordPull(ctx, tracker.snapshot())
 		s.logger.ErrorContext(ctx, "Failed to pull image", "image", imageName, "error", err)
 		return fmt.Errorf("failed to pull image %s: %w", imageName, err)
 	}
-	defer reader.Close()
 
-	// Read the pull output to ensure it completes
-	// This is necessary because ImagePull is asynchronous
-	_, err = io.Copy(io.Discard, reader)
+	tracker.finish(nil)
+	s.recordPull(ctx, tracker.snapshot())
+	s.logger.InfoContext(ctx, "Successfully pulled image", "image", imageName)
+	return nil
+}
+
+// pullImage pulls an image and feeds the progress messages into the tracker
+func (s *DockerService) pullImage(ctx context.Context, imageName string, tracker *pullTracker) error {
+	reader, err := s.client.ImagePull(ctx, imageName, image.PullOptions{})
 	if err != nil {
-		s.logger.ErrorContext(ctx, "Error reading image pull output", "image", imageName, "error", err)
-		return fmt.Errorf("error reading image pull output: %w", err)
+		return err
 	}
+	defer reader.Close()
 
-	s.logger.InfoContext(ctx, "Successfully pulled image", "image", imageName)
-	return nil
+	// Read the pull output until it ends, ImagePull only starts the pull.
+	// Errors during the pull are reported in the stream, not by ImagePull.
+	decoder := json.NewDecoder(reader)
+	for {
+		var msg pullMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("error reading image pull output: %w", err)
+		}
+		if errMsg := msg.errorMessage(); errMsg != "" {
+			return errors.New(errMsg)
+		}
+
+		tracker.apply(&msg)
+		s.recordPull(ctx, tracker.snapshot())
+	}
+}
+
+// recordPull stores the progress of a pull and reports it to the context's progress function
+func (s *DockerService) recordPull(ctx context.Context, pull *models.ImagePull) {
+	s.pullsMu.Lock()
+	s.pulls[pull.Image] = pull
+	s.pullsMu.Unlock()
+
+	notifyPullProgress(ctx, pull)
+}
+
+// ListImagePulls returns the progress of the latest pull of every image pulled since startup
+func (s *DockerService) ListImagePulls() []*models.ImagePull {
+	s.pullsMu.Lock()
+	defer s.pullsMu.Unlock()
+
+	pulls := make([]*models.ImagePull, 0, len(s.pulls))
+	for _, pull := range s.pulls {
+		pulls = append(pulls, pull)
+	}
+	sort.Slice(pulls, func(i, j int) bool {
+		return pulls[i].StartedAt.After(pulls[j].StartedAt)
+	})
+	return pulls
 }
 
 // ContainerState represents the state of a Docker container
 type ContainerState struct {
-	Exists      bool // Whether the container exists in Docker
-	Running     bool // Whether the container is running
-	Restarting  bool // Whether the container is restarting
-	Dead        bool // Whether the container is dead
-	OOMKilled   bool // Whether the container was killed due to OOM
+	Exists       bool   // Whether the container exists in Docker
+	Running      bool   // Whether the container is running
+	Restarting   bool   // Whether the container is restarting
+	Dead         bool   // Whether the container is dead
+	OOMKilled    bool   // Whether the container was killed due to OOM
+	ExitCode     int    // Exit code of the last run, only meaningful if not running
+	RestartCount int    // Restarts by the restart policy since the container was last started through the API
+	IPAddress    string // Address of the container in the minecraft network, or in any network it is connected to
 }
 
 // GetContainerState inspects a container and returns its current state
@@ -94,16 +155,51 @@ func (s *DockerService) GetContainerState(ctx context.Context, containerID strin
 	// Inspect the container
 	containerJSON, err := s.client.ContainerInspect(ctx, containerID)
 	if err != nil {
-		// Container doesn't exist
-		return &ContainerState{Exists: false}, nil
+		if errdefs.IsNotFound(err) {
+			// Container doesn't exist
+			return &ContainerState{Exists: false}, nil
+		}
+		// Any other error (e.g. daemon unreachable) says nothing about the container
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
 	}
 
 	// Container exists, extract state information
-	return &ContainerState{
-		Exists:     true,
-		Running:    containerJSON.State.Running,
-		Restarting: containerJSON.State.Restarting,
-		Dead:       containerJSON.State.Dead,
-		OOMKilled:  containerJSON.State.OOMKilled,
-	}, nil
+	state := &ContainerState{
+		Exists:       true,
+		Running:      containerJSON.State.Running,
+		Restarting:   containerJSON.State.Restarting,
+		Dead:         containerJSON.State.Dead,
+		OOMKilled:    containerJSON.State.OOMKilled,
+		ExitCode:     containerJSON.State.ExitCode,
+		RestartCount: containerJSON.RestartCount,
+	}
+	if containerJSON.NetworkSettings != nil {
+		for name, endpoint := range containerJSON.NetworkSettings.Networks {
+			if endpoint == nil || endpoint.IPAddress == "" {
+				continue
+			}
+			if name == MinecraftNetworkName || state.IPAddress == "" {
+				state.IPAddress = endpoint.IPAddress
+			}
+		}
+	}
+	return state, nil
+}
+
+// statusFromContainerState maps a container state to the status stored for servers and the proxy
+func statusFromContainerState(state *ContainerState) models.ContainerStatus {
+	switch {
+	case !state.Exists:
+		// Container doesn't exist anymore (deleted manually or crashed)
+		return models.StatusStopped
+	case state.Running:
+		return models.StatusRunning
+	case state.Restarting:
+		return models.StatusCreating
+	case state.Dead || state.OOMKilled:
+		return models.StatusError
+	default:
+		// Stopped, paused, or exited
+		return models.StatusStopped
+	}
 }
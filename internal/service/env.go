@@ -0,0 +1,195 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+)
+
+const (
+	// maxEnvOverrides limits the number of environment overrides per server
+	maxEnvOverrides = 100
+	// maxEnvValueLength limits the length of a single override value
+	maxEnvValueLength = 4096
+)
+
+// envKeyPattern matches the variable names of the itzg image
+var envKeyPattern = regexp.MustCompile(`^[A-Z_][A-Z0-9_]{0,127}$`)
+
+// managedEnv are the variables the manager sets itself, with the reason they can't be overridden
+var managedEnv = map[string]string{
+	"EULA":                  "the EULA is accepted by the manager",
+	"MAX_PLAYERS":           "set max_players instead",
+	"MOTD":                  "set motd instead",
+	"VERSION":               "the version is chosen when the server is created",
+	"TYPE":                  "the type is chosen when the server is created",
+	"FABRIC_LOADER_VERSION": "the loader version is chosen when the server is created",
+	"FORGE_VERSION":         "the loader version is chosen when the server is created",
+	"NEOFORGE_VERSION":      "the loader version is chosen when the server is created",
+	"MEMORY":                "set jvm_heap_mb or memory_mb instead",
+	"INIT_MEMORY":           "set jvm_heap_mb or memory_mb instead",
+	"MAX_MEMORY":            "set jvm_heap_mb or memory_mb instead",
+	"SERVER_PORT":           "the proxy and port publishing expect the default port",
+	"UID":                   "the manager writes files as uid 1000",
+	"GID":                   "the manager writes files as gid 1000",
+	"ENABLE_RCON":           "the manager sends console commands through RCON",
+	"RCON_PORT":             "the manager sends console commands through RCON",
+	"RCON_PASSWORD":         "the manager sends console commands through RCON",
+	"PATCH_DEFINITIONS":     "the manager patches the config for proxy forwarding",
+}
+
+// whitelistEnv enables the whitelist through the itzg image, it sets white-list in server.properties on every start
+const whitelistEnv = "ENABLE_WHITELIST"
+
+// Reasons for variables the itzg image applies to server.properties and the player lists on every start
+const (
+	useProperties = "it would undo changes on every start, use the properties endpoint instead"
+	useWhitelist  = "it would undo changes on every start, use the whitelist endpoints instead"
+	useOps        = "it would undo changes on every start, use the ops endpoints instead"
+)
+
+// fileEnv are the variables the itzg image writes to server.properties, whitelist.json or ops.json on every
+// start, with the reason they can't be overridden. They conflict with the properties and player list endpoints.
+// ENABLE_WHITELIST is allowed, the properties endpoint leaves white-list to it while it is set.
+var fileEnv = map[string]string{
+	"DIFFICULTY":                        useProperties,
+	"MODE":                              useProperties,
+	"FORCE_GAMEMODE":                    useProperties,
+	"HARDCORE":                          useProperties,
+	"PVP":                               useProperties,
+	"VIEW_DISTANCE":                     useProperties,
+	"SIMULATION_DISTANCE":               useProperties,
+	"SPAWN_PROTECTION":                  useProperties,
+	"SEED":                              useProperties,
+	"LEVEL_TYPE":                        useProperties,
+	"GENERATE_STRUCTURES":               useProperties,
+	"ALLOW_NETHER":                      useProperties,
+	"ALLOW_FLIGHT":                      useProperties,
+	"SPAWN_MONSTERS":                    useProperties,
+	"SPAWN_ANIMALS":                     useProperties,
+	"SPAWN_NPCS":                        useProperties,
+	"ENABLE_COMMAND_BLOCK":              useProperties,
+	"OP_PERMISSION_LEVEL":               useProperties,
+	"PLAYER_IDLE_TIMEOUT":               useProperties,
+	"MAX_WORLD_SIZE":                    useProperties,
+	"ENTITY_BROADCAST_RANGE_PERCENTAGE": useProperties,
+	"HIDE_ONLINE_PLAYERS":               useProperties,
+	"RESOURCE_PACK":                     useProperties,
+	"RESOURCE_PACK_SHA1":                useProperties,
+	"RESOURCE_PACK_ENFORCE":             useProperties,
+	"ENFORCE_WHITELIST":                 useProperties,
+	"CUSTOM_SERVER_PROPERTIES":          useProperties,
+	"OVERRIDE_SERVER_PROPERTIES":        useProperties,
+	"SKIP_SERVER_PROPERTIES":            "the manager sets online-mode, motd and max-players through server.properties",
+	"WHITELIST":                         useWhitelist,
+	"WHITELIST_FILE":                    useWhitelist,
+	"EXISTING_WHITELIST_FILE":           useWhitelist,
+	"OVERRIDE_WHITELIST":                useWhitelist,
+	"OPS":                               useOps,
+	"OPS_FILE":                          useOps,
+	"EXISTING_OPS_FILE":                 useOps,
+	"OVERRIDE_OPS":                      useOps,
+}
+
+// jvmOptionEnv are the variables the itzg image passes to the JVM as options
+var jvmOptionEnv = []string{"JVM_OPTS", "JVM_XX_OPTS"}
+
+// heapOptions are the JVM options that size the heap, the manager keeps the heap inside the container limit
+var heapOptions = []string{"-Xms", "-Xmx", "-XX:InitialHeapSize", "-XX:MaxHeapSize", "-XX:InitialRAM", "-XX:MinRAM", "-XX:MaxRAM"}
+
+// validateEnvOverride checks a single environment override of a server
+func validateEnvOverride(key, value string, behindProxy bool) error {
+	if !envKeyPattern.MatchString(key) {
+		return validationError("env key %q must consist of uppercase letters, digits and underscores", key)
+	}
+	if reason, ok := managedEnv[key]; ok {
+		return validationError("env %s can't be overridden, %s", key, reason)
+	}
+	if reason, ok := fileEnv[key]; ok {
+		return validationError("env %s can't be set, %s", key, reason)
+	}
+	// Servers behind the proxy must run in offline mode, the proxy authenticates the players.
+	// If a proxy is created later, the forwarding variables of the manager take precedence.
+	if key == "ONLINE_MODE" && behindProxy {
+		return validationError("env ONLINE_MODE can't be set for servers behind the proxy, the proxy authenticates players")
+	}
+	if slices.Contains(jvmOptionEnv, key) {
+		for _, option := range strings.Fields(value) {
+			for _, heapOption := range heapOptions {
+				if strings.HasPrefix(option, heapOption) {
+					return validationError("env %s can't contain %s, set jvm_heap_mb or memory_mb instead", key, heapOption)
+				}
+			}
+		}
+	}
+	if len(value) > maxEnvValueLength {
+		return validationError("env %s must be at most %d characters", key, maxEnvValueLength)
+	}
+	if strings.ContainsRune(value, 0) {
+		return validationError("env %s must not contain NUL characters", key)
+	}
+	return nil
+}
+
+// validateEnvOverrides checks all environment overrides of a server
+func validateEnvOverrides(env map[string]string, behindProxy bool) error {
+	if len(env) > maxEnvOverrides {
+		return validationError("at most %d env overrides are allowed", maxEnvOverrides)
+	}
+	for key, value := range env {
+		if err := validateEnvOverride(key, value, behindProxy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeEnv adds the overrides of a server to the variables set by the manager.
+// Variables set by the manager win, overrides come first in a stable order.
+func mergeEnv(managed []string, overrides map[string]string) []string {
+	if len(overrides) == 0 {
+		return managed
+	}
+
+	set := make(map[string]bool, len(managed))
+	for _, variable := range managed {
+		key, _, _ := strings.Cut(variable, "=")
+		set[key] = true
+	}
+
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		if !set[key] {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	env := make([]string, 0, len(keys)+len(managed))
+	for _, key := range keys {
+		env = append(env, fmt.Sprintf("%s=%s", key, overrides[key]))
+	}
+	return append(env, managed...)
+}
+
+// applyEnvUpdate applies the changes of an update request to the overrides of a server
+func applyEnvUpdate(server *models.MinecraftServer, update map[string]*string) {
+	if len(update) == 0 {
+		return
+	}
+	env := make(map[string]string, len(server.Env)+len(update))
+	for key, value := range server.Env {
+		env[key] = value
+	}
+	for key, value := range update {
+		if value == nil {
+			delete(env, key)
+		} else {
+			env[key] = *value
+		}
+	}
+	server.Env = env
+}
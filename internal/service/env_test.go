@@ -0,0 +1,80 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateEnvOverride(t *testing.T) {
+	tests := []struct {
+		name        string
+		key         string
+		behindProxy bool
+		message     string // Part of the error, empty if the override is valid
+	}{
+		{name: "image feature", key: "USE_AIKAR_FLAGS"},
+		{name: "lowercase key", key: "use_aikar_flags", message: "uppercase"},
+		{name: "managed by the manager", key: "RCON_PASSWORD", message: "RCON"},
+		{name: "server property", key: "DIFFICULTY", message: "properties endpoint"},
+		{name: "properties override", key: "OVERRIDE_SERVER_PROPERTIES", message: "properties endpoint"},
+		{name: "whitelist", key: "WHITELIST", message: "whitelist endpoints"},
+		{name: "ops", key: "OPS", message: "ops endpoints"},
+		{name: "online mode behind the proxy", key: "ONLINE_MODE", behindProxy: true, message: "behind the proxy"},
+		{name: "online mode without proxy", key: "ONLINE_MODE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateEnvOverride(tt.key, "true", tt.behindProxy)
+			if tt.message == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrValidation)
+			assert.ErrorContains(t, err, tt.message)
+		})
+	}
+}
+
+func TestValidateEnvOverridesImageFeatures(t *testing.T) {
+	env := map[string]string{
+		"ENABLE_WHITELIST": "true",
+		"SPIGET_RESOURCES": "9089,34315",
+		"USE_AIKAR_FLAGS":  "true",
+		"TZ":               "Europe/Berlin",
+	}
+	assert.NoError(t, validateEnvOverrides(env, true))
+}
+
+func TestValidateEnvOverrideHeapOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		value   string
+		message string // Part of the error, empty if the override is valid
+	}{
+		{name: "memory", key: "MEMORY", value: "8G", message: "jvm_heap_mb"},
+		{name: "initial memory", key: "INIT_MEMORY", value: "8G", message: "jvm_heap_mb"},
+		{name: "max memory", key: "MAX_MEMORY", value: "8G", message: "jvm_heap_mb"},
+		{name: "gc options", key: "JVM_XX_OPTS", value: "-XX:+UseG1GC -XX:MaxGCPauseMillis=200"},
+		{name: "system properties", key: "JVM_OPTS", value: "-Dfile.encoding=UTF-8"},
+		{name: "max heap", key: "JVM_OPTS", value: "-Dfile.encoding=UTF-8 -Xmx8G", message: "-Xmx"},
+		{name: "initial heap", key: "JVM_OPTS", value: "-Xms8G", message: "-Xms"},
+		{name: "max heap size", key: "JVM_XX_OPTS", value: "-XX:MaxHeapSize=8g", message: "-XX:MaxHeapSize"},
+		{name: "ram percentage", key: "JVM_XX_OPTS", value: "-XX:MaxRAMPercentage=100", message: "-XX:MaxRAM"},
+		{name: "heap flags in other variables", key: "SPIGET_RESOURCES", value: "-Xmx8G"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateEnvOverride(tt.key, tt.value, false)
+			if tt.message == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrValidation)
+			assert.ErrorContains(t, err, tt.message)
+		})
+	}
+}
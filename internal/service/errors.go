@@ -0,0 +1,34 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrValidation is wrapped by all errors caused by invalid user input
+var ErrValidation = errors.New("validation failed")
+
+// ErrConflict is wrapped by all errors caused by an operation that conflicts with the current state,
+// e.g. restoring a backup into a running server
+var ErrConflict = errors.New("conflict")
+
+// ErrFileNotFound is wrapped by errors for files or directories missing in a server's volume
+var ErrFileNotFound = errors.New("file not found")
+
+// ErrTooLarge is wrapped by all errors caused by content exceeding a size limit
+var ErrTooLarge = errors.New("too large")
+
+// validationError creates an error wrapping ErrValidation
+func validationError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrValidation, fmt.Sprintf(format, args...))
+}
+
+// conflictError creates an error wrapping ErrConflict
+func conflictError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrConflict, fmt.Sprintf(format, args...))
+}
+
+// tooLargeError creates an error wrapping ErrTooLarge
+func tooLargeError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrTooLarge, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+)
+
+// subscriberBufferSize is the number of events a subscriber may lag behind before it is dropped
+const subscriberBufferSize = 64
+
+// EventBus distributes events to subscribers and keeps a bounded history,
+// so clients that reconnect with a cursor can catch up on what they missed
+type EventBus struct {
+	mu          sync.Mutex
+	epoch       string // Distinguishes cursors of different manager runs
+	seq         uint64
+	history     []models.Event
+	historySize int
+	subscribers map[chan models.Event]struct{}
+	closed      bool
+	logger      *slog.Logger
+}
+
+// NewEventBus creates a new event bus keeping the last historySize events
+func NewEventBus(historySize int, logger *slog.Logger) *EventBus {
+	return &EventBus{
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		historySize: historySize,
+		subscribers: make(map[chan models.Event]struct{}),
+		logger:      logger,
+	}
+}
+
+// Publish sends an event to all subscribers. Publishing on a nil bus does nothing,
+// so services can publish without checking whether streaming is enabled.
+func (b *EventBus) Publish(eventType models.EventType, serverID string, data any) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	event := models.Event{
+		ID:        fmt.Sprintf("%s-%d", b.epoch, b.seq),
+		Type:      eventType,
+		ServerID:  serverID,
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	}
+
+	b.history = append(b.history, event)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Drop subscribers that can't keep up, they resume with their cursor
+			b.logger.Warn("Dropping slow event subscriber")
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	b.logger.Debug("Event published", "id", event.ID, "type", eventType, "server_id", serverID)
+}
+
+// Subscribe registers a new subscriber. If cursor is set, the events published after it are
+// returned as backlog. resumed is false if the cursor is unknown, e.g. because it is from
+// before a restart or fell out of the history, in which case the client has to resync.
+// The returned channel is closed when the subscriber is dropped or cancel is called.
+func (b *EventBus) Subscribe(cursor string) (backlog []models.Event, events <-chan models.Event, resumed bool, cancel func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	resumed = true
+	if cursor != "" {
+		backlog, resumed = b.eventsAfter(cursor)
+	}
+
+	ch := make(chan models.Event, subscriberBufferSize)
+	if b.closed {
+		close(ch)
+	} else {
+		b.subscribers[ch] = struct{}{}
+	}
+
+	cancel = func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return backlog, ch, resumed, cancel
+}
+
+// Close ends all subscriptions, so streaming requests finish and don't block a graceful shutdown
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// Cursor returns the cursor of the latest event
+func (b *EventBus) Cursor() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return fmt.Sprintf("%s-%d", b.epoch, b.seq)
+}
+
+// eventsAfter returns the events after a cursor. The caller must hold the lock.
+func (b *EventBus) eventsAfter(cursor string) ([]models.Event, bool) {
+	epoch, seqStr, ok := strings.Cut(cursor, "-")
+	if !ok || epoch != b.epoch {
+		return nil, false
+	}
+	seq, err := strconv.ParseUint(seqStr, 10, 64)
+	if err != nil || seq > b.seq {
+		return nil, false
+	}
+
+	// The sequence of the oldest event still in history
+	oldest := b.seq - uint64(len(b.history)) + 1
+	if seq+1 < oldest {
+		return nil, false // Missed events are no longer available
+	}
+
+	start := int(seq + 1 - oldest)
+	backlog := make([]models.Event, len(b.history)-start)
+	copy(backlog, b.history[start:])
+	return backlog, true
+}
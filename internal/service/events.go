@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+)
+
+const (
+	// Container labels identifying managed containers
+	serverIDLabel = "minecraft-server-id"
+	proxyLabel    = "minecraft-proxy"
+
+	// Backoff between resubscription attempts after the event stream broke
+	minEventRetryDelay = time.Second
+	maxEventRetryDelay = 30 * time.Second
+)
+
+// watchedContainerActions are the container events that change the state of a server or the proxy
+var watchedContainerActions = []events.Action{
+	events.ActionStart,
+	events.ActionRestart,
+	events.ActionStop,
+	events.ActionDie,
+	events.ActionOOM,
+	events.ActionDestroy,
+	events.ActionHealthStatus, // Matches all health_status: * events
+}
+
+// EventWatcher subscribes to Docker container events and reconciles
+// the affected server or proxy as soon as its container changes state
+type EventWatcher struct {
+	dockerService *DockerService
+	reconciler    *Reconciler
+	logger        *slog.Logger
+}
+
+// NewEventWatcher creates a new Docker event watcher
+func NewEventWatcher(dockerService *DockerService, reconciler *Reconciler, logger *slog.Logger) *EventWatcher {
+	return &EventWatcher{
+		dockerService: dockerService,
+		reconciler:    reconciler,
+		logger:        logger,
+	}
+}
+
+// Run watches Docker events until the context is cancelled. When the stream breaks,
+// e.g. because the daemon restarted, it resubscribes with backoff and resyncs everything
+// to catch up on events missed in between.
+func (w *EventWatcher) Run(ctx context.Context) {
+	w.logger.InfoContext(ctx, "Docker event watcher started")
+
+	delay := minEventRetryDelay
+	for {
+		subscribedAt := time.Now()
+		err := w.watch(ctx)
+		if ctx.Err() != nil {
+			w.logger.InfoContext(ctx, "Docker event watcher stopped")
+			return
+		}
+
+		// Only back off further if the stream failed right away
+		if time.Since(subscribedAt) > maxEventRetryDelay {
+			delay = minEventRetryDelay
+		}
+
+		w.logger.WarnContext(ctx, "Docker event stream interrupted, resubscribing",
+			"error", err,
+			"retry_in", delay.String())
+
+		select {
+		case <-ctx.Done():
+			w.logger.InfoContext(ctx, "Docker event watcher stopped")
+			return
+		case <-time.After(delay):
+		}
+
+		delay = min(delay*2, maxEventRetryDelay)
+	}
+}
+
+// watch subscribes once and handles events until the stream returns an error
+func (w *EventWatcher) watch(ctx context.Context) error {
+	args := filters.NewArgs(filters.Arg("type", string(events.ContainerEventType)))
+	for _, action := range watchedContainerActions {
+		args.Add("event", string(action))
+	}
+
+	// Docker ANDs multiple label filters, so the server and proxy labels are checked in handle
+	messages, errs := w.dockerService.client.Events(ctx, events.ListOptions{Filters: args})
+
+	// Events may have been missed while not subscribed
+	w.reconciler.ReconcileAll(ctx)
+
+	for {
+		select {
+		case msg := <-messages:
+			w.handle(ctx, msg)
+		case err := <-errs:
+			return err
+		}
+	}
+}
+
+// handle reconciles the server or proxy owning the container of an event
+func (w *EventWatcher) handle(ctx context.Context, msg events.Message) {
+	attributes := msg.Actor.Attributes
+	action := strings.TrimSpace(string(msg.Action))
+
+	if serverID, ok := attributes[serverIDLabel]; ok {
+		w.logger.DebugContext(ctx, "Received container event for server",
+			"server_id", serverID,
+			"container_id", msg.Actor.ID,
+			"action", action)
+		w.reconciler.ReconcileServer(ctx, serverID)
+		return
+	}
+
+	if _, ok := attributes[proxyLabel]; ok {
+		w.logger.DebugContext(ctx, "Received container event for proxy",
+			"container_id", msg.Actor.ID,
+			"action", action)
+		w.reconciler.ReconcileProxy(ctx)
+	}
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+)
+
+// FileService manages the files in server volumes
+type FileService struct {
+	dockerService *DockerService
+	mcService     *MinecraftServerService
+	maxFileBytes  int64
+	logger        *slog.Logger
+}
+
+// NewFileService creates a new file service that reads and writes files of up to maxFileBytes
+func NewFileService(dockerService *DockerService, mcService *MinecraftServerService, maxFileBytes int64, logger *slog.Logger) *FileService {
+	return &FileService{
+		dockerService: dockerService,
+		mcService:     mcService,
+		maxFileBytes:  maxFileBytes,
+		logger:        logger,
+	}
+}
+
+// volume returns the file system of a server's volume
+func (s *FileService) volume(serverID string) (*VolumeFS, error) {
+	server, err := s.mcService.repo.FindByID(serverID)
+	if err != nil {
+		return nil, err
+	}
+	return s.dockerService.VolumeFS(server.VolumeID), nil
+}
+
+// Stat returns information about a file or directory in a server's volume
+func (s *FileService) Stat(ctx context.Context, serverID, p string) (*models.FileInfo, error) {
+	volume, err := s.volume(serverID)
+	if err != nil {
+		return nil, err
+	}
+	return volume.Stat(ctx, p)
+}
+
+// ListDirectory lists a directory in a server's volume
+func (s *FileService) ListDirectory(ctx context.Context, serverID, p string) (*models.DirectoryListing, error) {
+	volume, err := s.volume(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := volume.ReadDir(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return &models.DirectoryListing{Path: CleanVolumePath(p), Entries: entries}, nil
+}
+
+// OpenFile returns the content of a file in a server's volume. The caller must close it.
+func (s *FileService) OpenFile(ctx context.Context, serverID, p string) (io.ReadCloser, *models.FileInfo, error) {
+	volume, err := s.volume(serverID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, info, err := volume.Open(ctx, p)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.Size > s.maxFileBytes {
+		content.Close()
+		return nil, nil, tooLargeError("%s exceeds the maximum file size of %d MB", info.Path, s.maxFileBytes/1024/1024)
+	}
+	return content, info, nil
+}
+
+// WriteFile creates or replaces a file in a server's volume. If size is negative,
+// the content is buffered in a temporary file first to find out its size.
+func (s *FileService) WriteFile(ctx context.Context, serverID, p string, r io.Reader, size int64) (*models.FileInfo, error) {
+	volume, err := s.volume(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	if size > s.maxFileBytes {
+		return nil, tooLargeError("file exceeds the maximum size of %d MB", s.maxFileBytes/1024/1024)
+	}
+	if size < 0 {
+		buffer, err := os.CreateTemp("", "dockermc-upload-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temporary file: %w", err)
+		}
+		defer os.Remove(buffer.Name())
+		defer buffer.Close()
+
+		size, err = io.Copy(buffer, io.LimitReader(r, s.maxFileBytes+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to receive file: %w", err)
+		}
+		if size > s.maxFileBytes {
+			return nil, tooLargeError("file exceeds the maximum size of %d MB", s.maxFileBytes/1024/1024)
+		}
+		if _, err := buffer.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to receive file: %w", err)
+		}
+		r = buffer
+	}
+
+	info, err := volume.WriteFile(ctx, p, r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "File written",
+		"server_id", serverID,
+		"path", info.Path,
+		"size", info.Size)
+
+	return info, nil
+}
+
+// CreateDirectory creates a directory and its missing parents in a server's volume
+func (s *FileService) CreateDirectory(ctx context.Context, serverID, p string) (*models.FileInfo, error) {
+	volume, err := s.volume(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := volume.Mkdir(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "Directory created", "server_id", serverID, "path", info.Path)
+	return info, nil
+}
+
+// MoveFile renames or moves a file or directory in a server's volume
+func (s *FileService) MoveFile(ctx context.Context, serverID, from, to string) (*models.FileInfo, error) {
+	volume, err := s.volume(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := volume.Rename(ctx, from, to); err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "File moved",
+		"server_id", serverID,
+		"from", CleanVolumePath(from),
+		"to", CleanVolumePath(to))
+
+	return volume.Stat(ctx, to)
+}
+
+// DeleteFile removes a file or directory from a server's volume
+func (s *FileService) DeleteFile(ctx context.Context, serverID, p string, recursive bool) error {
+	volume, err := s.volume(serverID)
+	if err != nil {
+		return err
+	}
+
+	if err := volume.Remove(ctx, p, recursive); err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "File deleted",
+		"server_id", serverID,
+		"path", CleanVolumePath(p),
+		"recursive", recursive)
+	return nil
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+)
+
+// bungeeCordPatch is a patch definition enabling bungeecord in spigot.yml.
+// Note: Each file in the patches directory is a single patch, not an array
+const bungeeCordPatch = `{
+  "file": "/data/spigot.yml",
+  "ops": [
+    {
+      "$set": {
+        "path": "$.settings.bungeecord",
+        "value": true,
+        "value-type": "bool"
+      }
+    }
+  ]
+}`
+
+// fabricProxyConfig enables legacy BungeeCord forwarding in the FabricProxy mod
+const fabricProxyConfig = `BungeeCord = true
+Velocity = false
+allowBypassProxy = false
+`
+
+// volumeFile is a file that is written into a server volume
+type volumeFile struct {
+	Path    string // Absolute path inside the volume, "/" is the volume root
+	Content string
+}
+
+// serverTypeEnv returns the itzg environment variables selecting the server software
+func serverTypeEnv(serverType models.ServerType, loaderVersion string) []string {
+	env := []string{fmt.Sprintf("TYPE=%s", serverType)}
+	if loaderVersion == "" {
+		return env
+	}
+
+	switch serverType {
+	case models.ServerTypeFabric:
+		env = append(env, fmt.Sprintf("FABRIC_LOADER_VERSION=%s", loaderVersion))
+	case models.ServerTypeForge:
+		env = append(env, fmt.Sprintf("FORGE_VERSION=%s", loaderVersion))
+	case models.ServerTypeNeoForge:
+		env = append(env, fmt.Sprintf("NEOFORGE_VERSION=%s", loaderVersion))
+	}
+	return env
+}
+
+// proxyForwardingEnv returns the environment variables needed to run a server behind the proxy
+func proxyForwardingEnv(serverType models.ServerType) []string {
+	env := []string{"ONLINE_MODE=FALSE"} // Must be false when behind proxy
+	if serverType.IsPaperFamily() {
+		env = append(env, "PATCH_DEFINITIONS=/data/patches") // Directory containing patch definitions in volume
+	}
+	return env
+}
+
+// proxyForwardingFiles returns the files enabling legacy BungeeCord forwarding for a server type.
+// Vanilla, Forge and NeoForge have no built-in support, so nothing is written for them.
+func proxyForwardingFiles(serverType models.ServerType) []volumeFile {
+	switch {
+	case serverType.IsPaperFamily():
+		return []volumeFile{{Path: "/patches/bungeecord.json", Content: bungeeCordPatch}}
+	case serverType == models.ServerTypeFabric:
+		// Only takes effect if the FabricProxy mod is installed
+		return []volumeFile{{Path: "/config/FabricProxy.toml", Content: fabricProxyConfig}}
+	default:
+		return nil
+	}
+}
+
+// writeForwardingFilesToVolume writes the proxy forwarding files for the server type into its volume
+func (s *MinecraftServerService) writeForwardingFilesToVolume(ctx context.Context, server *models.MinecraftServer) error {
+	files := proxyForwardingFiles(server.Type)
+	if len(files) == 0 {
+		s.logger.WarnContext(ctx, "Server type has no legacy forwarding support, players will join with offline UUIDs",
+			"server_id", server.ID,
+			"type", server.Type)
+		return nil
+	}
+	return s.writeFilesToVolume(ctx, server.VolumeID, files)
+}
+
+// writeFilesToVolume writes files into a volume
+func (s *MinecraftServerService) writeFilesToVolume(ctx context.Context, volumeName string, files []volumeFile) error {
+	volume := s.dockerService.VolumeFS(volumeName)
+	for _, file := range files {
+		if _, err := volume.WriteFile(ctx, file.Path, strings.NewReader(file.Content), int64(len(file.Content))); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.Path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+)
+
+// MaxStopGrace is the longest countdown of a graceful stop
+const MaxStopGrace = 10 * time.Minute
+
+// playerTransferDelay gives the proxy time to move players before the server kicks them
+const playerTransferDelay = 3 * time.Second
+
+// stopAnnouncements are the remaining times the countdown of a graceful stop is announced at
+var stopAnnouncements = []time.Duration{
+	5 * time.Minute,
+	2 * time.Minute,
+	time.Minute,
+	30 * time.Second,
+	10 * time.Second,
+	5 * time.Second,
+	3 * time.Second,
+	2 * time.Second,
+	time.Second,
+}
+
+// ValidateStopGrace checks the grace period of a stop
+func ValidateStopGrace(grace time.Duration) error {
+	if grace < 0 || grace > MaxStopGrace {
+		return validationError("grace must be between 0 and %d seconds", int(MaxStopGrace.Seconds()))
+	}
+	return nil
+}
+
+// prepareStop warns the players of a running server with a countdown, moves them to the fallback server
+// of the proxy and saves the world. Only a cancelled ctx fails it, the other steps are best effort.
+func (s *MinecraftServerService) prepareStop(ctx context.Context, server *models.MinecraftServer, grace time.Duration, action string) error {
+	reportStep(ctx, "Counting down", 10)
+	if err := s.countdownStop(ctx, server, grace, action); err != nil {
+		return err
+	}
+
+	reportStep(ctx, "Moving players", 70)
+	s.moveToFallback(ctx, server)
+
+	reportStep(ctx, "Saving world", 80)
+	if _, err := s.ExecuteCommand(ctx, server, "save-all flush"); err != nil {
+		s.logger.WarnContext(ctx, "Failed to save world before stop",
+			"server_id", server.ID,
+			"error", err)
+	}
+	return nil
+}
+
+// countdownStop announces the stop in chat until the grace period is over
+func (s *MinecraftServerService) countdownStop(ctx context.Context, server *models.MinecraftServer, grace time.Duration, action string) error {
+	deadline := time.Now().Add(grace)
+	s.broadcast(ctx, server, fmt.Sprintf("This server %s in %s", action, formatCountdown(grace)))
+
+	for _, remaining := range stopAnnouncements {
+		if remaining >= grace {
+			continue
+		}
+		if err := sleepUntil(ctx, deadline.Add(-remaining)); err != nil {
+			return err
+		}
+		s.broadcast(ctx, server, fmt.Sprintf("This server %s in %s", action, formatCountdown(remaining)))
+	}
+	return sleepUntil(ctx, deadline)
+}
+
+// broadcast shows a message to all players of a server. Failures are only logged.
+func (s *MinecraftServerService) broadcast(ctx context.Context, server *models.MinecraftServer, message string) {
+	text, _ := json.Marshal(map[string]string{"text": message, "color": "gold"})
+	if _, err := s.ExecuteCommand(ctx, server, "tellraw @a "+string(text)); err != nil {
+		s.logger.WarnContext(ctx, "Failed to broadcast message",
+			"server_id", server.ID,
+			"error", err)
+	}
+}
+
+// moveToFallback sends the players of a server behind the proxy to another running server.
+// Without a fallback server the players are disconnected when the server stops.
+func (s *MinecraftServerService) moveToFallback(ctx context.Context, server *models.MinecraftServer) {
+	if server.ExposeDirectly || s.proxyService == nil {
+		return
+	}
+
+	fallback := s.fallbackServer(server)
+	if fallback == nil {
+		s.logger.InfoContext(ctx, "No fallback server running, players are disconnected",
+			"server_id", server.ID)
+		return
+	}
+
+	if err := s.proxyService.SendPlayers(ctx, server.Name, fallback.Name); err != nil {
+		s.logger.WarnContext(ctx, "Failed to move players to fallback server",
+			"server_id", server.ID,
+			"fallback", fallback.Name,
+			"error", err)
+		return
+	}
+	_ = sleepUntil(ctx, time.Now().Add(playerTransferDelay))
+}
+
+// fallbackServer returns the running server players are moved to when a server behind the proxy stops.
+// The default server of the proxy is preferred.
+func (s *MinecraftServerService) fallbackServer(server *models.MinecraftServer) *models.MinecraftServer {
+	proxy, err := s.proxyService.proxyRepo.FindByID(models.SingleProxyID)
+	if err != nil {
+		return nil
+	}
+	servers, err := s.repo.FindAll()
+	if err != nil {
+		return nil
+	}
+
+	var fallback *models.MinecraftServer
+	for _, candidate := range servers {
+		if candidate.ID == server.ID || candidate.ExposeDirectly || candidate.Status != models.StatusRunning {
+			continue
+		}
+		if candidate.ID == proxy.DefaultServerID {
+			return candidate
+		}
+		if fallback == nil {
+			fallback = candidate
+		}
+	}
+	return fallback
+}
+
+// sleepUntil waits until t or until ctx is cancelled
+func sleepUntil(ctx context.Context, t time.Time) error {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// formatCountdown formats the remaining time of a countdown for players
+func formatCountdown(d time.Duration) string {
+	switch {
+	case d >= 2*time.Minute:
+		return fmt.Sprintf("%d minutes", int(d.Minutes()))
+	case d >= time.Minute && d%time.Minute == 0:
+		return "1 minute"
+	case d.Seconds() == 1:
+		return "1 second"
+	default:
+		return fmt.Sprintf("%d seconds", int(d.Seconds()))
+	}
+}
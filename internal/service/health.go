@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+)
+
+const (
+	// serverPingTimeout bounds a single Server List Ping
+	serverPingTimeout = 3 * time.Second
+	// serverPingFailures is how many pings in a row a ready server may miss before it counts as starting again,
+	// so a single lag spike doesn't remove it from the proxy
+	serverPingFailures = 3
+)
+
+// pingResult is the last ping of a server and the number of failed pings since it last answered
+type pingResult struct {
+	ping     *models.ServerPing
+	failures int
+}
+
+// pingCache holds the ping results of running servers in memory
+type pingCache struct {
+	mu      sync.Mutex
+	results map[string]*pingResult
+}
+
+func newPingCache() *pingCache {
+	return &pingCache{results: make(map[string]*pingResult)}
+}
+
+// get returns a copy of the last ping of a server, or nil if it wasn't pinged since it started
+func (c *pingCache) get(serverID string) *models.ServerPing {
+	ping, _ := c.last(serverID)
+	return ping
+}
+
+// last returns a copy of the last ping of a server and the number of failed pings in a row.
+// The ping is nil if the server wasn't pinged since it started.
+func (c *pingCache) last(serverID string) (*models.ServerPing, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result, ok := c.results[serverID]
+	if !ok {
+		return nil, 0
+	}
+	ping := *result.ping
+	return &ping, result.failures
+}
+
+// record stores a ping and returns the number of failed pings in a row
+func (c *pingCache) record(serverID string, ping *models.ServerPing) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result, ok := c.results[serverID]
+	if !ok {
+		result = &pingResult{}
+		c.results[serverID] = result
+	}
+	result.ping = ping
+	if ping.Ready {
+		result.failures = 0
+	} else {
+		result.failures++
+	}
+	return result.failures
+}
+
+// forget removes the ping of a server, e.g. once its container stopped
+func (c *pingCache) forget(serverID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.results, serverID)
+}
+
+// SetServerPingEnabled enables the Server List Ping of running servers. Without it a running container counts as
+// ready, otherwise a server is starting until it answers the ping. The manager must be able to reach the
+// containers in the minecraft network for the ping to work.
+func (s *MinecraftServerService) SetServerPingEnabled(enabled bool) {
+	s.pingEnabled = enabled
+}
+
+// attachPing adds the last ping result to a server
+func (s *MinecraftServerService) attachPing(server *models.MinecraftServer) {
+	server.Ping = s.pings.get(server.ID)
+}
+
+// checkReady reports whether a server with a running container accepts logins, from the last ping of the
+// health checker. Reconciliations don't ping themselves, so Docker events are handled without waiting for a
+// server. A server is starting until it answered a ping and a ready server stays ready until it missed
+// serverPingFailures pings in a row. The caller must hold the server lock.
+func (s *MinecraftServerService) checkReady(server *models.MinecraftServer) bool {
+	if !s.pingEnabled {
+		return true
+	}
+
+	// Pings from before the container was last started don't count
+	if server.Status != models.StatusStarting && server.Status != models.StatusRunning {
+		return false
+	}
+
+	ping, failures := s.pings.last(server.ID)
+	if ping == nil {
+		return false
+	}
+	// Without a route to the container the ping can't tell, so the running container counts as ready
+	if ping.Unreachable {
+		return true
+	}
+	if !ping.Ready {
+		return server.Status == models.StatusRunning && failures < serverPingFailures
+	}
+	return true
+}
+
+// checkServer pings a server whose container is running and records the result for checkReady
+func (s *MinecraftServerService) checkServer(ctx context.Context, server *models.MinecraftServer) error {
+	if !s.pingEnabled {
+		return nil
+	}
+
+	state, err := s.dockerService.GetContainerState(ctx, server.ContainerID)
+	if err != nil {
+		return err
+	}
+	if !state.Running {
+		return nil
+	}
+
+	ping, err := s.pingServer(ctx, state)
+	if err != nil {
+		ping = &models.ServerPing{Error: err.Error(), Unreachable: pingUnreachable(err)}
+	}
+	ping.CheckedAt = time.Now()
+	failures := s.pings.record(server.ID, ping)
+
+	if ping.Unreachable {
+		s.pingWarning.Do(func() {
+			s.logger.WarnContext(ctx, "Can't reach the containers for the server ping, running servers count as ready",
+				"server_id", server.ID,
+				"error", err)
+		})
+	} else if err != nil {
+		s.logger.DebugContext(ctx, "Server didn't answer ping",
+			"server_id", server.ID,
+			"failures", failures,
+			"error", err)
+	}
+	return nil
+}
+
+// pingUnreachable reports whether a ping failed because the manager can't connect to the container network,
+// e.g. when it runs outside the Docker VM of Docker Desktop. A server that doesn't listen yet refuses the
+// connection instead, and one that is busy accepts it but doesn't answer.
+func pingUnreachable(err error) bool {
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) || opErr.Op != "dial" {
+		return false
+	}
+	return !errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// pingServer runs the Server List Ping against the container of a server
+func (s *MinecraftServerService) pingServer(ctx context.Context, state *ContainerState) (*models.ServerPing, error) {
+	if state.IPAddress == "" {
+		return nil, errors.New("container has no network address")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, serverPingTimeout)
+	defer cancel()
+	return pingServerList(ctx, net.JoinHostPort(state.IPAddress, "25565"))
+}
+
+// HealthChecker pings starting and running servers, so a server is reported as running as soon as it accepts
+// logins and the proxy only sends players to servers that do
+type HealthChecker struct {
+	mcService *MinecraftServerService
+	interval  time.Duration
+	logger    *slog.Logger
+}
+
+// NewHealthChecker creates a new health checker pinging every interval
+func NewHealthChecker(mcService *MinecraftServerService, interval time.Duration, logger *slog.Logger) *HealthChecker {
+	return &HealthChecker{
+		mcService: mcService,
+		interval:  interval,
+		logger:    logger,
+	}
+}
+
+// Run pings the servers on every tick until the context is cancelled
+func (h *HealthChecker) Run(ctx context.Context) {
+	h.logger.InfoContext(ctx, "Health checker started", "interval", h.interval.String())
+
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			h.logger.InfoContext(ctx, "Health checker stopped")
+			return
+		case <-ticker.C:
+			h.checkAll(ctx)
+		}
+	}
+}
+
+// checkAll pings the servers that are starting or running and reconciles them with the result
+func (h *HealthChecker) checkAll(ctx context.Context) {
+	servers, err := h.mcService.ListServers(ctx)
+	if err != nil {
+		h.logger.WarnContext(ctx, "Failed to list servers for health check", "error", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		if server.Status != models.StatusStarting && server.Status != models.StatusRunning {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := h.mcService.checkServer(ctx, server); err != nil {
+				h.logger.WarnContext(ctx, "Failed to ping server", "server_id", server.ID, "error", err)
+			}
+			if err := h.mcService.ReconcileServer(ctx, server.ID); err != nil {
+				h.logger.WarnContext(ctx, "Failed to check server health", "server_id", server.ID, "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
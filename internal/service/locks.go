@@ -0,0 +1,76 @@
+package service
+
+import (
+	"sync"
+)
+
+// keyedMutex serializes operations per key, e.g. per server ID, so the reconciler
+// and API operations never modify the same server concurrently
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+	busy  map[string]string // Keys whose holder keeps them for a long time, with what it is doing
+}
+
+// newKeyedMutex creates a new keyed mutex
+func newKeyedMutex() *keyedMutex {
+	return &keyedMutex{
+		locks: make(map[string]*sync.Mutex),
+		busy:  make(map[string]string),
+	}
+}
+
+// get returns the mutex for a key, creating it if needed
+func (m *keyedMutex) get(key string) *sync.Mutex {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lock, ok := m.locks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		m.locks[key] = lock
+	}
+	return lock
+}
+
+// Lock blocks until the key is free and returns the unlock function
+func (m *keyedMutex) Lock(key string) func() {
+	lock := m.get(key)
+	lock.Lock()
+	return lock.Unlock
+}
+
+// TryLock locks the key if it is free. The returned bool reports whether the lock was acquired.
+func (m *keyedMutex) TryLock(key string) (func(), bool) {
+	lock := m.get(key)
+	if !lock.TryLock() {
+		return nil, false
+	}
+	return lock.Unlock, true
+}
+
+// LockOrConflict locks the key like Lock, but fails with a conflict error instead of waiting
+// while the holder of the key marked it busy
+func (m *keyedMutex) LockOrConflict(key string) (func(), error) {
+	m.mu.Lock()
+	reason, busy := m.busy[key]
+	m.mu.Unlock()
+	if busy {
+		return nil, conflictError("%s, try again when it's done", reason)
+	}
+	return m.Lock(key), nil
+}
+
+// MarkBusy marks a held key as busy for a long time, e.g. during the countdown of a stop, so
+// LockOrConflict fails right away. The returned function clears the mark.
+func (m *keyedMutex) MarkBusy(key, reason string) func() {
+	m.mu.Lock()
+	m.busy[key] = reason
+	m.mu.Unlock()
+
+	return func() {
+		m.mu.Lock()
+		delete(m.busy, key)
+		m.mu.Unlock()
+	}
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyedMutexLockOrConflict(t *testing.T) {
+	locks := newKeyedMutex()
+
+	unlock := locks.Lock("server")
+	done := locks.MarkBusy("server", "a countdown before the server stops is running")
+
+	_, err := locks.LockOrConflict("server")
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.ErrorContains(t, err, "countdown")
+
+	// Other keys aren't affected
+	other, err := locks.LockOrConflict("other")
+	require.NoError(t, err)
+	other()
+
+	done()
+	unlock()
+
+	unlock, err = locks.LockOrConflict("server")
+	require.NoError(t, err)
+	unlock()
+}
@@ -1,24 +1,49 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/google/uuid"
 	"github.com/mlhmz/dockermc-cloud-manager/internal/database"
 	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
 )
 
+const (
+	MinecraftImage = "itzg/minecraft-server:latest"
+)
+
 // MinecraftServerService manages Minecraft server lifecycle
 type MinecraftServerService struct {
 	dockerService *DockerService
 	repo          *database.ServerRepository
 	proxyService  *ProxyService
+	createProxy   bool // Whether servers behind the proxy create it if it doesn't exist yet
+	portAllocator *PortAllocator
+	events        *EventBus
+	crashReports  *database.CrashReportRepository
+	crashStops    sync.Map // IDs of servers whose crashed container is being stopped
+	pings         *pingCache
+	pingEnabled   bool
+	pingWarning   sync.Once // Warns once that the containers can't be reached for the ping
+	rcon          *rconPool
+	secrets       *SecretBox
+	locks         *keyedMutex
 	logger        *slog.Logger
+
+	backupDestinations []string // Configured backup destinations servers may choose from, any if nil
 }
 
 // NewMinecraftServerService creates a new Minecraft server service
@@ -26,6 +51,10 @@ func NewMinecraftServerService(dockerService *DockerService, repo *database.Serv
 	return &MinecraftServerService{
 		dockerService: dockerService,
 		repo:          repo,
+		createProxy:   true,
+		pings:         newPingCache(),
+		rcon:          newRCONPool(),
+		locks:         newKeyedMutex(),
 		logger:        logger,
 	}
 }
@@ -35,18 +64,68 @@ func (s *MinecraftServerService) SetProxyService(proxyService *ProxyService) {
 	s.proxyService = proxyService
 }
 
+// SetCreateProxy sets whether servers behind the proxy create it if it doesn't exist yet. Without it, servers
+// only join a proxy that was already created, so the CLI doesn't start a proxy container as a side effect.
+func (s *MinecraftServerService) SetCreateProxy(enabled bool) {
+	s.createProxy = enabled
+}
+
+// SetPortAllocator sets the allocator used for servers that are exposed directly
+func (s *MinecraftServerService) SetPortAllocator(portAllocator *PortAllocator) {
+	s.portAllocator = portAllocator
+}
+
+// SetBackupDestinations sets the names of the configured backup destinations servers may choose from
+func (s *MinecraftServerService) SetBackupDestinations(names []string) {
+	s.backupDestinations = names
+}
+
+// validateBackupDestination checks that the backup destination of a server is configured
+func (s *MinecraftServerService) validateBackupDestination(name string) error {
+	if name == "" || s.backupDestinations == nil || slices.Contains(s.backupDestinations, name) {
+		return nil
+	}
+	return validationError("backup_destination %q is not configured, must be one of %s", name, strings.Join(s.backupDestinations, ", "))
+}
+
+// SetEventBus sets the bus that server changes are published on
+func (s *MinecraftServerService) SetEventBus(events *EventBus) {
+	s.events = events
+}
+
+// ValidateCreateServerRequest normalizes and checks a create request,
+// so invalid requests can be rejected before the creation runs in the background
+func (s *MinecraftServerService) ValidateCreateServerRequest(req *models.CreateServerRequest) error {
+	req.Type = models.ServerType(strings.ToUpper(string(req.Type)))
+	if err := s.validateBackupDestination(req.BackupDestination); err != nil {
+		return err
+	}
+	return validateCreateServerRequest(req, s.behindProxy(req.ExposeDirectly))
+}
+
 // CreateServer creates a new Minecraft server
 func (s *MinecraftServerService) CreateServer(ctx context.Context, req *models.CreateServerRequest) (*models.MinecraftServer, error) {
+	if err := s.ValidateCreateServerRequest(req); err != nil {
+		return nil, err
+	}
+
 	// Generate unique ID
 	serverID := uuid.New().String()
+	reportServerID(ctx, serverID)
+
+	// Keep the reconciler away until the server is fully set up
+	unlock := s.locks.Lock(serverID)
+	defer unlock()
 
 	s.logger.InfoContext(ctx, "Creating new Minecraft server",
 		"server_id", serverID,
 		"server_name", req.Name,
 		"max_players", req.MaxPlayers,
-		"version", req.Version)
+		"version", req.Version,
+		"type", req.Type)
 
 	// Create volume for persistent storage
+	reportStep(ctx, "Creating volume", 5)
 	volumeName := fmt.Sprintf("mc-server-%s", serverID)
 	vol, err := s.dockerService.client.VolumeCreate(ctx, volume.CreateOptions{
 		Name: volumeName,
@@ -83,68 +162,304 @@ func (s *MinecraftServerService) CreateServer(ctx context.Context, req *models.C
 		version = "LATEST"
 	}
 
+	serverType := req.Type
+	if serverType == "" {
+		serverType = models.DefaultServerType
+	}
+
 	// Pull the image if it doesn't exist
-	imageName := "itzg/minecraft-server:latest"
-	if err := s.dockerService.PullImage(ctx, imageName); err != nil {
+	reportStep(ctx, "Pulling image", 10)
+	pullCtx := WithPullProgress(ctx, func(pull *models.ImagePull) {
+		// The pull covers the operation progress from 10 to 60 percent
+		reportProgress(ctx, 10+int(pull.Percent/2))
+	})
+	if err := s.dockerService.PullImage(pullCtx, MinecraftImage); err != nil {
 		// Cleanup volume on failure
 		s.dockerService.client.VolumeRemove(ctx, vol.Name, true)
 		s.logger.ErrorContext(ctx, "Failed to pull Docker image",
 			"server_id", serverID,
 			"server_name", req.Name,
-			"image", imageName,
+			"image", MinecraftImage,
 			"error", err)
 		return nil, fmt.Errorf("failed to pull image: %w", err)
 	}
 
+	// Create server model
+	server := &models.MinecraftServer{
+		ID:             serverID,
+		Name:           req.Name,
+		VolumeID:       vol.Name,
+		Status:         models.StatusCreating,
+		MaxPlayers:     maxPlayers,
+		MOTD:           motd,
+		Version:        version,
+		Type:           serverType,
+		LoaderVersion:  req.LoaderVersion,
+		MemoryMB:       req.MemoryMB,
+		CPULimit:       req.CPULimit,
+		JVMHeapMB:      req.JVMHeapMB,
+		ExposeDirectly: req.ExposeDirectly,
+		Env:            req.Env,
+	}
+	if req.CrashPolicy != nil {
+		server.CrashPolicy = *req.CrashPolicy
+	}
+	server.BackupDestination = req.BackupDestination
+
+	// Directly exposed servers get their own host port and bypass the proxy
+	if server.ExposeDirectly {
+		port, err := s.allocatePort(ctx, serverID)
+		if err != nil {
+			// Cleanup volume on failure
+			s.dockerService.client.VolumeRemove(ctx, vol.Name, true)
+			s.logger.ErrorContext(ctx, "Failed to allocate host port",
+				"server_id", serverID,
+				"server_name", req.Name,
+				"error", err)
+			return nil, err
+		}
+		server.Port = port
+	}
+
 	// Check if proxy exists to determine if we should configure for proxy mode
-	hasProxy := false
-	if s.proxyService != nil {
-		if _, err := s.proxyService.EnsureProxyExists(ctx); err == nil {
-			hasProxy = true
-			s.logger.InfoContext(ctx, "Configuring server for proxy mode",
-				"server_id", serverID)
+	reportStep(ctx, "Preparing proxy", 60)
+	hasProxy := !server.ExposeDirectly && s.proxyModeEnabled(ctx)
+	if hasProxy {
+		s.logger.InfoContext(ctx, "Configuring server for proxy mode",
+			"server_id", serverID)
+	}
+
+	reportStep(ctx, "Creating container", 75)
+	containerID, err := s.createContainer(ctx, server, hasProxy)
+	if err != nil {
+		// Cleanup volume and port on failure
+		s.dockerService.client.VolumeRemove(ctx, vol.Name, true)
+		s.releasePort(ctx, server)
+		s.logger.ErrorContext(ctx, "Failed to create Docker container",
+			"server_id", serverID,
+			"server_name", req.Name,
+			"error", err)
+		return nil, fmt.Errorf("failed to create container: %w", err)
+	}
+	server.ContainerID = containerID
+
+	// If configured for proxy, create the forwarding files in the volume BEFORE saving to database
+	// This needs to happen before the container starts
+	if hasProxy {
+		reportStep(ctx, "Writing forwarding files", 80)
+		s.logger.DebugContext(ctx, "Creating forwarding files for proxy compatibility",
+			"server_id", serverID,
+			"type", serverType)
+
+		if err := s.writeForwardingFilesToVolume(ctx, server); err != nil {
+			// Cleanup on failure
+			s.dockerService.client.ContainerRemove(ctx, containerID, container.RemoveOptions{Force: true})
+			s.dockerService.client.VolumeRemove(ctx, vol.Name, true)
+			s.releasePort(ctx, server)
+			s.logger.ErrorContext(ctx, "Failed to create forwarding files",
+				"server_id", serverID,
+				"error", err)
+			return nil, fmt.Errorf("failed to create forwarding files: %w", err)
 		}
 	}
 
-	// Create container configuration
+	// Save to database
+	reportStep(ctx, "Saving server", 90)
+	s.logger.DebugContext(ctx, "Saving server to database",
+		"server_id", serverID,
+		"server_name", req.Name)
+
+	if err := s.repo.Create(server); err != nil {
+		// Cleanup container, volume and port on failure
+		s.dockerService.client.ContainerRemove(ctx, containerID, container.RemoveOptions{Force: true})
+		s.dockerService.client.VolumeRemove(ctx, vol.Name, true)
+		s.releasePort(ctx, server)
+		s.logger.ErrorContext(ctx, "Failed to save server to database",
+			"server_id", serverID,
+			"server_name", req.Name,
+			"error", err)
+		return nil, fmt.Errorf("failed to save server to database: %w", err)
+	}
+
+	// Auto-connect server to proxy if proxy service is available
+	if hasProxy {
+		reportStep(ctx, "Connecting to proxy", 95)
+		s.connectToProxy(ctx, server)
+	}
+
+	s.logger.InfoContext(ctx, "Server created successfully",
+		"server_id", serverID,
+		"server_name", req.Name,
+		"container_id", containerID)
+
+	s.events.Publish(models.EventServerCreated, server.ID, server)
+
+	return server, nil
+}
+
+// proxyModeEnabled ensures the proxy exists and reports whether servers should be configured for proxy forwarding
+func (s *MinecraftServerService) proxyModeEnabled(ctx context.Context) bool {
+	if s.proxyService == nil {
+		return false
+	}
+	return s.ensureProxy(ctx) == nil
+}
+
+// behindProxy reports whether a server would be configured for proxy forwarding, without creating the proxy.
+// Servers that aren't exposed directly only run without a proxy if they may not create it and none exists.
+func (s *MinecraftServerService) behindProxy(exposeDirectly bool) bool {
+	if exposeDirectly || s.proxyService == nil {
+		return false
+	}
+	if s.createProxy {
+		return true
+	}
+	proxy, err := s.proxyService.proxyRepo.FindByID(models.SingleProxyID)
+	return err == nil && proxy.ContainerID != ""
+}
+
+// ensureProxy creates the proxy if it doesn't exist and servers may create it, otherwise it fails
+// if there is no proxy
+func (s *MinecraftServerService) ensureProxy(ctx context.Context) error {
+	if s.createProxy {
+		_, err := s.proxyService.EnsureProxyExists(ctx)
+		return err
+	}
+
+	proxy, err := s.proxyService.proxyRepo.FindByID(models.SingleProxyID)
+	if err != nil {
+		return err
+	}
+	if proxy.ContainerID == "" {
+		return database.ErrProxyNotFound
+	}
+	return nil
+}
+
+// connectToProxy connects a server to the proxy network and regenerates the proxy config.
+// Failures are only logged because a server can still function standalone.
+func (s *MinecraftServerService) connectToProxy(ctx context.Context, server *models.MinecraftServer) {
+	if s.proxyService == nil {
+		return
+	}
+
+	// Ensure proxy exists
+	if err := s.ensureProxy(ctx); err != nil {
+		s.logger.WarnContext(ctx, "Failed to connect server to proxy",
+			"server_id", server.ID,
+			"error", err)
+		return
+	}
+
+	// Connect server to proxy network
+	if err := s.proxyService.ConnectServerToProxy(ctx, server); err != nil {
+		s.logger.WarnContext(ctx, "Failed to connect server to proxy network",
+			"server_id", server.ID,
+			"error", err)
+		return
+	}
+
+	s.logger.InfoContext(ctx, "Connected server to proxy network",
+		"server_id", server.ID)
+
+	// Regenerate proxy config to include new server
+	if err := s.proxyService.RegenerateProxyConfig(ctx); err != nil {
+		s.logger.WarnContext(ctx, "Failed to regenerate proxy config",
+			"server_id", server.ID,
+			"error", err)
+	}
+}
+
+// refreshProxyConfig regenerates the proxy config if a proxy exists, e.g. after a server left the proxy
+func (s *MinecraftServerService) refreshProxyConfig(ctx context.Context) {
+	if s.proxyService == nil {
+		return
+	}
+	if err := s.proxyService.RegenerateProxyConfig(ctx); err != nil && !errors.Is(err, database.ErrProxyNotFound) {
+		s.logger.WarnContext(ctx, "Failed to regenerate proxy config", "error", err)
+	}
+}
+
+// allocatePort reserves a host port for a directly exposed server
+func (s *MinecraftServerService) allocatePort(ctx context.Context, serverID string) (int, error) {
+	if s.portAllocator == nil {
+		return 0, fmt.Errorf("direct port publishing is not configured")
+	}
+	return s.portAllocator.Allocate(ctx, serverID)
+}
+
+// releasePort frees the host port of a directly exposed server. Failures are only logged.
+func (s *MinecraftServerService) releasePort(ctx context.Context, server *models.MinecraftServer) {
+	if !server.ExposeDirectly || s.portAllocator == nil {
+		return
+	}
+	if err := s.portAllocator.Release(ctx, server.ID); err != nil {
+		s.logger.WarnContext(ctx, "Failed to release host port",
+			"server_id", server.ID,
+			"port", server.Port,
+			"error", err)
+	}
+}
+
+// buildContainerConfig builds the Docker container configuration for a server
+func (s *MinecraftServerService) buildContainerConfig(server *models.MinecraftServer, hasProxy bool, rconPassword string) (*container.Config, *container.HostConfig) {
 	env := []string{
 		"EULA=TRUE",
-		fmt.Sprintf("MAX_PLAYERS=%d", maxPlayers),
-		fmt.Sprintf("MOTD=%s", motd),
-		fmt.Sprintf("VERSION=%s", version),
-		"TYPE=PAPER",
+		fmt.Sprintf("RCON_PASSWORD=%s", rconPassword),
+		fmt.Sprintf("MAX_PLAYERS=%d", server.MaxPlayers),
+		fmt.Sprintf("MOTD=%s", server.MOTD),
+		fmt.Sprintf("VERSION=%s", server.Version),
 	}
+	env = append(env, serverTypeEnv(server.Type, server.LoaderVersion)...)
+	env = append(env, resourceEnv(server)...)
 
 	// Configure for legacy BungeeCord/Velocity forwarding if proxy exists
 	if hasProxy {
-		env = append(env,
-			"ONLINE_MODE=FALSE",               // Must be false when behind proxy
-			"PATCH_DEFINITIONS=/data/patches", // Directory containing patch definitions in volume
-		)
+		env = append(env, proxyForwardingEnv(server.Type)...)
 	}
 
+	// Overrides enable itzg features the manager doesn't know about, its own variables take precedence
+	env = mergeEnv(env, server.Env)
+
+	exposedPorts, bindings := portBindings(server)
+
 	containerConfig := &container.Config{
-		Image: imageName,
+		Image: MinecraftImage,
 		Env:   env,
 		Labels: map[string]string{
-			"minecraft-server-id":   serverID,
-			"minecraft-server-name": req.Name,
+			serverIDLabel:           server.ID,
+			"minecraft-server-name": server.Name,
 		},
+		ExposedPorts: exposedPorts,
 	}
 
 	hostConfig := &container.HostConfig{
 		Binds: []string{
-			fmt.Sprintf("%s:/data", vol.Name),
+			fmt.Sprintf("%s:/data", server.VolumeID),
 		},
+		PortBindings: bindings,
 		RestartPolicy: container.RestartPolicy{
 			Name: "unless-stopped",
 		},
+		Resources: containerResources(server),
 	}
 
-	// Create container
+	return containerConfig, hostConfig
+}
+
+// createContainer creates the Docker container for a server and returns its ID
+func (s *MinecraftServerService) createContainer(ctx context.Context, server *models.MinecraftServer, hasProxy bool) (string, error) {
+	rconPassword, err := s.containerRCONPassword(ctx, server)
+	if err != nil {
+		return "", err
+	}
+
+	containerConfig, hostConfig := s.buildContainerConfig(server, hasProxy, rconPassword)
+	containerName := fmt.Sprintf("mc-server-%s", server.ID)
+
 	s.logger.DebugContext(ctx, "Creating Docker container",
-		"server_id", serverID,
-		"container_name", fmt.Sprintf("mc-server-%s", serverID))
+		"server_id", server.ID,
+		"container_name", containerName)
 
 	resp, err := s.dockerService.client.ContainerCreate(
 		ctx,
@@ -152,327 +467,655 @@ func (s *MinecraftServerService) CreateServer(ctx context.Context, req *models.C
 		hostConfig,
 		nil,
 		nil,
-		fmt.Sprintf("mc-server-%s", serverID),
+		containerName,
 	)
 	if err != nil {
-		// Cleanup volume on failure
-		s.dockerService.client.VolumeRemove(ctx, vol.Name, true)
-		s.logger.ErrorContext(ctx, "Failed to create Docker container",
-			"server_id", serverID,
-			"server_name", req.Name,
-			"error", err)
-		return nil, fmt.Errorf("failed to create container: %w", err)
+		return "", err
 	}
 
 	s.logger.DebugContext(ctx, "Created Docker container",
-		"server_id", serverID,
+		"server_id", server.ID,
 		"container_id", resp.ID)
 
-	// Create server model
-	server := &models.MinecraftServer{
-		ID:          serverID,
-		Name:        req.Name,
-		ContainerID: resp.ID,
-		VolumeID:    vol.Name,
-		Status:      models.StatusCreating,
-		MaxPlayers:  maxPlayers,
-		MOTD:        motd,
+	return resp.ID, nil
+}
+
+// recreateContainer replaces the container of a server with one built from the current server model.
+// The volume is kept, so the world survives. The new container is started if the old one was running.
+func (s *MinecraftServerService) recreateContainer(ctx context.Context, server *models.MinecraftServer) error {
+	state, err := s.dockerService.GetContainerState(ctx, server.ContainerID)
+	if err != nil {
+		return fmt.Errorf("failed to get container state: %w", err)
 	}
+	wasRunning := state.Running || state.Restarting
 
-	// If configured for proxy, create the patch file in the volume BEFORE saving to database
-	// This needs to happen before the container starts
-	if hasProxy {
-		s.logger.DebugContext(ctx, "Creating BungeeCord patch file for proxy compatibility",
-			"server_id", serverID)
+	// Servers created before the version and type were stored ran the latest Paper
+	if server.Version == "" {
+		server.Version = "LATEST"
+	}
+	if server.Type == "" {
+		server.Type = models.ServerTypePaper
+	}
 
-		if err := s.createBungeeCordPatchFileInVolume(ctx, vol.Name); err != nil {
-			// Cleanup on failure
-			s.dockerService.client.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
-			s.dockerService.client.VolumeRemove(ctx, vol.Name, true)
-			s.logger.ErrorContext(ctx, "Failed to create BungeeCord patch file",
-				"server_id", serverID,
+	s.logger.InfoContext(ctx, "Recreating server container",
+		"server_id", server.ID,
+		"server_name", server.Name,
+		"container_id", server.ContainerID,
+		"was_running", wasRunning)
+
+	if state.Exists {
+		s.rcon.close(server.ID)
+		timeout := 30
+		s.dockerService.client.ContainerStop(ctx, server.ContainerID, container.StopOptions{
+			Timeout: &timeout,
+		})
+		if err := s.dockerService.client.ContainerRemove(ctx, server.ContainerID, container.RemoveOptions{
+			Force: true,
+		}); err != nil {
+			return fmt.Errorf("failed to remove container: %w", err)
+		}
+	}
+
+	hasProxy := !server.ExposeDirectly && s.proxyModeEnabled(ctx)
+	if hasProxy {
+		// The proxy may have been created after this server, so make sure the forwarding files are present
+		if err := s.writeForwardingFilesToVolume(ctx, server); err != nil {
+			s.logger.WarnContext(ctx, "Failed to create forwarding files",
+				"server_id", server.ID,
 				"error", err)
-			return nil, fmt.Errorf("failed to create patch file: %w", err)
 		}
 	}
 
-	// Save to database
-	s.logger.DebugContext(ctx, "Saving server to database",
-		"server_id", serverID,
-		"server_name", req.Name)
+	containerID, err := s.createContainer(ctx, server, hasProxy)
+	if err != nil {
+		// The old container is gone, persist that so the server doesn't point at it anymore
+		server.ContainerID = ""
+		server.Status = models.StatusError
+		if updateErr := s.repo.Update(server); updateErr != nil {
+			s.logger.ErrorContext(ctx, "Failed to update server in database",
+				"server_id", server.ID,
+				"error", updateErr)
+		}
+		return fmt.Errorf("failed to create container: %w", err)
+	}
 
-	if err := s.repo.Create(server); err != nil {
-		// Cleanup container and volume on failure
-		s.dockerService.client.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
-		s.dockerService.client.VolumeRemove(ctx, vol.Name, true)
-		s.logger.ErrorContext(ctx, "Failed to save server to database",
-			"server_id", serverID,
-			"server_name", req.Name,
-			"error", err)
-		return nil, fmt.Errorf("failed to save server to database: %w", err)
+	server.ContainerID = containerID
+	server.Status = models.StatusStopped
+	if err := s.repo.Update(server); err != nil {
+		return fmt.Errorf("failed to save server to database: %w", err)
 	}
 
-	// Auto-connect server to proxy if proxy service is available
-	if s.proxyService != nil {
-		// Ensure proxy exists
-		if _, err := s.proxyService.EnsureProxyExists(ctx); err != nil {
-			// Log error but don't fail server creation
-			// Server can still function standalone
-			s.logger.WarnContext(ctx, "Failed to connect server to proxy",
-				"server_id", serverID,
-				"error", err)
-		} else {
-			// Connect server to proxy network
-			if err := s.proxyService.ConnectServerToProxy(ctx, server); err != nil {
-				// Log error but don't fail server creation
-				s.logger.WarnContext(ctx, "Failed to connect server to proxy network",
-					"server_id", serverID,
-					"error", err)
-			} else {
-				s.logger.InfoContext(ctx, "Connected server to proxy network",
-					"server_id", serverID)
-
-				// Regenerate proxy config to include new server
-				if err := s.proxyService.RegenerateProxyConfig(ctx); err != nil {
-					s.logger.WarnContext(ctx, "Failed to regenerate proxy config",
-						"server_id", serverID,
-						"error", err)
-				}
-			}
+	if hasProxy {
+		s.connectToProxy(ctx, server)
+	} else {
+		// The server may have been behind the proxy before
+		s.refreshProxyConfig(ctx)
+	}
+
+	if wasRunning {
+		if err := s.dockerService.client.ContainerStart(ctx, containerID, container.StartOptions{}); err != nil {
+			return fmt.Errorf("failed to start container: %w", err)
+		}
+		// The server counts as running once it answers the ping
+		server.Status = models.StatusStarting
+		if err := s.repo.Update(server); err != nil {
+			return fmt.Errorf("failed to save server to database: %w", err)
 		}
 	}
 
-	s.logger.InfoContext(ctx, "Server created successfully",
-		"server_id", serverID,
-		"server_name", req.Name,
-		"container_id", resp.ID)
+	s.logger.InfoContext(ctx, "Server container recreated",
+		"server_id", server.ID,
+		"container_id", containerID)
 
-	return server, nil
+	return nil
 }
 
-// createBungeeCordPatchFileInVolume creates a patch definition file in the volume using a temporary container
-func (s *MinecraftServerService) createBungeeCordPatchFileInVolume(ctx context.Context, volumeName string) error {
-	// Pull alpine image if not present
-	alpineImage := "alpine:latest"
-	if err := s.dockerService.PullImage(ctx, alpineImage); err != nil {
-		return fmt.Errorf("failed to pull alpine image: %w", err)
-	}
-
-	// Patch definition to enable bungeecord in spigot.yml
-	// Note: Each file in the patches directory is a single patch, not an array
-	patchContent := `{
-  "file": "/data/spigot.yml",
-  "ops": [
-    {
-      "$set": {
-        "path": "$.settings.bungeecord",
-        "value": true,
-        "value-type": "bool"
-      }
-    }
-  ]
-}`
-
-	// Use a temporary alpine container to write the file to the volume
-	tempContainerConfig := &container.Config{
-		Image: "alpine:latest",
-		Cmd: []string{"sh", "-c", fmt.Sprintf(`
-mkdir -p /data/patches && cat > /data/patches/bungeecord.json << 'PATCHEOF'
-%s
-PATCHEOF
-`, patchContent)},
-	}
-
-	tempHostConfig := &container.HostConfig{
-		Binds: []string{
-			fmt.Sprintf("%s:/data", volumeName),
-		},
+// ValidateUpdateServerRequest checks an update request before it is run in the background.
+// Limits and overrides are checked again by UpdateServer against the merged server settings.
+func (s *MinecraftServerService) ValidateUpdateServerRequest(req *models.UpdateServerRequest) error {
+	if req.BackupDestination != nil {
+		if err := s.validateBackupDestination(*req.BackupDestination); err != nil {
+			return err
+		}
 	}
+	return validateUpdateServerRequest(req)
+}
 
-	// Create temporary container
-	tempResp, err := s.dockerService.client.ContainerCreate(ctx, tempContainerConfig, tempHostConfig, nil, nil, "")
+// UpdateServer applies changes to a server and recreates its container with the new configuration
+func (s *MinecraftServerService) UpdateServer(ctx context.Context, id string, req *models.UpdateServerRequest) (*models.MinecraftServer, error) {
+	if err := s.ValidateUpdateServerRequest(req); err != nil {
+		return nil, err
+	}
+
+	unlock, err := s.locks.LockOrConflict(id)
 	if err != nil {
-		return fmt.Errorf("failed to create temp container: %w", err)
+		return nil, err
 	}
-	defer s.dockerService.client.ContainerRemove(ctx, tempResp.ID, container.RemoveOptions{Force: true})
+	defer unlock()
 
-	// Start and wait for the temp container to finish
-	if err := s.dockerService.client.ContainerStart(ctx, tempResp.ID, container.StartOptions{}); err != nil {
-		return fmt.Errorf("failed to start temp container: %w", err)
+	server, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
 	}
 
-	// Wait for container to finish
-	statusCh, errCh := s.dockerService.client.ContainerWait(ctx, tempResp.ID, container.WaitConditionNotRunning)
-	select {
-	case err := <-errCh:
-		if err != nil {
-			return fmt.Errorf("error waiting for temp container: %w", err)
+	if req.MaxPlayers != nil {
+		server.MaxPlayers = *req.MaxPlayers
+	}
+	if req.MOTD != nil {
+		server.MOTD = *req.MOTD
+	}
+	if req.MemoryMB != nil {
+		server.MemoryMB = *req.MemoryMB
+	}
+	if req.CPULimit != nil {
+		server.CPULimit = *req.CPULimit
+	}
+	if req.JVMHeapMB != nil {
+		server.JVMHeapMB = *req.JVMHeapMB
+	}
+
+	// Validate the merged limits since the heap has to fit the resulting container limit
+	if err := validateResources(server.MemoryMB, server.CPULimit, server.JVMHeapMB); err != nil {
+		return nil, err
+	}
+
+	// Validate the merged overrides since ONLINE_MODE depends on whether the server is behind the proxy
+	exposeDirectly := server.ExposeDirectly
+	if req.ExposeDirectly != nil {
+		exposeDirectly = *req.ExposeDirectly
+	}
+	applyEnvUpdate(server, req.Env)
+	if err := validateEnvOverrides(server.Env, s.behindProxy(exposeDirectly)); err != nil {
+		return nil, err
+	}
+
+	// The previous port stays reserved until the new container exists
+	previous := *server
+	exposureChanged := req.ExposeDirectly != nil && *req.ExposeDirectly != server.ExposeDirectly
+	if exposureChanged {
+		if *req.ExposeDirectly {
+			port, err := s.allocatePort(ctx, server.ID)
+			if err != nil {
+				return nil, err
+			}
+			server.Port = port
+		} else {
+			server.Port = 0
 		}
-	case <-statusCh:
+		server.ExposeDirectly = *req.ExposeDirectly
 	}
 
-	return nil
+	if req.CrashPolicy != nil {
+		server.CrashPolicy = *req.CrashPolicy
+	}
+	if req.BackupDestination != nil {
+		server.BackupDestination = *req.BackupDestination
+	}
+
+	// The new container starts without the crash history of the old one
+	server.Crashes = 0
+	server.CrashWindowStart = nil
+	server.NextRestartAt = nil
+
+	s.logger.InfoContext(ctx, "Updating server",
+		"server_id", server.ID,
+		"server_name", server.Name,
+		"max_players", server.MaxPlayers,
+		"motd", server.MOTD,
+		"memory_mb", server.MemoryMB,
+		"cpu_limit", server.CPULimit,
+		"jvm_heap_mb", server.JVMHeapMB,
+		"expose_directly", server.ExposeDirectly,
+		"port", server.Port,
+		"env_keys", len(server.Env))
+
+	if err := s.recreateContainer(ctx, server); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to recreate server container",
+			"server_id", server.ID,
+			"error", err)
+		if exposureChanged {
+			s.settleUpdatedPort(ctx, &previous, server)
+		}
+		return nil, err
+	}
+
+	// Only directly exposed servers hold a port, so this releases the port of a server moved behind the proxy
+	if exposureChanged {
+		s.releasePort(ctx, &previous)
+	}
+
+	s.logger.InfoContext(ctx, "Server updated successfully",
+		"server_id", server.ID,
+		"server_name", server.Name)
+
+	s.events.Publish(models.EventServerUpdated, server.ID, server)
+
+	return server, nil
+}
+
+// settleUpdatedPort releases the port a failed update no longer needs. The update may have failed before or
+// after the server was saved with the new settings, so the port follows the stored settings.
+func (s *MinecraftServerService) settleUpdatedPort(ctx context.Context, previous, updated *models.MinecraftServer) {
+	stored, err := s.repo.FindByID(updated.ID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "Failed to load server to release its host port",
+			"server_id", updated.ID,
+			"error", err)
+		return
+	}
+	if stored.ExposeDirectly == updated.ExposeDirectly {
+		// Saved with the new settings, a server that isn't exposed anymore doesn't need its old port
+		s.releasePort(ctx, previous)
+	} else {
+		// Still stored with the old settings, the newly allocated port isn't used
+		s.releasePort(ctx, updated)
+	}
 }
 
-// syncServerState checks Docker container state and updates database if needed
-func (s *MinecraftServerService) syncServerState(ctx context.Context, server *models.MinecraftServer) error {
+// validateCreateServerRequest checks the values of a create request for a server that is behind the proxy or not
+func validateCreateServerRequest(req *models.CreateServerRequest, behindProxy bool) error {
+	if req.Type != "" && !req.Type.IsSupported() {
+		return validationError("type must be one of %v", models.SupportedServerTypes)
+	}
+	if req.LoaderVersion != "" && !req.Type.HasModLoader() {
+		return validationError("loader_version is only supported for FABRIC, FORGE and NEOFORGE")
+	}
+	if err := validateEnvOverrides(req.Env, behindProxy); err != nil {
+		return err
+	}
+	if err := validateCrashPolicy(req.CrashPolicy); err != nil {
+		return err
+	}
+	return validateResources(req.MemoryMB, req.CPULimit, req.JVMHeapMB)
+}
+
+// validateUpdateServerRequest checks the values of an update request
+func validateUpdateServerRequest(req *models.UpdateServerRequest) error {
+	if req.MaxPlayers != nil && (*req.MaxPlayers < 1 || *req.MaxPlayers > 1000) {
+		return validationError("max_players must be between 1 and 1000")
+	}
+	if req.MOTD != nil && len(*req.MOTD) > 255 {
+		return validationError("motd must be at most 255 characters")
+	}
+	return validateCrashPolicy(req.CrashPolicy)
+}
+
+// setStatus stores a status the manager sets itself, e.g. while stopping a server. Failures are only logged,
+// the next reconciliation corrects the status anyway. The caller must hold the server lock.
+func (s *MinecraftServerService) setStatus(ctx context.Context, server *models.MinecraftServer, status models.ContainerStatus) {
+	if server.Status == status {
+		return
+	}
+
+	previousStatus := server.Status
+	server.Status = status
+	if err := s.repo.UpdateState(server); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to update server state in database",
+			"server_id", server.ID,
+			"error", err)
+		return
+	}
+
+	s.events.Publish(models.EventServerStatusChanged, server.ID, models.StatusChange{
+		PreviousStatus: previousStatus,
+		Status:         status,
+	})
+}
+
+// reconcileServerState compares a server with its Docker container and fixes the stored state.
+// The caller must hold the server lock.
+func (s *MinecraftServerService) reconcileServerState(ctx context.Context, server *models.MinecraftServer) error {
 	// Get container state from Docker
 	state, err := s.dockerService.GetContainerState(ctx, server.ContainerID)
 	if err != nil {
-		s.logger.ErrorContext(ctx, "Failed to get container state during sync",
+		s.logger.ErrorContext(ctx, "Failed to get container state during reconciliation",
 			"server_id", server.ID,
 			"server_name", server.Name,
 			"error", err)
 		return fmt.Errorf("failed to get container state: %w", err)
 	}
 
-	// Determine the new status based on container state
-	var newStatus models.ContainerStatus
-	if !state.Exists {
-		// Container doesn't exist anymore (deleted manually or crashed)
+	state, crashesChanged := s.handleCrashes(ctx, server, state)
+
+	newStatus := serverStatus(server, state)
+	if newStatus == models.StatusRunning && !s.checkReady(server) {
+		newStatus = models.StatusStarting
+	} else if !state.Running {
+		s.pings.forget(server.ID)
+	}
+	containerID := server.ContainerID
+	exitCode := server.ExitCode
+	oomKilled := server.OOMKilled
+
+	if !state.Exists && server.ContainerID != "" {
 		s.logger.WarnContext(ctx, "Container no longer exists in Docker, marking server as stopped",
 			"server_id", server.ID,
 			"server_name", server.Name,
 			"previous_status", server.Status,
 			"container_id", server.ContainerID)
-		newStatus = models.StatusStopped
-		server.ContainerID = "" // Clear the container ID
-	} else if state.Running {
-		newStatus = models.StatusRunning
-	} else if state.Restarting {
-		newStatus = models.StatusCreating
-	} else if state.Dead || state.OOMKilled {
-		newStatus = models.StatusError
-	} else {
-		// Stopped, paused, or exited
-		newStatus = models.StatusStopped
+		containerID = "" // Clear the container ID
+	}
+
+	// Exit information is only meaningful once the container stopped running. The manager stops crashed
+	// containers itself, so the exit information of the crash is kept.
+	if state.Exists && !state.Running && newStatus != models.StatusCrashLoop && server.NextRestartAt == nil {
+		exitCode = state.ExitCode
+		oomKilled = state.OOMKilled
 	}
 
-	// Update database if status changed
-	if newStatus != server.Status {
-		s.logger.InfoContext(ctx, "Server status changed, updating database",
+	if !crashesChanged && newStatus == server.Status && containerID == server.ContainerID &&
+		exitCode == server.ExitCode && oomKilled == server.OOMKilled {
+		return nil
+	}
+
+	s.logger.InfoContext(ctx, "Server state changed, updating database",
+		"server_id", server.ID,
+		"server_name", server.Name,
+		"previous_status", server.Status,
+		"new_status", newStatus,
+		"exit_code", exitCode,
+		"oom_killed", oomKilled)
+
+	previousStatus := server.Status
+	server.Status = newStatus
+	server.ContainerID = containerID
+	server.ExitCode = exitCode
+	server.OOMKilled = oomKilled
+	if err := s.repo.UpdateState(server); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to update server state in database",
 			"server_id", server.ID,
 			"server_name", server.Name,
-			"previous_status", server.Status,
-			"new_status", newStatus)
-		server.Status = newStatus
-		if err := s.repo.Update(server); err != nil {
-			s.logger.ErrorContext(ctx, "Failed to update server status in database",
-				"server_id", server.ID,
-				"server_name", server.Name,
-				"error", err)
-			return err
-		}
+			"error", err)
+		return err
+	}
+
+	if newStatus != previousStatus {
+		s.events.Publish(models.EventServerStatusChanged, server.ID, models.StatusChange{
+			PreviousStatus: previousStatus,
+			Status:         newStatus,
+		})
+	}
+
+	// The proxy only lists servers that accept logins
+	if !server.ExposeDirectly && listedInProxy(previousStatus) != listedInProxy(newStatus) {
+		s.refreshProxyConfig(ctx)
 	}
 
 	return nil
 }
 
-// ListServers returns all servers with synced state
-func (s *MinecraftServerService) ListServers(ctx context.Context) ([]*models.MinecraftServer, error) {
+// ReconcileServer reconciles a single server with Docker.
+// Servers with an operation in progress are skipped, the operation sets their state itself.
+func (s *MinecraftServerService) ReconcileServer(ctx context.Context, id string) error {
+	unlock, ok := s.locks.TryLock(id)
+	if !ok {
+		s.logger.DebugContext(ctx, "Operation in progress, skipping reconciliation", "server_id", id)
+		return nil
+	}
+	defer unlock()
+
+	server, err := s.repo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	return s.reconcileServerState(ctx, server)
+}
+
+// ReconcileServers reconciles all servers with Docker
+func (s *MinecraftServerService) ReconcileServers(ctx context.Context) error {
 	servers, err := s.repo.FindAll()
 	if err != nil {
 		s.logger.ErrorContext(ctx, "Failed to retrieve servers from database", "error", err)
-		return nil, err
+		return err
 	}
 
-	s.logger.DebugContext(ctx, "Syncing state for all servers", "count", len(servers))
+	s.logger.DebugContext(ctx, "Reconciling all servers", "count", len(servers))
 
-	// Sync state for each server
 	for _, server := range servers {
-		if err := s.syncServerState(ctx, server); err != nil {
+		if err := s.ReconcileServer(ctx, server.ID); err != nil && !errors.Is(err, database.ErrServerNotFound) {
 			// Log error but continue with other servers
-			// The server will still be returned with its last known state
-			s.logger.WarnContext(ctx, "Failed to sync server state, returning last known state",
+			s.logger.WarnContext(ctx, "Failed to reconcile server",
 				"server_id", server.ID,
 				"server_name", server.Name,
 				"error", err)
 		}
 	}
 
+	return nil
+}
+
+// ListServers returns all servers with their last reconciled state
+func (s *MinecraftServerService) ListServers(ctx context.Context) ([]*models.MinecraftServer, error) {
+	servers, err := s.repo.FindAll()
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to retrieve servers from database", "error", err)
+		return nil, err
+	}
+	for _, server := range servers {
+		s.attachPing(server)
+	}
 	return servers, nil
 }
 
-// GetServer returns a specific server by ID with synced state
+// GetServer returns a specific server by ID with its last reconciled state
 func (s *MinecraftServerService) GetServer(ctx context.Context, id string) (*models.MinecraftServer, error) {
 	server, err := s.repo.FindByID(id)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "Failed to retrieve server from database", "server_id", id, "error", err)
 		return nil, err
 	}
-
-	// Sync state before returning
-	if err := s.syncServerState(ctx, server); err != nil {
-		// Return server with last known state if sync fails
-		s.logger.WarnContext(ctx, "Failed to sync server state, returning last known state",
-			"server_id", server.ID,
-			"server_name", server.Name,
-			"error", err)
-	}
-
+	s.attachPing(server)
 	return server, nil
 }
 
 // StartServer starts a Minecraft server
 func (s *MinecraftServerService) StartServer(ctx context.Context, id string) error {
+	unlock, err := s.locks.LockOrConflict(id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	server, err := s.repo.FindByID(id)
 	if err != nil {
 		return err
 	}
 
+	// Starting by hand gives a crashed server a fresh start
+	s.resetCrashes(ctx, server)
+
+	if err := s.ensureContainer(ctx, server); err != nil {
+		return err
+	}
+
+	reportStep(ctx, "Starting container", 10)
 	if err := s.dockerService.client.ContainerStart(ctx, server.ContainerID, container.StartOptions{}); err != nil {
 		return fmt.Errorf("failed to start container: %w", err)
 	}
 
-	server.Status = models.StatusRunning
-	return s.repo.Update(server)
+	// Store what Docker reports instead of assuming the start worked
+	return s.reconcileServerState(ctx, server)
 }
 
 // StopServer stops a Minecraft server
 func (s *MinecraftServerService) StopServer(ctx context.Context, id string) error {
+	return s.StopServerGracefully(ctx, id, 0)
+}
+
+// StopServerGracefully stops a Minecraft server. With a grace period, the players of a running server see
+// a countdown, are moved to the fallback server of the proxy and the world is saved before the container stops.
+func (s *MinecraftServerService) StopServerGracefully(ctx context.Context, id string, grace time.Duration) error {
+	if err := ValidateStopGrace(grace); err != nil {
+		return err
+	}
+
+	unlock, err := s.locks.LockOrConflict(id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	server, err := s.repo.FindByID(id)
 	if err != nil {
 		return err
 	}
 
+	// A crashed server waiting for its backoff must not be started again
+	s.resetCrashes(ctx, server)
+
+	if err := s.stopContainer(ctx, server, grace, "stops"); err != nil {
+		return err
+	}
+	return s.reconcileServerState(ctx, server)
+}
+
+// RestartServer stops and starts a Minecraft server within one operation, so no status is written in between.
+// The grace period works like for StopServerGracefully, a stopped server is just started.
+func (s *MinecraftServerService) RestartServer(ctx context.Context, id string, grace time.Duration) error {
+	if err := ValidateStopGrace(grace); err != nil {
+		return err
+	}
+
+	unlock, err := s.locks.LockOrConflict(id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	server, err := s.repo.FindByID(id)
+	if err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "Restarting server",
+		"server_id", server.ID,
+		"grace", grace)
+
+	s.resetCrashes(ctx, server)
+
+	if err := s.stopContainer(ctx, server, grace, "restarts"); err != nil {
+		return err
+	}
+	if err := s.ensureContainer(ctx, server); err != nil {
+		return err
+	}
+
+	reportStep(ctx, "Starting container", 95)
+	if err := s.dockerService.client.ContainerStart(ctx, server.ContainerID, container.StartOptions{}); err != nil {
+		return errors.Join(fmt.Errorf("failed to start container: %w", err), s.reconcileServerState(context.WithoutCancel(ctx), server))
+	}
+
+	return s.reconcileServerState(ctx, server)
+}
+
+// stopContainer stops the container of a server and reports the server as stopping meanwhile, see
+// StopServerGracefully for the grace period. action describes the stop in the countdown, e.g. "restarts".
+// The caller must hold the server lock.
+func (s *MinecraftServerService) stopContainer(ctx context.Context, server *models.MinecraftServer, grace time.Duration, action string) error {
+	state, err := s.dockerService.GetContainerState(ctx, server.ContainerID)
+	if err != nil {
+		return fmt.Errorf("failed to get container state: %w", err)
+	}
+	if state.Running {
+		s.setStatus(ctx, server, models.StatusStopping)
+
+		if grace > 0 {
+			s.logger.InfoContext(ctx, "Stopping server gracefully",
+				"server_id", server.ID,
+				"grace", grace)
+			// Other operations fail instead of waiting for the countdown with the server lock held
+			done := s.locks.MarkBusy(server.ID, fmt.Sprintf("a countdown before the server %s is running", action))
+			err := s.prepareStop(ctx, server, grace, action)
+			done()
+			if err != nil {
+				// The stop was cancelled during the countdown, the server keeps running
+				return errors.Join(err, s.reconcileServerState(context.WithoutCancel(ctx), server))
+			}
+		}
+	}
+
+	if !state.Exists {
+		// Nothing to stop, e.g. the container was removed outside the manager
+		return nil
+	}
+
+	reportStep(ctx, "Stopping container", 90)
 	timeout := 30
 	if err := s.dockerService.client.ContainerStop(ctx, server.ContainerID, container.StopOptions{
 		Timeout: &timeout,
 	}); err != nil {
-		return fmt.Errorf("failed to stop container: %w", err)
+		return errors.Join(fmt.Errorf("failed to stop container: %w", err), s.reconcileServerState(context.WithoutCancel(ctx), server))
 	}
+	return nil
+}
 
-	server.Status = models.StatusStopped
-	return s.repo.Update(server)
+// ensureContainer recreates the container of a server if it was removed outside the manager,
+// so the server can be started again. The caller must hold the server lock.
+func (s *MinecraftServerService) ensureContainer(ctx context.Context, server *models.MinecraftServer) error {
+	state, err := s.dockerService.GetContainerState(ctx, server.ContainerID)
+	if err != nil {
+		return fmt.Errorf("failed to get container state: %w", err)
+	}
+	if state.Exists {
+		return nil
+	}
+
+	s.logger.WarnContext(ctx, "Server container is missing, recreating it",
+		"server_id", server.ID,
+		"container_id", server.ContainerID)
+
+	reportStep(ctx, "Recreating container", 5)
+	return s.recreateContainer(ctx, server)
 }
 
 // DeleteServer removes a Minecraft server and its resources
 func (s *MinecraftServerService) DeleteServer(ctx context.Context, id string) error {
-	server, err := s.repo.FindByID(id)
+	unlock, err := s.locks.LockOrConflict(id)
 	if err != nil {
 		return err
 	}
+	defer unlock()
 
-	// Stop container if running
-	timeout := 30
-	s.dockerService.client.ContainerStop(ctx, server.ContainerID, container.StopOptions{
-		Timeout: &timeout,
-	})
+	server, err := s.repo.FindByID(id)
+	if err != nil {
+		return err
+	}
 
-	// Remove container
-	if err := s.dockerService.client.ContainerRemove(ctx, server.ContainerID, container.RemoveOptions{
-		Force: true,
-	}); err != nil {
-		return fmt.Errorf("failed to remove container: %w", err)
+	// The container may already be gone, e.g. when it was removed outside the manager
+	if server.ContainerID != "" {
+		// Stop container if running
+		reportStep(ctx, "Stopping container", 10)
+		timeout := 30
+		s.dockerService.client.ContainerStop(ctx, server.ContainerID, container.StopOptions{
+			Timeout: &timeout,
+		})
+
+		// Remove container
+		reportStep(ctx, "Removing container", 60)
+		if err := s.dockerService.client.ContainerRemove(ctx, server.ContainerID, container.RemoveOptions{
+			Force: true,
+		}); err != nil && !errdefs.IsNotFound(err) {
+			return fmt.Errorf("failed to remove container: %w", err)
+		}
 	}
 
 	// Remove volume
-	if err := s.dockerService.client.VolumeRemove(ctx, server.VolumeID, true); err != nil {
+	reportStep(ctx, "Removing volume", 80)
+	if err := s.dockerService.client.VolumeRemove(ctx, server.VolumeID, true); err != nil && !errdefs.IsNotFound(err) {
 		return fmt.Errorf("failed to remove volume: %w", err)
 	}
 
+	// Free the host port for other servers
+	s.releasePort(ctx, server)
+	s.rcon.close(server.ID)
+
 	// Remove from database
-	return s.repo.Delete(id)
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+
+	s.events.Publish(models.EventServerDeleted, id, nil)
+	return nil
 }
 
 // GetServerLogs retrieves logs from a server's Docker container
@@ -493,8 +1136,46 @@ func (s *MinecraftServerService) GetServerLogs(ctx context.Context, containerID
 	return logs, nil
 }
 
-// ExecuteCommand executes a Minecraft command via rcon-cli in the container
-func (s *MinecraftServerService) ExecuteCommand(ctx context.Context, containerID string, command string) (string, error) {
+// suspendSaving disables automatic saving and flushes the world to disk, so the volume can be copied consistently.
+// The returned function turns saving back on and must be called once the copy is done.
+func (s *MinecraftServerService) suspendSaving(ctx context.Context, server *models.MinecraftServer) (func(), error) {
+	resume := func() {
+		// Turn saving back on even if ctx was cancelled
+		if _, err := s.ExecuteCommand(context.WithoutCancel(ctx), server, "save-on"); err != nil {
+			s.logger.ErrorContext(ctx, "Failed to re-enable world saving",
+				"server_id", server.ID,
+				"error", err)
+		}
+	}
+
+	for _, command := range []string{"save-off", "save-all flush"} {
+		if _, err := s.ExecuteCommand(ctx, server, command); err != nil {
+			resume()
+			return nil, fmt.Errorf("failed to run %q: %w", command, err)
+		}
+	}
+	return resume, nil
+}
+
+// ExecuteCommand runs a console command on a server over RCON and returns its output. Only if RCON can't be
+// reached, e.g. because the manager isn't in the minecraft network, the command runs through rcon-cli in the container.
+func (s *MinecraftServerService) ExecuteCommand(ctx context.Context, server *models.MinecraftServer, command string) (string, error) {
+	if len(command) > maxRCONCommandLength {
+		return "", validationError("command must be at most %d bytes", maxRCONCommandLength)
+	}
+
+	output, err := s.executeRCON(ctx, server, command)
+	if errors.Is(err, errRCONUnreachable) {
+		s.logger.DebugContext(ctx, "RCON unreachable, running command through rcon-cli",
+			"server_id", server.ID,
+			"error", err)
+		return s.execRCONCLI(ctx, server.ContainerID, command)
+	}
+	return output, err
+}
+
+// execRCONCLI executes a Minecraft command via rcon-cli in the container
+func (s *MinecraftServerService) execRCONCLI(ctx context.Context, containerID string, command string) (string, error) {
 	// Create exec configuration to run rcon-cli
 	execConfig := container.ExecOptions{
 		Cmd:          []string{"rcon-cli", command},
@@ -515,11 +1196,19 @@ func (s *MinecraftServerService) ExecuteCommand(ctx context.Context, containerID
 	}
 	defer attachResp.Close()
 
-	// Read the output
-	output, err := io.ReadAll(attachResp.Reader)
-	if err != nil {
+	// Read the output, stdout and stderr are multiplexed without a TTY
+	var output bytes.Buffer
+	if _, err := stdcopy.StdCopy(&output, &output, attachResp.Reader); err != nil {
 		return "", fmt.Errorf("failed to read exec output: %w", err)
 	}
 
-	return string(output), nil
+	inspectResp, err := s.dockerService.client.ContainerExecInspect(ctx, execResp.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect exec: %w", err)
+	}
+	if inspectResp.ExitCode != 0 {
+		return "", fmt.Errorf("rcon-cli failed with exit code %d: %s", inspectResp.ExitCode, strings.TrimSpace(output.String()))
+	}
+
+	return output.String(), nil
 }
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// mojangProfileURL resolves player names to their profile
+const mojangProfileURL = "https://api.mojang.com/users/profiles/minecraft/"
+
+// ErrPlayerNotFound is returned if no Minecraft account has the looked up name
+var ErrPlayerNotFound = errors.New("player not found")
+
+// PlayerProfile is the identity of a Minecraft account
+type PlayerProfile struct {
+	UUID string
+	Name string
+}
+
+// MojangClient looks up player profiles with the Mojang API
+type MojangClient struct {
+	httpClient *http.Client
+	profileURL string
+}
+
+// NewMojangClient creates a new Mojang API client
+func NewMojangClient() *MojangClient {
+	return &MojangClient{
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		profileURL: mojangProfileURL,
+	}
+}
+
+// LookupProfile returns the profile of the account with the given name, the name is matched case-insensitively
+func (c *MojangClient) LookupProfile(ctx context.Context, name string) (*PlayerProfile, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.profileURL+url.PathEscape(name), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up player %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent, http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", ErrPlayerNotFound, name)
+	default:
+		return nil, fmt.Errorf("failed to look up player %s: Mojang API returned %s", name, resp.Status)
+	}
+
+	var profile struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&profile); err != nil {
+		return nil, fmt.Errorf("failed to decode profile of %s: %w", name, err)
+	}
+	id, err := uuid.Parse(profile.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid uuid in profile of %s: %w", name, err)
+	}
+	return &PlayerProfile{UUID: id.String(), Name: profile.Name}, nil
+}
+
+// OfflinePlayerUUID returns the UUID an offline mode server assigns to a player name
+func OfflinePlayerUUID(name string) string {
+	// Same as Java's UUID.nameUUIDFromBytes, a version 3 UUID without namespace
+	sum := md5.Sum([]byte("OfflinePlayer:" + name))
+	sum[6] = sum[6]&0x0f | 0x30
+	sum[8] = sum[8]&0x3f | 0x80
+	return uuid.UUID(sum).String()
+}
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/database"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+)
+
+// operationTimeout bounds how long a background operation may run
+const operationTimeout = 30 * time.Minute
+
+// OperationFunc is the work of an operation. Its result is stored on the operation when it succeeds.
+type OperationFunc func(ctx context.Context) (any, error)
+
+// OperationService runs long operations in the background and records their progress,
+// so they are neither bound to request timeouts nor cancelled when the client disconnects
+type OperationService struct {
+	repo   *database.OperationRepository
+	events *EventBus
+	wg     sync.WaitGroup
+	logger *slog.Logger
+}
+
+// NewOperationService creates a new operation service
+func NewOperationService(repo *database.OperationRepository, logger *slog.Logger) *OperationService {
+	return &OperationService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// SetEventBus sets the bus that operation progress is published on
+func (s *OperationService) SetEventBus(events *EventBus) {
+	s.events = events
+}
+
+// FailInterrupted marks operations that were interrupted by a restart of the manager as failed
+func (s *OperationService) FailInterrupted(ctx context.Context) error {
+	count, err := s.repo.FailUnfinished("interrupted by a restart of the manager")
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		s.logger.WarnContext(ctx, "Marked interrupted operations as failed", "count", count)
+	}
+	return nil
+}
+
+// Run starts fn in the background and returns the operation that tracks it
+func (s *OperationService) Run(ctx context.Context, opType models.OperationType, serverID string, fn OperationFunc) (*models.Operation, error) {
+	operation := &models.Operation{
+		ID:       uuid.New().String(),
+		Type:     opType,
+		ServerID: serverID,
+		Status:   models.OperationPending,
+		Steps:    []models.OperationStep{},
+	}
+	if err := s.repo.Create(operation); err != nil {
+		return nil, fmt.Errorf("failed to save operation: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "Operation started",
+		"operation_id", operation.ID,
+		"type", opType,
+		"server_id", serverID)
+
+	s.events.Publish(models.EventOperationUpdated, serverID, cloneOperation(operation))
+
+	s.wg.Add(1)
+	go s.execute(operation, fn)
+
+	return cloneOperation(operation), nil
+}
+
+// GetOperation returns an operation by ID
+func (s *OperationService) GetOperation(ctx context.Context, id string) (*models.Operation, error) {
+	return s.repo.FindByID(id)
+}
+
+// ListOperations returns the most recent operations, optionally only those of one server
+func (s *OperationService) ListOperations(ctx context.Context, serverID string, limit int) ([]*models.Operation, error) {
+	return s.repo.FindRecent(serverID, limit)
+}
+
+// Wait blocks until all running operations finished or ctx is done
+func (s *OperationService) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// execute runs the operation detached from the request that started it
+func (s *OperationService) execute(operation *models.Operation, fn OperationFunc) {
+	defer s.wg.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), operationTimeout)
+	defer cancel()
+
+	tracker := &operationTracker{service: s, operation: operation}
+	ctx = context.WithValue(ctx, operationTrackerKey{}, tracker)
+
+	tracker.update(func(op *models.Operation) {
+		op.Status = models.OperationRunning
+	})
+
+	result, err := runOperationFunc(ctx, fn)
+	tracker.finish(result, err)
+}
+
+// runOperationFunc runs fn and turns a panic into an error, so a bug can't leave an operation running forever
+func runOperationFunc(ctx context.Context, fn OperationFunc) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("operation panicked: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
+
+// operationTrackerKey is the context key of the tracker of the running operation
+type operationTrackerKey struct{}
+
+// operationTracker records the progress of a running operation
+type operationTracker struct {
+	mu        sync.Mutex
+	service   *OperationService
+	operation *models.Operation
+}
+
+// update applies a change to the operation, stores it and publishes it
+func (t *operationTracker) update(change func(op *models.Operation)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	change(t.operation)
+	if err := t.service.repo.Update(t.operation); err != nil {
+		t.service.logger.Error("Failed to save operation progress",
+			"operation_id", t.operation.ID,
+			"error", err)
+	}
+	t.service.events.Publish(models.EventOperationUpdated, t.operation.ServerID, cloneOperation(t.operation))
+}
+
+// finish completes the current step and stores the outcome of the operation
+func (t *operationTracker) finish(result any, err error) {
+	t.update(func(op *models.Operation) {
+		now := time.Now()
+		status := models.OperationSucceeded
+		if err != nil {
+			status = models.OperationFailed
+			op.Error = err.Error()
+		} else {
+			op.Progress = 100
+			if result != nil {
+				data, marshalErr := json.Marshal(result)
+				if marshalErr != nil {
+					t.service.logger.Error("Failed to marshal operation result",
+						"operation_id", op.ID,
+						"error", marshalErr)
+				} else {
+					op.Result = data
+				}
+			}
+		}
+		finishCurrentStep(op, status, now)
+		op.Status = status
+		op.FinishedAt = &now
+	})
+
+	if err != nil {
+		t.service.logger.Error("Operation failed",
+			"operation_id", t.operation.ID,
+			"type", t.operation.Type,
+			"server_id", t.operation.ServerID,
+			"error", err)
+		return
+	}
+	t.service.logger.Info("Operation succeeded",
+		"operation_id", t.operation.ID,
+		"type", t.operation.Type,
+		"server_id", t.operation.ServerID)
+}
+
+// finishCurrentStep sets the status of the running step, if any
+func finishCurrentStep(op *models.Operation, status models.OperationStatus, now time.Time) {
+	if len(op.Steps) == 0 {
+		return
+	}
+	step := &op.Steps[len(op.Steps)-1]
+	if step.Status == models.OperationRunning {
+		step.Status = status
+		step.FinishedAt = &now
+	}
+}
+
+// trackerFromContext returns the tracker of the operation running in ctx, or nil
+func trackerFromContext(ctx context.Context) *operationTracker {
+	tracker, _ := ctx.Value(operationTrackerKey{}).(*operationTracker)
+	return tracker
+}
+
+// reportStep completes the current step of the operation running in ctx and starts the next one
+// at the given overall progress. It does nothing if ctx doesn't belong to an operation.
+func reportStep(ctx context.Context, name string, progress int) {
+	tracker := trackerFromContext(ctx)
+	if tracker == nil {
+		return
+	}
+	tracker.update(func(op *models.Operation) {
+		now := time.Now()
+		finishCurrentStep(op, models.OperationSucceeded, now)
+		op.Steps = append(op.Steps, models.OperationStep{
+			Name:      name,
+			Status:    models.OperationRunning,
+			StartedAt: now,
+		})
+		op.Progress = progress
+	})
+}
+
+// reportProgress updates the overall progress of the operation running in ctx within the current step
+func reportProgress(ctx context.Context, progress int) {
+	tracker := trackerFromContext(ctx)
+	if tracker == nil {
+		return
+	}
+
+	// Skip unchanged progress, fine grained sources like image pulls report very often
+	tracker.mu.Lock()
+	unchanged := tracker.operation.Progress == progress
+	tracker.mu.Unlock()
+	if unchanged {
+		return
+	}
+
+	tracker.update(func(op *models.Operation) {
+		op.Progress = progress
+	})
+}
+
+// reportServerID records the server of the operation running in ctx, for operations that create it
+func reportServerID(ctx context.Context, serverID string) {
+	tracker := trackerFromContext(ctx)
+	if tracker == nil {
+		return
+	}
+	tracker.update(func(op *models.Operation) {
+		op.ServerID = serverID
+	})
+}
+
+// cloneOperation copies an operation, so published events don't change while the operation progresses
+func cloneOperation(op *models.Operation) *models.Operation {
+	clone := *op
+	clone.Steps = append([]models.OperationStep(nil), op.Steps...)
+	return &clone
+}
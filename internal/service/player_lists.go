@@ -0,0 +1,634 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/google/uuid"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+)
+
+// Locations of the player lists in a server's volume
+const (
+	whitelistPath     = "/whitelist.json"
+	opsPath           = "/ops.json"
+	bannedPlayersPath = "/banned-players.json"
+	bannedIPsPath     = "/banned-ips.json"
+)
+
+const (
+	// banTimeLayout is the date format Minecraft uses in the ban lists
+	banTimeLayout = "2006-01-02 15:04:05 -0700"
+	// banSource is written as source of bans added to the files of stopped servers
+	banSource = "Server"
+	// defaultBanReason is the reason Minecraft uses when none is given
+	defaultBanReason = "Banned by an operator."
+	// maxBanReasonLength limits ban reasons, they are shown to the player on join
+	maxBanReasonLength = 256
+)
+
+// playerNamePattern matches the names of Java edition accounts
+var playerNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,16}$`)
+
+// colorCodePattern matches the formatting codes in console output
+var colorCodePattern = regexp.MustCompile(`§.`)
+
+// whitelistFileEntry is an entry of whitelist.json
+type whitelistFileEntry struct {
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+}
+
+// opsFileEntry is an entry of ops.json
+type opsFileEntry struct {
+	UUID                string `json:"uuid"`
+	Name                string `json:"name"`
+	Level               int    `json:"level"`
+	BypassesPlayerLimit bool   `json:"bypassesPlayerLimit"`
+}
+
+// banFileEntry is an entry of banned-players.json or banned-ips.json
+type banFileEntry struct {
+	UUID    string `json:"uuid,omitempty"`
+	Name    string `json:"name,omitempty"`
+	IP      string `json:"ip,omitempty"`
+	Created string `json:"created"`
+	Source  string `json:"source"`
+	Expires string `json:"expires"`
+	Reason  string `json:"reason"`
+}
+
+func (e whitelistFileEntry) playerName() string { return e.Name }
+func (e opsFileEntry) playerName() string       { return e.Name }
+func (e banFileEntry) playerName() string       { return e.Name }
+
+// isPlayer matches the entries of a player, Minecraft compares names case-insensitively
+func isPlayer[T interface{ playerName() string }](name string) func(T) bool {
+	return func(entry T) bool {
+		return strings.EqualFold(entry.playerName(), name)
+	}
+}
+
+// PlayerListService manages the whitelist, operators and bans of servers. Changes to running servers
+// are sent as console commands, so they apply immediately, the files of stopped servers are edited directly.
+type PlayerListService struct {
+	fileService *FileService
+	mcService   *MinecraftServerService
+	profiles    *MojangClient
+	logger      *slog.Logger
+}
+
+// NewPlayerListService creates a new player list service
+func NewPlayerListService(fileService *FileService, mcService *MinecraftServerService, profiles *MojangClient, logger *slog.Logger) *PlayerListService {
+	return &PlayerListService{
+		fileService: fileService,
+		mcService:   mcService,
+		profiles:    profiles,
+		logger:      logger,
+	}
+}
+
+// Whitelist returns the whitelisted players of a server
+func (s *PlayerListService) Whitelist(ctx context.Context, serverID string) ([]models.WhitelistEntry, error) {
+	if _, err := s.mcService.GetServer(ctx, serverID); err != nil {
+		return nil, err
+	}
+
+	entries, err := readListFile[whitelistFileEntry](ctx, s.fileService, serverID, whitelistPath)
+	if err != nil {
+		return nil, err
+	}
+	whitelist := make([]models.WhitelistEntry, 0, len(entries))
+	for _, entry := range entries {
+		whitelist = append(whitelist, models.WhitelistEntry{UUID: entry.UUID, Name: entry.Name})
+	}
+	return whitelist, nil
+}
+
+// AddToWhitelist adds a player to the whitelist of a server
+func (s *PlayerListService) AddToWhitelist(ctx context.Context, serverID string, req *models.PlayerRequest) ([]models.WhitelistEntry, error) {
+	if err := validatePlayer(req.Name, req.UUID); err != nil {
+		return nil, err
+	}
+
+	err := s.change(ctx, serverID, playerListChange{
+		command: "whitelist add " + req.Name,
+		applied: func(ctx context.Context) (bool, error) {
+			entries, err := readListFile[whitelistFileEntry](ctx, s.fileService, serverID, whitelistPath)
+			return slices.ContainsFunc(entries, isPlayer[whitelistFileEntry](req.Name)), err
+		},
+		edit: func(ctx context.Context, server *models.MinecraftServer) error {
+			entries, err := readListFile[whitelistFileEntry](ctx, s.fileService, serverID, whitelistPath)
+			if err != nil {
+				return err
+			}
+			if slices.ContainsFunc(entries, isPlayer[whitelistFileEntry](req.Name)) {
+				return nil
+			}
+			profile, err := s.resolveProfile(ctx, server, req.Name, req.UUID)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, whitelistFileEntry{UUID: profile.UUID, Name: profile.Name})
+			return writeListFile(ctx, s.fileService, serverID, whitelistPath, entries)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Whitelist(ctx, serverID)
+}
+
+// RemoveFromWhitelist removes a player from the whitelist of a server
+func (s *PlayerListService) RemoveFromWhitelist(ctx context.Context, serverID, name string) ([]models.WhitelistEntry, error) {
+	if err := validatePlayer(name, ""); err != nil {
+		return nil, err
+	}
+
+	err := s.change(ctx, serverID, playerListChange{
+		command: "whitelist remove " + name,
+		applied: func(ctx context.Context) (bool, error) {
+			entries, err := readListFile[whitelistFileEntry](ctx, s.fileService, serverID, whitelistPath)
+			return !slices.ContainsFunc(entries, isPlayer[whitelistFileEntry](name)), err
+		},
+		edit: func(ctx context.Context, server *models.MinecraftServer) error {
+			return removeListEntries(ctx, s.fileService, serverID, whitelistPath, isPlayer[whitelistFileEntry](name))
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Whitelist(ctx, serverID)
+}
+
+// Operators returns the operators of a server
+func (s *PlayerListService) Operators(ctx context.Context, serverID string) ([]models.OperatorEntry, error) {
+	if _, err := s.mcService.GetServer(ctx, serverID); err != nil {
+		return nil, err
+	}
+
+	entries, err := readListFile[opsFileEntry](ctx, s.fileService, serverID, opsPath)
+	if err != nil {
+		return nil, err
+	}
+	operators := make([]models.OperatorEntry, 0, len(entries))
+	for _, entry := range entries {
+		operators = append(operators, models.OperatorEntry{
+			UUID:                entry.UUID,
+			Name:                entry.Name,
+			Level:               entry.Level,
+			BypassesPlayerLimit: entry.BypassesPlayerLimit,
+		})
+	}
+	return operators, nil
+}
+
+// AddOperator gives a player operator permissions on a server, with the level of op-permission-level
+func (s *PlayerListService) AddOperator(ctx context.Context, serverID string, req *models.PlayerRequest) ([]models.OperatorEntry, error) {
+	if err := validatePlayer(req.Name, req.UUID); err != nil {
+		return nil, err
+	}
+
+	err := s.change(ctx, serverID, playerListChange{
+		command: "op " + req.Name,
+		applied: func(ctx context.Context) (bool, error) {
+			entries, err := readListFile[opsFileEntry](ctx, s.fileService, serverID, opsPath)
+			return slices.ContainsFunc(entries, isPlayer[opsFileEntry](req.Name)), err
+		},
+		edit: func(ctx context.Context, server *models.MinecraftServer) error {
+			entries, err := readListFile[opsFileEntry](ctx, s.fileService, serverID, opsPath)
+			if err != nil {
+				return err
+			}
+			if slices.ContainsFunc(entries, isPlayer[opsFileEntry](req.Name)) {
+				return nil
+			}
+			profile, err := s.resolveProfile(ctx, server, req.Name, req.UUID)
+			if err != nil {
+				return err
+			}
+			level, err := s.operatorLevel(ctx, serverID)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, opsFileEntry{UUID: profile.UUID, Name: profile.Name, Level: level})
+			return writeListFile(ctx, s.fileService, serverID, opsPath, entries)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Operators(ctx, serverID)
+}
+
+// RemoveOperator takes the operator permissions of a player on a server
+func (s *PlayerListService) RemoveOperator(ctx context.Context, serverID, name string) ([]models.OperatorEntry, error) {
+	if err := validatePlayer(name, ""); err != nil {
+		return nil, err
+	}
+
+	err := s.change(ctx, serverID, playerListChange{
+		command: "deop " + name,
+		applied: func(ctx context.Context) (bool, error) {
+			entries, err := readListFile[opsFileEntry](ctx, s.fileService, serverID, opsPath)
+			return !slices.ContainsFunc(entries, isPlayer[opsFileEntry](name)), err
+		},
+		edit: func(ctx context.Context, server *models.MinecraftServer) error {
+			return removeListEntries(ctx, s.fileService, serverID, opsPath, isPlayer[opsFileEntry](name))
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Operators(ctx, serverID)
+}
+
+// PlayerBans returns the banned players of a server
+func (s *PlayerListService) PlayerBans(ctx context.Context, serverID string) ([]models.PlayerBan, error) {
+	if _, err := s.mcService.GetServer(ctx, serverID); err != nil {
+		return nil, err
+	}
+
+	entries, err := readListFile[banFileEntry](ctx, s.fileService, serverID, bannedPlayersPath)
+	if err != nil {
+		return nil, err
+	}
+	bans := make([]models.PlayerBan, 0, len(entries))
+	for _, entry := range entries {
+		bans = append(bans, models.PlayerBan{
+			UUID:    entry.UUID,
+			Name:    entry.Name,
+			Reason:  entry.Reason,
+			Source:  entry.Source,
+			Created: parseBanTime(entry.Created),
+			Expires: parseBanTime(entry.Expires),
+		})
+	}
+	return bans, nil
+}
+
+// BanPlayer permanently bans a player from a server, a running server kicks the player
+func (s *PlayerListService) BanPlayer(ctx context.Context, serverID string, req *models.BanPlayerRequest) ([]models.PlayerBan, error) {
+	if err := validatePlayer(req.Name, req.UUID); err != nil {
+		return nil, err
+	}
+	if err := validateBanReason(req.Reason); err != nil {
+		return nil, err
+	}
+
+	err := s.change(ctx, serverID, playerListChange{
+		command: strings.TrimSpace("ban " + req.Name + " " + req.Reason),
+		applied: func(ctx context.Context) (bool, error) {
+			entries, err := readListFile[banFileEntry](ctx, s.fileService, serverID, bannedPlayersPath)
+			return slices.ContainsFunc(entries, isPlayer[banFileEntry](req.Name)), err
+		},
+		edit: func(ctx context.Context, server *models.MinecraftServer) error {
+			entries, err := readListFile[banFileEntry](ctx, s.fileService, serverID, bannedPlayersPath)
+			if err != nil {
+				return err
+			}
+			if slices.ContainsFunc(entries, isPlayer[banFileEntry](req.Name)) {
+				return nil
+			}
+			profile, err := s.resolveProfile(ctx, server, req.Name, req.UUID)
+			if err != nil {
+				return err
+			}
+			entry := newBanFileEntry(req.Reason)
+			entry.UUID, entry.Name = profile.UUID, profile.Name
+			entries = append(entries, entry)
+			return writeListFile(ctx, s.fileService, serverID, bannedPlayersPath, entries)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.PlayerBans(ctx, serverID)
+}
+
+// PardonPlayer lifts the ban of a player on a server
+func (s *PlayerListService) PardonPlayer(ctx context.Context, serverID, name string) ([]models.PlayerBan, error) {
+	if err := validatePlayer(name, ""); err != nil {
+		return nil, err
+	}
+
+	err := s.change(ctx, serverID, playerListChange{
+		command: "pardon " + name,
+		applied: func(ctx context.Context) (bool, error) {
+			entries, err := readListFile[banFileEntry](ctx, s.fileService, serverID, bannedPlayersPath)
+			return !slices.ContainsFunc(entries, isPlayer[banFileEntry](name)), err
+		},
+		edit: func(ctx context.Context, server *models.MinecraftServer) error {
+			return removeListEntries(ctx, s.fileService, serverID, bannedPlayersPath, isPlayer[banFileEntry](name))
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.PlayerBans(ctx, serverID)
+}
+
+// IPBans returns the banned IP addresses of a server
+func (s *PlayerListService) IPBans(ctx context.Context, serverID string) ([]models.IPBan, error) {
+	if _, err := s.mcService.GetServer(ctx, serverID); err != nil {
+		return nil, err
+	}
+
+	entries, err := readListFile[banFileEntry](ctx, s.fileService, serverID, bannedIPsPath)
+	if err != nil {
+		return nil, err
+	}
+	bans := make([]models.IPBan, 0, len(entries))
+	for _, entry := range entries {
+		bans = append(bans, models.IPBan{
+			IP:      entry.IP,
+			Reason:  entry.Reason,
+			Source:  entry.Source,
+			Created: parseBanTime(entry.Created),
+			Expires: parseBanTime(entry.Expires),
+		})
+	}
+	return bans, nil
+}
+
+// BanIP permanently bans an IP address from a server, a running server kicks players connected from it
+func (s *PlayerListService) BanIP(ctx context.Context, serverID string, req *models.BanIPRequest) ([]models.IPBan, error) {
+	ip, err := parseBanIP(req.IP)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateBanReason(req.Reason); err != nil {
+		return nil, err
+	}
+
+	err = s.change(ctx, serverID, playerListChange{
+		command: strings.TrimSpace("ban-ip " + ip + " " + req.Reason),
+		applied: func(ctx context.Context) (bool, error) {
+			entries, err := readListFile[banFileEntry](ctx, s.fileService, serverID, bannedIPsPath)
+			return slices.ContainsFunc(entries, func(e banFileEntry) bool { return e.IP == ip }), err
+		},
+		edit: func(ctx context.Context, server *models.MinecraftServer) error {
+			entries, err := readListFile[banFileEntry](ctx, s.fileService, serverID, bannedIPsPath)
+			if err != nil {
+				return err
+			}
+			if slices.ContainsFunc(entries, func(e banFileEntry) bool { return e.IP == ip }) {
+				return nil
+			}
+			entry := newBanFileEntry(req.Reason)
+			entry.IP = ip
+			entries = append(entries, entry)
+			return writeListFile(ctx, s.fileService, serverID, bannedIPsPath, entries)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.IPBans(ctx, serverID)
+}
+
+// PardonIP lifts the ban of an IP address on a server
+func (s *PlayerListService) PardonIP(ctx context.Context, serverID, address string) ([]models.IPBan, error) {
+	ip, err := parseBanIP(address)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.change(ctx, serverID, playerListChange{
+		command: "pardon-ip " + ip,
+		applied: func(ctx context.Context) (bool, error) {
+			entries, err := readListFile[banFileEntry](ctx, s.fileService, serverID, bannedIPsPath)
+			return !slices.ContainsFunc(entries, func(e banFileEntry) bool { return e.IP == ip }), err
+		},
+		edit: func(ctx context.Context, server *models.MinecraftServer) error {
+			return removeListEntries(ctx, s.fileService, serverID, bannedIPsPath, func(e banFileEntry) bool { return e.IP == ip })
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.IPBans(ctx, serverID)
+}
+
+// playerListChange describes a change of a player list for both running and stopped servers
+type playerListChange struct {
+	command string                                                          // Console command applying the change on a running server
+	applied func(ctx context.Context) (bool, error)                         // Reports whether the server wrote the change to its file
+	edit    func(ctx context.Context, server *models.MinecraftServer) error // Edits the file of a stopped server
+}
+
+// change applies a change to a player list. Minecraft keeps the lists in memory and writes them on every
+// change, so a running server has to make the change itself or it would overwrite the edited file.
+func (s *PlayerListService) change(ctx context.Context, serverID string, change playerListChange) error {
+	unlock, err := s.mcService.locks.LockOrConflict(serverID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	server, err := s.mcService.repo.FindByID(serverID)
+	if err != nil {
+		return err
+	}
+
+	state, err := s.mcService.dockerService.GetContainerState(ctx, server.ContainerID)
+	if err != nil {
+		return fmt.Errorf("failed to get container state: %w", err)
+	}
+	if state.Restarting {
+		return conflictError("server is restarting, try again once it is running")
+	}
+
+	if !state.Running {
+		if err := change.edit(ctx, server); err != nil {
+			return err
+		}
+		s.logger.InfoContext(ctx, "Player list file changed",
+			"server_id", serverID,
+			"change", change.command)
+		return nil
+	}
+
+	output, err := s.mcService.ExecuteCommand(ctx, server, change.command)
+	if err != nil {
+		return fmt.Errorf("failed to run %q: %w", change.command, err)
+	}
+
+	// The console output is meant for humans, the written file tells if the command worked
+	applied, err := change.applied(ctx)
+	if err != nil {
+		return err
+	}
+	if !applied {
+		return conflictError("server rejected %q: %s", change.command, consoleText(output))
+	}
+
+	s.logger.InfoContext(ctx, "Player list changed through console",
+		"server_id", serverID,
+		"change", change.command)
+	return nil
+}
+
+// resolveProfile returns the profile written to the files of a stopped server
+func (s *PlayerListService) resolveProfile(ctx context.Context, server *models.MinecraftServer, name, id string) (*PlayerProfile, error) {
+	if id != "" {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return nil, validationError("invalid uuid %q", id)
+		}
+		return &PlayerProfile{UUID: parsed.String(), Name: name}, nil
+	}
+
+	// Servers behind the proxy get the UUIDs of the online mode proxy forwarded
+	if server.ExposeDirectly && strings.EqualFold(server.Env["ONLINE_MODE"], "false") {
+		return &PlayerProfile{UUID: OfflinePlayerUUID(name), Name: name}, nil
+	}
+
+	profile, err := s.profiles.LookupProfile(ctx, name)
+	if errors.Is(err, ErrPlayerNotFound) {
+		return nil, validationError("player %s does not exist", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w, pass the uuid of the player instead", err)
+	}
+	return profile, nil
+}
+
+// operatorLevel returns the permission level the op command would give on a server
+func (s *PlayerListService) operatorLevel(ctx context.Context, serverID string) (int, error) {
+	file, err := readPropertiesFile(ctx, s.fileService, serverID)
+	if err != nil {
+		return 0, err
+	}
+	level, err := strconv.Atoi(strings.TrimSpace(file.Values()["op-permission-level"]))
+	if err != nil || level < 1 || level > 4 {
+		return 4, nil
+	}
+	return level, nil
+}
+
+// readListFile reads a player list of a server, missing files are empty lists
+func readListFile[T any](ctx context.Context, fileService *FileService, serverID, path string) ([]T, error) {
+	content, _, err := fileService.OpenFile(ctx, serverID, path)
+	if errors.Is(err, ErrFileNotFound) {
+		return []T{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	entries := []T{}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return entries, nil
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// writeListFile replaces a player list of a server
+func writeListFile[T any](ctx context.Context, fileService *FileService, serverID, path string, entries []T) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	_, err = fileService.WriteFile(ctx, serverID, path, bytes.NewReader(data), int64(len(data)))
+	return err
+}
+
+// removeListEntries removes the matching entries from a player list, the file is only written if one matched
+func removeListEntries[T any](ctx context.Context, fileService *FileService, serverID, path string, match func(T) bool) error {
+	entries, err := readListFile[T](ctx, fileService, serverID, path)
+	if err != nil {
+		return err
+	}
+	remaining := slices.DeleteFunc(slices.Clone(entries), match)
+	if len(remaining) == len(entries) {
+		return nil
+	}
+	return writeListFile(ctx, fileService, serverID, path, remaining)
+}
+
+// newBanFileEntry creates a permanent ban issued now
+func newBanFileEntry(reason string) banFileEntry {
+	if reason == "" {
+		reason = defaultBanReason
+	}
+	return banFileEntry{
+		Created: time.Now().Format(banTimeLayout),
+		Source:  banSource,
+		Expires: "forever",
+		Reason:  reason,
+	}
+}
+
+// parseBanTime parses a date of the ban lists, permanent bans and unknown formats have none
+func parseBanTime(value string) *time.Time {
+	t, err := time.Parse(banTimeLayout, value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// validatePlayer checks a player name and the optional UUID, names end up in console commands
+func validatePlayer(name, id string) error {
+	if !playerNamePattern.MatchString(name) {
+		return validationError("invalid player name %q, must be 1-16 letters, digits or underscores", name)
+	}
+	if id != "" {
+		if _, err := uuid.Parse(id); err != nil {
+			return validationError("invalid uuid %q", id)
+		}
+	}
+	return nil
+}
+
+// validateBanReason checks a ban reason, it ends up in a console command
+func validateBanReason(reason string) error {
+	if len(reason) > maxBanReasonLength {
+		return validationError("reason must be at most %d characters", maxBanReasonLength)
+	}
+	if strings.ContainsFunc(reason, unicode.IsControl) {
+		return validationError("reason must not contain control characters")
+	}
+	return nil
+}
+
+// parseBanIP checks an IP address and returns it in the form Minecraft writes to banned-ips.json
+func parseBanIP(value string) (string, error) {
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return "", validationError("invalid IP address %q", value)
+	}
+	return addr.Unmap().String(), nil
+}
+
+// consoleText cleans the output of a console command for error messages
+func consoleText(output string) string {
+	// The exec output is framed like a Docker log stream
+	var text strings.Builder
+	if _, err := stdcopy.StdCopy(&text, &text, strings.NewReader(output)); err == nil {
+		output = text.String()
+	}
+	return strings.Join(strings.Fields(colorCodePattern.ReplaceAllString(output, "")), " ")
+}
@@ -0,0 +1,436 @@
+package service
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/google/uuid"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/database"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+)
+
+// playerTrackerInterval is how often the tracker looks for servers whose log it doesn't follow yet
+const playerTrackerInterval = 10 * time.Second
+
+var (
+	// logMessagePattern extracts the message of an INFO line. Vanilla and Fabric log "[12:00:00] [Server thread/INFO]: ",
+	// Forge and NeoForge add the logger name and Paper, Spigot and Purpur log "[12:00:00 INFO]: ".
+	logMessagePattern = regexp.MustCompile(`^\[[^\]]+?(?:\] \[[^\]]+/| )INFO\](?: \[[^\]]+\])?: (.*)$`)
+	// ansiPattern matches the color escape sequences some servers write to the console
+	ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+	// Messages of the server about players. Names are restricted to the characters of Java and Bedrock
+	// (Geyser) names, so chat messages like "<Steve> Alex joined the game" don't match.
+	playerUUIDPattern     = regexp.MustCompile(`^UUID of player ([A-Za-z0-9_.]{1,32}) is ([0-9a-f-]{36})$`)
+	playerLoggedInPattern = regexp.MustCompile(`^([A-Za-z0-9_.]{1,32})\[/(.+)\] logged in with entity id`)
+	playerJoinedPattern   = regexp.MustCompile(`^([A-Za-z0-9_.]{1,32})(?: \(formerly known as [A-Za-z0-9_.]{1,32}\))? joined the game$`)
+	playerLeftPattern     = regexp.MustCompile(`^([A-Za-z0-9_.]{1,32}) left the game$`)
+	serverStartingPattern = regexp.MustCompile(`^Starting minecraft server version`)
+)
+
+// PlayerService answers which players were on which server when, from the sessions the PlayerTracker records
+type PlayerService struct {
+	sessions  *database.PlayerSessionRepository
+	mcService *MinecraftServerService
+	logger    *slog.Logger
+}
+
+// NewPlayerService creates a new player service
+func NewPlayerService(sessions *database.PlayerSessionRepository, mcService *MinecraftServerService, logger *slog.Logger) *PlayerService {
+	return &PlayerService{
+		sessions:  sessions,
+		mcService: mcService,
+		logger:    logger,
+	}
+}
+
+// ListPlayers returns the players with sessions matching the filter, most recently seen first
+func (s *PlayerService) ListPlayers(ctx context.Context, filter models.PlayerSessionFilter) ([]*models.Player, error) {
+	if err := validatePlayerFilter(filter); err != nil {
+		return nil, err
+	}
+	if filter.ServerID != "" {
+		if _, err := s.mcService.GetServer(ctx, filter.ServerID); err != nil {
+			return nil, err
+		}
+	}
+
+	sessions, err := s.sessions.Find(filter)
+	if err != nil {
+		return nil, err
+	}
+	return summarizePlayers(sessions), nil
+}
+
+// GetPlayer returns a player with its sessions matching the filter
+func (s *PlayerService) GetPlayer(ctx context.Context, playerUUID string, filter models.PlayerSessionFilter) (*models.PlayerDetail, error) {
+	id, err := uuid.Parse(playerUUID)
+	if err != nil {
+		return nil, validationError("invalid player uuid %q", playerUUID)
+	}
+	if err := validatePlayerFilter(filter); err != nil {
+		return nil, err
+	}
+
+	filter.PlayerUUID = id.String()
+	sessions, err := s.sessions.Find(filter)
+	if err != nil {
+		return nil, err
+	}
+	players := summarizePlayers(sessions)
+	if len(players) == 0 {
+		return nil, fmt.Errorf("%w: %s", database.ErrPlayerNotFound, playerUUID)
+	}
+	return &models.PlayerDetail{Player: *players[0], SessionList: sessions}, nil
+}
+
+// OnlinePlayers returns the open sessions on a server, i.e. the players online
+func (s *PlayerService) OnlinePlayers(ctx context.Context, serverID string) ([]*models.PlayerSession, error) {
+	if _, err := s.mcService.GetServer(ctx, serverID); err != nil {
+		return nil, err
+	}
+	return s.sessions.FindOpen(serverID)
+}
+
+// validatePlayerFilter checks the time range of a filter
+func validatePlayerFilter(filter models.PlayerSessionFilter) error {
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && filter.Until.Before(filter.Since) {
+		return validationError("until must not be before since")
+	}
+	return nil
+}
+
+// summarizePlayers groups sessions, newest first, by player. Sessions without a known uuid are grouped by name.
+func summarizePlayers(sessions []*models.PlayerSession) []*models.Player {
+	var players []*models.Player
+	byKey := make(map[string]*models.Player)
+	for _, session := range sessions {
+		key := session.PlayerUUID
+		if key == "" {
+			key = "name:" + session.PlayerName
+		}
+
+		player, ok := byKey[key]
+		if !ok {
+			// The newest session comes first and names the player
+			player = &models.Player{
+				UUID:     session.PlayerUUID,
+				Name:     session.PlayerName,
+				LastSeen: session.JoinedAt,
+			}
+			byKey[key] = player
+			players = append(players, player)
+		}
+
+		player.Sessions++
+		player.FirstSeen = session.JoinedAt
+		if session.LeftAt == nil {
+			player.Online = true
+			player.ServerID = session.ServerID
+			player.LastSeen = time.Now().UTC()
+		} else if session.LeftAt.After(player.LastSeen) {
+			player.LastSeen = *session.LeftAt
+		}
+	}
+	return players
+}
+
+// PlayerTracker follows the logs of running servers and records the sessions of their players
+type PlayerTracker struct {
+	mcService *MinecraftServerService
+	sessions  *database.PlayerSessionRepository
+	events    *EventBus
+	logger    *slog.Logger
+
+	mu        sync.Mutex
+	following map[string]bool // Containers whose log is followed
+}
+
+// NewPlayerTracker creates a new player tracker
+func NewPlayerTracker(mcService *MinecraftServerService, sessions *database.PlayerSessionRepository, logger *slog.Logger) *PlayerTracker {
+	return &PlayerTracker{
+		mcService: mcService,
+		sessions:  sessions,
+		logger:    logger,
+		following: make(map[string]bool),
+	}
+}
+
+// SetEventBus sets the event bus joins and leaves are published on
+func (t *PlayerTracker) SetEventBus(events *EventBus) {
+	t.events = events
+}
+
+// Run follows the logs of running servers until the context is cancelled
+func (t *PlayerTracker) Run(ctx context.Context) {
+	t.logger.InfoContext(ctx, "Player tracker started")
+
+	ticker := time.NewTicker(playerTrackerInterval)
+	defer ticker.Stop()
+
+	t.sync(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			t.logger.InfoContext(ctx, "Player tracker stopped")
+			return
+		case <-ticker.C:
+			t.sync(ctx)
+		}
+	}
+}
+
+// sync starts following the logs of running servers and ends the sessions on servers that don't run anymore
+func (t *PlayerTracker) sync(ctx context.Context) {
+	servers, err := t.mcService.ListServers(ctx)
+	if err != nil {
+		t.logger.WarnContext(ctx, "Failed to list servers for player tracking", "error", err)
+		return
+	}
+
+	for _, server := range servers {
+		if server.ContainerID == "" {
+			continue
+		}
+		state, err := t.mcService.dockerService.GetContainerState(ctx, server.ContainerID)
+		if err != nil {
+			t.logger.WarnContext(ctx, "Failed to get container state for player tracking", "server_id", server.ID, "error", err)
+			continue
+		}
+		if !state.Running {
+			// Players can't be online on a stopped server, e.g. if it crashed or stopped while the manager was down
+			if err := t.sessions.CloseAll(server.ID, time.Now()); err != nil {
+				t.logger.WarnContext(ctx, "Failed to close player sessions", "server_id", server.ID, "error", err)
+			}
+			continue
+		}
+
+		t.mu.Lock()
+		following := t.following[server.ContainerID]
+		t.following[server.ContainerID] = true
+		t.mu.Unlock()
+		if !following {
+			go t.follow(ctx, server)
+		}
+	}
+}
+
+// follow reads the log of a server's container from the last recorded join on until the container stops.
+// Joins that were recorded before are skipped, so reading a part of the log again is harmless.
+func (t *PlayerTracker) follow(ctx context.Context, server *models.MinecraftServer) {
+	defer func() {
+		t.mu.Lock()
+		delete(t.following, server.ContainerID)
+		t.mu.Unlock()
+	}()
+
+	options := container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+		Timestamps: true,
+	}
+	since, err := t.sessions.LastJoinTime(server.ID)
+	if err != nil {
+		return
+	}
+	if !since.IsZero() {
+		options.Since = since.Format(time.RFC3339Nano)
+	}
+
+	logs, err := t.mcService.dockerService.client.ContainerLogs(ctx, server.ContainerID, options)
+	if err != nil {
+		t.logger.WarnContext(ctx, "Failed to follow server log for player tracking", "server_id", server.ID, "error", err)
+		return
+	}
+	defer logs.Close()
+
+	t.logger.DebugContext(ctx, "Following server log for player tracking", "server_id", server.ID)
+
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := stdcopy.StdCopy(pw, pw, logs)
+		pw.CloseWithError(err)
+	}()
+
+	parser := &playerLogParser{tracker: t, server: server, uuids: make(map[string]string), ips: make(map[string]string)}
+	scanner := bufio.NewScanner(pr)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		parser.parseLine(ctx, scanner.Text())
+	}
+	pr.Close()
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	// The log ends when the container stops, its players are gone then
+	state, err := t.mcService.dockerService.GetContainerState(ctx, server.ContainerID)
+	if err != nil || state.Running {
+		return
+	}
+	if err := t.sessions.CloseAll(server.ID, time.Now()); err != nil {
+		t.logger.WarnContext(ctx, "Failed to close player sessions", "server_id", server.ID, "error", err)
+	}
+}
+
+// playerLogParser turns the log lines of a server into player sessions
+type playerLogParser struct {
+	tracker *PlayerTracker
+	server  *models.MinecraftServer
+	uuids   map[string]string // Player uuids the server logged before they joined
+	ips     map[string]string // Addresses of players that logged in but didn't join yet
+}
+
+// parseLine handles a log line prefixed with the timestamp Docker recorded for it
+func (p *playerLogParser) parseLine(ctx context.Context, line string) {
+	timestamp, line, ok := strings.Cut(line, " ")
+	if !ok {
+		return
+	}
+	at, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return
+	}
+
+	message, ok := logMessage(line)
+	if !ok {
+		return
+	}
+
+	if m := playerUUIDPattern.FindStringSubmatch(message); m != nil {
+		p.uuids[m[1]] = m[2]
+		return
+	}
+	if m := playerLoggedInPattern.FindStringSubmatch(message); m != nil {
+		ip := m[2]
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+		p.ips[m[1]] = ip
+		return
+	}
+	if m := playerJoinedPattern.FindStringSubmatch(message); m != nil {
+		p.joined(ctx, m[1], at)
+		return
+	}
+	if m := playerLeftPattern.FindStringSubmatch(message); m != nil {
+		p.left(ctx, m[1], at)
+		return
+	}
+	if serverStartingPattern.MatchString(message) {
+		// Sessions still open from a previous run of the container ended when it crashed
+		_ = p.tracker.sessions.CloseAll(p.server.ID, at)
+	}
+}
+
+// logMessage returns the message of an INFO line of the server console without colors
+func logMessage(line string) (string, bool) {
+	line = colorCodePattern.ReplaceAllString(ansiPattern.ReplaceAllString(line, ""), "")
+	match := logMessagePattern.FindStringSubmatch(strings.TrimRight(line, "\r"))
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+// joined records the session of a player that joined the server
+func (p *playerLogParser) joined(ctx context.Context, name string, at time.Time) {
+	t := p.tracker
+	session := &models.PlayerSession{
+		ID:         uuid.New().String(),
+		ServerID:   p.server.ID,
+		PlayerUUID: p.uuids[name],
+		PlayerName: name,
+		IP:         p.ips[name],
+		JoinedAt:   at,
+	}
+	delete(p.uuids, name)
+	delete(p.ips, name)
+
+	// Offline mode servers behind the proxy don't log the uuid, the user cache has it once the player joined
+	if session.PlayerUUID == "" {
+		playerUUID, err := t.mcService.userCacheUUID(ctx, p.server, name)
+		if err != nil {
+			t.logger.DebugContext(ctx, "Failed to look up player uuid",
+				"server_id", p.server.ID,
+				"player", name,
+				"error", err)
+		}
+		session.PlayerUUID = playerUUID
+	}
+
+	id := session.ID
+	if err := t.sessions.CreateIfMissing(session); err != nil {
+		return
+	}
+	if session.ID != id {
+		// Seen before, the log was read again
+		return
+	}
+
+	t.logger.InfoContext(ctx, "Player joined",
+		"server_id", p.server.ID,
+		"player", name,
+		"player_uuid", session.PlayerUUID)
+	t.events.Publish(models.EventPlayerJoined, p.server.ID, session)
+}
+
+// left ends the session of a player that left the server
+func (p *playerLogParser) left(ctx context.Context, name string, at time.Time) {
+	t := p.tracker
+	if err := t.sessions.Close(p.server.ID, name, at); err != nil {
+		return
+	}
+
+	t.logger.InfoContext(ctx, "Player left",
+		"server_id", p.server.ID,
+		"player", name)
+	t.events.Publish(models.EventPlayerLeft, p.server.ID, map[string]any{
+		"player_name": name,
+		"left_at":     at,
+	})
+}
+
+// userCacheUUID looks up the uuid of a player in the usercache.json of a running server
+func (s *MinecraftServerService) userCacheUUID(ctx context.Context, server *models.MinecraftServer, name string) (string, error) {
+	body, _, err := s.dockerService.client.CopyFromContainer(ctx, server.ContainerID, "/data/usercache.json")
+	if err != nil {
+		return "", fmt.Errorf("failed to read usercache.json: %w", err)
+	}
+	defer body.Close()
+
+	// Docker wraps the file in a tar archive
+	tr := tar.NewReader(body)
+	if _, err := tr.Next(); err != nil {
+		return "", fmt.Errorf("failed to read usercache.json: %w", err)
+	}
+	var entries []struct {
+		Name string `json:"name"`
+		UUID string `json:"uuid"`
+	}
+	if err := json.NewDecoder(tr).Decode(&entries); err != nil {
+		return "", fmt.Errorf("invalid usercache.json: %w", err)
+	}
+
+	for _, entry := range entries {
+		if strings.EqualFold(entry.Name, name) {
+			return entry.UUID, nil
+		}
+	}
+	return "", errors.New("player is not in usercache.json")
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		message string
+		ok      bool
+	}{
+		{
+			name:    "vanilla",
+			line:    "[14:02:11] [Server thread/INFO]: Steve joined the game",
+			message: "Steve joined the game",
+			ok:      true,
+		},
+		{
+			name:    "paper",
+			line:    "[14:02:11 INFO]: Steve joined the game",
+			message: "Steve joined the game",
+			ok:      true,
+		},
+		{
+			name:    "forge",
+			line:    "[14:02:11] [Server thread/INFO] [minecraft/MinecraftServer]: Steve joined the game",
+			message: "Steve joined the game",
+			ok:      true,
+		},
+		{
+			name:    "paper with colors",
+			line:    "\x1b[0;33m[14:02:11 INFO]: \x1b[0;93;1mSteve §ejoined the game\x1b[m\r",
+			message: "Steve joined the game",
+			ok:      true,
+		},
+		{
+			name: "vanilla warning",
+			line: "[14:02:11] [Server thread/WARN]: Can't keep up! Is the server overloaded?",
+		},
+		{
+			name: "paper warning",
+			line: "[14:02:11 WARN]: Can't keep up! Is the server overloaded?",
+		},
+		{
+			name: "itzg startup output",
+			line: "[init] Running as uid=1000 gid=1000 with /data as 'drwxr-xr-x 2 1000 1000 4096 Oct 16 12:00 /data'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, ok := logMessage(tt.line)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.message, message)
+		})
+	}
+}
+
+func TestPlayerLogPatterns(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		pattern *regexp.Regexp
+		want    []string // Submatches, nil if the line must not match
+	}{
+		{
+			name:    "vanilla uuid",
+			line:    "[14:02:10] [User Authenticator #1/INFO]: UUID of player Steve is 069a79f4-44e9-4726-a5be-fca90e38aaf5",
+			pattern: playerUUIDPattern,
+			want:    []string{"Steve", "069a79f4-44e9-4726-a5be-fca90e38aaf5"},
+		},
+		{
+			name:    "paper uuid",
+			line:    "[14:02:10 INFO]: UUID of player Steve is 069a79f4-44e9-4726-a5be-fca90e38aaf5",
+			pattern: playerUUIDPattern,
+			want:    []string{"Steve", "069a79f4-44e9-4726-a5be-fca90e38aaf5"},
+		},
+		{
+			name:    "forge uuid",
+			line:    "[14:02:10] [User Authenticator #1/INFO] [minecraft/ServerLoginPacketListenerImpl]: UUID of player Steve is 069a79f4-44e9-4726-a5be-fca90e38aaf5",
+			pattern: playerUUIDPattern,
+			want:    []string{"Steve", "069a79f4-44e9-4726-a5be-fca90e38aaf5"},
+		},
+		{
+			name:    "vanilla logged in",
+			line:    "[14:02:11] [Server thread/INFO]: Steve[/172.18.0.5:53422] logged in with entity id 213 at (8.5, 64.0, -3.5)",
+			pattern: playerLoggedInPattern,
+			want:    []string{"Steve", "172.18.0.5:53422"},
+		},
+		{
+			name:    "paper logged in",
+			line:    "[14:02:11 INFO]: Steve[/172.18.0.5:53422] logged in with entity id 213 at ([world]8.5, 64.0, -3.5)",
+			pattern: playerLoggedInPattern,
+			want:    []string{"Steve", "172.18.0.5:53422"},
+		},
+		{
+			name:    "forge logged in",
+			line:    "[14:02:11] [Server thread/INFO] [minecraft/PlayerList]: Steve[/172.18.0.5:53422] logged in with entity id 213 at (8.5, 64.0, -3.5)",
+			pattern: playerLoggedInPattern,
+			want:    []string{"Steve", "172.18.0.5:53422"},
+		},
+		{
+			name:    "vanilla joined",
+			line:    "[14:02:11] [Server thread/INFO]: Steve joined the game",
+			pattern: playerJoinedPattern,
+			want:    []string{"Steve"},
+		},
+		{
+			name:    "paper joined",
+			line:    "[14:02:11 INFO]: Steve joined the game",
+			pattern: playerJoinedPattern,
+			want:    []string{"Steve"},
+		},
+		{
+			name:    "paper joined after rename",
+			line:    "[14:02:11 INFO]: Steve (formerly known as Alex) joined the game",
+			pattern: playerJoinedPattern,
+			want:    []string{"Steve"},
+		},
+		{
+			name:    "forge joined",
+			line:    "[14:02:11] [Server thread/INFO] [minecraft/MinecraftServer]: Steve joined the game",
+			pattern: playerJoinedPattern,
+			want:    []string{"Steve"},
+		},
+		{
+			name:    "bedrock player joined",
+			line:    "[14:02:11 INFO]: .Steve_01 joined the game",
+			pattern: playerJoinedPattern,
+			want:    []string{".Steve_01"},
+		},
+		{
+			name:    "paper chat message",
+			line:    "[14:02:12 INFO]: <Steve> Alex joined the game",
+			pattern: playerJoinedPattern,
+		},
+		{
+			name:    "vanilla chat message",
+			line:    "[14:02:12] [Server thread/INFO]: <Steve> Alex left the game",
+			pattern: playerLeftPattern,
+		},
+		{
+			name:    "vanilla left",
+			line:    "[14:05:40] [Server thread/INFO]: Steve left the game",
+			pattern: playerLeftPattern,
+			want:    []string{"Steve"},
+		},
+		{
+			name:    "paper left",
+			line:    "[14:05:40 INFO]: Steve left the game",
+			pattern: playerLeftPattern,
+			want:    []string{"Steve"},
+		},
+		{
+			name:    "forge left",
+			line:    "[14:05:40] [Server thread/INFO] [minecraft/MinecraftServer]: Steve left the game",
+			pattern: playerLeftPattern,
+			want:    []string{"Steve"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, ok := logMessage(tt.line)
+			require.True(t, ok, "line is not an INFO line")
+
+			match := tt.pattern.FindStringSubmatch(message)
+			if tt.want == nil {
+				assert.Nil(t, match)
+				return
+			}
+			require.NotNil(t, match)
+			assert.Equal(t, tt.want, match[1:])
+		})
+	}
+}
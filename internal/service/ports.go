@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/database"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+)
+
+// minecraftContainerPort is the port the Minecraft server listens on inside its container
+const minecraftContainerPort = nat.Port("25565/tcp")
+
+// PortAllocator hands out host ports from a configured range to servers that are exposed directly
+type PortAllocator struct {
+	repo   *database.PortRepository
+	start  int
+	end    int
+	logger *slog.Logger
+}
+
+// NewPortAllocator creates a new port allocator for the range [start, end]
+func NewPortAllocator(repo *database.PortRepository, start, end int, logger *slog.Logger) *PortAllocator {
+	return &PortAllocator{
+		repo:   repo,
+		start:  start,
+		end:    end,
+		logger: logger,
+	}
+}
+
+// Allocate reserves a host port for a server
+func (a *PortAllocator) Allocate(ctx context.Context, serverID string) (int, error) {
+	port, err := a.repo.Allocate(serverID, a.start, a.end)
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate port in range %d-%d: %w", a.start, a.end, err)
+	}
+	a.logger.InfoContext(ctx, "Allocated host port", "server_id", serverID, "port", port)
+	return port, nil
+}
+
+// Release frees the host port of a server
+func (a *PortAllocator) Release(ctx context.Context, serverID string) error {
+	if err := a.repo.Release(serverID); err != nil {
+		return fmt.Errorf("failed to release port: %w", err)
+	}
+	a.logger.InfoContext(ctx, "Released host port", "server_id", serverID)
+	return nil
+}
+
+// portBindings returns the exposed ports and bindings publishing a server on its host port
+func portBindings(server *models.MinecraftServer) (nat.PortSet, nat.PortMap) {
+	if !server.ExposeDirectly || server.Port == 0 {
+		return nil, nil
+	}
+	exposedPorts := nat.PortSet{
+		minecraftContainerPort: struct{}{},
+	}
+	bindings := nat.PortMap{
+		minecraftContainerPort: []nat.PortBinding{
+			{HostIP: "0.0.0.0", HostPort: fmt.Sprintf("%d", server.Port)},
+		},
+	}
+	return exposedPorts, bindings
+}
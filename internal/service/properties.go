@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+)
+
+// serverPropertiesPath is the location of server.properties in a server's volume
+const serverPropertiesPath = "/server.properties"
+
+// propertyRule validates the value of a property before it is written
+type propertyRule struct {
+	min, max int      // Range of integer properties
+	values   []string // Allowed values of string properties, any value if empty
+	pattern  *regexp.Regexp
+}
+
+// propertyRules holds the constraints of the typed properties, booleans need none
+var propertyRules = map[string]propertyRule{
+	"difficulty":                        {values: []string{"peaceful", "easy", "normal", "hard"}},
+	"gamemode":                          {values: []string{"survival", "creative", "adventure", "spectator"}},
+	"view-distance":                     {min: 3, max: 32},
+	"simulation-distance":               {min: 3, max: 32},
+	"spawn-protection":                  {min: 0, max: 29999984},
+	"level-type":                        {pattern: regexp.MustCompile(`^[a-z0-9_.-]+(:[a-z0-9_./-]+)?$`)},
+	"op-permission-level":               {min: 1, max: 4},
+	"player-idle-timeout":               {min: 0, max: 1 << 30},
+	"max-world-size":                    {min: 1, max: 29999984},
+	"entity-broadcast-range-percentage": {min: 10, max: 1000},
+	"resource-pack-sha1":                {pattern: regexp.MustCompile(`^([0-9a-fA-F]{40})?$`)},
+}
+
+// propertyField links a field of models.ServerProperties to its key in server.properties
+type propertyField struct {
+	key   string
+	index int
+	kind  reflect.Kind
+}
+
+// propertyFields are the typed properties in the order of models.ServerProperties
+var propertyFields = func() []propertyField {
+	t := reflect.TypeOf(models.ServerProperties{})
+	fields := make([]propertyField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fields = append(fields, propertyField{
+			key:   field.Tag.Get("property"),
+			index: i,
+			kind:  field.Type.Elem().Kind(),
+		})
+	}
+	return fields
+}()
+
+// decodeServerProperties splits the values of server.properties into the typed properties and the other keys.
+// Values that don't parse as the type of their field are kept in the other keys.
+func decodeServerProperties(values map[string]string) (models.ServerProperties, map[string]string) {
+	var props models.ServerProperties
+	other := make(map[string]string, len(values))
+	for key, value := range values {
+		other[key] = value
+	}
+
+	v := reflect.ValueOf(&props).Elem()
+	for _, field := range propertyFields {
+		value, ok := values[field.key]
+		if !ok {
+			continue
+		}
+		parsed, err := parsePropertyValue(field, value)
+		if err != nil {
+			continue
+		}
+		v.Field(field.index).Set(parsed)
+		delete(other, field.key)
+	}
+	return props, other
+}
+
+// parsePropertyValue parses a value of server.properties into a pointer for the field
+func parsePropertyValue(field propertyField, value string) (reflect.Value, error) {
+	switch field.kind {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(&b), nil
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(&n), nil
+	default:
+		return reflect.ValueOf(&value), nil
+	}
+}
+
+// encodeServerProperties validates the set fields and returns them by key in server.properties
+func encodeServerProperties(props *models.ServerProperties) (map[string]string, error) {
+	values := make(map[string]string)
+	var problems []string
+
+	v := reflect.ValueOf(props).Elem()
+	for _, field := range propertyFields {
+		ptr := v.Field(field.index)
+		if ptr.IsNil() {
+			continue
+		}
+		value := ptr.Elem()
+		rule := propertyRules[field.key]
+
+		switch field.kind {
+		case reflect.Bool:
+			values[field.key] = strconv.FormatBool(value.Bool())
+		case reflect.Int:
+			n := int(value.Int())
+			if n < rule.min || n > rule.max {
+				problems = append(problems, fmt.Sprintf("%s must be between %d and %d", field.key, rule.min, rule.max))
+				continue
+			}
+			values[field.key] = strconv.Itoa(n)
+		default:
+			s := value.String()
+			if len(rule.values) > 0 && !slices.Contains(rule.values, s) {
+				problems = append(problems, fmt.Sprintf("%s must be one of %s", field.key, strings.Join(rule.values, ", ")))
+				continue
+			}
+			if rule.pattern != nil && !rule.pattern.MatchString(s) {
+				problems = append(problems, fmt.Sprintf("%s has an invalid value %q", field.key, s))
+				continue
+			}
+			if strings.ContainsAny(s, "\r\n") {
+				problems = append(problems, fmt.Sprintf("%s must not contain line breaks", field.key))
+				continue
+			}
+			values[field.key] = s
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, validationError("%s", strings.Join(problems, "; "))
+	}
+	return values, nil
+}
+
+// ParseServerProperties builds typed properties from keys of server.properties, e.g. view-distance=12 given on the CLI
+func ParseServerProperties(values map[string]string) (*models.ServerProperties, error) {
+	props, other := decodeServerProperties(values)
+	if len(other) > 0 {
+		keys := make([]string, 0, len(other))
+		for key := range other {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		return nil, validationError("unsupported properties or invalid values: %s", strings.Join(keys, ", "))
+	}
+	return &props, nil
+}
+
+// PropertyValue is a typed property with its key and value as written in server.properties
+type PropertyValue struct {
+	Key   string
+	Value string
+}
+
+// ListServerProperties returns the set typed properties in the order of models.ServerProperties
+func ListServerProperties(props *models.ServerProperties) []PropertyValue {
+	var list []PropertyValue
+	v := reflect.ValueOf(props).Elem()
+	for _, field := range propertyFields {
+		if ptr := v.Field(field.index); !ptr.IsNil() {
+			list = append(list, PropertyValue{Key: field.key, Value: fmt.Sprint(ptr.Elem().Interface())})
+		}
+	}
+	return list
+}
+
+// PropertiesService reads and changes the server.properties of servers
+type PropertiesService struct {
+	fileService *FileService
+	mcService   *MinecraftServerService
+	logger      *slog.Logger
+}
+
+// NewPropertiesService creates a new properties service
+func NewPropertiesService(fileService *FileService, mcService *MinecraftServerService, logger *slog.Logger) *PropertiesService {
+	return &PropertiesService{
+		fileService: fileService,
+		mcService:   mcService,
+		logger:      logger,
+	}
+}
+
+// readPropertiesFile reads the server.properties of a server, it's empty if the server never started
+func readPropertiesFile(ctx context.Context, fileService *FileService, serverID string) (*propertiesFile, error) {
+	content, _, err := fileService.OpenFile(ctx, serverID, serverPropertiesPath)
+	if errors.Is(err, ErrFileNotFound) {
+		return parsePropertiesFile(""), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read server.properties: %w", err)
+	}
+	return parsePropertiesFile(string(data)), nil
+}
+
+// GetProperties returns the server.properties of a server
+func (s *PropertiesService) GetProperties(ctx context.Context, serverID string) (*models.ServerPropertiesResponse, error) {
+	if _, err := s.mcService.GetServer(ctx, serverID); err != nil {
+		return nil, err
+	}
+
+	file, err := readPropertiesFile(ctx, s.fileService, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	props, other := decodeServerProperties(file.Values())
+	return &models.ServerPropertiesResponse{Properties: props, Other: other}, nil
+}
+
+// UpdateProperties changes the set properties in the server.properties of a server. Comments, unknown keys
+// and the order of the file are kept. Minecraft only reads the file on start, so changes to a running
+// server require a restart.
+func (s *PropertiesService) UpdateProperties(ctx context.Context, serverID string, update *models.ServerProperties) (*models.ServerPropertiesResponse, error) {
+	values, err := encodeServerProperties(update)
+	if err != nil {
+		return nil, err
+	}
+
+	server, err := s.mcService.GetServer(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The image writes white-list on every start while the override is set, so a change here would be undone
+	if _, ok := values["white-list"]; ok && server.Env[whitelistEnv] != "" {
+		return nil, validationError("white-list is set by the %s env override, change it through the server env instead", whitelistEnv)
+	}
+
+	// Share the server lock, so recreating, restoring, importing a world and player list changes don't
+	// write the file between reading and writing it here
+	unlock, err := s.mcService.locks.LockOrConflict(serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	file, err := readPropertiesFile(ctx, s.fileService, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := []string{}
+	for _, field := range propertyFields {
+		if value, ok := values[field.key]; ok && file.Set(field.key, value) {
+			changed = append(changed, field.key)
+		}
+	}
+
+	restartRequired := false
+	if len(changed) > 0 {
+		content := file.String()
+		if _, err := s.fileService.WriteFile(ctx, serverID, serverPropertiesPath, strings.NewReader(content), int64(len(content))); err != nil {
+			return nil, err
+		}
+
+		state, err := s.mcService.dockerService.GetContainerState(ctx, server.ContainerID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get container state: %w", err)
+		}
+		restartRequired = state.Running || state.Restarting
+
+		s.logger.InfoContext(ctx, "Server properties updated",
+			"server_id", serverID,
+			"changed", changed,
+			"restart_required", restartRequired)
+	}
+
+	props, other := decodeServerProperties(file.Values())
+	return &models.ServerPropertiesResponse{
+		Properties:      props,
+		Other:           other,
+		Changed:         changed,
+		RestartRequired: restartRequired,
+	}, nil
+}
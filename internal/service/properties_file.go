@@ -0,0 +1,216 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// propertiesLine is a logical line of a .properties file. Continued lines are kept together in raw,
+// so lines that aren't changed are written back exactly as they were.
+type propertiesLine struct {
+	raw   string
+	key   string
+	value string
+	isSet bool // Whether the line is a key/value pair and not a comment or blank line
+}
+
+// propertiesFile is a Java .properties file like server.properties that keeps comments, unknown keys
+// and the order of the entries when it's changed
+type propertiesFile struct {
+	lines []propertiesLine
+}
+
+// parsePropertiesFile parses the content of a .properties file
+func parsePropertiesFile(data string) *propertiesFile {
+	file := &propertiesFile{}
+	data = strings.TrimSuffix(data, "\n")
+	if data == "" {
+		return file
+	}
+
+	physical := strings.Split(data, "\n")
+	for i := 0; i < len(physical); i++ {
+		raw := physical[i]
+		logical := strings.TrimLeft(strings.TrimSuffix(raw, "\r"), " \t\f")
+
+		if logical == "" || logical[0] == '#' || logical[0] == '!' {
+			file.lines = append(file.lines, propertiesLine{raw: raw})
+			continue
+		}
+
+		// A line ending in an odd number of backslashes continues on the next line
+		for continuesLine(logical) && i+1 < len(physical) {
+			i++
+			raw += "\n" + physical[i]
+			logical = logical[:len(logical)-1] + strings.TrimLeft(strings.TrimSuffix(physical[i], "\r"), " \t\f")
+		}
+		// Like in Java, a continuation at the end of the file continues with nothing
+		if continuesLine(logical) {
+			logical = logical[:len(logical)-1]
+		}
+
+		key, value := splitProperty(logical)
+		file.lines = append(file.lines, propertiesLine{raw: raw, key: key, value: value, isSet: true})
+	}
+	return file
+}
+
+// continuesLine reports whether a line ends with an unescaped backslash
+func continuesLine(line string) bool {
+	backslashes := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		backslashes++
+	}
+	return backslashes%2 == 1
+}
+
+// splitProperty splits a logical line into its unescaped key and value.
+// The key ends at the first unescaped '=', ':' or whitespace.
+func splitProperty(line string) (string, string) {
+	end := len(line)
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if line[i] == '=' || line[i] == ':' || line[i] == ' ' || line[i] == '\t' || line[i] == '\f' {
+			end = i
+			break
+		}
+	}
+	key := line[:end]
+
+	rest := strings.TrimLeft(line[end:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	return unescapeProperty(key), unescapeProperty(rest)
+}
+
+// unescapeProperty resolves the escape sequences of .properties files, including \uXXXX
+func unescapeProperty(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 == len(s) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+4 < len(s) {
+				if r, err := strconv.ParseUint(s[i+1:i+5], 16, 16); err == nil {
+					b.WriteRune(rune(r))
+					i += 4
+					continue
+				}
+			}
+			b.WriteByte('u')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// escapeProperty escapes a key or value the way Java writes .properties files.
+// Characters outside of ASCII are written as they are, Minecraft reads the file as UTF-8.
+func escapeProperty(s string, isKey bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '=', ':', '#', '!':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case ' ':
+			// Spaces only need escaping where they would be trimmed or end the key
+			if isKey || i == 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&b, `\u%04x`, r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Values returns all keys and their values, later entries win like in Java
+func (f *propertiesFile) Values() map[string]string {
+	values := make(map[string]string)
+	for _, line := range f.lines {
+		if line.isSet {
+			values[line.key] = line.value
+		}
+	}
+	return values
+}
+
+// Set changes the value of a key or appends it, and reports whether the file changed.
+// Duplicates of the key are removed, since the last entry would win.
+func (f *propertiesFile) Set(key, value string) bool {
+	changed := false
+	found := false
+	lines := f.lines[:0]
+	for _, line := range f.lines {
+		if !line.isSet || line.key != key {
+			lines = append(lines, line)
+			continue
+		}
+		if found {
+			changed = true
+			continue
+		}
+		found = true
+		if line.value != value {
+			line = propertiesLine{raw: escapeProperty(key, true) + "=" + escapeProperty(value, false), key: key, value: value, isSet: true}
+			changed = true
+		}
+		lines = append(lines, line)
+	}
+
+	if !found {
+		lines = append(lines, propertiesLine{raw: escapeProperty(key, true) + "=" + escapeProperty(value, false), key: key, value: value, isSet: true})
+		changed = true
+	}
+	f.lines = lines
+	return changed
+}
+
+// String returns the content of the file
+func (f *propertiesFile) String() string {
+	var b strings.Builder
+	for _, line := range f.lines {
+		b.WriteString(line.raw)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePropertiesFile(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want map[string]string
+	}{
+		{
+			name: "separators",
+			data: "a=1\nb:2\nc 3\nd = 4\ne\t:\t5\nf   =   \ng\n",
+			want: map[string]string{"a": "1", "b": "2", "c": "3", "d": "4", "e": "5", "f": "", "g": ""},
+		},
+		{
+			name: "separator in the value",
+			data: "motd=a=b:c d\n",
+			want: map[string]string{"motd": "a=b:c d"},
+		},
+		{
+			name: "escaped separators in the key",
+			data: "a\\=b\\:c\\ d=value\n",
+			want: map[string]string{"a=b:c d": "value"},
+		},
+		{
+			name: "continuation lines",
+			data: "motd=Hello \\\n    World\\\n\t!\nlevel-name=world\n",
+			want: map[string]string{"motd": "Hello World!", "level-name": "world"},
+		},
+		{
+			name: "continued key",
+			data: "level-\\\n  name=world\n",
+			want: map[string]string{"level-name": "world"},
+		},
+		{
+			name: "escaped backslash at the end doesn't continue",
+			data: "path=C:\\\\\nnext=1\n",
+			want: map[string]string{"path": `C:\`, "next": "1"},
+		},
+		{
+			name: "continuation on the last line",
+			data: "motd=end\\",
+			want: map[string]string{"motd": "end"},
+		},
+		{
+			name: "escapes",
+			data: "motd=tab\\there\\nnew\\\\line\\q\n",
+			want: map[string]string{"motd": "tab\there\nnew\\lineq"},
+		},
+		{
+			name: "unicode escapes",
+			data: "motd=\\u00a7aHello \\u2713\nbroken=\\u00zz\n",
+			want: map[string]string{"motd": "§aHello ✓", "broken": "u00zz"},
+		},
+		{
+			name: "utf-8",
+			data: "motd=Grüße aus Köln ✓\n",
+			want: map[string]string{"motd": "Grüße aus Köln ✓"},
+		},
+		{
+			name: "comments and blank lines",
+			data: "#Minecraft server properties\n! old style comment\n\n   # indented=comment\nmotd=Hi # not a comment\n",
+			want: map[string]string{"motd": "Hi # not a comment"},
+		},
+		{
+			name: "leading whitespace",
+			data: "   motd =  Hi\n",
+			want: map[string]string{"motd": "Hi"},
+		},
+		{
+			name: "windows line endings",
+			data: "motd=Hi\r\npvp=true\r\n",
+			want: map[string]string{"motd": "Hi", "pvp": "true"},
+		},
+		{
+			name: "later entries win",
+			data: "pvp=true\npvp=false\n",
+			want: map[string]string{"pvp": "false"},
+		},
+		{
+			name: "empty",
+			data: "",
+			want: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parsePropertiesFile(tt.data).Values())
+		})
+	}
+}
+
+func TestPropertiesFileRoundTrip(t *testing.T) {
+	data := "#Minecraft server properties\n" +
+		"#Fri Oct 16 12:00:00 UTC 2026\n" +
+		"\n" +
+		"motd=\\u00a7aHello \\\n    World\n" +
+		"level-name : world\n" +
+		"custom-plugin-key   value with  spaces\n" +
+		"! comment\r\n" +
+		"pvp=true\n"
+
+	file := parsePropertiesFile(data)
+	assert.Equal(t, data, file.String())
+
+	// Setting a key to its current value changes nothing
+	assert.False(t, file.Set("pvp", "true"))
+	assert.False(t, file.Set("motd", "§aHello World"))
+	assert.Equal(t, data, file.String())
+}
+
+func TestPropertiesFileSet(t *testing.T) {
+	file := parsePropertiesFile("#comment\nunknown-key=kept\npvp=true\nmotd=Hi\npvp=true\n")
+
+	assert.True(t, file.Set("pvp", "false"))
+	assert.True(t, file.Set("difficulty", "hard"))
+	assert.Equal(t, "#comment\nunknown-key=kept\npvp=false\nmotd=Hi\ndifficulty=hard\n", file.String())
+}
+
+func TestPropertiesFileSetEscapes(t *testing.T) {
+	values := map[string]string{
+		"motd":       " §aWelcome: a=b #1 !",
+		"path":       `C:\worlds\lobby`,
+		"multi-line": "first\nsecond\ttab",
+		"unicode":    "Grüße ✓",
+		"control":    "bell\x07",
+	}
+
+	file := parsePropertiesFile("")
+	for key, value := range values {
+		assert.True(t, file.Set(key, value))
+	}
+	assert.Equal(t, values, parsePropertiesFile(file.String()).Values())
+	assert.Contains(t, file.String(), "motd=\\ §aWelcome\\: a\\=b \\#1 \\!\n")
+	assert.Contains(t, file.String(), "control=bell\\u0007\n")
+}
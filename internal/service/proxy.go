@@ -2,10 +2,12 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"strings"
+	"sync"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/network"
@@ -26,6 +28,8 @@ type ProxyService struct {
 	dockerService *DockerService
 	proxyRepo     *database.ProxyRepository
 	serverRepo    *database.ServerRepository
+	events        *EventBus
+	mu            sync.Mutex // Serializes proxy operations with the reconciler
 	logger        *slog.Logger
 }
 
@@ -44,8 +48,16 @@ func NewProxyService(
 	}
 }
 
+// SetEventBus sets the bus that proxy changes are published on
+func (s *ProxyService) SetEventBus(events *EventBus) {
+	s.events = events
+}
+
 // EnsureProxyExists creates the proxy if it doesn't exist
 func (s *ProxyService) EnsureProxyExists(ctx context.Context) (*models.ProxyServer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	s.logger.DebugContext(ctx, "Checking if proxy exists")
 
 	// Check if proxy already exists
@@ -61,6 +73,9 @@ func (s *ProxyService) EnsureProxyExists(ctx context.Context) (*models.ProxyServ
 }
 
 func (s *ProxyService) UpdateProxy(ctx context.Context, proxy *models.ProxyServer) (*models.ProxyServer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	s.logger.InfoContext(ctx, "Updating proxy configuration", "proxy_id", proxy.ID)
 
 	// Update the proxy configuration
@@ -70,10 +85,11 @@ func (s *ProxyService) UpdateProxy(ctx context.Context, proxy *models.ProxyServe
 	}
 
 	s.logger.DebugContext(ctx, "Proxy updated successfully", "proxy_id", proxy.ID)
+	s.events.Publish(models.EventProxyUpdated, "", proxy)
 	return proxy, nil
 }
 
-// createProxy creates the single Velocity proxy server
+// createProxy creates the single Velocity proxy server. The caller must hold the proxy lock.
 func (s *ProxyService) createProxy(ctx context.Context) (*models.ProxyServer, error) {
 	s.logger.InfoContext(ctx, "Creating proxy server")
 
@@ -175,9 +191,15 @@ func (s *ProxyService) createProxy(ctx context.Context) (*models.ProxyServer, er
 		Port:        DefaultProxyPort,
 	}
 
-	// Save to database
+	// Save to database, reusing the record of a proxy whose container disappeared
 	s.logger.DebugContext(ctx, "Saving proxy to database", "proxy_id", proxy.ID)
-	if err := s.proxyRepo.Create(proxy); err != nil {
+	save := s.proxyRepo.Create
+	if existing, err := s.proxyRepo.FindByID(models.SingleProxyID); err == nil {
+		proxy.DefaultServerID = existing.DefaultServerID
+		proxy.CreatedAt = existing.CreatedAt
+		save = s.proxyRepo.Update
+	}
+	if err := save(proxy); err != nil {
 		s.dockerService.client.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
 		s.dockerService.client.VolumeRemove(ctx, vol.Name, true)
 		s.logger.ErrorContext(ctx, "Failed to save proxy to database", "proxy_id", proxy.ID, "error", err)
@@ -186,7 +208,7 @@ func (s *ProxyService) createProxy(ctx context.Context) (*models.ProxyServer, er
 
 	// Start the proxy
 	s.logger.InfoContext(ctx, "Starting proxy server", "proxy_id", proxy.ID)
-	if err := s.StartProxy(ctx); err != nil {
+	if err := s.startProxy(ctx); err != nil {
 		s.logger.ErrorContext(ctx, "Failed to start proxy", "proxy_id", proxy.ID, "error", err)
 		return nil, fmt.Errorf("failed to start proxy: %w", err)
 	}
@@ -220,6 +242,13 @@ func (s *ProxyService) ensureNetwork(ctx context.Context, networkName string) er
 
 // StartProxy starts the proxy server
 func (s *ProxyService) StartProxy(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.startProxy(ctx)
+}
+
+// startProxy starts the proxy server. The caller must hold the proxy lock.
+func (s *ProxyService) startProxy(ctx context.Context) error {
 	proxy, err := s.proxyRepo.FindByID(models.SingleProxyID)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "Failed to find proxy", "error", err)
@@ -233,9 +262,8 @@ func (s *ProxyService) StartProxy(ctx context.Context) error {
 		return fmt.Errorf("failed to start container: %w", err)
 	}
 
-	proxy.Status = models.StatusRunning
-	if err := s.proxyRepo.Update(proxy); err != nil {
-		s.logger.ErrorContext(ctx, "Failed to update proxy status", "proxy_id", proxy.ID, "error", err)
+	// Store what Docker reports instead of assuming the start worked
+	if err := s.reconcileProxyState(ctx, proxy); err != nil {
 		return err
 	}
 
@@ -245,6 +273,9 @@ func (s *ProxyService) StartProxy(ctx context.Context) error {
 
 // StopProxy stops the proxy server
 func (s *ProxyService) StopProxy(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	proxy, err := s.proxyRepo.FindByID(models.SingleProxyID)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "Failed to find proxy", "error", err)
@@ -261,9 +292,7 @@ func (s *ProxyService) StopProxy(ctx context.Context) error {
 		return fmt.Errorf("failed to stop container: %w", err)
 	}
 
-	proxy.Status = models.StatusStopped
-	if err := s.proxyRepo.Update(proxy); err != nil {
-		s.logger.ErrorContext(ctx, "Failed to update proxy status", "proxy_id", proxy.ID, "error", err)
+	if err := s.reconcileProxyState(ctx, proxy); err != nil {
 		return err
 	}
 
@@ -271,73 +300,98 @@ func (s *ProxyService) StopProxy(ctx context.Context) error {
 	return nil
 }
 
-// syncProxyState checks Docker container state and updates database if needed
-func (s *ProxyService) syncProxyState(ctx context.Context, proxy *models.ProxyServer) error {
+// reconcileProxyState compares the proxy with its Docker container and fixes the stored state.
+// The caller must hold the proxy lock.
+func (s *ProxyService) reconcileProxyState(ctx context.Context, proxy *models.ProxyServer) error {
 	// Get container state from Docker
 	state, err := s.dockerService.GetContainerState(ctx, proxy.ContainerID)
 	if err != nil {
-		s.logger.ErrorContext(ctx, "Failed to get container state during sync",
+		s.logger.ErrorContext(ctx, "Failed to get container state during reconciliation",
 			"proxy_id", proxy.ID,
 			"error", err)
 		return fmt.Errorf("failed to get container state: %w", err)
 	}
 
-	// Determine the new status based on container state
-	var newStatus models.ContainerStatus
-	if !state.Exists {
-		// Container doesn't exist anymore (deleted manually or crashed)
+	newStatus := statusFromContainerState(state)
+	containerID := proxy.ContainerID
+	exitCode := proxy.ExitCode
+	oomKilled := proxy.OOMKilled
+
+	if !state.Exists && proxy.ContainerID != "" {
+		// Clearing the container ID lets EnsureProxyExists create a new proxy container
 		s.logger.WarnContext(ctx, "Proxy container no longer exists in Docker, marking as stopped",
 			"proxy_id", proxy.ID,
 			"previous_status", proxy.Status,
 			"container_id", proxy.ContainerID)
-		s.proxyRepo.Delete(proxy.ID)
-		newStatus = models.StatusStopped
-		proxy.ContainerID = "" // Clear the container ID
-	} else if state.Running {
-		newStatus = models.StatusRunning
-	} else if state.Restarting {
-		newStatus = models.StatusCreating
-	} else if state.Dead || state.OOMKilled {
-		newStatus = models.StatusError
-	} else {
-		// Stopped, paused, or exited
-		newStatus = models.StatusStopped
+		containerID = ""
+	}
+
+	// Exit information is only meaningful once the container stopped running
+	if state.Exists && !state.Running {
+		exitCode = state.ExitCode
+		oomKilled = state.OOMKilled
+	}
+
+	if newStatus == proxy.Status && containerID == proxy.ContainerID &&
+		exitCode == proxy.ExitCode && oomKilled == proxy.OOMKilled {
+		return nil
 	}
 
-	// Update database if status changed
-	if newStatus != proxy.Status {
-		s.logger.InfoContext(ctx, "Proxy status changed, updating database",
+	s.logger.InfoContext(ctx, "Proxy state changed, updating database",
+		"proxy_id", proxy.ID,
+		"previous_status", proxy.Status,
+		"new_status", newStatus,
+		"exit_code", exitCode,
+		"oom_killed", oomKilled)
+
+	previousStatus := proxy.Status
+	proxy.Status = newStatus
+	proxy.ContainerID = containerID
+	proxy.ExitCode = exitCode
+	proxy.OOMKilled = oomKilled
+	if err := s.proxyRepo.UpdateState(proxy); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to update proxy state in database",
 			"proxy_id", proxy.ID,
-			"previous_status", proxy.Status,
-			"new_status", newStatus)
-		proxy.Status = newStatus
-		if err := s.proxyRepo.Update(proxy); err != nil {
-			s.logger.ErrorContext(ctx, "Failed to update proxy status in database",
-				"proxy_id", proxy.ID,
-				"error", err)
-			return err
-		}
+			"error", err)
+		return err
+	}
+
+	if newStatus != previousStatus {
+		s.events.Publish(models.EventProxyStatusChanged, "", models.StatusChange{
+			PreviousStatus: previousStatus,
+			Status:         newStatus,
+		})
 	}
 
 	return nil
 }
 
-// GetProxy retrieves the proxy with synced state
+// ReconcileProxy reconciles the proxy with Docker.
+// It is skipped while a proxy operation is in progress, the operation sets the state itself.
+func (s *ProxyService) ReconcileProxy(ctx context.Context) error {
+	if !s.mu.TryLock() {
+		s.logger.DebugContext(ctx, "Proxy operation in progress, skipping reconciliation")
+		return nil
+	}
+	defer s.mu.Unlock()
+
+	proxy, err := s.proxyRepo.FindByID(models.SingleProxyID)
+	if err != nil {
+		if errors.Is(err, database.ErrProxyNotFound) {
+			return nil // No proxy yet, nothing to reconcile
+		}
+		return err
+	}
+	return s.reconcileProxyState(ctx, proxy)
+}
+
+// GetProxy retrieves the proxy with its last reconciled state
 func (s *ProxyService) GetProxy(ctx context.Context) (*models.ProxyServer, error) {
 	proxy, err := s.proxyRepo.FindByID(models.SingleProxyID)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "Failed to retrieve proxy from database", "error", err)
 		return nil, err
 	}
-
-	// Sync state before returning
-	if err := s.syncProxyState(ctx, proxy); err != nil {
-		// Return proxy with last known state if sync fails
-		s.logger.WarnContext(ctx, "Failed to sync proxy state, returning last known state",
-			"proxy_id", proxy.ID,
-			"error", err)
-	}
-
 	return proxy, nil
 }
 
@@ -396,9 +450,10 @@ func (s *ProxyService) RegenerateProxyConfig(ctx context.Context) error {
 		if err != nil {
 			return err
 		}
-		defaultServerName = server.Name
-	} else {
-		defaultServerName = ""
+		// Players can't join a default server that doesn't accept logins, they go to the try list instead
+		if server.Status == models.StatusRunning {
+			defaultServerName = server.Name
+		}
 	}
 
 	config := s.generateVelocityConfig(servers, defaultServerName)
@@ -408,48 +463,81 @@ func (s *ProxyService) RegenerateProxyConfig(ctx context.Context) error {
 		return fmt.Errorf("failed to write config to container: %w", err)
 	}
 
+	s.events.Publish(models.EventProxyConfigRegenerated, "", nil)
 	return nil
 }
 
 // writeConfigToContainer writes the Velocity config to the container via docker exec
 func (s *ProxyService) writeConfigToContainer(ctx context.Context, containerID, config string) error {
-	// Create exec to write the config file
 	// We use sh -c with cat to write the file
+	_, err := s.execInContainer(ctx, containerID, []string{"sh", "-c", fmt.Sprintf("cat > /server/velocity.toml << 'VELOCITYEOF'\n%s\nVELOCITYEOF", config)})
+	return err
+}
+
+// SendPlayers moves all players on one server to another through the proxy console. Velocity has no RCON of
+// its own, so the proxy container needs rcon-cli and an RCON plugin for it.
+func (s *ProxyService) SendPlayers(ctx context.Context, from, to string) error {
+	proxy, err := s.proxyRepo.FindByID(models.SingleProxyID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.execInContainer(ctx, proxy.ContainerID, []string{"sh", "-c", "command -v rcon-cli"}); err != nil {
+		return fmt.Errorf("failed to send players from %s to %s: the proxy image has no rcon-cli to reach the proxy console", from, to)
+	}
+	if _, err := s.execInContainer(ctx, proxy.ContainerID, []string{"rcon-cli", "send", from, to}); err != nil {
+		return fmt.Errorf("failed to send players from %s to %s, the proxy needs an RCON plugin for its console: %w", from, to, err)
+	}
+
+	s.logger.InfoContext(ctx, "Players sent to another server",
+		"from", from,
+		"to", to)
+	return nil
+}
+
+// execInContainer runs a command in the proxy container and fails if it exits with an error
+func (s *ProxyService) execInContainer(ctx context.Context, containerID string, cmd []string) (string, error) {
 	execConfig := container.ExecOptions{
-		Cmd:          []string{"sh", "-c", fmt.Sprintf("cat > /server/velocity.toml << 'VELOCITYEOF'\n%s\nVELOCITYEOF", config)},
+		Cmd:          cmd,
 		AttachStdout: true,
 		AttachStderr: true,
 	}
 
 	execResp, err := s.dockerService.client.ContainerExecCreate(ctx, containerID, execConfig)
 	if err != nil {
-		return fmt.Errorf("failed to create exec: %w", err)
+		return "", fmt.Errorf("failed to create exec: %w", err)
 	}
 
 	// Start the exec
 	attachResp, err := s.dockerService.client.ContainerExecAttach(ctx, execResp.ID, container.ExecStartOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to attach to exec: %w", err)
+		return "", fmt.Errorf("failed to attach to exec: %w", err)
 	}
 	defer attachResp.Close()
 
 	// Wait for exec to complete and read any output
 	output, err := io.ReadAll(attachResp.Reader)
 	if err != nil {
-		return fmt.Errorf("failed to read exec output: %w", err)
+		return "", fmt.Errorf("failed to read exec output: %w", err)
 	}
 
 	// Check if exec was successful
 	inspectResp, err := s.dockerService.client.ContainerExecInspect(ctx, execResp.ID)
 	if err != nil {
-		return fmt.Errorf("failed to inspect exec: %w", err)
+		return "", fmt.Errorf("failed to inspect exec: %w", err)
 	}
 
 	if inspectResp.ExitCode != 0 {
-		return fmt.Errorf("exec failed with exit code %d: %s", inspectResp.ExitCode, string(output))
+		return "", fmt.Errorf("exec failed with exit code %d: %s", inspectResp.ExitCode, string(output))
 	}
 
-	return nil
+	return string(output), nil
+}
+
+// listedInProxy reports whether a server with the given status is registered in the proxy config.
+// Running servers accept logins, stopping servers still have players that are moved to the fallback server.
+func listedInProxy(status models.ContainerStatus) bool {
+	return status == models.StatusRunning || status == models.StatusStopping
 }
 
 // generateVelocityConfig generates Velocity TOML configuration
@@ -458,10 +546,20 @@ func (s *ProxyService) generateVelocityConfig(servers []*models.MinecraftServer,
 	var tryList []string
 
 	for _, server := range servers {
+		// Directly exposed servers bypass the proxy, servers that don't accept logins are left out
+		// so Velocity never sends players to them
+		if server.ExposeDirectly || !listedInProxy(server.Status) {
+			continue
+		}
+
 		// Use server name as DNS name (Docker network alias)
 		serverEntries = append(serverEntries, fmt.Sprintf(`
 %s = "%s:25565"`, server.Name, server.Name))
-		tryList = append(tryList, fmt.Sprintf(`"%s"`, server.Name))
+
+		// A stopping server stays registered so its players can be moved, but new players don't join it
+		if server.Status == models.StatusRunning {
+			tryList = append(tryList, fmt.Sprintf(`"%s"`, server.Name))
+		}
 	}
 
 	var tryConfigProperty string
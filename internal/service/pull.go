@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+)
+
+// pullMessage is a message of the JSON stream returned by an image pull
+type pullMessage struct {
+	ID             string `json:"id"`
+	Status         string `json:"status"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+	ErrorDetail *struct {
+		Message string `json:"message"`
+	} `json:"errorDetail"`
+	Error string `json:"error"`
+}
+
+// errorMessage returns the error reported by the message, if any
+func (m *pullMessage) errorMessage() string {
+	if m.ErrorDetail != nil && m.ErrorDetail.Message != "" {
+		return m.ErrorDetail.Message
+	}
+	return m.Error
+}
+
+// pullTracker aggregates the messages of an image pull into per-layer and total progress
+type pullTracker struct {
+	pull   models.ImagePull
+	layers map[string]*models.LayerProgress
+	order  []string // Layer IDs in the order Docker reported them
+}
+
+// newPullTracker creates a tracker for a pull that starts now
+func newPullTracker(imageName string) *pullTracker {
+	return &pullTracker{
+		pull: models.ImagePull{
+			Image:     imageName,
+			Status:    models.ImagePullPulling,
+			StartedAt: time.Now().UTC(),
+		},
+		layers: make(map[string]*models.LayerProgress),
+	}
+}
+
+// apply updates the progress with a message of the pull stream
+func (t *pullTracker) apply(msg *pullMessage) {
+	// Messages without an ID are about the image as a whole, e.g. "Digest: ..."
+	if msg.ID == "" || msg.ID == t.pull.Image {
+		return
+	}
+
+	layer, ok := t.layers[msg.ID]
+	if !ok {
+		layer = &models.LayerProgress{ID: msg.ID}
+		t.layers[msg.ID] = layer
+		t.order = append(t.order, msg.ID)
+	}
+	layer.Status = msg.Status
+
+	switch msg.Status {
+	case "Downloading":
+		layer.DownloadedBytes = msg.ProgressDetail.Current
+		if msg.ProgressDetail.Total > 0 {
+			layer.TotalBytes = msg.ProgressDetail.Total
+		}
+	case "Verifying Checksum", "Download complete", "Extracting", "Pull complete":
+		// Extraction progress is reported in the same fields, only count the download
+		layer.DownloadedBytes = layer.TotalBytes
+	}
+}
+
+// finish marks the pull as completed, or failed if err is set
+func (t *pullTracker) finish(err error) {
+	now := time.Now().UTC()
+	t.pull.FinishedAt = &now
+	if err != nil {
+		t.pull.Status = models.ImagePullFailed
+		t.pull.Error = err.Error()
+		return
+	}
+	t.pull.Status = models.ImagePullCompleted
+}
+
+// snapshot returns the current progress
+func (t *pullTracker) snapshot() *models.ImagePull {
+	pull := t.pull
+	pull.Layers = make([]models.LayerProgress, 0, len(t.order))
+	pull.DownloadedBytes = 0
+	pull.TotalBytes = 0
+	for _, id := range t.order {
+		layer := t.layers[id]
+		pull.Layers = append(pull.Layers, *layer)
+		pull.DownloadedBytes += layer.DownloadedBytes
+		pull.TotalBytes += layer.TotalBytes
+	}
+
+	switch {
+	case pull.Status == models.ImagePullCompleted:
+		pull.Percent = 100
+	case pull.TotalBytes > 0:
+		pull.Percent = float64(pull.DownloadedBytes) * 100 / float64(pull.TotalBytes)
+	}
+	return &pull
+}
+
+// PullProgressFunc receives the progress of an image pull
+type PullProgressFunc func(pull *models.ImagePull)
+
+// pullProgressKey is the context key of the PullProgressFunc
+type pullProgressKey struct{}
+
+// WithPullProgress returns a context that reports the progress of image pulls made with it to fn.
+// Functions registered on parent contexts keep receiving the progress as well.
+func WithPullProgress(ctx context.Context, fn PullProgressFunc) context.Context {
+	if parent, ok := ctx.Value(pullProgressKey{}).(PullProgressFunc); ok {
+		next := fn
+		fn = func(pull *models.ImagePull) {
+			parent(pull)
+			next(pull)
+		}
+	}
+	return context.WithValue(ctx, pullProgressKey{}, fn)
+}
+
+// notifyPullProgress passes the progress of a pull to the function registered on ctx
+func notifyPullProgress(ctx context.Context, pull *models.ImagePull) {
+	if fn, ok := ctx.Value(pullProgressKey{}).(PullProgressFunc); ok {
+		fn(pull)
+	}
+}
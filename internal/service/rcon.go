@@ -0,0 +1,495 @@
+package service
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+)
+
+// Packet types of the Source RCON protocol Minecraft implements
+const (
+	rconTypeResponse = 0
+	rconTypeCommand  = 2
+	rconTypeAuth     = 3
+)
+
+const (
+	// rconPort is the RCON port of itzg servers, RCON_PORT can't be overridden
+	rconPort = "25575"
+	// rconDialTimeout bounds connecting and authenticating
+	rconDialTimeout = 3 * time.Second
+	// rconTimeout bounds a single command unless ctx ends earlier
+	rconTimeout = 10 * time.Second
+	// maxRCONCommandLength is the longest command Minecraft accepts in a single packet
+	maxRCONCommandLength = 1446
+	// maxRCONPacketSize limits a single packet, Minecraft splits responses into packets of 4096 bytes
+	maxRCONPacketSize = 1 << 16
+	// maxRCONResponseSize limits the response of a command over all packets
+	maxRCONResponseSize = 1 << 20
+	// rconIdleConns is how many connections per server are kept open between commands
+	rconIdleConns = 2
+)
+
+var (
+	// errRCONUnreachable is returned if no RCON connection could be established, so the command was not sent
+	errRCONUnreachable = errors.New("rcon unreachable")
+	// errRCONAuthFailed is returned if the server rejected the RCON password
+	errRCONAuthFailed = errors.New("rcon authentication failed")
+)
+
+// rconConn is an authenticated RCON connection to a server
+type rconConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	nextID int32
+}
+
+// dialRCON connects to a server and authenticates with the password
+func dialRCON(ctx context.Context, address, password string) (*rconConn, error) {
+	ctx, cancel := context.WithTimeout(ctx, rconDialTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	c := &rconConn{conn: conn, reader: bufio.NewReader(conn)}
+
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	if err := c.authenticate(password); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return c, nil
+}
+
+// authenticate sends the password, the server answers with the request id or -1 if it is wrong
+func (c *rconConn) authenticate(password string) error {
+	id := c.newID()
+	if err := c.writePacket(id, rconTypeAuth, password); err != nil {
+		return err
+	}
+
+	// Source servers send an empty response before the auth response, Minecraft doesn't
+	for {
+		responseID, packetType, _, err := c.readPacket()
+		if err != nil {
+			return err
+		}
+		if packetType != rconTypeCommand {
+			continue
+		}
+		if responseID == -1 {
+			return errRCONAuthFailed
+		}
+		if responseID != id {
+			return fmt.Errorf("unexpected auth response id %d", responseID)
+		}
+		return nil
+	}
+}
+
+// execute runs a command and returns its output. Long outputs arrive in several packets, so an invalid
+// request follows the command and everything up to its answer belongs to the command. Minecraft drops the
+// connection if a single read contains two packets, so the invalid request is only sent once the first
+// packet of the output arrived.
+func (c *rconConn) execute(ctx context.Context, command string) (string, error) {
+	deadline := time.Now().Add(rconTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	c.conn.SetDeadline(deadline)
+	defer c.conn.SetDeadline(time.Time{})
+
+	// Unblock reads and writes as soon as ctx is cancelled
+	stop := context.AfterFunc(ctx, func() {
+		c.conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	id := c.newID()
+	endID := c.newID()
+	if err := c.writePacket(id, rconTypeCommand, command); err != nil {
+		return "", err
+	}
+
+	endSent := false
+	var output bytes.Buffer
+	for {
+		responseID, _, body, err := c.readPacket()
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			return "", err
+		}
+		switch responseID {
+		case endID:
+			return output.String(), nil
+		case id:
+			if output.Len()+len(body) > maxRCONResponseSize {
+				return "", fmt.Errorf("response exceeds %d bytes", maxRCONResponseSize)
+			}
+			output.Write(body)
+
+			// Minecraft sends all packets of the output before it reads the next request
+			if !endSent {
+				if err := c.writePacket(endID, rconTypeResponse, ""); err != nil {
+					return "", err
+				}
+				endSent = true
+			}
+		}
+	}
+}
+
+// newID returns the id of the next request
+func (c *rconConn) newID() int32 {
+	c.nextID++
+	return c.nextID
+}
+
+// writePacket sends a packet: length, request id and type as little endian int32, then the body
+// terminated by two NUL bytes
+func (c *rconConn) writePacket(id, packetType int32, body string) error {
+	var packet bytes.Buffer
+	binary.Write(&packet, binary.LittleEndian, int32(4+4+len(body)+2))
+	binary.Write(&packet, binary.LittleEndian, id)
+	binary.Write(&packet, binary.LittleEndian, packetType)
+	packet.WriteString(body)
+	packet.Write([]byte{0, 0})
+	_, err := c.conn.Write(packet.Bytes())
+	return err
+}
+
+// readPacket reads a packet and returns its request id, type and body
+func (c *rconConn) readPacket() (int32, int32, []byte, error) {
+	var length int32
+	if err := binary.Read(c.reader, binary.LittleEndian, &length); err != nil {
+		return 0, 0, nil, err
+	}
+	if length < 10 || length > maxRCONPacketSize {
+		return 0, 0, nil, fmt.Errorf("invalid packet length %d", length)
+	}
+	packet := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, packet); err != nil {
+		return 0, 0, nil, err
+	}
+
+	id := int32(binary.LittleEndian.Uint32(packet[0:4]))
+	packetType := int32(binary.LittleEndian.Uint32(packet[4:8]))
+	return id, packetType, packet[8 : length-2], nil
+}
+
+// Close closes the connection
+func (c *rconConn) Close() error {
+	return c.conn.Close()
+}
+
+// rconServer holds what is known about the RCON endpoint of a server and its idle connections
+type rconServer struct {
+	containerID string
+	password    string
+	idle        []*rconConn
+}
+
+// rconPool keeps RCON connections to servers open between commands
+type rconPool struct {
+	mu      sync.Mutex
+	servers map[string]*rconServer
+}
+
+func newRCONPool() *rconPool {
+	return &rconPool{servers: make(map[string]*rconServer)}
+}
+
+// take returns an idle connection to the container of a server, or nil if there is none
+func (p *rconPool) take(serverID, containerID string) *rconConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	server, ok := p.servers[serverID]
+	if !ok || server.containerID != containerID || len(server.idle) == 0 {
+		return nil
+	}
+	conn := server.idle[len(server.idle)-1]
+	server.idle = server.idle[:len(server.idle)-1]
+	return conn
+}
+
+// put returns a connection for reuse, it is closed if enough connections are idle
+func (p *rconPool) put(serverID, containerID string, conn *rconConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	server, ok := p.servers[serverID]
+	if !ok || server.containerID != containerID || len(server.idle) >= rconIdleConns {
+		conn.Close()
+		return
+	}
+	server.idle = append(server.idle, conn)
+}
+
+// password returns the password last used for a server, or "" if it is unknown
+func (p *rconPool) password(serverID string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if server, ok := p.servers[serverID]; ok {
+		return server.password
+	}
+	return ""
+}
+
+// setEndpoint records the container and password of a server. Idle connections to another container
+// or with another password are closed.
+func (p *rconPool) setEndpoint(serverID, containerID, password string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	server, ok := p.servers[serverID]
+	if ok && server.containerID == containerID && server.password == password {
+		return
+	}
+	if ok {
+		closeAll(server.idle)
+	}
+	p.servers[serverID] = &rconServer{containerID: containerID, password: password}
+}
+
+// close closes the connections of a server and forgets it, e.g. when its container is removed
+func (p *rconPool) close(serverID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if server, ok := p.servers[serverID]; ok {
+		closeAll(server.idle)
+		delete(p.servers, serverID)
+	}
+}
+
+// closeAll closes connections
+func closeAll(conns []*rconConn) {
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
+// SetSecretBox sets the secret box RCON passwords are encrypted with in the database
+func (s *MinecraftServerService) SetSecretBox(secrets *SecretBox) {
+	s.secrets = secrets
+}
+
+// RotateRCONPassword gives a server a new RCON password. The container is recreated to pass it to the
+// server, so a running server restarts.
+func (s *MinecraftServerService) RotateRCONPassword(ctx context.Context, id string) error {
+	unlock, err := s.locks.LockOrConflict(id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	server, err := s.repo.FindByID(id)
+	if err != nil {
+		return err
+	}
+
+	reportStep(ctx, "Generating password", 10)
+	password, err := generateRCONPassword()
+	if err != nil {
+		return err
+	}
+	if err := s.storeRCONPassword(server, password); err != nil {
+		return err
+	}
+
+	reportStep(ctx, "Recreating container", 30)
+	if err := s.recreateContainer(ctx, server); err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "RCON password rotated", "server_id", server.ID)
+	s.events.Publish(models.EventServerUpdated, server.ID, server)
+	return nil
+}
+
+// containerRCONPassword returns the RCON password passed to a new container of a server. Servers created
+// before passwords were generated get one, a password stored in plaintext by older versions is encrypted.
+// If the stored password can't be decrypted because the master key changed, a new one is generated.
+func (s *MinecraftServerService) containerRCONPassword(ctx context.Context, server *models.MinecraftServer) (string, error) {
+	password, err := s.rconPassword(server)
+	if err != nil {
+		s.logger.WarnContext(ctx, "Failed to decrypt RCON password, generating a new one",
+			"server_id", server.ID,
+			"error", err)
+		password = ""
+	}
+	if password == "" {
+		if password, err = generateRCONPassword(); err != nil {
+			return "", err
+		}
+	}
+
+	if err := s.storeRCONPassword(server, password); err != nil {
+		return "", err
+	}
+	return password, nil
+}
+
+// storeRCONPassword encrypts an RCON password into the server model, the caller persists the server
+func (s *MinecraftServerService) storeRCONPassword(server *models.MinecraftServer, password string) error {
+	if s.secrets == nil {
+		return errors.New("no master key configured")
+	}
+	encrypted, err := s.secrets.Encrypt(password)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt RCON password: %w", err)
+	}
+	server.RCONPassword = encrypted
+	return nil
+}
+
+// rconPassword returns the decrypted RCON password of a server, or "" if it has none
+func (s *MinecraftServerService) rconPassword(server *models.MinecraftServer) (string, error) {
+	if server.RCONPassword == "" || !isEncryptedSecret(server.RCONPassword) {
+		// Stored in plaintext by an older version, encrypted once the container is recreated
+		return server.RCONPassword, nil
+	}
+	if s.secrets == nil {
+		return "", errors.New("no master key configured")
+	}
+	return s.secrets.Decrypt(server.RCONPassword)
+}
+
+// executeRCON runs a command over a pooled RCON connection to a server
+func (s *MinecraftServerService) executeRCON(ctx context.Context, server *models.MinecraftServer, command string) (string, error) {
+	// An idle connection may have broken since its last command, e.g. because the server restarted.
+	// The command didn't reach the server then and is sent again on a new connection.
+	if conn := s.rcon.take(server.ID, server.ContainerID); conn != nil {
+		output, err := conn.execute(ctx, command)
+		if err == nil {
+			s.rcon.put(server.ID, server.ContainerID, conn)
+			return output, nil
+		}
+		conn.Close()
+		if ctx.Err() != nil || isTimeout(err) {
+			// The server may be executing the command, sending it again could run it twice
+			return "", fmt.Errorf("rcon command failed: %w", err)
+		}
+	}
+
+	conn, err := s.dialServerRCON(ctx, server)
+	if err != nil {
+		return "", err
+	}
+	output, err := conn.execute(ctx, command)
+	if err != nil {
+		conn.Close()
+		return "", fmt.Errorf("rcon command failed: %w", err)
+	}
+	s.rcon.put(server.ID, server.ContainerID, conn)
+	return output, nil
+}
+
+// dialServerRCON opens an RCON connection to the container of a server. If the stored password is missing
+// or rejected, the current one is read from server.properties and stored. All errors wrap errRCONUnreachable.
+func (s *MinecraftServerService) dialServerRCON(ctx context.Context, server *models.MinecraftServer) (*rconConn, error) {
+	state, err := s.dockerService.GetContainerState(ctx, server.ContainerID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errRCONUnreachable, err)
+	}
+	if !state.Running || state.IPAddress == "" {
+		return nil, fmt.Errorf("%w: container is not running or has no network address", errRCONUnreachable)
+	}
+	address := net.JoinHostPort(state.IPAddress, rconPort)
+
+	password := s.rcon.password(server.ID)
+	if password == "" {
+		if password, err = s.rconPassword(server); err != nil {
+			s.logger.WarnContext(ctx, "Failed to decrypt RCON password",
+				"server_id", server.ID,
+				"error", err)
+		}
+	}
+	if password != "" {
+		conn, err := dialRCON(ctx, address, password)
+		if err == nil {
+			s.rcon.setEndpoint(server.ID, server.ContainerID, password)
+			return conn, nil
+		}
+		if !errors.Is(err, errRCONAuthFailed) {
+			return nil, fmt.Errorf("%w: %w", errRCONUnreachable, err)
+		}
+	}
+
+	password, err = s.readRCONPassword(ctx, server)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errRCONUnreachable, err)
+	}
+	conn, err := dialRCON(ctx, address, password)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errRCONUnreachable, err)
+	}
+	s.rcon.setEndpoint(server.ID, server.ContainerID, password)
+
+	// Containers created before the manager generated passwords get a new one from itzg on every start
+	if err := s.storeRCONPassword(server, password); err != nil {
+		s.logger.WarnContext(ctx, "Failed to encrypt RCON password",
+			"server_id", server.ID,
+			"error", err)
+	} else if err := s.repo.UpdateRCONPassword(server.ID, server.RCONPassword); err != nil {
+		s.logger.WarnContext(ctx, "Failed to store RCON password",
+			"server_id", server.ID,
+			"error", err)
+	}
+	return conn, nil
+}
+
+// readRCONPassword reads the RCON password from the server.properties of a running server
+func (s *MinecraftServerService) readRCONPassword(ctx context.Context, server *models.MinecraftServer) (string, error) {
+	body, _, err := s.dockerService.client.CopyFromContainer(ctx, server.ContainerID, path.Join("/data", serverPropertiesPath))
+	if err != nil {
+		return "", fmt.Errorf("failed to read server.properties: %w", err)
+	}
+	defer body.Close()
+
+	// Docker wraps the file in a tar archive
+	tr := tar.NewReader(body)
+	if _, err := tr.Next(); err != nil {
+		return "", fmt.Errorf("failed to read server.properties: %w", err)
+	}
+	data, err := io.ReadAll(tr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read server.properties: %w", err)
+	}
+
+	values := parsePropertiesFile(string(data)).Values()
+	if values["enable-rcon"] != "true" {
+		return "", errors.New("rcon is disabled in server.properties")
+	}
+	password := values["rcon.password"]
+	if password == "" {
+		return "", errors.New("server.properties has no rcon password")
+	}
+	return password, nil
+}
+
+// isTimeout reports whether a network operation failed because its deadline passed
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
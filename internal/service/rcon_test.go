@@ -0,0 +1,182 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rconFragmentSize is the size of the packets Minecraft splits long outputs into
+const rconFragmentSize = 4096
+
+// asyncWriteConn buffers writes like the send buffer of a TCP socket, so the client can write a request
+// while the fake server is still writing the previous response into the unbuffered pipe
+type asyncWriteConn struct {
+	net.Conn
+	writes chan []byte
+}
+
+func newAsyncWriteConn(conn net.Conn) *asyncWriteConn {
+	c := &asyncWriteConn{Conn: conn, writes: make(chan []byte, 16)}
+	go func() {
+		for data := range c.writes {
+			if _, err := c.Conn.Write(data); err != nil {
+				return
+			}
+		}
+	}()
+	return c
+}
+
+func (c *asyncWriteConn) Write(data []byte) (int, error) {
+	c.writes <- append([]byte(nil), data...)
+	return len(data), nil
+}
+
+// serveFakeRCON answers RCON requests on conn like Minecraft does. It handles one request at a time and fails
+// if the next request was sent before the response, since Minecraft drops the connection if a single read
+// contains more than one packet.
+func serveFakeRCON(conn net.Conn, password string, output func(command string) string) error {
+	server := &rconConn{conn: conn, reader: bufio.NewReader(conn)}
+	for {
+		// Errors reading or writing mean the client closed the connection
+		id, packetType, body, err := server.readPacket()
+		if err != nil {
+			return nil
+		}
+
+		if server.reader.Buffered() > 0 {
+			return errors.New("received another packet along with the request")
+		}
+		conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+		_, err = server.reader.Peek(1)
+		conn.SetReadDeadline(time.Time{})
+		if err == nil {
+			return errors.New("received the next request before the response was sent")
+		}
+
+		switch packetType {
+		case rconTypeAuth:
+			if string(body) != password {
+				id = -1
+			}
+			if err := server.writePacket(id, rconTypeCommand, ""); err != nil {
+				return nil
+			}
+		case rconTypeCommand:
+			response := output(string(body))
+			for {
+				fragment := response[:min(len(response), rconFragmentSize)]
+				response = response[len(fragment):]
+				if err := server.writePacket(id, rconTypeResponse, fragment); err != nil {
+					return nil
+				}
+				if response == "" {
+					break
+				}
+			}
+		default:
+			if err := server.writePacket(id, rconTypeResponse, fmt.Sprintf("Unknown request %x", packetType)); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// connectFakeRCON starts a fake server and returns an unauthenticated connection to it
+func connectFakeRCON(t *testing.T, password string, output func(command string) string) *rconConn {
+	t.Helper()
+
+	clientConn, serverConn := net.Pipe()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- serveFakeRCON(serverConn, password, output)
+	}()
+
+	client := newAsyncWriteConn(clientConn)
+	t.Cleanup(func() {
+		client.Close()
+		serverConn.Close()
+		assert.NoError(t, <-errs)
+	})
+	return &rconConn{conn: client, reader: bufio.NewReader(client)}
+}
+
+func TestRCONAuthenticate(t *testing.T) {
+	c := connectFakeRCON(t, "secret", func(string) string { return "" })
+	require.NoError(t, c.authenticate("secret"))
+}
+
+func TestRCONAuthenticateWrongPassword(t *testing.T) {
+	c := connectFakeRCON(t, "secret", func(string) string { return "" })
+	assert.ErrorIs(t, c.authenticate("wrong"), errRCONAuthFailed)
+}
+
+func TestRCONExecute(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+	}{
+		{
+			name:   "empty response",
+			output: "",
+		},
+		{
+			name:   "short response",
+			output: "There are 0 of a max of 20 players online: ",
+		},
+		{
+			name:   "response split into two packets",
+			output: strings.Repeat("a", rconFragmentSize) + "end",
+		},
+		{
+			name:   "response of exactly one packet",
+			output: strings.Repeat("b", rconFragmentSize),
+		},
+		{
+			name:   "large response",
+			output: strings.Repeat("0123456789", 10*rconFragmentSize),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := connectFakeRCON(t, "secret", func(command string) string {
+				if command != "list" {
+					return "Unknown command"
+				}
+				return tt.output
+			})
+			require.NoError(t, c.authenticate("secret"))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			// Run it twice, the second command must not see the end of the first
+			for range 2 {
+				output, err := c.execute(ctx, "list")
+				require.NoError(t, err)
+				assert.Equal(t, tt.output, output)
+			}
+		})
+	}
+}
+
+func TestRCONExecuteCancelled(t *testing.T) {
+	c := connectFakeRCON(t, "secret", func(string) string { return "" })
+	require.NoError(t, c.authenticate("secret"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := c.execute(ctx, "list")
+	assert.Error(t, err)
+}
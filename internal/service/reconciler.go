@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Reconciler periodically compares all servers and the proxy with Docker and fixes their stored state.
+// The EventWatcher uses it to reconcile single containers as soon as Docker reports a change.
+type Reconciler struct {
+	mcService    *MinecraftServerService
+	proxyService *ProxyService
+	interval     time.Duration
+	logger       *slog.Logger
+}
+
+// NewReconciler creates a new reconciler running every interval
+func NewReconciler(mcService *MinecraftServerService, proxyService *ProxyService, interval time.Duration, logger *slog.Logger) *Reconciler {
+	return &Reconciler{
+		mcService:    mcService,
+		proxyService: proxyService,
+		interval:     interval,
+		logger:       logger,
+	}
+}
+
+// Run reconciles immediately and then on every tick until the context is cancelled
+func (r *Reconciler) Run(ctx context.Context) {
+	r.logger.InfoContext(ctx, "Reconciler started", "interval", r.interval.String())
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	r.ReconcileAll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.InfoContext(ctx, "Reconciler stopped")
+			return
+		case <-ticker.C:
+			r.ReconcileAll(ctx)
+		}
+	}
+}
+
+// ReconcileServer reconciles a single server, e.g. after a Docker event for its container
+func (r *Reconciler) ReconcileServer(ctx context.Context, id string) {
+	if err := r.mcService.ReconcileServer(ctx, id); err != nil {
+		r.logger.WarnContext(ctx, "Failed to reconcile server", "server_id", id, "error", err)
+	}
+}
+
+// ReconcileProxy reconciles the proxy, e.g. after a Docker event for its container
+func (r *Reconciler) ReconcileProxy(ctx context.Context) {
+	if err := r.proxyService.ReconcileProxy(ctx); err != nil {
+		r.logger.WarnContext(ctx, "Failed to reconcile proxy", "error", err)
+	}
+}
+
+// ReconcileAll reconciles all servers and the proxy once
+func (r *Reconciler) ReconcileAll(ctx context.Context) {
+	if err := r.mcService.ReconcileServers(ctx); err != nil {
+		r.logger.WarnContext(ctx, "Failed to reconcile servers", "error", err)
+	}
+	if err := r.proxyService.ReconcileProxy(ctx); err != nil {
+		r.logger.WarnContext(ctx, "Failed to reconcile proxy", "error", err)
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+)
+
+const (
+	// jvmOverheadMB is the memory kept free inside the container limit for non-heap JVM memory
+	jvmOverheadMB = 256
+	// minMemoryMB is the smallest container memory limit a server can run with
+	minMemoryMB = 512
+	// minJVMHeapMB is the smallest heap a server can start with
+	minJVMHeapMB = 256
+)
+
+// validateResources checks that resource limits are sane and that the JVM heap fits inside the container limit
+func validateResources(memoryMB int, cpuLimit float64, jvmHeapMB int) error {
+	if memoryMB < 0 || cpuLimit < 0 || jvmHeapMB < 0 {
+		return validationError("memory_mb, cpu_limit and jvm_heap_mb must not be negative")
+	}
+	if memoryMB > 0 && memoryMB < minMemoryMB {
+		return validationError("memory_mb must be at least %d", minMemoryMB)
+	}
+	if jvmHeapMB > 0 && jvmHeapMB < minJVMHeapMB {
+		return validationError("jvm_heap_mb must be at least %d", minJVMHeapMB)
+	}
+	if cpuLimit > 0 && cpuLimit < 0.1 {
+		return validationError("cpu_limit must be at least 0.1")
+	}
+	if memoryMB > 0 && jvmHeapMB > memoryMB-jvmOverheadMB {
+		return validationError("jvm_heap_mb must leave at least %d MB of memory_mb for the JVM overhead (max %d)",
+			jvmOverheadMB, memoryMB-jvmOverheadMB)
+	}
+	return nil
+}
+
+// effectiveJVMHeapMB returns the heap size passed to the server, or 0 to keep the image default.
+// Without an explicit heap, 75% of the container limit is used so the JVM can't exceed it,
+// but at most what leaves jvmOverheadMB for the JVM like an explicit heap has to.
+func effectiveJVMHeapMB(server *models.MinecraftServer) int {
+	if server.JVMHeapMB > 0 {
+		return server.JVMHeapMB
+	}
+	if server.MemoryMB > 0 {
+		return min(server.MemoryMB*3/4, server.MemoryMB-jvmOverheadMB)
+	}
+	return 0
+}
+
+// resourceEnv returns the itzg environment variables for the JVM heap. The initial and maximum heap are
+// set themselves instead of through MEMORY, which the image only uses as their default.
+func resourceEnv(server *models.MinecraftServer) []string {
+	heapMB := effectiveJVMHeapMB(server)
+	if heapMB == 0 {
+		return nil
+	}
+	return []string{
+		fmt.Sprintf("INIT_MEMORY=%dM", heapMB),
+		fmt.Sprintf("MAX_MEMORY=%dM", heapMB),
+	}
+}
+
+// containerResources maps the server's limits to Docker resources
+func containerResources(server *models.MinecraftServer) container.Resources {
+	return container.Resources{
+		Memory:   int64(server.MemoryMB) * 1024 * 1024,
+		NanoCPUs: int64(server.CPULimit * 1e9),
+	}
+}
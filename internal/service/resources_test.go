@@ -0,0 +1,67 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEffectiveJVMHeapMB(t *testing.T) {
+	tests := []struct {
+		name      string
+		memoryMB  int
+		jvmHeapMB int
+		want      int
+	}{
+		{name: "unlimited memory keeps the image default", want: 0},
+		{name: "explicit heap", memoryMB: 4096, jvmHeapMB: 3072, want: 3072},
+		{name: "75% of a large limit", memoryMB: 4096, want: 3072},
+		{name: "overhead of a small limit", memoryMB: 512, want: 256},
+		{name: "overhead and 75% are equal", memoryMB: 1024, want: 768},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &models.MinecraftServer{MemoryMB: tt.memoryMB, JVMHeapMB: tt.jvmHeapMB}
+			assert.Equal(t, tt.want, effectiveJVMHeapMB(server))
+		})
+	}
+}
+
+func TestValidateResources(t *testing.T) {
+	tests := []struct {
+		name      string
+		memoryMB  int
+		cpuLimit  float64
+		jvmHeapMB int
+		valid     bool
+	}{
+		{name: "unlimited", valid: true},
+		{name: "limits with heap", memoryMB: 4096, cpuLimit: 2, jvmHeapMB: 3072, valid: true},
+		{name: "smallest heap", memoryMB: 512, jvmHeapMB: 256, valid: true},
+		{name: "heap without memory limit", jvmHeapMB: 2048, valid: true},
+		{name: "heap too small", jvmHeapMB: 1},
+		{name: "heap leaves no overhead", memoryMB: 1024, jvmHeapMB: 1000},
+		{name: "memory too small", memoryMB: 256},
+		{name: "cpu too small", cpuLimit: 0.01},
+		{name: "negative", memoryMB: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateResources(tt.memoryMB, tt.cpuLimit, tt.jvmHeapMB)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrValidation)
+			}
+		})
+	}
+}
+
+func TestResourceEnv(t *testing.T) {
+	assert.Nil(t, resourceEnv(&models.MinecraftServer{}))
+	assert.Equal(t, []string{"INIT_MEMORY=3072M", "MAX_MEMORY=3072M"}, resourceEnv(&models.MinecraftServer{MemoryMB: 4096}))
+	assert.Equal(t, []string{"INIT_MEMORY=2048M", "MAX_MEMORY=2048M"}, resourceEnv(&models.MinecraftServer{JVMHeapMB: 2048}))
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/database"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+)
+
+// RestartScheduler manages restart schedules and runs them from the serve process.
+// Like backup schedules they are read from the database on every check.
+type RestartScheduler struct {
+	mcService  *MinecraftServerService
+	operations *OperationService
+	repo       *database.RestartScheduleRepository
+	mu         sync.Mutex
+	running    map[string]bool // Servers with a scheduled restart in progress
+	logger     *slog.Logger
+}
+
+// NewRestartScheduler creates a new restart scheduler. Restarts run as operations, like restarts through the API.
+func NewRestartScheduler(mcService *MinecraftServerService, operations *OperationService, repo *database.RestartScheduleRepository, logger *slog.Logger) *RestartScheduler {
+	return &RestartScheduler{
+		mcService:  mcService,
+		operations: operations,
+		repo:       repo,
+		running:    make(map[string]bool),
+		logger:     logger,
+	}
+}
+
+// GetSchedule returns the restart schedule of a server
+func (s *RestartScheduler) GetSchedule(ctx context.Context, serverID string) (*models.RestartSchedule, error) {
+	return s.repo.FindByServerID(serverID)
+}
+
+// SetSchedule creates or replaces the restart schedule of a server
+func (s *RestartScheduler) SetSchedule(ctx context.Context, serverID string, req *models.RestartScheduleRequest) (*models.RestartSchedule, error) {
+	schedule, err := cronParser.Parse(req.Cron)
+	if err != nil {
+		return nil, validationError("invalid cron expression: %v", err)
+	}
+	if req.WarningSeconds < 0 || time.Duration(req.WarningSeconds)*time.Second > MaxStopGrace {
+		return nil, validationError("warning_seconds must be between 0 and %d", int(MaxStopGrace.Seconds()))
+	}
+
+	if _, err := s.mcService.repo.FindByID(serverID); err != nil {
+		return nil, err
+	}
+
+	// Keep the outcome of previous runs when the schedule is changed
+	restartSchedule, err := s.repo.FindByServerID(serverID)
+	if err != nil {
+		if !errors.Is(err, database.ErrRestartScheduleNotFound) {
+			return nil, err
+		}
+		restartSchedule = &models.RestartSchedule{ServerID: serverID}
+	}
+
+	restartSchedule.Cron = req.Cron
+	restartSchedule.Enabled = req.Enabled == nil || *req.Enabled
+	restartSchedule.WarningSeconds = req.WarningSeconds
+	nextRunAt := schedule.Next(time.Now())
+	restartSchedule.NextRunAt = &nextRunAt
+
+	if err := s.repo.Save(restartSchedule); err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "Restart schedule set",
+		"server_id", serverID,
+		"cron", req.Cron,
+		"enabled", restartSchedule.Enabled,
+		"next_run_at", nextRunAt)
+
+	return restartSchedule, nil
+}
+
+// DeleteSchedule removes the restart schedule of a server
+func (s *RestartScheduler) DeleteSchedule(ctx context.Context, serverID string) error {
+	if err := s.repo.Delete(serverID); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "Restart schedule deleted", "server_id", serverID)
+	return nil
+}
+
+// Run starts due restarts until ctx is cancelled.
+// A run that was missed while the manager was down is started once on startup.
+func (s *RestartScheduler) Run(ctx context.Context) {
+	s.logger.InfoContext(ctx, "Starting restart scheduler")
+
+	ticker := time.NewTicker(scheduleCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		s.startDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			s.logger.InfoContext(ctx, "Stopping restart scheduler")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// startDue starts the restarts of all due schedules that are not running yet
+func (s *RestartScheduler) startDue(ctx context.Context) {
+	now := time.Now()
+	schedules, err := s.repo.FindDue(now)
+	if err != nil {
+		s.logger.WarnContext(ctx, "Failed to look up due restart schedules", "error", err)
+		return
+	}
+
+	for _, schedule := range schedules {
+		// Move the schedule forward before running, so a restart with a long warning isn't started twice
+		cronSchedule, err := cronParser.Parse(schedule.Cron)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "Invalid cron expression in restart schedule",
+				"server_id", schedule.ServerID,
+				"cron", schedule.Cron,
+				"error", err)
+			continue
+		}
+		if err := s.repo.UpdateNextRun(schedule.ServerID, cronSchedule.Next(now)); err != nil {
+			continue
+		}
+
+		s.mu.Lock()
+		if s.running[schedule.ServerID] {
+			s.mu.Unlock()
+			s.logger.WarnContext(ctx, "Previous scheduled restart still running, skipping this run",
+				"server_id", schedule.ServerID)
+			continue
+		}
+		s.running[schedule.ServerID] = true
+		s.mu.Unlock()
+
+		if !s.runSchedule(ctx, schedule) {
+			s.finished(schedule.ServerID)
+		}
+	}
+}
+
+// finished allows the next scheduled restart of a server
+func (s *RestartScheduler) finished(serverID string) {
+	s.mu.Lock()
+	delete(s.running, serverID)
+	s.mu.Unlock()
+}
+
+// runSchedule starts the restart of the server of a schedule as an operation if the server is running.
+// The outcome is recorded on the schedule once the operation finished. It reports whether the operation
+// was started, it calls finished for the server when it is done.
+func (s *RestartScheduler) runSchedule(ctx context.Context, schedule *models.RestartSchedule) bool {
+	server, err := s.mcService.repo.FindByID(schedule.ServerID)
+	if errors.Is(err, database.ErrServerNotFound) {
+		s.logger.WarnContext(ctx, "Server of restart schedule no longer exists, deleting schedule",
+			"server_id", schedule.ServerID)
+		if err := s.repo.Delete(schedule.ServerID); err != nil && !errors.Is(err, database.ErrRestartScheduleNotFound) {
+			s.logger.ErrorContext(ctx, "Failed to delete restart schedule", "server_id", schedule.ServerID, "error", err)
+		}
+		return false
+	}
+
+	if err != nil {
+		s.recordRun(ctx, schedule, models.ScheduleRunFailed, err)
+		return false
+	}
+	if server.Status != models.StatusRunning {
+		// Restarting a stopped server would start it, which the schedule isn't meant for
+		s.logger.InfoContext(ctx, "Server is not running, skipping scheduled restart",
+			"server_id", schedule.ServerID,
+			"status", server.Status)
+		s.recordRun(ctx, schedule, models.ScheduleRunSkipped, nil)
+		return false
+	}
+
+	s.logger.InfoContext(ctx, "Running scheduled restart", "server_id", schedule.ServerID)
+
+	// The operation tracks the restart like one started through the API and isn't cancelled with ctx.
+	// serve waits for it on shutdown, a restart still in its countdown then is marked as interrupted.
+	grace := time.Duration(schedule.WarningSeconds) * time.Second
+	_, err = s.operations.Run(ctx, models.OperationRestartServer, schedule.ServerID, func(ctx context.Context) (any, error) {
+		defer s.finished(schedule.ServerID)
+
+		err := s.mcService.RestartServer(ctx, schedule.ServerID, grace)
+		if err != nil {
+			s.recordRun(ctx, schedule, models.ScheduleRunFailed, err)
+			return nil, err
+		}
+		s.recordRun(ctx, schedule, models.ScheduleRunSucceeded, nil)
+		return nil, nil
+	})
+	if err != nil {
+		s.recordRun(ctx, schedule, models.ScheduleRunFailed, err)
+		return false
+	}
+	return true
+}
+
+// recordRun stores the outcome of a scheduled restart on the schedule
+func (s *RestartScheduler) recordRun(ctx context.Context, schedule *models.RestartSchedule, status models.ScheduleRunStatus, runErr error) {
+	now := time.Now()
+	schedule.LastRunAt = &now
+	schedule.LastStatus = status
+	schedule.LastError = ""
+
+	switch status {
+	case models.ScheduleRunFailed:
+		schedule.LastError = runErr.Error()
+		schedule.ConsecutiveFailures++
+		s.logger.ErrorContext(ctx, "Scheduled restart failed",
+			"server_id", schedule.ServerID,
+			"consecutive_failures", schedule.ConsecutiveFailures,
+			"error", runErr)
+	case models.ScheduleRunSucceeded:
+		schedule.ConsecutiveFailures = 0
+		s.logger.InfoContext(ctx, "Scheduled restart succeeded", "server_id", schedule.ServerID)
+	}
+
+	if err := s.repo.UpdateLastRun(schedule); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to record scheduled restart run", "server_id", schedule.ServerID, "error", err)
+	}
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// masterKeySize is the size of the AES-256 master key
+	masterKeySize = 32
+	// secretPrefix marks values encrypted by a SecretBox, so plaintext stored by older versions is recognized
+	secretPrefix = "enc:v1:"
+	// rconPasswordBytes is the entropy of generated RCON passwords
+	rconPasswordBytes = 24
+)
+
+// SecretBox encrypts secrets stored in the database with the master key, using AES-256-GCM
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox creates a secret box for a 32 byte master key
+func NewSecretBox(key []byte) (*SecretBox, error) {
+	if len(key) != masterKeySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", masterKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+// LoadSecretBox creates the secret box for the master key. A base64 encoded key is used if given,
+// otherwise the key is read from path, where a new key is created on first start.
+func LoadSecretBox(key, path string) (*SecretBox, error) {
+	if key == "" {
+		var err error
+		if key, err = loadOrCreateMasterKey(path); err != nil {
+			return nil, err
+		}
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return nil, fmt.Errorf("master key must be base64 encoded: %w", err)
+	}
+	return NewSecretBox(decoded)
+}
+
+// loadOrCreateMasterKey reads the base64 encoded master key from path and creates it if it doesn't exist.
+// Losing the key makes the stored secrets unreadable, so it is only created once.
+func loadOrCreateMasterKey(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return string(data), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to read master key: %w", err)
+	}
+
+	key := make([]byte, masterKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate master key: %w", err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create master key directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(encoded+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to write master key: %w", err)
+	}
+	return encoded, nil
+}
+
+// Encrypt encrypts a secret, the result is prefixed and base64 encoded for storage
+func (b *SecretBox) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return secretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a secret created by Encrypt
+func (b *SecretBox) Decrypt(value string) (string, error) {
+	if !isEncryptedSecret(value) {
+		return "", errors.New("secret is not encrypted")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, secretPrefix))
+	if err != nil {
+		return "", fmt.Errorf("invalid secret: %w", err)
+	}
+	nonceSize := b.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", errors.New("invalid secret: too short")
+	}
+	plaintext, err := b.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		// Most likely the master key changed since the secret was stored
+		return "", fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// isEncryptedSecret reports whether a stored value was encrypted by a SecretBox
+func isEncryptedSecret(value string) bool {
+	return strings.HasPrefix(value, secretPrefix)
+}
+
+// generateRCONPassword returns a random RCON password
+func generateRCONPassword() (string, error) {
+	b := make([]byte, rconPasswordBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate RCON password: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// sftpHandshakeTimeout limits how long a client may take to authenticate
+const sftpHandshakeTimeout = 30 * time.Second
+
+// LoadOrCreateHostKey reads the SSH host key of the SFTP server, a new ed25519 key is generated on first start
+func LoadOrCreateHostKey(path string) (ssh.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		signer, err := ssh.ParsePrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SFTP host key %s: %w", path, err)
+		}
+		return signer, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read SFTP host key: %w", err)
+	}
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate SFTP host key: %w", err)
+	}
+	block, err := ssh.MarshalPrivateKey(privateKey, "dockermc-cloud-manager sftp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode SFTP host key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create SFTP host key directory: %w", err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write SFTP host key: %w", err)
+	}
+
+	return ssh.NewSignerFromKey(privateKey)
+}
+
+// SFTPServer serves the volumes of servers over SFTP. Every account sees a directory per server
+// it has access to, named after the server.
+type SFTPServer struct {
+	accounts    *SFTPAccountService
+	fileService *FileService
+	mcService   *MinecraftServerService
+	config      *ssh.ServerConfig
+	logger      *slog.Logger
+}
+
+// NewSFTPServer creates a new SFTP server identifying itself with hostKey
+func NewSFTPServer(accounts *SFTPAccountService, fileService *FileService, mcService *MinecraftServerService, hostKey ssh.Signer, logger *slog.Logger) *SFTPServer {
+	s := &SFTPServer{
+		accounts:    accounts,
+		fileService: fileService,
+		mcService:   mcService,
+		logger:      logger,
+	}
+
+	s.config = &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if err := accounts.AuthenticatePassword(conn.User(), string(password)); err != nil {
+				s.logger.Warn("SFTP login failed", "username", conn.User(), "method", "password", "remote_addr", conn.RemoteAddr().String())
+				return nil, err
+			}
+			return &ssh.Permissions{Extensions: map[string]string{"auth-method": "password"}}, nil
+		},
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			// Clients offer all their keys, so a mismatch is only logged at debug level
+			if err := accounts.AuthenticateKey(conn.User(), key); err != nil {
+				s.logger.Debug("SFTP key rejected", "username", conn.User(), "fingerprint", ssh.FingerprintSHA256(key), "remote_addr", conn.RemoteAddr().String())
+				return nil, err
+			}
+			return &ssh.Permissions{Extensions: map[string]string{
+				"auth-method": "publickey",
+				"fingerprint": ssh.FingerprintSHA256(key),
+			}}, nil
+		},
+	}
+	s.config.AddHostKey(hostKey)
+
+	return s
+}
+
+// ListenAndServe accepts SFTP connections on addr until the context is cancelled
+func (s *SFTPServer) ListenAndServe(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for SFTP connections: %w", err)
+	}
+	return s.Serve(ctx, listener)
+}
+
+// Serve accepts SFTP connections on the listener until the context is cancelled.
+// Open connections are closed once the context is cancelled.
+func (s *SFTPServer) Serve(ctx context.Context, listener net.Listener) error {
+	s.logger.InfoContext(ctx, "SFTP server listening", "address", listener.Addr().String())
+
+	stop := context.AfterFunc(ctx, func() { listener.Close() })
+	defer stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				s.logger.InfoContext(ctx, "SFTP server stopped")
+				return nil
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return fmt.Errorf("failed to accept SFTP connection: %w", err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.handleConn(ctx, conn)
+		}()
+	}
+}
+
+// handleConn authenticates a client and serves its sessions
+func (s *SFTPServer) handleConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	conn.SetDeadline(time.Now().Add(sftpHandshakeTimeout))
+	sshConn, channels, requests, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		s.logger.DebugContext(ctx, "SFTP handshake failed", "remote_addr", conn.RemoteAddr().String(), "error", err)
+		return
+	}
+	defer sshConn.Close()
+	conn.SetDeadline(time.Time{})
+
+	logger := s.logger.With(
+		"username", sshConn.User(),
+		"remote_addr", sshConn.RemoteAddr().String(),
+	)
+	attrs := []any{"auth_method", sshConn.Permissions.Extensions["auth-method"]}
+	if fingerprint, ok := sshConn.Permissions.Extensions["fingerprint"]; ok {
+		attrs = append(attrs, "fingerprint", fingerprint)
+	}
+	logger.InfoContext(ctx, "SFTP client connected", attrs...)
+	defer logger.InfoContext(ctx, "SFTP client disconnected")
+
+	go ssh.DiscardRequests(requests)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only session channels are supported")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			logger.WarnContext(ctx, "Failed to accept SFTP session", "error", err)
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.handleSession(ctx, channel, channelRequests, sshConn.User(), logger)
+		}()
+	}
+}
+
+// handleSession serves the sftp subsystem on a session, shells and commands are refused
+func (s *SFTPServer) handleSession(ctx context.Context, channel ssh.Channel, requests <-chan *ssh.Request, username string, logger *slog.Logger) {
+	defer channel.Close()
+
+	for req := range requests {
+		// The payload of subsystem requests is the SSH string "sftp": a uint32 length and the name
+		if req.Type != "subsystem" || len(req.Payload) < 4 || string(req.Payload[4:]) != "sftp" {
+			req.Reply(false, nil)
+			continue
+		}
+		req.Reply(true, nil)
+
+		go ssh.DiscardRequests(requests)
+
+		handler := &sftpHandler{server: s, username: username, ctx: ctx, logger: logger}
+		server := sftp.NewRequestServer(channel, sftp.Handlers{
+			FileGet:  handler,
+			FilePut:  handler,
+			FileCmd:  handler,
+			FileList: handler,
+		})
+		if err := server.Serve(); err != nil && !errors.Is(err, io.EOF) {
+			logger.WarnContext(ctx, "SFTP session ended with error", "error", err)
+		}
+		server.Close()
+		return
+	}
+}
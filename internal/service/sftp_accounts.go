@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/database"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/ssh"
+)
+
+// minSFTPPasswordLength is the minimum length of SFTP account passwords
+const minSFTPPasswordLength = 8
+
+// sftpUsernamePattern restricts usernames to what SSH clients accept without quoting
+var sftpUsernamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,31}$`)
+
+// errSFTPAuthentication is returned for every failed login, so it doesn't reveal which accounts exist
+var errSFTPAuthentication = errors.New("authentication failed")
+
+// sftpDummyHash is compared against for unknown accounts, so they take as long as wrong passwords
+var sftpDummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dockermc-cloud-manager"), bcrypt.DefaultCost)
+	return hash
+})
+
+// SFTPAccountService manages the accounts of the embedded SFTP server and their permissions
+type SFTPAccountService struct {
+	repo      *database.SFTPAccountRepository
+	mcService *MinecraftServerService
+	logger    *slog.Logger
+}
+
+// NewSFTPAccountService creates a new SFTP account service
+func NewSFTPAccountService(repo *database.SFTPAccountRepository, mcService *MinecraftServerService, logger *slog.Logger) *SFTPAccountService {
+	return &SFTPAccountService{
+		repo:      repo,
+		mcService: mcService,
+		logger:    logger,
+	}
+}
+
+// CreateAccount creates an SFTP account, password may be empty for accounts that only log in with keys
+func (s *SFTPAccountService) CreateAccount(ctx context.Context, username, password string) (*models.SFTPAccount, error) {
+	if !sftpUsernamePattern.MatchString(username) {
+		return nil, validationError("username must be 1-32 lowercase letters, digits, dots, dashes or underscores")
+	}
+	if _, err := s.repo.FindByUsername(username); err == nil {
+		return nil, conflictError("sftp account %s already exists", username)
+	} else if !errors.Is(err, database.ErrSFTPAccountNotFound) {
+		return nil, err
+	}
+
+	account := &models.SFTPAccount{Username: username}
+	if password != "" {
+		hash, err := hashSFTPPassword(password)
+		if err != nil {
+			return nil, err
+		}
+		account.PasswordHash = hash
+	}
+
+	if err := s.repo.Create(account); err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "SFTP account created", "username", username)
+	return account, nil
+}
+
+// GetAccount returns an SFTP account with its keys and permissions
+func (s *SFTPAccountService) GetAccount(ctx context.Context, username string) (*models.SFTPAccount, error) {
+	return s.repo.FindByUsername(username)
+}
+
+// ListAccounts returns all SFTP accounts
+func (s *SFTPAccountService) ListAccounts(ctx context.Context) ([]*models.SFTPAccount, error) {
+	return s.repo.FindAll()
+}
+
+// DeleteAccount removes an SFTP account with its keys and permissions
+func (s *SFTPAccountService) DeleteAccount(ctx context.Context, username string) error {
+	if err := s.repo.Delete(username); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "SFTP account deleted", "username", username)
+	return nil
+}
+
+// SetPassword replaces the password of an SFTP account, an empty password disables password logins
+func (s *SFTPAccountService) SetPassword(ctx context.Context, username, password string) error {
+	hash := ""
+	if password != "" {
+		var err error
+		if hash, err = hashSFTPPassword(password); err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.UpdatePasswordHash(username, hash); err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "SFTP password changed", "username", username, "password_login", hash != "")
+	return nil
+}
+
+// AddKey adds a public key in authorized_keys format to an SFTP account
+func (s *SFTPAccountService) AddKey(ctx context.Context, username, authorizedKey string) (*models.SFTPKey, error) {
+	publicKey, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(authorizedKey))
+	if err != nil {
+		return nil, validationError("invalid public key: %v", err)
+	}
+	if _, err := s.repo.FindByUsername(username); err != nil {
+		return nil, err
+	}
+
+	fingerprint := ssh.FingerprintSHA256(publicKey)
+	if existing, err := s.repo.FindKey(fingerprint); err == nil {
+		return nil, conflictError("key %s is already used by sftp account %s", fingerprint, existing.Username)
+	} else if !errors.Is(err, database.ErrSFTPKeyNotFound) {
+		return nil, err
+	}
+
+	key := &models.SFTPKey{
+		Fingerprint: fingerprint,
+		Username:    username,
+		Type:        publicKey.Type(),
+		PublicKey:   strings.TrimSpace(string(ssh.MarshalAuthorizedKey(publicKey))),
+		Comment:     comment,
+	}
+	if err := s.repo.AddKey(key); err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "SFTP key added", "username", username, "fingerprint", fingerprint)
+	return key, nil
+}
+
+// RemoveKey removes a public key from an SFTP account
+func (s *SFTPAccountService) RemoveKey(ctx context.Context, username, fingerprint string) error {
+	if err := s.repo.DeleteKey(username, fingerprint); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "SFTP key removed", "username", username, "fingerprint", fingerprint)
+	return nil
+}
+
+// Grant gives an SFTP account read or write access to the files of a server
+func (s *SFTPAccountService) Grant(ctx context.Context, username, serverID string, access models.SFTPAccess) (*models.SFTPPermission, error) {
+	if access != models.SFTPAccessRead && access != models.SFTPAccessWrite {
+		return nil, validationError("access must be %s or %s", models.SFTPAccessRead, models.SFTPAccessWrite)
+	}
+	if _, err := s.repo.FindByUsername(username); err != nil {
+		return nil, err
+	}
+	if _, err := s.mcService.GetServer(ctx, serverID); err != nil {
+		return nil, err
+	}
+
+	permission := &models.SFTPPermission{Username: username, ServerID: serverID, Access: access}
+	if err := s.repo.SavePermission(permission); err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "SFTP access granted", "username", username, "server_id", serverID, "access", access)
+	return permission, nil
+}
+
+// Revoke removes the access of an SFTP account to the files of a server
+func (s *SFTPAccountService) Revoke(ctx context.Context, username, serverID string) error {
+	if err := s.repo.DeletePermission(username, serverID); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "SFTP access revoked", "username", username, "server_id", serverID)
+	return nil
+}
+
+// Permissions returns the servers an SFTP account has access to
+func (s *SFTPAccountService) Permissions(username string) ([]*models.SFTPPermission, error) {
+	return s.repo.FindPermissions(username)
+}
+
+// Access returns the access of an SFTP account to a server, it's looked up on every use so revocations apply immediately
+func (s *SFTPAccountService) Access(username, serverID string) (models.SFTPAccess, error) {
+	permission, err := s.repo.FindPermission(username, serverID)
+	if err != nil {
+		return "", err
+	}
+	return permission.Access, nil
+}
+
+// AuthenticatePassword checks the password of an SFTP account
+func (s *SFTPAccountService) AuthenticatePassword(username, password string) error {
+	account, err := s.repo.FindByUsername(username)
+	if err != nil || !account.HasPassword() {
+		// Take as long as a wrong password
+		bcrypt.CompareHashAndPassword(sftpDummyHash(), []byte(password))
+		return errSFTPAuthentication
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(password)); err != nil {
+		return errSFTPAuthentication
+	}
+	return nil
+}
+
+// AuthenticateKey checks that a public key belongs to an SFTP account
+func (s *SFTPAccountService) AuthenticateKey(username string, publicKey ssh.PublicKey) error {
+	key, err := s.repo.FindKey(ssh.FingerprintSHA256(publicKey))
+	if err != nil || key.Username != username {
+		return errSFTPAuthentication
+	}
+	return nil
+}
+
+// hashSFTPPassword validates and hashes an SFTP account password
+func hashSFTPPassword(password string) (string, error) {
+	if len(password) < minSFTPPasswordLength {
+		return "", validationError("password must be at least %d characters", minSFTPPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", validationError("invalid password: %v", err)
+	}
+	return string(hash), nil
+}
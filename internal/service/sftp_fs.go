@@ -0,0 +1,409 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/database"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"github.com/pkg/sftp"
+)
+
+// sftpHandler is the virtual file system of an SFTP session. The root lists the servers the account
+// has access to, /<server name>/ is the server's /data.
+type sftpHandler struct {
+	server   *SFTPServer
+	username string
+	ctx      context.Context
+	logger   *slog.Logger
+}
+
+// sftpTarget is a path resolved to a server and the path inside its volume.
+// The server is nil for the root directory.
+type sftpTarget struct {
+	server *models.MinecraftServer
+	access models.SFTPAccess
+	path   string
+}
+
+// resolve maps an SFTP path to a server the account has access to
+func (h *sftpHandler) resolve(p string) (*sftpTarget, error) {
+	name, rest, _ := strings.Cut(strings.TrimPrefix(path.Clean("/"+p), "/"), "/")
+	if name == "" {
+		return &sftpTarget{path: "/"}, nil
+	}
+
+	server, err := h.server.mcService.repo.FindByName(name)
+	if err != nil {
+		if errors.Is(err, database.ErrServerNotFound) {
+			return nil, sftp.ErrSSHFxNoSuchFile
+		}
+		return nil, err
+	}
+
+	// Servers without access look like they don't exist
+	access, err := h.server.accounts.Access(h.username, server.ID)
+	if err != nil {
+		if errors.Is(err, database.ErrSFTPPermissionNotFound) {
+			return nil, sftp.ErrSSHFxNoSuchFile
+		}
+		return nil, err
+	}
+
+	return &sftpTarget{server: server, access: access, path: CleanVolumePath(rest)}, nil
+}
+
+// resolveWritable resolves a path inside a server the account may write to
+func (h *sftpHandler) resolveWritable(p string) (*sftpTarget, error) {
+	target, err := h.resolve(p)
+	if err != nil {
+		return nil, err
+	}
+	if target.server == nil || target.path == "/" {
+		return nil, sftp.ErrSSHFxPermissionDenied
+	}
+	if target.access != models.SFTPAccessWrite {
+		return nil, sftp.ErrSSHFxPermissionDenied
+	}
+	return target, nil
+}
+
+// logWrite records a change to a server's files together with the account that made it
+func (h *sftpHandler) logWrite(action string, target *sftpTarget, args ...any) {
+	h.logger.InfoContext(h.ctx, "SFTP write",
+		append([]any{
+			"action", action,
+			"server_id", target.server.ID,
+			"server_name", target.server.Name,
+			"path", target.path,
+		}, args...)...)
+}
+
+// sftpError translates service errors to SFTP status codes
+func sftpError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrFileNotFound), errors.Is(err, database.ErrServerNotFound):
+		return sftp.ErrSSHFxNoSuchFile
+	default:
+		return err
+	}
+}
+
+// Filelist implements sftp.FileLister
+func (h *sftpHandler) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	target, err := h.resolve(r.Filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	switch r.Method {
+	case "List":
+		if target.server == nil {
+			return h.listServers()
+		}
+		listing, err := h.server.fileService.ListDirectory(h.ctx, target.server.ID, target.path)
+		if err != nil {
+			return nil, sftpError(err)
+		}
+		infos := make(sftpListerAt, len(listing.Entries))
+		for i := range listing.Entries {
+			infos[i] = sftpFileInfo{listing.Entries[i]}
+		}
+		return infos, nil
+
+	case "Stat":
+		if target.server == nil {
+			return sftpListerAt{sftpDirInfo("/", time.Time{})}, nil
+		}
+		info, err := h.server.fileService.Stat(h.ctx, target.server.ID, target.path)
+		if err != nil {
+			return nil, sftpError(err)
+		}
+		if target.path == "/" {
+			// The volume root is shown under the server's name
+			info.Name = target.server.Name
+		}
+		return sftpListerAt{sftpFileInfo{*info}}, nil
+
+	default:
+		return nil, sftp.ErrSSHFxOpUnsupported
+	}
+}
+
+// listServers lists the servers the account has access to as directories
+func (h *sftpHandler) listServers() (sftp.ListerAt, error) {
+	permissions, err := h.server.accounts.Permissions(h.username)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make(sftpListerAt, 0, len(permissions))
+	for _, permission := range permissions {
+		server, err := h.server.mcService.repo.FindByID(permission.ServerID)
+		if err != nil {
+			// Permissions of deleted servers are skipped
+			continue
+		}
+		infos = append(infos, sftpDirInfo(server.Name, server.CreatedAt))
+	}
+	return infos, nil
+}
+
+// Fileread implements sftp.FileReader. The file is copied to a temporary file first,
+// since clients read at arbitrary offsets.
+func (h *sftpHandler) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	target, err := h.resolve(r.Filepath)
+	if err != nil {
+		return nil, err
+	}
+	if target.server == nil {
+		return nil, sftp.ErrSSHFxFailure
+	}
+
+	spool, err := h.download(target)
+	if err != nil {
+		return nil, err
+	}
+	return spool, nil
+}
+
+// download copies a file of a server's volume into a temporary file
+func (h *sftpHandler) download(target *sftpTarget) (*sftpSpoolFile, error) {
+	content, _, err := h.server.fileService.OpenFile(h.ctx, target.server.ID, target.path)
+	if err != nil {
+		return nil, sftpError(err)
+	}
+	defer content.Close()
+
+	spool, err := newSFTPSpoolFile()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(spool.file, content); err != nil {
+		spool.Close()
+		return nil, fmt.Errorf("failed to read %s: %w", target.path, err)
+	}
+	return spool, nil
+}
+
+// Filewrite implements sftp.FileWriter. Writes go to a temporary file that is written to the volume
+// once the client closes the file.
+func (h *sftpHandler) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	target, err := h.resolveWritable(r.Filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without truncation the client resumes or appends, so it writes on top of the existing content
+	var spool *sftpSpoolFile
+	if flags := r.Pflags(); !flags.Trunc {
+		spool, err = h.download(target)
+		if err != nil && !errors.Is(err, sftp.ErrSSHFxNoSuchFile) {
+			return nil, err
+		}
+	}
+	if spool == nil {
+		if spool, err = newSFTPSpoolFile(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &sftpWriteFile{
+		sftpSpoolFile: spool,
+		handler:       h,
+		target:        target,
+		maxBytes:      h.server.fileService.maxFileBytes,
+	}, nil
+}
+
+// Filecmd implements sftp.FileCmder
+func (h *sftpHandler) Filecmd(r *sftp.Request) error {
+	switch r.Method {
+	case "Setstat":
+		// Ownership, permissions and times are managed by the server, clients setting them is not an error
+		_, err := h.resolve(r.Filepath)
+		return err
+
+	case "Mkdir":
+		target, err := h.resolveWritable(r.Filepath)
+		if err != nil {
+			return err
+		}
+		if _, err := h.server.fileService.CreateDirectory(h.ctx, target.server.ID, target.path); err != nil {
+			return sftpError(err)
+		}
+		h.logWrite("mkdir", target)
+		return nil
+
+	case "Remove", "Rmdir":
+		target, err := h.resolveWritable(r.Filepath)
+		if err != nil {
+			return err
+		}
+		if err := h.server.fileService.DeleteFile(h.ctx, target.server.ID, target.path, false); err != nil {
+			return sftpError(err)
+		}
+		h.logWrite("remove", target)
+		return nil
+
+	case "Rename":
+		from, err := h.resolveWritable(r.Filepath)
+		if err != nil {
+			return err
+		}
+		to, err := h.resolveWritable(r.Target)
+		if err != nil {
+			return err
+		}
+		if from.server.ID != to.server.ID {
+			return fmt.Errorf("files can't be moved between servers")
+		}
+		if _, err := h.server.fileService.MoveFile(h.ctx, from.server.ID, from.path, to.path); err != nil {
+			return sftpError(err)
+		}
+		h.logWrite("rename", from, "target", to.path)
+		return nil
+
+	default:
+		return sftp.ErrSSHFxOpUnsupported
+	}
+}
+
+// sftpSpoolFile is a temporary file that is removed when closed
+type sftpSpoolFile struct {
+	file *os.File
+}
+
+// newSFTPSpoolFile creates an empty temporary file
+func newSFTPSpoolFile() (*sftpSpoolFile, error) {
+	file, err := os.CreateTemp("", "dockermc-sftp-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	return &sftpSpoolFile{file: file}, nil
+}
+
+// ReadAt implements io.ReaderAt
+func (f *sftpSpoolFile) ReadAt(p []byte, off int64) (int, error) {
+	return f.file.ReadAt(p, off)
+}
+
+// Close closes and removes the temporary file
+func (f *sftpSpoolFile) Close() error {
+	f.file.Close()
+	return os.Remove(f.file.Name())
+}
+
+// sftpWriteFile collects the writes of a client and uploads the file to the volume when closed
+type sftpWriteFile struct {
+	*sftpSpoolFile
+	handler  *sftpHandler
+	target   *sftpTarget
+	maxBytes int64
+
+	mu     sync.Mutex
+	failed bool
+}
+
+// WriteAt implements io.WriterAt
+func (f *sftpWriteFile) WriteAt(p []byte, off int64) (int, error) {
+	if off+int64(len(p)) > f.maxBytes {
+		return 0, tooLargeError("%s exceeds the maximum file size of %d MB", f.target.path, f.maxBytes/1024/1024)
+	}
+	return f.file.WriteAt(p, off)
+}
+
+// TransferError implements sftp.TransferError, files of failed transfers are not written to the volume
+func (f *sftpWriteFile) TransferError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failed = true
+}
+
+// Close writes the file to the volume and removes the temporary file
+func (f *sftpWriteFile) Close() error {
+	defer f.sftpSpoolFile.Close()
+
+	f.mu.Lock()
+	failed := f.failed
+	f.mu.Unlock()
+	if failed {
+		return nil
+	}
+
+	stat, err := f.file.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	h := f.handler
+	if _, err := h.server.fileService.WriteFile(h.ctx, f.target.server.ID, f.target.path, f.file, stat.Size()); err != nil {
+		h.logger.WarnContext(h.ctx, "SFTP upload failed",
+			"server_id", f.target.server.ID,
+			"path", f.target.path,
+			"error", err)
+		return sftpError(err)
+	}
+	h.logWrite("write", f.target, "size", stat.Size())
+	return nil
+}
+
+// sftpListerAt serves a fixed list of files
+type sftpListerAt []os.FileInfo
+
+// ListAt implements sftp.ListerAt
+func (l sftpListerAt) ListAt(dst []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	n := copy(dst, l[offset:])
+	if n < len(dst) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// sftpFileInfo adapts a file of a volume to os.FileInfo
+type sftpFileInfo struct {
+	info models.FileInfo
+}
+
+// sftpDirInfo returns a virtual directory, like the root and the server directories in it
+func sftpDirInfo(name string, modTime time.Time) sftpFileInfo {
+	return sftpFileInfo{models.FileInfo{Name: name, IsDir: true, Mode: "drwxr-xr-x", ModTime: modTime}}
+}
+
+func (i sftpFileInfo) Name() string       { return i.info.Name }
+func (i sftpFileInfo) Size() int64        { return i.info.Size }
+func (i sftpFileInfo) ModTime() time.Time { return i.info.ModTime }
+func (i sftpFileInfo) IsDir() bool        { return i.info.IsDir }
+func (i sftpFileInfo) Sys() any           { return nil }
+
+// Mode parses the permissions from their ls form like -rw-r--r--
+func (i sftpFileInfo) Mode() os.FileMode {
+	var mode os.FileMode
+	if perm := i.info.Mode; len(perm) == 10 {
+		for bit, c := range perm[1:] {
+			if c != '-' {
+				mode |= 1 << (8 - bit)
+			}
+		}
+	}
+	if i.info.IsDir {
+		mode |= os.ModeDir
+	}
+	return mode
+}
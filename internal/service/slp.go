@@ -0,0 +1,219 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+)
+
+const (
+	// slpProtocolVersion is sent in the handshake, -1 is the convention for pings that don't know the version
+	slpProtocolVersion = -1
+	// maxSLPPacketSize limits the status response, servers with many favicons or mods stay well below it
+	maxSLPPacketSize = 1 << 20
+)
+
+// slpStatus is the JSON status response of the Server List Ping
+type slpStatus struct {
+	Version struct {
+		Name     string `json:"name"`
+		Protocol int    `json:"protocol"`
+	} `json:"version"`
+	Players struct {
+		Max    int `json:"max"`
+		Online int `json:"online"`
+	} `json:"players"`
+	Description json.RawMessage `json:"description"`
+}
+
+// pingServerList runs the Server List Ping against a Minecraft server: a handshake with the status intent,
+// the status request and a ping to measure the latency. ctx bounds the whole exchange.
+func pingServerList(ctx context.Context, address string) (*models.ServerPing, error) {
+	host, portValue, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(portValue, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port in %s: %w", address, err)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// Handshake with next state 1 (status), followed by the empty status request
+	var handshake bytes.Buffer
+	writeVarInt(&handshake, 0x00)
+	writeVarInt(&handshake, slpProtocolVersion)
+	writeString(&handshake, host)
+	binary.Write(&handshake, binary.BigEndian, uint16(port))
+	writeVarInt(&handshake, 1)
+
+	var request bytes.Buffer
+	writePacket(&request, handshake.Bytes())
+	writePacket(&request, []byte{0x00})
+	if _, err := conn.Write(request.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to send status request: %w", err)
+	}
+
+	r := bufio.NewReader(conn)
+	id, payload, err := readPacket(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read status response: %w", err)
+	}
+	if id != 0x00 {
+		return nil, fmt.Errorf("unexpected packet 0x%02x instead of status response", id)
+	}
+	statusJSON, err := readString(bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("invalid status response: %w", err)
+	}
+	var status slpStatus
+	if err := json.Unmarshal([]byte(statusJSON), &status); err != nil {
+		return nil, fmt.Errorf("invalid status response: %w", err)
+	}
+
+	// The ping echoes a payload, its round trip is the latency
+	sent := time.Now()
+	var ping bytes.Buffer
+	ping.WriteByte(0x01)
+	binary.Write(&ping, binary.BigEndian, sent.UnixMilli())
+	var pingPacket bytes.Buffer
+	writePacket(&pingPacket, ping.Bytes())
+	if _, err := conn.Write(pingPacket.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to send ping: %w", err)
+	}
+	id, _, err = readPacket(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pong: %w", err)
+	}
+	if id != 0x01 {
+		return nil, fmt.Errorf("unexpected packet 0x%02x instead of pong", id)
+	}
+
+	return &models.ServerPing{
+		Ready:         true,
+		OnlinePlayers: status.Players.Online,
+		MaxPlayers:    status.Players.Max,
+		Version:       status.Version.Name,
+		Protocol:      status.Version.Protocol,
+		MOTD:          colorCodePattern.ReplaceAllString(chatText(status.Description), ""),
+		LatencyMS:     time.Since(sent).Milliseconds(),
+	}, nil
+}
+
+// chatText returns the plain text of a chat component, which is either a string or an object with text and extra
+func chatText(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+
+	var component struct {
+		Text  string            `json:"text"`
+		Extra []json.RawMessage `json:"extra"`
+	}
+	if err := json.Unmarshal(raw, &component); err != nil {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(component.Text)
+	for _, extra := range component.Extra {
+		b.WriteString(chatText(extra))
+	}
+	return b.String()
+}
+
+// writePacket writes a packet prefixed with its length
+func writePacket(w *bytes.Buffer, packet []byte) {
+	writeVarInt(w, int32(len(packet)))
+	w.Write(packet)
+}
+
+// readPacket reads a length prefixed packet and returns its id and the remaining payload
+func readPacket(r *bufio.Reader) (int32, []byte, error) {
+	length, err := readVarInt(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if length <= 0 || length > maxSLPPacketSize {
+		return 0, nil, fmt.Errorf("invalid packet length %d", length)
+	}
+	packet := make([]byte, length)
+	if _, err := io.ReadFull(r, packet); err != nil {
+		return 0, nil, err
+	}
+
+	packetReader := bytes.NewReader(packet)
+	id, err := readVarInt(packetReader)
+	if err != nil {
+		return 0, nil, err
+	}
+	return id, packet[len(packet)-packetReader.Len():], nil
+}
+
+// writeVarInt writes a value in the variable length encoding of the protocol, 7 bits per byte
+func writeVarInt(w *bytes.Buffer, value int32) {
+	v := uint32(value)
+	for v >= 0x80 {
+		w.WriteByte(byte(v) | 0x80)
+		v >>= 7
+	}
+	w.WriteByte(byte(v))
+}
+
+// readVarInt reads a value in the variable length encoding of the protocol
+func readVarInt(r io.ByteReader) (int32, error) {
+	var value uint32
+	for i := 0; i < 5; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		value |= uint32(b&0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			return int32(value), nil
+		}
+	}
+	return 0, errors.New("varint is too long")
+}
+
+// writeString writes a length prefixed UTF-8 string
+func writeString(w *bytes.Buffer, s string) {
+	writeVarInt(w, int32(len(s)))
+	w.WriteString(s)
+}
+
+// readString reads a length prefixed UTF-8 string
+func readString(r *bytes.Reader) (string, error) {
+	length, err := readVarInt(r)
+	if err != nil {
+		return "", err
+	}
+	if length < 0 || int(length) > r.Len() {
+		return "", fmt.Errorf("invalid string length %d", length)
+	}
+	b := make([]byte, length)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVarInt(t *testing.T) {
+	tests := []struct {
+		value   int32
+		encoded []byte
+	}{
+		{value: 0, encoded: []byte{0x00}},
+		{value: 1, encoded: []byte{0x01}},
+		{value: 127, encoded: []byte{0x7f}},
+		{value: 128, encoded: []byte{0x80, 0x01}},
+		{value: 255, encoded: []byte{0xff, 0x01}},
+		{value: 25565, encoded: []byte{0xdd, 0xc7, 0x01}},
+		{value: 2097151, encoded: []byte{0xff, 0xff, 0x7f}},
+		{value: 2147483647, encoded: []byte{0xff, 0xff, 0xff, 0xff, 0x07}},
+		{value: -1, encoded: []byte{0xff, 0xff, 0xff, 0xff, 0x0f}},
+		{value: -2147483648, encoded: []byte{0x80, 0x80, 0x80, 0x80, 0x08}},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(int(tt.value)), func(t *testing.T) {
+			var b bytes.Buffer
+			writeVarInt(&b, tt.value)
+			assert.Equal(t, tt.encoded, b.Bytes())
+
+			value, err := readVarInt(bytes.NewReader(tt.encoded))
+			require.NoError(t, err)
+			assert.Equal(t, tt.value, value)
+		})
+	}
+}
+
+func TestReadVarIntTooLong(t *testing.T) {
+	_, err := readVarInt(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0x01}))
+	assert.ErrorContains(t, err, "too long")
+}
+
+func TestReadPacket(t *testing.T) {
+	var b bytes.Buffer
+	writePacket(&b, []byte{0x01, 0xaa, 0xbb})
+
+	id, payload, err := readPacket(bufio.NewReader(&b))
+	require.NoError(t, err)
+	assert.Equal(t, int32(0x01), id)
+	assert.Equal(t, []byte{0xaa, 0xbb}, payload)
+}
+
+func TestReadPacketInvalidLength(t *testing.T) {
+	for _, length := range []int32{0, -1, maxSLPPacketSize + 1} {
+		var b bytes.Buffer
+		writeVarInt(&b, length)
+		_, _, err := readPacket(bufio.NewReader(&b))
+		assert.ErrorContains(t, err, "invalid packet length")
+	}
+}
+
+func TestReadString(t *testing.T) {
+	var b bytes.Buffer
+	writeString(&b, "localhost §a✓")
+	s, err := readString(bytes.NewReader(b.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, "localhost §a✓", s)
+
+	// The length must not exceed the packet
+	b.Reset()
+	writeVarInt(&b, 10)
+	b.WriteString("short")
+	_, err = readString(bytes.NewReader(b.Bytes()))
+	assert.ErrorContains(t, err, "invalid string length")
+}
+
+func TestChatText(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{name: "string", raw: `"A Minecraft Server"`, want: "A Minecraft Server"},
+		{name: "component", raw: `{"text":"Welcome"}`, want: "Welcome"},
+		{name: "nested extra", raw: `{"text":"","extra":[{"text":"Wel"},{"text":"come","extra":[" to ","lobby"]}]}`, want: "Welcome to lobby"},
+		{name: "invalid", raw: `42`, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, chatText(json.RawMessage(tt.raw)))
+		})
+	}
+}
+
+// serveFakeSLP answers one Server List Ping like a Minecraft server with the status JSON
+func serveFakeSLP(conn net.Conn, status string) error {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	id, payload, err := readPacket(r)
+	if err != nil {
+		return fmt.Errorf("read handshake: %w", err)
+	}
+	handshake := bytes.NewReader(payload)
+	protocol, err := readVarInt(handshake)
+	if err != nil {
+		return err
+	}
+	host, err := readString(handshake)
+	if err != nil {
+		return err
+	}
+	var port uint16
+	if err := binary.Read(handshake, binary.BigEndian, &port); err != nil {
+		return err
+	}
+	nextState, err := readVarInt(handshake)
+	if err != nil {
+		return err
+	}
+	if id != 0x00 || protocol != slpProtocolVersion || host != "127.0.0.1" || port == 0 || nextState != 1 || handshake.Len() != 0 {
+		return fmt.Errorf("unexpected handshake: id %d, protocol %d, address %s:%d, next state %d", id, protocol, host, port, nextState)
+	}
+
+	id, payload, err = readPacket(r)
+	if err != nil {
+		return fmt.Errorf("read status request: %w", err)
+	}
+	if id != 0x00 || len(payload) != 0 {
+		return fmt.Errorf("unexpected status request: id %d, %d bytes", id, len(payload))
+	}
+
+	var response, body bytes.Buffer
+	body.WriteByte(0x00)
+	writeString(&body, status)
+	writePacket(&response, body.Bytes())
+	if _, err := conn.Write(response.Bytes()); err != nil {
+		return err
+	}
+
+	// The client closes the connection if it couldn't parse the status
+	id, payload, err = readPacket(r)
+	if err != nil {
+		return nil
+	}
+	if id != 0x01 || len(payload) != 8 {
+		return fmt.Errorf("unexpected ping: id %d, %d bytes", id, len(payload))
+	}
+	var pong bytes.Buffer
+	writePacket(&pong, append([]byte{0x01}, payload...))
+	_, err = conn.Write(pong.Bytes())
+	return err
+}
+
+// startFakeSLP listens on a local port, answers one ping with the status JSON and returns the address
+func startFakeSLP(t *testing.T, status string) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	errs := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			errs <- err
+			return
+		}
+		errs <- serveFakeSLP(conn, status)
+	}()
+	t.Cleanup(func() {
+		listener.Close()
+		assert.NoError(t, <-errs)
+	})
+	return listener.Addr().String()
+}
+
+func TestPingServerList(t *testing.T) {
+	address := startFakeSLP(t, `{
+		"version": {"name": "Paper 1.21.1", "protocol": 767},
+		"players": {"max": 20, "online": 3, "sample": [{"name": "Steve", "id": "069a79f4-44e9-4726-a5be-fca90e38aaf5"}]},
+		"description": {"text": "§aWelcome", "extra": [{"text": " to §lthe lobby"}]},
+		"favicon": "data:image/png;base64,AAAA",
+		"enforcesSecureChat": true
+	}`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ping, err := pingServerList(ctx, address)
+	require.NoError(t, err)
+
+	assert.True(t, ping.Ready)
+	assert.Equal(t, 3, ping.OnlinePlayers)
+	assert.Equal(t, 20, ping.MaxPlayers)
+	assert.Equal(t, "Paper 1.21.1", ping.Version)
+	assert.Equal(t, 767, ping.Protocol)
+	assert.Equal(t, "Welcome to the lobby", ping.MOTD)
+	assert.GreaterOrEqual(t, ping.LatencyMS, int64(0))
+}
+
+func TestPingServerListInvalidStatus(t *testing.T) {
+	address := startFakeSLP(t, `{"version": `)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := pingServerList(ctx, address)
+	assert.ErrorContains(t, err, "invalid status response")
+}
+
+func TestPingUnreachable(t *testing.T) {
+	// A port nobody listens on refuses the connection like a server that is still starting
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, refused := pingServerList(ctx, address)
+	require.Error(t, refused)
+
+	tests := []struct {
+		name        string
+		err         error
+		unreachable bool
+	}{
+		{name: "connection refused", err: refused},
+		{
+			name:        "no route to host",
+			err:         &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.EHOSTUNREACH)},
+			unreachable: true,
+		},
+		{
+			name:        "network unreachable",
+			err:         &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ENETUNREACH)},
+			unreachable: true,
+		},
+		{
+			name:        "connect timeout",
+			err:         &net.OpError{Op: "dial", Net: "tcp", Err: context.DeadlineExceeded},
+			unreachable: true,
+		},
+		{
+			name: "no answer after connecting",
+			err:  fmt.Errorf("failed to read status response: %w", &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}),
+		},
+		{name: "invalid response", err: errors.New("invalid status response")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.unreachable, pingUnreachable(tt.err))
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+const (
+	// alpineImage is used for temporary containers that access volumes
+	alpineImage = "alpine:latest"
+	// volumeMountPath is where temporary containers mount the volume
+	volumeMountPath = "/data"
+	// restoreStagingDir is the directory in the volume an archive is extracted to before it replaces the content
+	restoreStagingDir = ".restore"
+)
+
+// createVolumeContainer creates a temporary container with the volume mounted at /data and runs cmd in it if set.
+// The returned function removes the container.
+func (s *DockerService) createVolumeContainer(ctx context.Context, volumeName string, cmd []string) (string, func(), error) {
+	// Pull alpine image if not present
+	if err := s.PullImage(ctx, alpineImage); err != nil {
+		return "", nil, fmt.Errorf("failed to pull alpine image: %w", err)
+	}
+
+	tempContainerConfig := &container.Config{
+		Image: alpineImage,
+		Cmd:   cmd,
+	}
+
+	tempHostConfig := &container.HostConfig{
+		Binds: []string{
+			fmt.Sprintf("%s:%s", volumeName, volumeMountPath),
+		},
+	}
+
+	tempResp, err := s.client.ContainerCreate(ctx, tempContainerConfig, tempHostConfig, nil, nil, "")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temp container: %w", err)
+	}
+
+	remove := func() {
+		// Use a fresh context, the container has to go even if ctx was cancelled
+		if err := s.client.ContainerRemove(context.Background(), tempResp.ID, container.RemoveOptions{Force: true}); err != nil {
+			s.logger.Warn("Failed to remove temp container", "container_id", tempResp.ID, "error", err)
+		}
+	}
+	return tempResp.ID, remove, nil
+}
+
+// runInVolume runs a shell script in a temporary container with the volume mounted at /data.
+// args are passed to the script as $1, $2, ..., so paths never have to be quoted into the script.
+func (s *DockerService) runInVolume(ctx context.Context, volumeName, script string, args ...string) error {
+	_, err := s.runInVolumeOutput(ctx, volumeName, script, args...)
+	return err
+}
+
+// runInVolumeOutput runs a shell script like runInVolume and returns what it wrote to stdout
+func (s *DockerService) runInVolumeOutput(ctx context.Context, volumeName, script string, args ...string) (string, error) {
+	containerID, remove, err := s.createVolumeContainer(ctx, volumeName, append([]string{"sh", "-c", script, "sh"}, args...))
+	if err != nil {
+		return "", err
+	}
+	defer remove()
+
+	return s.runVolumeContainer(ctx, containerID)
+}
+
+// volumeScriptError is returned when the command of a temporary container exits with a non-zero code
+type volumeScriptError struct {
+	code   int64
+	stderr string
+}
+
+func (e *volumeScriptError) Error() string {
+	if e.stderr != "" {
+		return fmt.Sprintf("temp container exited with code %d: %s", e.code, e.stderr)
+	}
+	return fmt.Sprintf("temp container exited with code %d", e.code)
+}
+
+// runVolumeContainer starts a temporary container, waits for it to finish and returns its stdout
+func (s *DockerService) runVolumeContainer(ctx context.Context, containerID string) (string, error) {
+	// Attach before starting, so no output is missed
+	attach, err := s.client.ContainerAttach(ctx, containerID, container.AttachOptions{Stream: true, Stdout: true, Stderr: true})
+	if err != nil {
+		return "", fmt.Errorf("failed to attach to temp container: %w", err)
+	}
+	defer attach.Close()
+
+	var stdout, stderr bytes.Buffer
+	copied := make(chan struct{})
+	go func() {
+		stdcopy.StdCopy(&stdout, &stderr, attach.Reader)
+		close(copied)
+	}()
+
+	// Start and wait for the temp container to finish
+	if err := s.client.ContainerStart(ctx, containerID, container.StartOptions{}); err != nil {
+		return "", fmt.Errorf("failed to start temp container: %w", err)
+	}
+
+	statusCh, errCh := s.client.ContainerWait(ctx, containerID, container.WaitConditionNotRunning)
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return "", fmt.Errorf("error waiting for temp container: %w", err)
+		}
+	case status := <-statusCh:
+		// The output stream ends with the container
+		<-copied
+		if status.StatusCode != 0 {
+			return "", &volumeScriptError{code: status.StatusCode, stderr: strings.TrimSpace(stderr.String())}
+		}
+	}
+
+	return stdout.String(), nil
+}
+
+// volumeExport streams a volume archive and removes the temporary container when closed
+type volumeExport struct {
+	io.ReadCloser
+	remove func()
+}
+
+func (e *volumeExport) Close() error {
+	err := e.ReadCloser.Close()
+	e.remove()
+	return err
+}
+
+// ExportVolume returns the content of a volume as an uncompressed tar stream.
+// All entries are prefixed with "data/". The caller must close the stream.
+func (s *DockerService) ExportVolume(ctx context.Context, volumeName string) (io.ReadCloser, error) {
+	return s.exportVolumePath(ctx, volumeName, volumeMountPath)
+}
+
+// ExportVolumePath returns a file or directory of a volume as an uncompressed tar stream.
+// All entries are prefixed with the base name of subPath. A missing path returns an error wrapping fs.ErrNotExist.
+// The caller must close the stream.
+func (s *DockerService) ExportVolumePath(ctx context.Context, volumeName, subPath string) (io.ReadCloser, error) {
+	return s.exportVolumePath(ctx, volumeName, path.Join(volumeMountPath, subPath))
+}
+
+func (s *DockerService) exportVolumePath(ctx context.Context, volumeName, srcPath string) (io.ReadCloser, error) {
+	// The container doesn't need to run, Docker copies from its mounts either way
+	containerID, remove, err := s.createVolumeContainer(ctx, volumeName, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, _, err := s.client.CopyFromContainer(ctx, containerID, srcPath)
+	if err != nil {
+		remove()
+		if errdefs.IsNotFound(err) {
+			return nil, fmt.Errorf("%s not found in volume: %w", srcPath, fs.ErrNotExist)
+		}
+		return nil, fmt.Errorf("failed to copy from volume: %w", err)
+	}
+
+	return &volumeExport{ReadCloser: reader, remove: remove}, nil
+}
+
+// RestoreVolume replaces the content of a volume with an uncompressed tar stream as produced by ExportVolume.
+// The archive is extracted next to the current content first, which is only replaced once the extraction succeeded.
+func (s *DockerService) RestoreVolume(ctx context.Context, volumeName string, archive io.Reader) error {
+	if err := s.stageArchive(ctx, volumeName, archive); err != nil {
+		return err
+	}
+
+	// Swap the current content with the extracted archive
+	script := fmt.Sprintf(
+		"cd '%s' && find . -mindepth 1 -maxdepth 1 ! -name '%s' -exec rm -rf {} + && "+
+			"find '%s' -mindepth 1 -maxdepth 1 -exec mv {} . \\; && rmdir '%s'",
+		volumeMountPath, restoreStagingDir, restoreStagingDir, restoreStagingDir)
+	if err := s.runInVolume(ctx, volumeName, script); err != nil {
+		return fmt.Errorf("failed to replace volume content: %w", err)
+	}
+
+	return nil
+}
+
+// ReplaceVolumePaths replaces top level files or directories of a volume with the ones in an uncompressed tar stream,
+// whose entries are prefixed with "data/". Paths that aren't in the archive are removed, everything else is kept.
+// Like RestoreVolume, nothing is replaced unless the archive was extracted successfully.
+func (s *DockerService) ReplaceVolumePaths(ctx context.Context, volumeName string, archive io.Reader, paths []string) error {
+	if err := s.stageArchive(ctx, volumeName, archive); err != nil {
+		return err
+	}
+
+	var script strings.Builder
+	fmt.Fprintf(&script, "cd '%s'", volumeMountPath)
+	for _, p := range paths {
+		fmt.Fprintf(&script, " && rm -rf './%s' && { [ ! -e '%s/%s' ] || mv '%s/%s' './%s'; }",
+			p, restoreStagingDir, p, restoreStagingDir, p, p)
+	}
+	fmt.Fprintf(&script, " && rm -rf '%s'", restoreStagingDir)
+	if err := s.runInVolume(ctx, volumeName, script.String()); err != nil {
+		return fmt.Errorf("failed to replace volume content: %w", err)
+	}
+
+	return nil
+}
+
+// stageArchive extracts an uncompressed tar stream with entries prefixed by "data/" into the staging directory of a volume
+func (s *DockerService) stageArchive(ctx context.Context, volumeName string, archive io.Reader) error {
+	staging := path.Join(volumeMountPath, restoreStagingDir)
+
+	// Remove leftovers of a failed restore
+	if err := s.runInVolume(ctx, volumeName, fmt.Sprintf("rm -rf '%s'", staging)); err != nil {
+		return fmt.Errorf("failed to prepare restore: %w", err)
+	}
+
+	containerID, remove, err := s.createVolumeContainer(ctx, volumeName, nil)
+	if err != nil {
+		return err
+	}
+	defer remove()
+
+	// Move the entries into the staging directory while streaming them to Docker
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(rewriteArchivePrefix(archive, pw, "data", path.Join("data", restoreStagingDir)))
+	}()
+
+	if err := s.client.CopyToContainer(ctx, containerID, "/", pr, container.CopyToContainerOptions{}); err != nil {
+		pr.CloseWithError(err)
+		s.runInVolume(ctx, volumeName, fmt.Sprintf("rm -rf '%s'", staging))
+		return fmt.Errorf("failed to copy archive to volume: %w", err)
+	}
+	pr.Close()
+
+	return nil
+}
+
+// rewriteArchivePrefix copies a tar stream and replaces the first path element of every entry.
+// Entries outside of the prefix are rejected, so an archive can't write outside the target directory.
+func rewriteArchivePrefix(src io.Reader, dst io.Writer, from, to string) error {
+	tr := tar.NewReader(src)
+	tw := tar.NewWriter(dst)
+
+	rewrite := func(name string) (string, error) {
+		clean := path.Clean(strings.TrimPrefix(name, "./"))
+		if clean != from && !strings.HasPrefix(clean, from+"/") {
+			return "", fmt.Errorf("archive entry %q is outside of %s/", name, from)
+		}
+		return to + strings.TrimPrefix(clean, from), nil
+	}
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		if header.Name, err = rewrite(header.Name); err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeLink {
+			if header.Linkname, err = rewrite(header.Linkname); err != nil {
+				return err
+			}
+		}
+		if header.Typeflag == tar.TypeDir && !strings.HasSuffix(header.Name, "/") {
+			header.Name += "/"
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+	}
+
+	// Read the rest of the source, so readers that verify the stream at EOF get to do so
+	// before the end of the archive is written
+	if _, err := io.Copy(io.Discard, src); err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	return tw.Close()
+}
@@ -0,0 +1,356 @@
+package service
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/errdefs"
+	"github.com/google/uuid"
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+)
+
+const (
+	// volumeUID and volumeGID own files written to volumes, itzg images run the server as this user
+	volumeUID = 1000
+	volumeGID = 1000
+)
+
+// Exit codes of the volume scripts, mapped to errors by volumeFSError
+const (
+	exitNotFound     = 3
+	exitNotDirectory = 4
+	exitIsDirectory  = 5
+	exitExists       = 6
+	exitNotEmpty     = 7
+)
+
+// mkparentsScript defines a shell function creating a directory and its missing parents owned by the server user
+var mkparentsScript = fmt.Sprintf(
+	`mkparents() { [ -d "$1" ] || { mkparents "$(dirname "$1")" && mkdir "$1" && chown %d:%d "$1"; }; }; `,
+	volumeUID, volumeGID)
+
+// VolumeFS accesses the files of a Docker volume through temporary containers,
+// so it works the same whether the container using the volume is running or not.
+// Paths are absolute inside the volume ("/" is the volume root) and can't leave it.
+type VolumeFS struct {
+	docker *DockerService
+	volume string
+}
+
+// VolumeFS returns the file system of a volume
+func (s *DockerService) VolumeFS(volumeName string) *VolumeFS {
+	return &VolumeFS{docker: s, volume: volumeName}
+}
+
+// CleanVolumePath normalizes a path inside a volume. ".." can't go above the volume root.
+func CleanVolumePath(p string) string {
+	return path.Clean("/" + p)
+}
+
+// containerPath returns where a volume path is in a temporary container
+func containerPath(p string) (string, error) {
+	if strings.ContainsRune(p, 0) {
+		return "", validationError("path must not contain NUL bytes")
+	}
+	return path.Join(volumeMountPath, CleanVolumePath(p)), nil
+}
+
+// volumeFSError maps the exit codes of volume scripts to errors callers can check
+func volumeFSError(err error, p string) error {
+	var scriptErr *volumeScriptError
+	if !errors.As(err, &scriptErr) {
+		return err
+	}
+	switch scriptErr.code {
+	case exitNotFound:
+		return fmt.Errorf("%w: %s", ErrFileNotFound, p)
+	case exitNotDirectory:
+		return validationError("%s is not a directory", p)
+	case exitIsDirectory:
+		return validationError("%s is a directory", p)
+	case exitExists:
+		return conflictError("%s already exists", p)
+	case exitNotEmpty:
+		return conflictError("directory %s is not empty", p)
+	default:
+		return err
+	}
+}
+
+// Stat returns information about a file or directory
+func (v *VolumeFS) Stat(ctx context.Context, p string) (*models.FileInfo, error) {
+	full, err := containerPath(p)
+	if err != nil {
+		return nil, err
+	}
+
+	containerID, remove, err := v.docker.createVolumeContainer(ctx, v.volume, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer remove()
+
+	stat, err := v.docker.client.ContainerStatPath(ctx, containerID, full)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", ErrFileNotFound, CleanVolumePath(p))
+		}
+		return nil, fmt.Errorf("failed to stat %s: %w", CleanVolumePath(p), err)
+	}
+
+	return newFileInfo(CleanVolumePath(p), stat.Mode, stat.Size, stat.Mtime), nil
+}
+
+// ReadDir lists a directory, directories first, then files, each sorted by name
+func (v *VolumeFS) ReadDir(ctx context.Context, p string) ([]models.FileInfo, error) {
+	full, err := containerPath(p)
+	if err != nil {
+		return nil, err
+	}
+
+	// busybox stat prints one line per entry: type|size|mtime|permissions|name
+	script := fmt.Sprintf(`[ -e "$1" ] || exit %d; [ -d "$1" ] || exit %d; cd "$1" && `+
+		`find . -mindepth 1 -maxdepth 1 -exec stat -c '%%F|%%s|%%Y|%%a|%%n' {} +`,
+		exitNotFound, exitNotDirectory)
+	output, err := v.docker.runInVolumeOutput(ctx, v.volume, script, full)
+	if err != nil {
+		return nil, volumeFSError(err, CleanVolumePath(p))
+	}
+
+	entries := []models.FileInfo{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "|", 5)
+		if len(fields) != 5 {
+			continue
+		}
+		size, _ := strconv.ParseInt(fields[1], 10, 64)
+		mtime, _ := strconv.ParseInt(fields[2], 10, 64)
+		perm, _ := strconv.ParseUint(fields[3], 8, 32)
+
+		mode := fs.FileMode(perm) & fs.ModePerm
+		switch fields[0] {
+		case "directory":
+			mode |= fs.ModeDir
+		case "symbolic link":
+			mode |= fs.ModeSymlink
+		}
+
+		name := strings.TrimPrefix(fields[4], "./")
+		entries = append(entries, *newFileInfo(path.Join(CleanVolumePath(p), name), mode, size, time.Unix(mtime, 0)))
+	}
+
+	slices.SortFunc(entries, func(a, b models.FileInfo) int {
+		if a.IsDir != b.IsDir {
+			if a.IsDir {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return entries, nil
+}
+
+// volumeFileReader streams a file out of a volume and removes the temporary container when closed
+type volumeFileReader struct {
+	io.Reader
+	body   io.Closer
+	remove func()
+}
+
+func (r *volumeFileReader) Close() error {
+	err := r.body.Close()
+	r.remove()
+	return err
+}
+
+// Open returns the content of a regular file. The caller must close it.
+func (v *VolumeFS) Open(ctx context.Context, p string) (io.ReadCloser, *models.FileInfo, error) {
+	full, err := containerPath(p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	containerID, remove, err := v.docker.createVolumeContainer(ctx, v.volume, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	body, stat, err := v.docker.client.CopyFromContainer(ctx, containerID, full)
+	if err != nil {
+		remove()
+		if errdefs.IsNotFound(err) {
+			return nil, nil, fmt.Errorf("%w: %s", ErrFileNotFound, CleanVolumePath(p))
+		}
+		return nil, nil, fmt.Errorf("failed to read %s: %w", CleanVolumePath(p), err)
+	}
+	if !stat.Mode.IsRegular() {
+		body.Close()
+		remove()
+		return nil, nil, validationError("%s is not a regular file", CleanVolumePath(p))
+	}
+
+	// Docker wraps the file in a tar archive
+	tr := tar.NewReader(body)
+	if _, err := tr.Next(); err != nil {
+		body.Close()
+		remove()
+		return nil, nil, fmt.Errorf("failed to read %s: %w", CleanVolumePath(p), err)
+	}
+
+	info := newFileInfo(CleanVolumePath(p), stat.Mode, stat.Size, stat.Mtime)
+	return &volumeFileReader{Reader: tr, body: body, remove: remove}, info, nil
+}
+
+// WriteFile creates or replaces a file with size bytes from r, creating missing parent directories.
+// The content is written next to the target first and moved into place once complete,
+// so a running server never sees a partially written file.
+func (v *VolumeFS) WriteFile(ctx context.Context, p string, r io.Reader, size int64) (*models.FileInfo, error) {
+	full, err := containerPath(p)
+	if err != nil {
+		return nil, err
+	}
+	if CleanVolumePath(p) == "/" {
+		return nil, validationError("/ is a directory")
+	}
+
+	// The upload lands in the volume root, the parents of the target may not exist yet
+	upload := path.Join(volumeMountPath, ".upload-"+uuid.New().String())
+	script := fmt.Sprintf(`%sif [ -d "$2" ]; then rm -f "$1"; exit %d; fi; `+
+		`mkparents "$(dirname "$2")" && mv -f "$1" "$2" || { rm -f "$1"; exit 1; }`,
+		mkparentsScript, exitIsDirectory)
+
+	containerID, remove, err := v.docker.createVolumeContainer(ctx, v.volume, []string{"sh", "-c", script, "sh", upload, full})
+	if err != nil {
+		return nil, err
+	}
+	defer remove()
+
+	modTime := time.Now()
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     strings.TrimPrefix(upload, "/"),
+			Size:     size,
+			Mode:     0o644,
+			Uid:      volumeUID,
+			Gid:      volumeGID,
+			ModTime:  modTime,
+		})
+		if err == nil {
+			_, err = io.CopyN(tw, r, size)
+		}
+		if err == nil {
+			err = tw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	if err := v.docker.client.CopyToContainer(ctx, containerID, "/", pr, container.CopyToContainerOptions{}); err != nil {
+		pr.CloseWithError(err)
+		// Docker may have extracted part of the upload
+		v.docker.runInVolume(context.WithoutCancel(ctx), v.volume, `rm -f "$1"`, upload)
+		return nil, fmt.Errorf("failed to write %s: %w", CleanVolumePath(p), err)
+	}
+
+	if _, err := v.docker.runVolumeContainer(ctx, containerID); err != nil {
+		return nil, volumeFSError(err, CleanVolumePath(p))
+	}
+
+	return newFileInfo(CleanVolumePath(p), 0o644, size, modTime), nil
+}
+
+// Mkdir creates a directory and its missing parents
+func (v *VolumeFS) Mkdir(ctx context.Context, p string) (*models.FileInfo, error) {
+	full, err := containerPath(p)
+	if err != nil {
+		return nil, err
+	}
+
+	script := fmt.Sprintf(`%s[ ! -e "$1" ] || exit %d; mkparents "$1"`, mkparentsScript, exitExists)
+	if err := v.docker.runInVolume(ctx, v.volume, script, full); err != nil {
+		return nil, volumeFSError(err, CleanVolumePath(p))
+	}
+
+	return newFileInfo(CleanVolumePath(p), fs.ModeDir|0o755, 0, time.Now()), nil
+}
+
+// Rename moves a file or directory, the target must not exist yet
+func (v *VolumeFS) Rename(ctx context.Context, from, to string) error {
+	fullFrom, err := containerPath(from)
+	if err != nil {
+		return err
+	}
+	fullTo, err := containerPath(to)
+	if err != nil {
+		return err
+	}
+	if CleanVolumePath(from) == "/" || CleanVolumePath(to) == "/" {
+		return validationError("the volume root can't be moved")
+	}
+	if strings.HasPrefix(CleanVolumePath(to)+"/", CleanVolumePath(from)+"/") {
+		return validationError("%s can't be moved into itself", CleanVolumePath(from))
+	}
+
+	script := fmt.Sprintf(`%s[ -e "$1" ] || [ -L "$1" ] || exit %d; [ ! -e "$2" ] || exit %d; `+
+		`mkparents "$(dirname "$2")" && mv "$1" "$2"`,
+		mkparentsScript, exitNotFound, exitExists)
+	if err := v.docker.runInVolume(ctx, v.volume, script, fullFrom, fullTo); err != nil {
+		var scriptErr *volumeScriptError
+		if errors.As(err, &scriptErr) && scriptErr.code == exitExists {
+			return volumeFSError(err, CleanVolumePath(to))
+		}
+		return volumeFSError(err, CleanVolumePath(from))
+	}
+	return nil
+}
+
+// Remove deletes a file or directory. Directories must be empty unless recursive is set.
+func (v *VolumeFS) Remove(ctx context.Context, p string, recursive bool) error {
+	full, err := containerPath(p)
+	if err != nil {
+		return err
+	}
+	if CleanVolumePath(p) == "/" {
+		return validationError("the volume root can't be deleted")
+	}
+
+	removeDir := fmt.Sprintf(`rmdir "$1" 2>/dev/null || exit %d`, exitNotEmpty)
+	if recursive {
+		removeDir = `rm -rf "$1"`
+	}
+	script := fmt.Sprintf(`[ -e "$1" ] || [ -L "$1" ] || exit %d; `+
+		`if [ -d "$1" ] && [ ! -L "$1" ]; then %s; else rm -f "$1"; fi`,
+		exitNotFound, removeDir)
+	if err := v.docker.runInVolume(ctx, v.volume, script, full); err != nil {
+		return volumeFSError(err, CleanVolumePath(p))
+	}
+	return nil
+}
+
+// newFileInfo creates the file info of a volume path
+func newFileInfo(p string, mode fs.FileMode, size int64, modTime time.Time) *models.FileInfo {
+	if mode.IsDir() {
+		size = 0
+	}
+	return &models.FileInfo{
+		Name:    path.Base(p),
+		Path:    p,
+		IsDir:   mode.IsDir(),
+		Size:    size,
+		Mode:    mode.String(),
+		ModTime: modTime.UTC(),
+	}
+}
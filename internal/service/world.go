@@ -0,0 +1,547 @@
+package service
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+)
+
+const (
+	// worldExtractRatio bounds the extracted size of an imported archive relative to the maximum upload size.
+	// Region files are compressed already, so real worlds are barely smaller in an archive.
+	worldExtractRatio = 4
+	// maxWorldEntries bounds the number of files and directories in an imported archive
+	maxWorldEntries = 200000
+)
+
+// WorldService imports world archives into server volumes and exports the worlds of servers
+type WorldService struct {
+	dockerService  *DockerService
+	mcService      *MinecraftServerService
+	maxUploadBytes int64
+	logger         *slog.Logger
+}
+
+// NewWorldService creates a new world service accepting archives of up to maxUploadBytes
+func NewWorldService(dockerService *DockerService, mcService *MinecraftServerService, maxUploadBytes int64, logger *slog.Logger) *WorldService {
+	return &WorldService{
+		dockerService:  dockerService,
+		mcService:      mcService,
+		maxUploadBytes: maxUploadBytes,
+		logger:         logger,
+	}
+}
+
+// ImportWorld replaces the worlds of a stopped server with the ones in a zip, tar or tar.gz archive.
+// Server layouts (world/, world_nether/, world_the_end/) and singleplayer saves with level.dat at their root
+// are supported, also when wrapped in a folder. Everything outside the world folders is skipped.
+func (s *WorldService) ImportWorld(ctx context.Context, serverID string, upload io.Reader) (*models.WorldImport, error) {
+	// Fail fast before receiving a possibly large upload
+	server, err := s.mcService.repo.FindByID(serverID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireStopped(ctx, server); err != nil {
+		return nil, err
+	}
+
+	// Zip archives need random access, and the layout is only known after seeing all entries
+	archive, err := os.CreateTemp("", "dockermc-world-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	size, err := io.Copy(archive, io.LimitReader(upload, s.maxUploadBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive world archive: %w", err)
+	}
+	if size > s.maxUploadBytes {
+		return nil, validationError("world archive exceeds the maximum size of %d MB", s.maxUploadBytes/1024/1024)
+	}
+
+	// Zip and tar bombs are rejected by the sizes in their headers before anything is written
+	maxExtractedBytes := s.maxUploadBytes * worldExtractRatio
+	var entries []worldArchiveEntry
+	err = walkWorldArchive(archive, size, maxExtractedBytes, func(header *tar.Header, _ io.Reader) error {
+		entries = append(entries, worldArchiveEntry{name: header.Name, dir: header.Typeflag == tar.TypeDir})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	layout, mappings, err := detectWorldLayout(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := s.mcService.locks.LockOrConflict(serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// The server may have been started while the archive was uploaded
+	server, err = s.mcService.repo.FindByID(serverID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireStopped(ctx, server); err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "Importing world",
+		"server_id", server.ID,
+		"layout", layout,
+		"archive_size_bytes", size)
+
+	result := &models.WorldImport{Layout: layout}
+	for _, mapping := range mappings {
+		result.Worlds = append(result.Worlds, mapping.target)
+	}
+
+	// Rewrite the entries into the world folders while streaming them to the volume
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+
+		// Entries are owned by the user the server runs as, it has to write the world and lock session.lock.
+		// Docker would create directories missing in the archive as root, so they are added.
+		modTime := time.Now()
+		dirs := make(map[string]bool)
+		var writeParents func(name string) error
+		writeParents = func(name string) error {
+			dir := path.Dir(name)
+			if dir == "." || dirs[dir] {
+				return nil
+			}
+			if err := writeParents(dir); err != nil {
+				return err
+			}
+			dirs[dir] = true
+			return tw.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeDir,
+				Name:     path.Join("data", dir) + "/",
+				Mode:     0o755,
+				Uid:      volumeUID,
+				Gid:      volumeGID,
+				ModTime:  modTime,
+			})
+		}
+
+		err := walkWorldArchive(archive, size, maxExtractedBytes, func(header *tar.Header, r io.Reader) error {
+			name, ok := mapWorldEntry(header.Name, mappings)
+			if !ok {
+				result.Skipped++
+				return nil
+			}
+			if err := writeParents(name); err != nil {
+				return err
+			}
+
+			out := &tar.Header{Name: path.Join("data", name), Uid: volumeUID, Gid: volumeGID, ModTime: header.ModTime}
+			if header.Typeflag == tar.TypeDir {
+				dirs[name] = true
+				out.Typeflag = tar.TypeDir
+				out.Name += "/"
+				out.Mode = 0o755
+				return tw.WriteHeader(out)
+			}
+
+			out.Typeflag = tar.TypeReg
+			out.Mode = 0o644
+			out.Size = header.Size
+			if err := tw.WriteHeader(out); err != nil {
+				return err
+			}
+			if _, err := io.Copy(tw, r); err != nil {
+				return err
+			}
+			result.Files++
+			result.SizeBytes += header.Size
+			return nil
+		})
+		if err == nil {
+			err = tw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	if err := s.dockerService.ReplaceVolumePaths(ctx, server.VolumeID, pr, models.WorldFolders); err != nil {
+		pr.CloseWithError(err)
+		s.logger.ErrorContext(ctx, "Failed to import world",
+			"server_id", server.ID,
+			"error", err)
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "World imported successfully",
+		"server_id", server.ID,
+		"worlds", result.Worlds,
+		"files", result.Files,
+		"skipped", result.Skipped)
+
+	return result, nil
+}
+
+// requireStopped fails with a conflict if the container of a server is running
+func (s *WorldService) requireStopped(ctx context.Context, server *models.MinecraftServer) error {
+	state, err := s.dockerService.GetContainerState(ctx, server.ContainerID)
+	if err != nil {
+		return fmt.Errorf("failed to get container state: %w", err)
+	}
+	if state.Running || state.Restarting {
+		return conflictError("server must be stopped to import a world")
+	}
+	return nil
+}
+
+// worldArchiveEntry is a file or directory in an uploaded world archive
+type worldArchiveEntry struct {
+	name string
+	dir  bool
+}
+
+// worldMapping moves an archive folder to a world folder of the server, an empty source is the archive root
+type worldMapping struct {
+	source string
+	target string
+}
+
+// detectWorldLayout finds the worlds in an archive and decides which world folder each of them becomes
+func detectWorldLayout(entries []worldArchiveEntry) (models.WorldLayout, []worldMapping, error) {
+	// Zip archives often leave out directory entries, so every parent of an entry counts as present
+	present := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		for name := entry.name; name != "" && !present[name]; name = parentDir(name) {
+			present[name] = true
+		}
+	}
+
+	// Archives are often wrapped in a folder named after the map, look inside it
+	root := ""
+	for !present[path.Join(root, "level.dat")] && !present[path.Join(root, "world", "level.dat")] {
+		top, ok := commonTopDir(entries, root)
+		if !ok {
+			break
+		}
+		root = path.Join(root, top)
+	}
+
+	if present[path.Join(root, "level.dat")] {
+		return models.WorldLayoutSingleplayer, []worldMapping{{source: root, target: "world"}}, nil
+	}
+
+	if present[path.Join(root, "world", "level.dat")] {
+		var mappings []worldMapping
+		for _, folder := range models.WorldFolders {
+			if present[path.Join(root, folder)] {
+				mappings = append(mappings, worldMapping{source: path.Join(root, folder), target: folder})
+			}
+		}
+		return models.WorldLayoutServer, mappings, nil
+	}
+
+	// A single save folder next to other files
+	var saves []string
+	for _, entry := range entries {
+		if path.Base(entry.name) == "level.dat" && parentDir(parentDir(entry.name)) == root {
+			saves = append(saves, parentDir(entry.name))
+		}
+	}
+	switch len(saves) {
+	case 0:
+		return "", nil, validationError("no world found in archive, expected a level.dat")
+	case 1:
+		return models.WorldLayoutSingleplayer, []worldMapping{{source: saves[0], target: "world"}}, nil
+	default:
+		return "", nil, validationError("archive contains multiple worlds %v, expected world/ with world_nether/ and world_the_end/ or a single save", saves)
+	}
+}
+
+// commonTopDir returns the folder all entries below root are in, if there is exactly one
+func commonTopDir(entries []worldArchiveEntry, root string) (string, bool) {
+	top := ""
+	for _, entry := range entries {
+		rel := entry.name
+		if root != "" {
+			if entry.name == root {
+				continue
+			}
+			rel = strings.TrimPrefix(entry.name, root+"/")
+		}
+
+		first, _, nested := strings.Cut(rel, "/")
+		if !nested && !entry.dir {
+			return "", false
+		}
+		if top != "" && first != top {
+			return "", false
+		}
+		top = first
+	}
+	return top, top != ""
+}
+
+// mapWorldEntry returns the path of an archive entry in the volume, false if it isn't part of a world
+func mapWorldEntry(name string, mappings []worldMapping) (string, bool) {
+	for _, mapping := range mappings {
+		if mapping.source == "" {
+			return path.Join(mapping.target, name), true
+		}
+		if name == mapping.source || strings.HasPrefix(name, mapping.source+"/") {
+			return mapping.target + strings.TrimPrefix(name, mapping.source), true
+		}
+	}
+	return "", false
+}
+
+// parentDir returns the parent of an archive path, an empty string for top level entries
+func parentDir(name string) string {
+	if dir := path.Dir(name); dir != "." {
+		return dir
+	}
+	return ""
+}
+
+// cleanArchivePath normalizes the name of an archive entry and rejects names that leave the archive
+func cleanArchivePath(name string) (string, error) {
+	// Zip archives created on Windows may use backslashes
+	slashed := strings.ReplaceAll(name, "\\", "/")
+	if slices.Contains(strings.Split(slashed, "/"), "..") {
+		return "", validationError("archive entry %q is outside of the archive", name)
+	}
+	return strings.Trim(path.Clean("/"+slashed), "/"), nil
+}
+
+// walkWorldArchive calls fn for every file and directory in a zip, tar or tar.gz archive.
+// Zip entries are passed as tar headers, other entry types and macOS metadata are skipped.
+// Archives with more than maxWorldEntries entries or whose files add up to more than maxBytes are rejected.
+func walkWorldArchive(archive *os.File, size, maxBytes int64, fn func(header *tar.Header, r io.Reader) error) error {
+	magic := make([]byte, 4)
+	if _, err := archive.ReadAt(magic, 0); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read world archive: %w", err)
+	}
+
+	// Readers of both formats fail if a file is longer than its header says, so the header sizes are the limit
+	entries := 0
+	var extracted int64
+	visit := func(header *tar.Header, r io.Reader) error {
+		entries++
+		if entries > maxWorldEntries {
+			return validationError("world archive contains more than %d entries", maxWorldEntries)
+		}
+		if header.Size < 0 || header.Size > maxBytes-extracted {
+			return validationError("world archive extracts to more than %d MB", maxBytes/1024/1024)
+		}
+		extracted += header.Size
+
+		name, err := cleanArchivePath(header.Name)
+		if err != nil {
+			return err
+		}
+		if name == "" || name == "__MACOSX" || strings.HasPrefix(name, "__MACOSX/") || path.Base(name) == ".DS_Store" {
+			return nil
+		}
+		header.Name = name
+		return fn(header, r)
+	}
+
+	if bytes.HasPrefix(magic, []byte("PK\x03\x04")) || bytes.HasPrefix(magic, []byte("PK\x05\x06")) {
+		zr, err := zip.NewReader(archive, size)
+		if err != nil {
+			return validationError("invalid zip archive: %v", err)
+		}
+		for _, file := range zr.File {
+			header := &tar.Header{Name: file.Name, ModTime: file.Modified, Size: int64(file.UncompressedSize64)}
+			if file.FileInfo().IsDir() {
+				header.Typeflag = tar.TypeDir
+				if err := visit(header, nil); err != nil {
+					return err
+				}
+				continue
+			}
+			if !file.Mode().IsRegular() {
+				continue
+			}
+
+			header.Typeflag = tar.TypeReg
+			rc, err := file.Open()
+			if err != nil {
+				return validationError("invalid zip archive: %v", err)
+			}
+			err = visit(header, rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read world archive: %w", err)
+	}
+	var r io.Reader = archive
+	if bytes.HasPrefix(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(archive)
+		if err != nil {
+			return validationError("invalid gzip archive: %v", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return validationError("unsupported world archive, expected zip, tar or tar.gz: %v", err)
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeDir {
+			continue
+		}
+		if err := visit(header, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// WorldExport streams the worlds of a server. Close must be called once it is written.
+type WorldExport struct {
+	Server  *models.MinecraftServer
+	Worlds  []string // World folders in the export
+	streams []io.ReadCloser
+	release func()
+}
+
+// OpenWorldExport prepares the export of a server's world folders. The server is locked until the export is closed.
+// With saveWorld a running server flushes the world to disk first and doesn't save until the export is closed.
+func (s *WorldService) OpenWorldExport(ctx context.Context, serverID string, saveWorld bool) (*WorldExport, error) {
+	unlock, err := s.mcService.locks.LockOrConflict(serverID)
+	if err != nil {
+		return nil, err
+	}
+	export := &WorldExport{release: unlock}
+
+	server, err := s.mcService.repo.FindByID(serverID)
+	if err != nil {
+		export.Close()
+		return nil, err
+	}
+	export.Server = server
+
+	state, err := s.dockerService.GetContainerState(ctx, server.ContainerID)
+	if err != nil {
+		export.Close()
+		return nil, fmt.Errorf("failed to get container state: %w", err)
+	}
+	if saveWorld && state.Running {
+		resumeSaving, err := s.mcService.suspendSaving(ctx, server)
+		if err != nil {
+			export.Close()
+			return nil, err
+		}
+		export.release = func() {
+			resumeSaving()
+			unlock()
+		}
+	}
+
+	for _, folder := range models.WorldFolders {
+		stream, err := s.dockerService.ExportVolumePath(ctx, server.VolumeID, folder)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			export.Close()
+			return nil, err
+		}
+		export.Worlds = append(export.Worlds, folder)
+		export.streams = append(export.streams, stream)
+	}
+	if len(export.streams) == 0 {
+		export.Close()
+		return nil, conflictError("server has no world yet, start it once to generate one")
+	}
+
+	s.logger.InfoContext(ctx, "Exporting world",
+		"server_id", server.ID,
+		"worlds", export.Worlds,
+		"world_saved", saveWorld && state.Running)
+
+	return export, nil
+}
+
+// WriteZip writes the world folders as zip archive, which can be imported into another server
+func (e *WorldExport) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for _, stream := range e.streams {
+		tr := tar.NewReader(stream)
+		for {
+			header, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read world: %w", err)
+			}
+
+			switch header.Typeflag {
+			case tar.TypeDir:
+				fh := &zip.FileHeader{Name: strings.TrimSuffix(header.Name, "/") + "/", Method: zip.Store, Modified: header.ModTime}
+				fh.SetMode(fs.ModeDir | 0o755)
+				if _, err := zw.CreateHeader(fh); err != nil {
+					return fmt.Errorf("failed to write world archive: %w", err)
+				}
+			case tar.TypeReg:
+				fh := &zip.FileHeader{Name: header.Name, Method: zip.Deflate, Modified: header.ModTime}
+				fh.SetMode(0o644)
+				fw, err := zw.CreateHeader(fh)
+				if err != nil {
+					return fmt.Errorf("failed to write world archive: %w", err)
+				}
+				if _, err := io.Copy(fw, tr); err != nil {
+					return fmt.Errorf("failed to write world archive: %w", err)
+				}
+			}
+		}
+	}
+	return zw.Close()
+}
+
+// Close releases the volume streams and the server, and turns saving back on
+func (e *WorldExport) Close() {
+	for _, stream := range e.streams {
+		stream.Close()
+	}
+	e.streams = nil
+	if e.release != nil {
+		e.release()
+		e.release = nil
+	}
+}
+
+// FileName returns the file name the export is offered as
+func (e *WorldExport) FileName() string {
+	return fmt.Sprintf("%s-world-%s.zip", e.Server.Name, time.Now().Format("20060102-150405"))
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mlhmz/dockermc-cloud-manager/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanArchivePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		invalid bool
+	}{
+		{name: "file", path: "world/level.dat", want: "world/level.dat"},
+		{name: "directory", path: "world/region/", want: "world/region"},
+		{name: "current directory", path: "./world/./level.dat", want: "world/level.dat"},
+		{name: "absolute path stays in the archive", path: "/etc/passwd", want: "etc/passwd"},
+		{name: "windows separators", path: `MyWorld\region\r.0.0.mca`, want: "MyWorld/region/r.0.0.mca"},
+		{name: "root", path: "./", want: ""},
+		{name: "parent", path: "../level.dat", invalid: true},
+		{name: "parent inside", path: "world/../../etc/passwd", invalid: true},
+		{name: "parent that stays inside", path: "world/../level.dat", invalid: true},
+		{name: "absolute parent", path: "/../etc/passwd", invalid: true},
+		{name: "windows parent", path: `world\..\..\evil`, invalid: true},
+		{name: "dots in names", path: "world/..data/level.dat", want: "world/..data/level.dat"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, err := cleanArchivePath(tt.path)
+			if tt.invalid {
+				assert.ErrorIs(t, err, ErrValidation)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, name)
+		})
+	}
+}
+
+func TestDetectWorldLayout(t *testing.T) {
+	tests := []struct {
+		name     string
+		entries  []string // Directories end with a slash
+		layout   models.WorldLayout
+		mappings []worldMapping
+		message  string // Part of the error, empty if a layout is found
+	}{
+		{
+			name:     "singleplayer save",
+			entries:  []string{"level.dat", "region/r.0.0.mca", "DIM-1/region/r.0.0.mca"},
+			layout:   models.WorldLayoutSingleplayer,
+			mappings: []worldMapping{{source: "", target: "world"}},
+		},
+		{
+			name:     "singleplayer save in a folder",
+			entries:  []string{"My World/", "My World/level.dat", "My World/region/r.0.0.mca"},
+			layout:   models.WorldLayoutSingleplayer,
+			mappings: []worldMapping{{source: "My World", target: "world"}},
+		},
+		{
+			name:     "singleplayer save in nested folders without directory entries",
+			entries:  []string{"backup/2026/My World/level.dat", "backup/2026/My World/region/r.0.0.mca"},
+			layout:   models.WorldLayoutSingleplayer,
+			mappings: []worldMapping{{source: "backup/2026/My World", target: "world"}},
+		},
+		{
+			name:     "singleplayer save next to other files",
+			entries:  []string{"README.txt", "My World/level.dat", "My World/region/r.0.0.mca"},
+			layout:   models.WorldLayoutSingleplayer,
+			mappings: []worldMapping{{source: "My World", target: "world"}},
+		},
+		{
+			name:     "vanilla server",
+			entries:  []string{"world/level.dat", "world/region/r.0.0.mca", "world/DIM-1/region/r.0.0.mca", "server.properties"},
+			layout:   models.WorldLayoutServer,
+			mappings: []worldMapping{{source: "world", target: "world"}},
+		},
+		{
+			name: "bukkit split worlds",
+			entries: []string{
+				"world/level.dat", "world/region/r.0.0.mca",
+				"world_nether/level.dat", "world_nether/DIM-1/region/r.0.0.mca",
+				"world_the_end/level.dat", "world_the_end/DIM1/region/r.0.0.mca",
+				"plugins/Essentials/config.yml",
+			},
+			layout: models.WorldLayoutServer,
+			mappings: []worldMapping{
+				{source: "world", target: "world"},
+				{source: "world_nether", target: "world_nether"},
+				{source: "world_the_end", target: "world_the_end"},
+			},
+		},
+		{
+			name:    "bukkit split worlds in a folder",
+			entries: []string{"lobby/", "lobby/world/level.dat", "lobby/world_nether/level.dat"},
+			layout:  models.WorldLayoutServer,
+			mappings: []worldMapping{
+				{source: "lobby/world", target: "world"},
+				{source: "lobby/world_nether", target: "world_nether"},
+			},
+		},
+		{
+			name:    "multiple saves",
+			entries: []string{"First/level.dat", "Second/level.dat"},
+			message: "multiple worlds",
+		},
+		{
+			name:    "no world",
+			entries: []string{"plugins/Essentials/config.yml", "server.properties"},
+			message: "no world found",
+		},
+		{
+			name:    "empty",
+			message: "no world found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := make([]worldArchiveEntry, len(tt.entries))
+			for i, name := range tt.entries {
+				entries[i] = worldArchiveEntry{name: strings.TrimSuffix(name, "/"), dir: strings.HasSuffix(name, "/")}
+			}
+
+			layout, mappings, err := detectWorldLayout(entries)
+			if tt.message != "" {
+				assert.ErrorIs(t, err, ErrValidation)
+				assert.ErrorContains(t, err, tt.message)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.layout, layout)
+			assert.Equal(t, tt.mappings, mappings)
+		})
+	}
+}
+
+func TestMapWorldEntry(t *testing.T) {
+	mappings := []worldMapping{{source: "lobby/world", target: "world"}, {source: "lobby/world_nether", target: "world_nether"}}
+
+	name, ok := mapWorldEntry("lobby/world/region/r.0.0.mca", mappings)
+	assert.True(t, ok)
+	assert.Equal(t, "world/region/r.0.0.mca", name)
+
+	name, ok = mapWorldEntry("lobby/world_nether", mappings)
+	assert.True(t, ok)
+	assert.Equal(t, "world_nether", name)
+
+	// A folder whose name starts like a world isn't part of it
+	_, ok = mapWorldEntry("lobby/world_old/level.dat", mappings)
+	assert.False(t, ok)
+
+	name, ok = mapWorldEntry("region/r.0.0.mca", []worldMapping{{source: "", target: "world"}})
+	assert.True(t, ok)
+	assert.Equal(t, "world/region/r.0.0.mca", name)
+}
+
+// worldArchiveFile is an entry of a test archive
+type worldArchiveFile struct {
+	header  tar.Header
+	content string
+}
+
+// createTarArchive writes a tar archive with the files to a temporary file
+func createTarArchive(t *testing.T, files []worldArchiveFile) (*os.File, int64) {
+	t.Helper()
+
+	archive, err := os.CreateTemp(t.TempDir(), "world-*.tar")
+	require.NoError(t, err)
+	t.Cleanup(func() { archive.Close() })
+
+	tw := tar.NewWriter(archive)
+	for _, file := range files {
+		header := file.header
+		if header.Typeflag == tar.TypeReg {
+			header.Size = int64(len(file.content))
+		}
+		require.NoError(t, tw.WriteHeader(&header))
+		_, err := tw.Write([]byte(file.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+
+	size, err := archive.Seek(0, io.SeekCurrent)
+	require.NoError(t, err)
+	return archive, size
+}
+
+// walkedEntries returns the names of the entries walkWorldArchive passes on
+func walkedEntries(archive *os.File, size, maxBytes int64) ([]string, error) {
+	var names []string
+	err := walkWorldArchive(archive, size, maxBytes, func(header *tar.Header, _ io.Reader) error {
+		names = append(names, header.Name)
+		return nil
+	})
+	return names, err
+}
+
+func TestWalkWorldArchiveSkipsLinks(t *testing.T) {
+	archive, size := createTarArchive(t, []worldArchiveFile{
+		{header: tar.Header{Typeflag: tar.TypeDir, Name: "world/", Mode: 0o755}},
+		{header: tar.Header{Typeflag: tar.TypeReg, Name: "world/level.dat", Mode: 0o644}, content: "level"},
+		{header: tar.Header{Typeflag: tar.TypeSymlink, Name: "world/region", Linkname: "/etc"}},
+		{header: tar.Header{Typeflag: tar.TypeLink, Name: "world/passwd", Linkname: "/etc/passwd"}},
+		{header: tar.Header{Typeflag: tar.TypeChar, Name: "world/null", Devmajor: 1, Devminor: 3}},
+		{header: tar.Header{Typeflag: tar.TypeReg, Name: "__MACOSX/world/._level.dat", Mode: 0o644}, content: "meta"},
+	})
+
+	names, err := walkedEntries(archive, size, 1<<20)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"world", "world/level.dat"}, names)
+}
+
+func TestWalkWorldArchiveRejectsTraversal(t *testing.T) {
+	archive, size := createTarArchive(t, []worldArchiveFile{
+		{header: tar.Header{Typeflag: tar.TypeReg, Name: "world/level.dat", Mode: 0o644}, content: "level"},
+		{header: tar.Header{Typeflag: tar.TypeReg, Name: "world/../../evil.sh", Mode: 0o755}, content: "evil"},
+	})
+
+	_, err := walkedEntries(archive, size, 1<<20)
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.ErrorContains(t, err, "outside of the archive")
+}
+
+func TestWalkWorldArchiveLimits(t *testing.T) {
+	archive, size := createTarArchive(t, []worldArchiveFile{
+		{header: tar.Header{Typeflag: tar.TypeReg, Name: "world/level.dat", Mode: 0o644}, content: strings.Repeat("a", 600)},
+		{header: tar.Header{Typeflag: tar.TypeReg, Name: "world/region/r.0.0.mca", Mode: 0o644}, content: strings.Repeat("b", 600)},
+	})
+
+	_, err := walkedEntries(archive, size, 1200)
+	assert.NoError(t, err)
+
+	_, err = walkedEntries(archive, size, 1000)
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.ErrorContains(t, err, "extracts to more than")
+}
+
+func TestWalkWorldArchiveZipBomb(t *testing.T) {
+	archive, err := os.CreateTemp(t.TempDir(), "world-*.zip")
+	require.NoError(t, err)
+	defer archive.Close()
+
+	// Zeros compress to about a thousandth of their size
+	zw := zip.NewWriter(archive)
+	w, err := zw.Create("world/level.dat")
+	require.NoError(t, err)
+	_, err = w.Write(make([]byte, 4<<20))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	size, err := archive.Seek(0, io.SeekCurrent)
+	require.NoError(t, err)
+	require.Less(t, size, int64(1<<20))
+
+	_, err = walkedEntries(archive, size, 4*size)
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.ErrorContains(t, err, "extracts to more than")
+}